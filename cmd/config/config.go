@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -18,25 +21,132 @@ type DBConfig struct {
 	URL      string
 }
 
+type RetentionConfig struct {
+	Sailings          time.Duration // How long route rows are kept past their service date
+	Snapshots         time.Duration // How long historical route snapshots are kept
+	FillHistory       time.Duration // How long fill samples of capacity sailings are kept
+	Departures        time.Duration // How long actual departure times are kept past their service date
+	ScrapeRuns        time.Duration // How long scrape run records are kept
+	WebhookDeliveries time.Duration // How long webhook delivery attempts are kept
+}
+
+type FetcherConfig struct {
+	Timeout      time.Duration // Per-request timeout
+	UserAgent    string        // User-Agent header, empty for the backend's default
+	Retries      int           // Retries after a failed request (0 = no retries)
+	RetryBackoff time.Duration // Delay before the first retry, doubled on each further retry
+}
+
+type StatusConfig struct {
+	DegradedAfter  time.Duration // Data older than this reports "degraded"
+	UnhealthyAfter time.Duration // Data older than this reports "unhealthy"
+}
+
+type WebhookConfig struct {
+	AdminToken   string        // Bearer token for the /v2/webhooks endpoints, which are disabled without one
+	Timeout      time.Duration // Per-delivery timeout
+	MaxAttempts  int           // Delivery attempts before giving up
+	RetryBackoff time.Duration // Delay before the first retry, doubled on each further retry
+}
+
+type ScrapeConfig struct {
+	Concurrency  int           // Routes scraped at once
+	RouteTimeout time.Duration // Deadline for all requests made for one route
+	RateLimit    int           // Requests per second to the upstream site, 0 = unlimited
+}
+
+// Default upstream site scraped for sailings and schedules
+const DefaultBCFBaseURL = "https://www.bcferries.com"
+
+// Desktop browser User-Agent sent by the HTTP fetcher by default
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+// Supported values for STORE
+const (
+	StorePostgres = "postgres"
+	StoreMemory   = "memory"
+)
+
+// Supported values for FETCHER_CAPACITY and FETCHER_NONCAPACITY
+const (
+	FetcherHTTP     = "http"     // Plain HTTP requests
+	FetcherChromedp = "chromedp" // Headless Chrome, runs JavaScript
+	FetcherFile     = "file"     // Recorded pages from FIXTURE_DIR
+)
+
 var (
-	DB         DBConfig
-	ServerPort string
+	Store               string // Storage backend: StorePostgres (default) or StoreMemory
+	DB                  DBConfig
+	ServerPort          string
+	Retention           RetentionConfig
+	ScheduleHorizonDays int
+	StreamReplayEvents  = 1000              // Change events kept for /v2/stream clients resuming with Last-Event-ID
+	BCFBaseURL          = DefaultBCFBaseURL // Upstream site, e.g. a local cmd/fakebcf server
+	CatalogueFile       string              // Terminal, leg and route catalogue replacing the built-in one
+	MigrateOnStart      bool                // Apply pending schema migrations when the server starts
+
+	// Freshness thresholds for /v2/status and /healthcheck
+	Status = StatusConfig{
+		DegradedAfter:  3 * time.Hour,
+		UnhealthyAfter: 24 * time.Hour,
+	}
+
+	// Webhook deliveries
+	Webhooks = WebhookConfig{
+		Timeout:      10 * time.Second,
+		MaxAttempts:  5,
+		RetryBackoff: 30 * time.Second,
+	}
+
+	// Worker pool and upstream rate limit
+	Scrape = ScrapeConfig{
+		Concurrency:  4,
+		RouteTimeout: 3 * time.Minute,
+		RateLimit:    4,
+	}
+
+	// Page fetchers used for each route family
+	CapacityFetcher    = FetcherHTTP
+	NonCapacityFetcher = FetcherChromedp
+
+	// Per-backend fetcher settings
+	HTTPFetcher = FetcherConfig{
+		Timeout:      30 * time.Second,
+		UserAgent:    DefaultUserAgent,
+		Retries:      2,
+		RetryBackoff: time.Second,
+	}
+	ChromedpFetcher = FetcherConfig{
+		Timeout:      60 * time.Second,
+		Retries:      1,
+		RetryBackoff: 2 * time.Second,
+	}
+	FixtureDir = "cmd/fakebcf/site" // Recorded pages read by the file fetcher
 )
 
 /*
  * LoadEnv
  *
- * Loads environment variables from a `.env` file using godotenv.
+ * Loads environment variables from a `.env` file using godotenv, if present.
  *
- * Populates the DB configuration and server port. Constructs the database URL
- * using the retrieved values. Logs a fatal error and exits if any required DB
- * variables are missing or if the `.env` file cannot be loaded.
+ * Populates the storage backend, DB configuration and server port. Constructs the
+ * database URL using the retrieved values. Logs a fatal error and exits if STORE is
+ * unknown, or if the PostgreSQL store is used and any required DB variables are missing.
  *
  * @return void
  */
 func LoadEnv() {
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+		log.Println("INFO: No .env file loaded, using environment variables")
+	}
+
+	// Storage backend
+	Store = os.Getenv("STORE")
+	if Store == "" {
+		Store = StorePostgres
+	}
+	if Store != StorePostgres && Store != StoreMemory {
+		log.Fatalf("Invalid STORE %q, expected %q or %q", Store, StorePostgres, StoreMemory)
 	}
 
 	// DB config
@@ -49,12 +159,223 @@ func LoadEnv() {
 		SSL:      os.Getenv("DB_SSL"),
 	}
 
-	if DB.User == "" || DB.Password == "" || DB.Host == "" || DB.Port == "" || DB.Database == "" || DB.SSL == "" {
+	if Store == StorePostgres && (DB.User == "" || DB.Password == "" || DB.Host == "" || DB.Port == "" || DB.Database == "" || DB.SSL == "") {
 		log.Fatal("Missing required SQL environment variables")
 	}
 
 	DB.URL = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s", DB.User, DB.Password, DB.Host, DB.Port, DB.Database, DB.SSL)
 
+	// Schema migrations (otherwise applied with the migrate command)
+	MigrateOnStart = getBool("MIGRATE_ON_START", false)
+
 	// Port
 	ServerPort = os.Getenv("PORT")
+
+	// Retention policy
+	Retention = RetentionConfig{
+		Sailings:    getDuration("RETENTION_SAILINGS", 48*time.Hour),
+		Snapshots:   getDuration("RETENTION_SNAPSHOTS", 14*24*time.Hour),
+		FillHistory: getDuration("RETENTION_FILL_HISTORY", 90*24*time.Hour),
+		Departures:  getDuration("RETENTION_DEPARTURES", 90*24*time.Hour),
+		ScrapeRuns:  getDuration("RETENTION_SCRAPE_RUNS", 14*24*time.Hour),
+
+		WebhookDeliveries: getDuration("RETENTION_WEBHOOK_DELIVERIES", 14*24*time.Hour),
+	}
+
+	// Number of days (starting today) of non-capacity schedules to scrape
+	ScheduleHorizonDays = getInt("SCHEDULE_HORIZON_DAYS", 14)
+
+	// Change events kept for replay
+	StreamReplayEvents = getInt("STREAM_REPLAY_EVENTS", StreamReplayEvents)
+
+	// Upstream BC Ferries site (without trailing slash)
+	if baseURL := os.Getenv("BCF_BASE_URL"); baseURL != "" {
+		BCFBaseURL = strings.TrimRight(baseURL, "/")
+	}
+
+	// Terminal, leg and route catalogue (empty = built-in)
+	CatalogueFile = os.Getenv("CATALOGUE_FILE")
+
+	// Freshness thresholds
+	Status = StatusConfig{
+		DegradedAfter:  getDuration("STATUS_DEGRADED_AFTER", Status.DegradedAfter),
+		UnhealthyAfter: getDuration("STATUS_UNHEALTHY_AFTER", Status.UnhealthyAfter),
+	}
+
+	// Webhook deliveries
+	Webhooks = WebhookConfig{
+		AdminToken:   os.Getenv("WEBHOOK_ADMIN_TOKEN"),
+		Timeout:      getDuration("WEBHOOK_TIMEOUT", Webhooks.Timeout),
+		MaxAttempts:  getInt("WEBHOOK_MAX_ATTEMPTS", Webhooks.MaxAttempts),
+		RetryBackoff: getDuration("WEBHOOK_RETRY_BACKOFF", Webhooks.RetryBackoff),
+	}
+
+	// Worker pool and upstream rate limit
+	Scrape = ScrapeConfig{
+		Concurrency:  getInt("SCRAPE_CONCURRENCY", Scrape.Concurrency),
+		RouteTimeout: getDuration("SCRAPE_ROUTE_TIMEOUT", Scrape.RouteTimeout),
+		RateLimit:    getNonNegativeInt("SCRAPE_RATE_LIMIT", Scrape.RateLimit),
+	}
+
+	// Page fetchers
+	CapacityFetcher = getFetcher("FETCHER_CAPACITY", CapacityFetcher)
+	NonCapacityFetcher = getFetcher("FETCHER_NONCAPACITY", NonCapacityFetcher)
+	HTTPFetcher = getFetcherConfig("HTTP", HTTPFetcher)
+	ChromedpFetcher = getFetcherConfig("CHROMEDP", ChromedpFetcher)
+	if dir := os.Getenv("FIXTURE_DIR"); dir != "" {
+		FixtureDir = dir
+	}
+}
+
+/*
+ * getFetcher
+ *
+ * Reads a fetcher backend name from the environment.
+ * Returns the fallback when the variable is unset, and logs a fatal error
+ * if the backend is unknown.
+ *
+ * @param string key - environment variable name
+ * @param string fallback - backend used when the variable is unset
+ *
+ * @return string
+ */
+func getFetcher(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	if value != FetcherHTTP && value != FetcherChromedp && value != FetcherFile {
+		log.Fatalf("Invalid %s %q, expected %q, %q or %q", key, value, FetcherHTTP, FetcherChromedp, FetcherFile)
+	}
+
+	return value
+}
+
+/*
+ * getFetcherConfig
+ *
+ * Reads the settings of a fetcher backend from <prefix>_TIMEOUT, <prefix>_USER_AGENT,
+ * <prefix>_RETRIES and <prefix>_RETRY_BACKOFF, keeping the defaults for unset variables.
+ *
+ * @param string prefix - e.g. "HTTP"
+ * @param FetcherConfig defaults
+ *
+ * @return FetcherConfig
+ */
+func getFetcherConfig(prefix string, defaults FetcherConfig) FetcherConfig {
+	cfg := FetcherConfig{
+		Timeout:      getDuration(prefix+"_TIMEOUT", defaults.Timeout),
+		UserAgent:    defaults.UserAgent,
+		Retries:      getNonNegativeInt(prefix+"_RETRIES", defaults.Retries),
+		RetryBackoff: getDuration(prefix+"_RETRY_BACKOFF", defaults.RetryBackoff),
+	}
+	if userAgent := os.Getenv(prefix + "_USER_AGENT"); userAgent != "" {
+		cfg.UserAgent = userAgent
+	}
+	return cfg
+}
+
+/*
+ * getDuration
+ *
+ * Reads a Go duration string (e.g. "48h", "90m") from the environment.
+ * Returns the fallback when the variable is unset, and logs a fatal error
+ * if the value cannot be parsed or is not positive.
+ *
+ * @param string key - environment variable name
+ * @param time.Duration fallback - value used when the variable is unset
+ *
+ * @return time.Duration
+ */
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("Invalid duration for %s: %q", key, value)
+	}
+
+	return duration
+}
+
+/*
+ * getBool
+ *
+ * Reads a boolean ("true", "false", "1", "0", ...) from the environment.
+ * Returns the fallback when the variable is unset, and logs a fatal error
+ * if the value cannot be parsed.
+ *
+ * @param string key - environment variable name
+ * @param bool fallback - value used when the variable is unset
+ *
+ * @return bool
+ */
+func getBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid boolean for %s: %q", key, value)
+	}
+
+	return b
+}
+
+/*
+ * getInt
+ *
+ * Reads a positive integer from the environment.
+ * Returns the fallback when the variable is unset, and logs a fatal error
+ * if the value cannot be parsed or is not positive.
+ *
+ * @param string key - environment variable name
+ * @param int fallback - value used when the variable is unset
+ *
+ * @return int
+ */
+func getInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		log.Fatalf("Invalid integer for %s: %q", key, value)
+	}
+
+	return number
+}
+
+/*
+ * getNonNegativeInt
+ *
+ * Reads an integer that may be zero from the environment.
+ * Returns the fallback when the variable is unset, and logs a fatal error
+ * if the value cannot be parsed or is negative.
+ *
+ * @param string key - environment variable name
+ * @param int fallback - value used when the variable is unset
+ *
+ * @return int
+ */
+func getNonNegativeInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		log.Fatalf("Invalid integer for %s: %q", key, value)
+	}
+
+	return number
 }
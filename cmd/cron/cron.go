@@ -13,37 +13,39 @@ import (
  * Initializes and starts scheduled background scraping tasks using gocron.
  *
  * - Scrapes non-capacity route data immediately on startup, then every 1 hour.
- * - Cleans up sailing records older than 48 hours every 6 hours.
+ * - Applies the retention policy (old sailings and history snapshots) every 6 hours.
  * - Capacity route scraping is disabled (not needed for Southern Gulf Islands focus).
  *
  * The scheduler runs asynchronously in the background.
  *
+ * @param *scraper.Scraper sc - scraper that saves to the configured store
+ *
  * @return void
  */
-func SetupCron() {
+func SetupCron(sc *scraper.Scraper) {
 	s := gocron.NewScheduler(time.UTC)
 
 	// Run non-capacity scraper immediately on startup
-	go scraper.ScrapeNonCapacityRoutes()
+	go sc.ScrapeNonCapacityRoutes()
 
 	// Run cleanup immediately on startup
-	go scraper.CleanupOldSailings()
+	go sc.CleanupOldSailings()
 
 	// Schedule non-capacity routes every 1 hour
 	s.Every(1).Hour().Do(func() {
-		scraper.ScrapeNonCapacityRoutes()
+		sc.ScrapeNonCapacityRoutes()
 	})
 
-	// Schedule database cleanup every 6 hours to remove old sailing data
+	// Schedule database cleanup every 6 hours to remove data outside the retention policy
 	s.Every(6).Hours().Do(func() {
-		scraper.CleanupOldSailings()
+		sc.CleanupOldSailings()
 	})
 
 	// Capacity scraping disabled - not needed for Southern Gulf Islands
 	// Uncomment below if you need capacity routes in the future:
-	// go scraper.ScrapeCapacityRoutes()
+	// go sc.ScrapeCapacityRoutes()
 	// s.Every(1).Minute().Do(func() {
-	//     scraper.ScrapeCapacityRoutes()
+	//     sc.ScrapeCapacityRoutes()
 	// })
 
 	s.StartAsync()
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/lib/pq"
+)
+
+/*
+ * SaveSailingDepartures
+ *
+ * Upserts the departures of a capacity route's sailings that have left (see
+ * models.CapacityDepartures) into `sailing_departures`. An ETA or arrival already
+ * recorded is kept when the page no longer shows it.
+ *
+ * @param models.CapacityRoute route
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveSailingDepartures(route models.CapacityRoute) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range models.CapacityDepartures(route) {
+		_, err := tx.Exec(`
+			INSERT INTO sailing_departures (
+				sailing_id, route_code, service_date, vessel_name, scheduled_departure,
+				actual_departure, eta, actual_arrival, delay_minutes, updated_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+			ON CONFLICT (sailing_id) DO UPDATE SET
+				vessel_name = EXCLUDED.vessel_name,
+				scheduled_departure = EXCLUDED.scheduled_departure,
+				actual_departure = EXCLUDED.actual_departure,
+				eta = COALESCE(EXCLUDED.eta, sailing_departures.eta),
+				actual_arrival = COALESCE(EXCLUDED.actual_arrival, sailing_departures.actual_arrival),
+				delay_minutes = EXCLUDED.delay_minutes,
+				updated_at = NOW()`,
+			d.SailingID, d.RouteCode, d.Date, d.VesselName, d.ScheduledDeparture,
+			d.ActualDeparture, d.ETA, d.ActualArrival, d.DelayMinutes,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+/*
+ * GetSailingDepartures
+ *
+ * Retrieves recorded departures between two service dates.
+ *
+ * @param string fromDate - first service date, YYYY-MM-DD
+ * @param string toDate - last service date, YYYY-MM-DD
+ * @param []string routeCodes - only these routes; all when empty
+ *
+ * @return []models.SailingDeparture - ordered by scheduled departure
+ */
+func (s *PostgresStore) GetSailingDepartures(fromDate, toDate string, routeCodes []string) []models.SailingDeparture {
+	departures := []models.SailingDeparture{}
+
+	sqlStatement := `
+		SELECT sailing_id, route_code, to_char(service_date, 'YYYY-MM-DD'), vessel_name, scheduled_departure,
+			actual_departure, eta, actual_arrival, delay_minutes
+		FROM sailing_departures
+		WHERE service_date BETWEEN $1::date AND $2::date
+			AND (cardinality($3::text[]) = 0 OR route_code = ANY($3::text[]))
+		ORDER BY scheduled_departure, sailing_id`
+
+	rows, err := s.conn.Query(sqlStatement, fromDate, toDate, pq.Array(nonNil(routeCodes)))
+	if err != nil {
+		log.Printf("GetSailingDepartures: query failed: %v", err)
+		return departures
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.SailingDeparture
+		var eta, actualArrival sql.NullTime
+		err := rows.Scan(&d.SailingID, &d.RouteCode, &d.Date, &d.VesselName, &d.ScheduledDeparture,
+			&d.ActualDeparture, &eta, &actualArrival, &d.DelayMinutes)
+		if err != nil {
+			log.Printf("GetSailingDepartures: scan failed: %v", err)
+			continue
+		}
+		if eta.Valid {
+			d.ETA = &eta.Time
+		}
+		if actualArrival.Valid {
+			d.ActualArrival = &actualArrival.Time
+		}
+		departures = append(departures, d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetSailingDepartures: rows error: %v", err)
+	}
+
+	return departures
+}
+
+/*
+ * DeleteSailingDeparturesBefore
+ *
+ * Deletes departures with a service date before the given date.
+ *
+ * @param string date - YYYY-MM-DD
+ *
+ * @return int64 - number of departures deleted
+ * @return error
+ */
+func (s *PostgresStore) DeleteSailingDeparturesBefore(date string) (int64, error) {
+	result, err := s.conn.Exec(`DELETE FROM sailing_departures WHERE service_date < $1`, date)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
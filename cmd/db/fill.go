@@ -0,0 +1,169 @@
+package db
+
+import (
+	"log"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+const fillSampleColumns = `sailing_id, route_code, to_char(service_date, 'YYYY-MM-DD'), scheduled_time, departure_minutes,
+	sampled_at, minutes_before_departure, sailing_status, fill, car_fill, oversize_fill`
+
+/*
+ * SaveFillSamples
+ *
+ * Appends the fill of a capacity route's sailings that have not departed yet (see
+ * models.FillHistories) to `sailing_fill_samples`. A sample identical to the sailing's
+ * latest one is skipped, so the history only grows when the fill changes.
+ *
+ * @param models.CapacityRoute route
+ * @param time.Time sampledAt - when the route was scraped
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveFillSamples(route models.CapacityRoute, sampledAt time.Time) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, history := range models.FillHistories(route, sampledAt) {
+		sample := history.Samples[0]
+		_, err := tx.Exec(`
+			INSERT INTO sailing_fill_samples (
+				sailing_id, route_code, service_date, scheduled_time, departure_minutes,
+				sampled_at, minutes_before_departure, sailing_status, fill, car_fill, oversize_fill
+			)
+			SELECT $1::varchar, $2::varchar, $3::date, $4::varchar, $5::integer, $6::timestamptz, $7::integer, $8::varchar, $9::integer, $10::integer, $11::integer
+			WHERE NOT EXISTS (
+				SELECT 1 FROM (
+					SELECT sailing_status, fill, car_fill, oversize_fill FROM sailing_fill_samples
+					WHERE sailing_id = $1::varchar
+					ORDER BY sampled_at DESC
+					LIMIT 1
+				) latest
+				WHERE latest.sailing_status = $8::varchar AND latest.fill = $9::integer
+					AND latest.car_fill = $10::integer AND latest.oversize_fill = $11::integer
+			)
+			ON CONFLICT (sailing_id, sampled_at) DO NOTHING`,
+			history.SailingID, history.RouteCode, history.Date, history.ScheduledTime, history.DepartureMinutes,
+			sample.SampledAt, sample.MinutesBeforeDeparture, sample.SailingStatus, sample.Fill, sample.CarFill, sample.OversizeFill,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+/*
+ * GetFillHistory
+ *
+ * Retrieves a sailing's fill curve.
+ *
+ * @param string sailingID
+ *
+ * @return *models.FillHistory - nil if the sailing was never sampled
+ */
+func (s *PostgresStore) GetFillHistory(sailingID string) *models.FillHistory {
+	histories := s.queryFillHistories("GetFillHistory", `
+		SELECT `+fillSampleColumns+`
+		FROM sailing_fill_samples
+		WHERE sailing_id = $1
+		ORDER BY sampled_at`, sailingID)
+	if len(histories) == 0 {
+		return nil
+	}
+	return &histories[0]
+}
+
+/*
+ * GetFillHistories
+ *
+ * Retrieves the fill curves of a route's past sailings that left at the same time on
+ * the same weekday, for predictions.
+ *
+ * @param string routeCode
+ * @param int departureMinutes - timetabled departure, in minutes after midnight
+ * @param time.Weekday weekday - of the service date
+ * @param string beforeDate - only service dates before this one, YYYY-MM-DD
+ *
+ * @return []models.FillHistory - ordered by service date
+ */
+func (s *PostgresStore) GetFillHistories(routeCode string, departureMinutes int, weekday time.Weekday, beforeDate string) []models.FillHistory {
+	return s.queryFillHistories("GetFillHistories", `
+		SELECT `+fillSampleColumns+`
+		FROM sailing_fill_samples
+		WHERE route_code = $1 AND departure_minutes = $2 AND EXTRACT(DOW FROM service_date) = $3 AND service_date < $4::date
+		ORDER BY service_date, sailing_id, sampled_at`,
+		routeCode, departureMinutes, int(weekday), beforeDate)
+}
+
+/*
+ * DeleteFillSamplesBefore
+ *
+ * Deletes fill samples taken before the cutoff.
+ *
+ * @param time.Time cutoff
+ *
+ * @return int64 - number of samples deleted
+ * @return error
+ */
+func (s *PostgresStore) DeleteFillSamplesBefore(cutoff time.Time) (int64, error) {
+	result, err := s.conn.Exec(`DELETE FROM sailing_fill_samples WHERE sampled_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+/*
+ * queryFillHistories
+ *
+ * Runs a query selecting fillSampleColumns, ordered by sailing and then sample time,
+ * and groups the samples by sailing.
+ *
+ * @param string caller - for log messages
+ * @param string sqlStatement
+ * @param ...any args
+ *
+ * @return []models.FillHistory
+ */
+func (s *PostgresStore) queryFillHistories(caller, sqlStatement string, args ...any) []models.FillHistory {
+	histories := []models.FillHistory{}
+
+	rows, err := s.conn.Query(sqlStatement, args...)
+	if err != nil {
+		log.Printf("%s: query failed: %v", caller, err)
+		return histories
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var history models.FillHistory
+		var sample models.FillSample
+		err := rows.Scan(
+			&history.SailingID, &history.RouteCode, &history.Date, &history.ScheduledTime, &history.DepartureMinutes,
+			&sample.SampledAt, &sample.MinutesBeforeDeparture, &sample.SailingStatus, &sample.Fill, &sample.CarFill, &sample.OversizeFill,
+		)
+		if err != nil {
+			log.Printf("%s: scan failed: %v", caller, err)
+			continue
+		}
+
+		if n := len(histories); n > 0 && histories[n-1].SailingID == history.SailingID {
+			histories[n-1].Samples = append(histories[n-1].Samples, sample)
+			continue
+		}
+		history.Samples = []models.FillSample{sample}
+		histories = append(histories, history)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("%s: rows error: %v", caller, err)
+	}
+
+	return histories
+}
@@ -0,0 +1,325 @@
+package db
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Column list shared by both snapshot tables (service_date is formatted as YYYY-MM-DD)
+const snapshotColumns = `SELECT route_code, from_terminal_code, to_terminal_code, to_char(service_date, 'YYYY-MM-DD'), sailing_duration, sailings, scraped_at`
+
+/*
+ * SaveCapacitySnapshot
+ *
+ * Appends a snapshot of a capacity route to the `capacity_route_snapshots` history table.
+ * Snapshots are keyed by route code, service date and scrape time, so every scrape
+ * is kept until it falls outside the retention policy.
+ *
+ * @param models.CapacityRoute route - the scraped route (route.Date is the service date)
+ * @param time.Time scrapedAt - when the route was scraped
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveCapacitySnapshot(route models.CapacityRoute, scrapedAt time.Time) error {
+	sailingsJSON, err := json.Marshal(route.Sailings)
+	if err != nil {
+		return err
+	}
+
+	sqlStatement := `
+		INSERT INTO capacity_route_snapshots (
+			route_code,
+			service_date,
+			scraped_at,
+			from_terminal_code,
+			to_terminal_code,
+			sailing_duration,
+			sailings
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (route_code, service_date, scraped_at) DO NOTHING`
+	_, err = s.conn.Exec(sqlStatement,
+		route.RouteCode, route.Date, scrapedAt, route.FromTerminalCode, route.ToTerminalCode, route.SailingDuration, sailingsJSON,
+	)
+
+	return err
+}
+
+/*
+ * SaveNonCapacitySnapshot
+ *
+ * Appends a snapshot of a non-capacity route to the `non_capacity_route_snapshots` history table.
+ *
+ * @param models.NonCapacityRoute route - the scraped route (route.Date is the service date)
+ * @param time.Time scrapedAt - when the route was scraped
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveNonCapacitySnapshot(route models.NonCapacityRoute, scrapedAt time.Time) error {
+	sailingsJSON, err := json.Marshal(route.Sailings)
+	if err != nil {
+		return err
+	}
+
+	sqlStatement := `
+		INSERT INTO non_capacity_route_snapshots (
+			route_code,
+			service_date,
+			scraped_at,
+			from_terminal_code,
+			to_terminal_code,
+			sailing_duration,
+			sailings
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (route_code, service_date, scraped_at) DO NOTHING`
+	_, err = s.conn.Exec(sqlStatement,
+		route.RouteCode, route.Date, scrapedAt, route.FromTerminalCode, route.ToTerminalCode, route.SailingDuration, sailingsJSON,
+	)
+
+	return err
+}
+
+/*
+ * GetLatestCapacitySnapshot
+ *
+ * Returns the most recent snapshot of a capacity route for a service date.
+ *
+ * @param string routeCode - e.g. "TSASWB"
+ * @param string serviceDate - ISO date (e.g. "2025-11-11")
+ *
+ * @return *models.CapacityRouteSnapshot - nil if no snapshot exists
+ */
+func (s *PostgresStore) GetLatestCapacitySnapshot(routeCode, serviceDate string) *models.CapacityRouteSnapshot {
+	snapshots := s.queryCapacitySnapshots("GetLatestCapacitySnapshot", `
+		WHERE route_code = $1 AND service_date = $2
+		ORDER BY scraped_at DESC
+		LIMIT 1`, routeCode, serviceDate)
+
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return &snapshots[0]
+}
+
+/*
+ * GetCapacitySnapshotAsOf
+ *
+ * Returns the capacity route snapshot for a service date as it was known at a given time,
+ * i.e. the latest snapshot scraped at or before asOf.
+ *
+ * @param string routeCode - e.g. "TSASWB"
+ * @param string serviceDate - ISO date (e.g. "2025-11-11")
+ * @param time.Time asOf - point in time to look back to
+ *
+ * @return *models.CapacityRouteSnapshot - nil if no snapshot exists
+ */
+func (s *PostgresStore) GetCapacitySnapshotAsOf(routeCode, serviceDate string, asOf time.Time) *models.CapacityRouteSnapshot {
+	snapshots := s.queryCapacitySnapshots("GetCapacitySnapshotAsOf", `
+		WHERE route_code = $1 AND service_date = $2 AND scraped_at <= $3
+		ORDER BY scraped_at DESC
+		LIMIT 1`, routeCode, serviceDate, asOf)
+
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return &snapshots[0]
+}
+
+/*
+ * GetCapacitySnapshots
+ *
+ * Returns every snapshot of a capacity route for a service date, oldest first.
+ *
+ * @param string routeCode - e.g. "TSASWB"
+ * @param string serviceDate - ISO date (e.g. "2025-11-11")
+ *
+ * @return []models.CapacityRouteSnapshot
+ */
+func (s *PostgresStore) GetCapacitySnapshots(routeCode, serviceDate string) []models.CapacityRouteSnapshot {
+	return s.queryCapacitySnapshots("GetCapacitySnapshots", `
+		WHERE route_code = $1 AND service_date = $2
+		ORDER BY scraped_at ASC`, routeCode, serviceDate)
+}
+
+/*
+ * GetLatestNonCapacitySnapshot
+ *
+ * Returns the most recent snapshot of a non-capacity route for a service date.
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param string serviceDate - ISO date (e.g. "2025-11-11")
+ *
+ * @return *models.NonCapacityRouteSnapshot - nil if no snapshot exists
+ */
+func (s *PostgresStore) GetLatestNonCapacitySnapshot(routeCode, serviceDate string) *models.NonCapacityRouteSnapshot {
+	snapshots := s.queryNonCapacitySnapshots("GetLatestNonCapacitySnapshot", `
+		WHERE route_code = $1 AND service_date = $2
+		ORDER BY scraped_at DESC
+		LIMIT 1`, routeCode, serviceDate)
+
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return &snapshots[0]
+}
+
+/*
+ * GetNonCapacitySnapshotAsOf
+ *
+ * Returns the non-capacity route snapshot for a service date as it was known at a given time,
+ * i.e. the latest snapshot scraped at or before asOf.
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param string serviceDate - ISO date (e.g. "2025-11-11")
+ * @param time.Time asOf - point in time to look back to
+ *
+ * @return *models.NonCapacityRouteSnapshot - nil if no snapshot exists
+ */
+func (s *PostgresStore) GetNonCapacitySnapshotAsOf(routeCode, serviceDate string, asOf time.Time) *models.NonCapacityRouteSnapshot {
+	snapshots := s.queryNonCapacitySnapshots("GetNonCapacitySnapshotAsOf", `
+		WHERE route_code = $1 AND service_date = $2 AND scraped_at <= $3
+		ORDER BY scraped_at DESC
+		LIMIT 1`, routeCode, serviceDate, asOf)
+
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return &snapshots[0]
+}
+
+/*
+ * GetNonCapacitySnapshots
+ *
+ * Returns every snapshot of a non-capacity route for a service date, oldest first.
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param string serviceDate - ISO date (e.g. "2025-11-11")
+ *
+ * @return []models.NonCapacityRouteSnapshot
+ */
+func (s *PostgresStore) GetNonCapacitySnapshots(routeCode, serviceDate string) []models.NonCapacityRouteSnapshot {
+	return s.queryNonCapacitySnapshots("GetNonCapacitySnapshots", `
+		WHERE route_code = $1 AND service_date = $2
+		ORDER BY scraped_at ASC`, routeCode, serviceDate)
+}
+
+/*
+ * DeleteSnapshotsBefore
+ *
+ * Deletes capacity and non-capacity snapshots scraped before the cutoff.
+ *
+ * @param time.Time cutoff
+ *
+ * @return int64 - number of snapshots deleted
+ * @return error
+ */
+func (s *PostgresStore) DeleteSnapshotsBefore(cutoff time.Time) (int64, error) {
+	var total int64
+
+	for _, table := range []string{"capacity_route_snapshots", "non_capacity_route_snapshots"} {
+		result, err := s.conn.Exec(`DELETE FROM `+table+` WHERE scraped_at < $1`, cutoff)
+		if err != nil {
+			return total, err
+		}
+		rowsAffected, _ := result.RowsAffected()
+		total += rowsAffected
+	}
+
+	return total, nil
+}
+
+/*
+ * queryCapacitySnapshots
+ *
+ * Runs a snapshot query against `capacity_route_snapshots` with the given filter clause.
+ *
+ * @param string caller - name used in log messages
+ * @param string clause - WHERE/ORDER/LIMIT clause appended to the SELECT
+ * @param ...any args - query arguments
+ *
+ * @return []models.CapacityRouteSnapshot
+ */
+func (s *PostgresStore) queryCapacitySnapshots(caller, clause string, args ...any) []models.CapacityRouteSnapshot {
+	var snapshots []models.CapacityRouteSnapshot
+
+	rows, err := s.conn.Query(snapshotColumns+` FROM capacity_route_snapshots `+clause, args...)
+	if err != nil {
+		log.Printf("%s: query failed: %v", caller, err)
+		return snapshots
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snapshot models.CapacityRouteSnapshot
+		var sailings []uint8
+
+		err := rows.Scan(&snapshot.RouteCode, &snapshot.FromTerminalCode, &snapshot.ToTerminalCode, &snapshot.Date, &snapshot.SailingDuration, &sailings, &snapshot.ScrapedAt)
+		if err != nil {
+			log.Printf("%s: row scan failed: %v", caller, err)
+			continue
+		}
+
+		if err := json.Unmarshal(sailings, &snapshot.Sailings); err != nil {
+			log.Printf("%s: JSON unmarshal failed: %v", caller, err)
+			continue
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("%s: row iteration error: %v", caller, err)
+	}
+
+	return snapshots
+}
+
+/*
+ * queryNonCapacitySnapshots
+ *
+ * Runs a snapshot query against `non_capacity_route_snapshots` with the given filter clause.
+ *
+ * @param string caller - name used in log messages
+ * @param string clause - WHERE/ORDER/LIMIT clause appended to the SELECT
+ * @param ...any args - query arguments
+ *
+ * @return []models.NonCapacityRouteSnapshot
+ */
+func (s *PostgresStore) queryNonCapacitySnapshots(caller, clause string, args ...any) []models.NonCapacityRouteSnapshot {
+	var snapshots []models.NonCapacityRouteSnapshot
+
+	rows, err := s.conn.Query(snapshotColumns+` FROM non_capacity_route_snapshots `+clause, args...)
+	if err != nil {
+		log.Printf("%s: query failed: %v", caller, err)
+		return snapshots
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snapshot models.NonCapacityRouteSnapshot
+		var sailings []uint8
+
+		err := rows.Scan(&snapshot.RouteCode, &snapshot.FromTerminalCode, &snapshot.ToTerminalCode, &snapshot.Date, &snapshot.SailingDuration, &sailings, &snapshot.ScrapedAt)
+		if err != nil {
+			log.Printf("%s: row scan failed: %v", caller, err)
+			continue
+		}
+
+		if err := json.Unmarshal(sailings, &snapshot.Sailings); err != nil {
+			log.Printf("%s: JSON unmarshal failed: %v", caller, err)
+			continue
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("%s: row iteration error: %v", caller, err)
+	}
+
+	return snapshots
+}
@@ -0,0 +1,973 @@
+package db
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * MemoryStore
+ *
+ * Store kept entirely in process memory. Data is lost on restart, so it is meant for
+ * tests, demos and running the API without PostgreSQL (STORE=memory).
+ *
+ * Routes are deep-copied on the way in and out, so callers can't mutate stored data,
+ * matching the behaviour of the JSON columns in PostgreSQL.
+ */
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	capacityRoutes       map[string]models.CapacityRoute               // route code → route
+	nonCapacityRoutes    map[string]map[string]models.NonCapacityRoute // route code → date → route
+	seasons              map[string]map[string]models.ScheduleSeason   // route code → effective from → season
+	capacitySnapshots    map[string][]models.CapacityRouteSnapshot     // route code + date → snapshots, oldest first
+	nonCapacitySnapshots map[string][]models.NonCapacityRouteSnapshot  // route code + date → snapshots, oldest first
+	fillHistories        map[string]models.FillHistory                 // sailing ID → samples, oldest first
+	departures           map[string]models.SailingDeparture            // sailing ID → departure
+	scrapeRuns           []models.ScrapeRun                            // oldest first
+	nextScrapeRunID      int64
+	webhooks             map[int64]models.WebhookSubscription // ID → subscription
+	nextWebhookID        int64
+	webhookDeliveries    []models.WebhookDelivery // oldest first
+	nextDeliveryID       int64
+}
+
+/*
+ * NewMemoryStore
+ *
+ * Creates an empty in-memory store.
+ *
+ * @return *MemoryStore
+ */
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		capacityRoutes:       make(map[string]models.CapacityRoute),
+		nonCapacityRoutes:    make(map[string]map[string]models.NonCapacityRoute),
+		seasons:              make(map[string]map[string]models.ScheduleSeason),
+		capacitySnapshots:    make(map[string][]models.CapacityRouteSnapshot),
+		nonCapacitySnapshots: make(map[string][]models.NonCapacityRouteSnapshot),
+		fillHistories:        make(map[string]models.FillHistory),
+		departures:           make(map[string]models.SailingDeparture),
+		webhooks:             make(map[int64]models.WebhookSubscription),
+	}
+}
+
+/*******************/
+/* Capacity routes */
+/*******************/
+
+func (m *MemoryStore) GetCapacitySailings() []models.CapacityRoute {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var routes []models.CapacityRoute
+	for _, routeCode := range sortedKeys(m.capacityRoutes) {
+		routes = append(routes, cloneCapacityRoute(m.capacityRoutes[routeCode]))
+	}
+	return routes
+}
+
+func (m *MemoryStore) GetCapacityRoute(routeCode string) *models.CapacityRoute {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	route, ok := m.capacityRoutes[routeCode]
+	if !ok {
+		return nil
+	}
+	route = cloneCapacityRoute(route)
+	return &route
+}
+
+func (m *MemoryStore) GetCapacityRoutesInfo(routeCodes []string) []models.CapacityRouteInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var routes []models.CapacityRouteInfo
+	for _, routeCode := range sortedKeys(m.capacityRoutes) {
+		if len(routeCodes) > 0 && !containsString(routeCodes, routeCode) {
+			continue
+		}
+		route := m.capacityRoutes[routeCode]
+		routes = append(routes, models.CapacityRouteInfo{
+			Date:             route.Date,
+			RouteCode:        route.RouteCode,
+			FromTerminalCode: route.FromTerminalCode,
+			ToTerminalCode:   route.ToTerminalCode,
+			SailingDuration:  route.SailingDuration,
+		})
+	}
+	return routes
+}
+
+func (m *MemoryStore) SaveCapacityRoute(route models.CapacityRoute) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.capacityRoutes[route.RouteCode] = cloneCapacityRoute(route)
+	return nil
+}
+
+func (m *MemoryStore) DeleteCapacityRoutesBefore(date string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for routeCode, route := range m.capacityRoutes {
+		if route.Date < date {
+			delete(m.capacityRoutes, routeCode)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+/***********************/
+/* Non-capacity routes */
+/***********************/
+
+func (m *MemoryStore) GetNonCapacitySailings(date string) []models.NonCapacityRoute {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var routes []models.NonCapacityRoute
+	for _, routeCode := range sortedKeys(m.nonCapacityRoutes) {
+		if route, ok := m.nonCapacityRoutes[routeCode][date]; ok {
+			routes = append(routes, cloneNonCapacityRoute(route))
+		}
+	}
+	return routes
+}
+
+func (m *MemoryStore) GetNonCapacityRoute(routeCode, date string) *models.NonCapacityRoute {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	route, ok := m.nonCapacityRoutes[routeCode][date]
+	if !ok {
+		return nil
+	}
+	route = cloneNonCapacityRoute(route)
+	return &route
+}
+
+func (m *MemoryStore) GetNonCapacityRoutesInfo(date string, routeCodes []string) []models.NonCapacityRouteInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var routes []models.NonCapacityRouteInfo
+	for _, routeCode := range sortedKeys(m.nonCapacityRoutes) {
+		if len(routeCodes) > 0 && !containsString(routeCodes, routeCode) {
+			continue
+		}
+		route, ok := m.nonCapacityRoutes[routeCode][date]
+		if !ok {
+			continue
+		}
+		routes = append(routes, models.NonCapacityRouteInfo{
+			Date:             route.Date,
+			RouteCode:        route.RouteCode,
+			FromTerminalCode: route.FromTerminalCode,
+			ToTerminalCode:   route.ToTerminalCode,
+			SailingDuration:  route.SailingDuration,
+		})
+	}
+	return routes
+}
+
+func (m *MemoryStore) SaveNonCapacityRoute(route models.NonCapacityRoute) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.nonCapacityRoutes[route.RouteCode] == nil {
+		m.nonCapacityRoutes[route.RouteCode] = make(map[string]models.NonCapacityRoute)
+	}
+	m.nonCapacityRoutes[route.RouteCode][route.Date] = cloneNonCapacityRoute(route)
+	return nil
+}
+
+func (m *MemoryStore) DeleteNonCapacityRoutesBefore(date string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for routeCode, byDate := range m.nonCapacityRoutes {
+		for routeDate := range byDate {
+			if routeDate < date {
+				delete(byDate, routeDate)
+				deleted++
+			}
+		}
+		if len(byDate) == 0 {
+			delete(m.nonCapacityRoutes, routeCode)
+		}
+	}
+	return deleted, nil
+}
+
+/************/
+/* Sailings */
+/************/
+
+// Sailing IDs start with their route code and service date, which key the routes here
+func (m *MemoryStore) GetSailings(sailingIDs []string) []models.SailingDetails {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	found := make(map[string]*models.SailingDetails)
+	for _, id := range sailingIDs {
+		routeCode, rest, _ := strings.Cut(id, "-")
+		date := rest[:min(len(rest), len("2006-01-02"))]
+
+		if route, ok := m.capacityRoutes[routeCode]; ok {
+			for _, sailing := range cloneCapacityRoute(route).Sailings {
+				if sailing.ID == id {
+					mergeSailingDetails(found, models.SailingDetails{
+						ID: id, RouteCode: routeCode, Date: route.Date, FromTerminalCode: route.FromTerminalCode,
+						ToTerminalCode: route.ToTerminalCode, SailingDuration: route.SailingDuration, Capacity: &sailing,
+					})
+					break
+				}
+			}
+		}
+		if route, ok := m.nonCapacityRoutes[routeCode][date]; ok {
+			for _, sailing := range cloneNonCapacityRoute(route).Sailings {
+				if sailing.ID == id {
+					mergeSailingDetails(found, models.SailingDetails{
+						ID: id, RouteCode: routeCode, Date: route.Date, FromTerminalCode: route.FromTerminalCode,
+						ToTerminalCode: route.ToTerminalCode, SailingDuration: route.SailingDuration, NonCapacity: &sailing,
+					})
+					break
+				}
+			}
+		}
+	}
+	return orderSailingDetails(found, sailingIDs)
+}
+
+// Matches in Go what the PostgreSQL store matches in SQL
+func (m *MemoryStore) FindSailings(filter models.SailingFilter) []models.SailingDetails {
+	type match struct {
+		id          string
+		departureAt *time.Time
+	}
+	var matches []match
+	vesselMatches := func(name string) bool {
+		return filter.VesselName == "" || strings.EqualFold(name, filter.VesselName)
+	}
+	departs := func(id, routeCode string, departureAt *time.Time) {
+		// Sailings without an ID have no row in the PostgreSQL store
+		if id == "" {
+			return
+		}
+		if len(filter.RouteCodes) > 0 && !slices.Contains(filter.RouteCodes, routeCode) {
+			return
+		}
+		if filter.FromTerminalCode != "" && !strings.HasPrefix(routeCode, filter.FromTerminalCode) {
+			return
+		}
+		if departureAt == nil && (filter.DepartAfter != nil || filter.DepartBefore != nil) {
+			return
+		}
+		if filter.DepartAfter != nil && departureAt.Before(*filter.DepartAfter) {
+			return
+		}
+		if filter.DepartBefore != nil && !departureAt.Before(*filter.DepartBefore) {
+			return
+		}
+		matches = append(matches, match{id, departureAt})
+	}
+
+	m.mu.RLock()
+	if filter.Kind != "noncapacity" {
+		for _, route := range m.capacityRoutes {
+			if route.Date != filter.Date {
+				continue
+			}
+			for _, sailing := range models.WithCapacityTimes(cloneCapacityRoute(route)).Sailings {
+				if filter.SailingStatus != "" && sailing.SailingStatus != filter.SailingStatus || !vesselMatches(sailing.VesselName) {
+					continue
+				}
+				departureAt := sailing.DepartureAt
+				if departureAt == nil {
+					departureAt = sailing.ScheduledDepartureAt
+				}
+				departs(sailing.ID, route.RouteCode, departureAt)
+			}
+		}
+	}
+	// Non-capacity sailings have no status
+	if filter.Kind != "capacity" && filter.SailingStatus == "" {
+		for _, routes := range m.nonCapacityRoutes {
+			route, ok := routes[filter.Date]
+			if !ok {
+				continue
+			}
+			for _, sailing := range models.WithNonCapacityTimes(cloneNonCapacityRoute(route)).Sailings {
+				onVessel := filter.VesselName == ""
+				for _, leg := range sailing.Legs {
+					onVessel = onVessel || leg.VesselName != nil && vesselMatches(*leg.VesselName)
+				}
+				if onVessel {
+					departs(sailing.ID, route.RouteCode, sailing.DepartureAt)
+				}
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	// Soonest first, then by ID, with sailings of unknown time last
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if (a.departureAt == nil) != (b.departureAt == nil) {
+			return b.departureAt == nil
+		}
+		if a.departureAt != nil && !a.departureAt.Equal(*b.departureAt) {
+			return a.departureAt.Before(*b.departureAt)
+		}
+		return a.id < b.id
+	})
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		if seen[match.id] {
+			continue
+		}
+		if filter.Limit > 0 && len(ids) == filter.Limit {
+			break
+		}
+		seen[match.id] = true
+		ids = append(ids, match.id)
+	}
+	return m.GetSailings(ids)
+}
+
+/********************/
+/* Schedule seasons */
+/********************/
+
+func (m *MemoryStore) GetScheduleSeasons(routeCode string) []models.ScheduleSeason {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seasons := []models.ScheduleSeason{}
+	for _, effectiveFrom := range sortedKeys(m.seasons[routeCode]) {
+		seasons = append(seasons, m.seasons[routeCode][effectiveFrom])
+	}
+	return seasons
+}
+
+func (m *MemoryStore) SaveScheduleSeasons(routeCode string, seasons []models.ScheduleSeason) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.seasons[routeCode] == nil {
+		m.seasons[routeCode] = make(map[string]models.ScheduleSeason)
+	}
+	for _, season := range seasons {
+		season.RouteCode = routeCode
+		m.seasons[routeCode][season.EffectiveFrom] = season
+	}
+	return nil
+}
+
+/*********************/
+/* History snapshots */
+/*********************/
+
+func (m *MemoryStore) SaveCapacitySnapshot(route models.CapacityRoute, scrapedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := snapshotKey(route.RouteCode, route.Date)
+	snapshots := m.capacitySnapshots[key]
+
+	snapshot := models.CapacityRouteSnapshot{CapacityRoute: cloneCapacityRoute(route), ScrapedAt: scrapedAt}
+	m.capacitySnapshots[key] = insertSnapshot(snapshots, snapshot, func(s models.CapacityRouteSnapshot) time.Time { return s.ScrapedAt })
+	return nil
+}
+
+func (m *MemoryStore) GetLatestCapacitySnapshot(routeCode, serviceDate string) *models.CapacityRouteSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := m.capacitySnapshots[snapshotKey(routeCode, serviceDate)]
+	if len(snapshots) == 0 {
+		return nil
+	}
+	snapshot := snapshots[len(snapshots)-1]
+	snapshot.CapacityRoute = cloneCapacityRoute(snapshot.CapacityRoute)
+	return &snapshot
+}
+
+func (m *MemoryStore) GetCapacitySnapshotAsOf(routeCode, serviceDate string, asOf time.Time) *models.CapacityRouteSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := m.capacitySnapshots[snapshotKey(routeCode, serviceDate)]
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !snapshots[i].ScrapedAt.After(asOf) {
+			snapshot := snapshots[i]
+			snapshot.CapacityRoute = cloneCapacityRoute(snapshot.CapacityRoute)
+			return &snapshot
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetCapacitySnapshots(routeCode, serviceDate string) []models.CapacityRouteSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var snapshots []models.CapacityRouteSnapshot
+	for _, snapshot := range m.capacitySnapshots[snapshotKey(routeCode, serviceDate)] {
+		snapshot.CapacityRoute = cloneCapacityRoute(snapshot.CapacityRoute)
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
+func (m *MemoryStore) SaveNonCapacitySnapshot(route models.NonCapacityRoute, scrapedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := snapshotKey(route.RouteCode, route.Date)
+	snapshots := m.nonCapacitySnapshots[key]
+
+	snapshot := models.NonCapacityRouteSnapshot{NonCapacityRoute: cloneNonCapacityRoute(route), ScrapedAt: scrapedAt}
+	m.nonCapacitySnapshots[key] = insertSnapshot(snapshots, snapshot, func(s models.NonCapacityRouteSnapshot) time.Time { return s.ScrapedAt })
+	return nil
+}
+
+func (m *MemoryStore) GetLatestNonCapacitySnapshot(routeCode, serviceDate string) *models.NonCapacityRouteSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := m.nonCapacitySnapshots[snapshotKey(routeCode, serviceDate)]
+	if len(snapshots) == 0 {
+		return nil
+	}
+	snapshot := snapshots[len(snapshots)-1]
+	snapshot.NonCapacityRoute = cloneNonCapacityRoute(snapshot.NonCapacityRoute)
+	return &snapshot
+}
+
+func (m *MemoryStore) GetNonCapacitySnapshotAsOf(routeCode, serviceDate string, asOf time.Time) *models.NonCapacityRouteSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := m.nonCapacitySnapshots[snapshotKey(routeCode, serviceDate)]
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !snapshots[i].ScrapedAt.After(asOf) {
+			snapshot := snapshots[i]
+			snapshot.NonCapacityRoute = cloneNonCapacityRoute(snapshot.NonCapacityRoute)
+			return &snapshot
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetNonCapacitySnapshots(routeCode, serviceDate string) []models.NonCapacityRouteSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var snapshots []models.NonCapacityRouteSnapshot
+	for _, snapshot := range m.nonCapacitySnapshots[snapshotKey(routeCode, serviceDate)] {
+		snapshot.NonCapacityRoute = cloneNonCapacityRoute(snapshot.NonCapacityRoute)
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
+func (m *MemoryStore) DeleteSnapshotsBefore(cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, snapshots := range m.capacitySnapshots {
+		kept := snapshots[:0]
+		for _, snapshot := range snapshots {
+			if snapshot.ScrapedAt.Before(cutoff) {
+				deleted++
+				continue
+			}
+			kept = append(kept, snapshot)
+		}
+		if len(kept) == 0 {
+			delete(m.capacitySnapshots, key)
+		} else {
+			m.capacitySnapshots[key] = kept
+		}
+	}
+	for key, snapshots := range m.nonCapacitySnapshots {
+		kept := snapshots[:0]
+		for _, snapshot := range snapshots {
+			if snapshot.ScrapedAt.Before(cutoff) {
+				deleted++
+				continue
+			}
+			kept = append(kept, snapshot)
+		}
+		if len(kept) == 0 {
+			delete(m.nonCapacitySnapshots, key)
+		} else {
+			m.nonCapacitySnapshots[key] = kept
+		}
+	}
+	return deleted, nil
+}
+
+/****************/
+/* Fill history */
+/****************/
+
+func (m *MemoryStore) SaveFillSamples(route models.CapacityRoute, sampledAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, history := range models.FillHistories(route, sampledAt) {
+		sample := history.Samples[0]
+		existing, ok := m.fillHistories[history.SailingID]
+		if !ok {
+			m.fillHistories[history.SailingID] = history
+			continue
+		}
+
+		latest := existing.Samples[len(existing.Samples)-1]
+		if latest.FillLevels == sample.FillLevels && latest.SailingStatus == sample.SailingStatus {
+			continue
+		}
+		existing.Samples = insertSnapshot(existing.Samples, sample, func(s models.FillSample) time.Time { return s.SampledAt })
+		m.fillHistories[history.SailingID] = existing
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetFillHistory(sailingID string) *models.FillHistory {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history, ok := m.fillHistories[sailingID]
+	if !ok {
+		return nil
+	}
+	history.Samples = append([]models.FillSample(nil), history.Samples...)
+	return &history
+}
+
+func (m *MemoryStore) GetFillHistories(routeCode string, departureMinutes int, weekday time.Weekday, beforeDate string) []models.FillHistory {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	histories := []models.FillHistory{}
+	for _, sailingID := range sortedKeys(m.fillHistories) {
+		history := m.fillHistories[sailingID]
+		if history.RouteCode != routeCode || history.DepartureMinutes != departureMinutes || history.Date >= beforeDate {
+			continue
+		}
+		if date, err := time.Parse("2006-01-02", history.Date); err != nil || date.Weekday() != weekday {
+			continue
+		}
+		history.Samples = append([]models.FillSample(nil), history.Samples...)
+		histories = append(histories, history)
+	}
+	sort.SliceStable(histories, func(i, j int) bool { return histories[i].Date < histories[j].Date })
+	return histories
+}
+
+func (m *MemoryStore) DeleteFillSamplesBefore(cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for sailingID, history := range m.fillHistories {
+		kept := history.Samples[:0]
+		for _, sample := range history.Samples {
+			if !sample.SampledAt.Before(cutoff) {
+				kept = append(kept, sample)
+			}
+		}
+		deleted += int64(len(history.Samples) - len(kept))
+		if len(kept) == 0 {
+			delete(m.fillHistories, sailingID)
+		} else {
+			history.Samples = kept
+			m.fillHistories[sailingID] = history
+		}
+	}
+	return deleted, nil
+}
+
+/**************/
+/* Departures */
+/**************/
+
+func (m *MemoryStore) SaveSailingDepartures(route models.CapacityRoute) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, departure := range models.CapacityDepartures(route) {
+		if existing, ok := m.departures[departure.SailingID]; ok {
+			if departure.ETA == nil {
+				departure.ETA = existing.ETA
+			}
+			if departure.ActualArrival == nil {
+				departure.ActualArrival = existing.ActualArrival
+			}
+		}
+		m.departures[departure.SailingID] = departure
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetSailingDepartures(fromDate, toDate string, routeCodes []string) []models.SailingDeparture {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	departures := []models.SailingDeparture{}
+	for _, departure := range m.departures {
+		if departure.Date < fromDate || departure.Date > toDate {
+			continue
+		}
+		if len(routeCodes) > 0 && !containsString(routeCodes, departure.RouteCode) {
+			continue
+		}
+		departures = append(departures, departure)
+	}
+	sort.Slice(departures, func(i, j int) bool {
+		if !departures[i].ScheduledDeparture.Equal(departures[j].ScheduledDeparture) {
+			return departures[i].ScheduledDeparture.Before(departures[j].ScheduledDeparture)
+		}
+		return departures[i].SailingID < departures[j].SailingID
+	})
+	return departures
+}
+
+func (m *MemoryStore) DeleteSailingDeparturesBefore(date string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for sailingID, departure := range m.departures {
+		if departure.Date < date {
+			delete(m.departures, sailingID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+/***************/
+/* Scrape runs */
+/***************/
+
+func (m *MemoryStore) SaveScrapeRun(run models.ScrapeRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextScrapeRunID++
+	run.ID = m.nextScrapeRunID
+	run.Routes = append([]models.ScrapeRouteResult(nil), run.Routes...)
+	sort.Slice(run.Routes, func(i, j int) bool { return run.Routes[i].RouteCode < run.Routes[j].RouteCode })
+
+	i := sort.Search(len(m.scrapeRuns), func(i int) bool { return m.scrapeRuns[i].StartedAt.After(run.StartedAt) })
+	m.scrapeRuns = append(m.scrapeRuns, models.ScrapeRun{})
+	copy(m.scrapeRuns[i+1:], m.scrapeRuns[i:])
+	m.scrapeRuns[i] = run
+	return nil
+}
+
+func (m *MemoryStore) GetLatestScrapeRuns() []models.ScrapeRun {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	latest := make(map[string]models.ScrapeRun)
+	for _, run := range m.scrapeRuns {
+		latest[run.Kind] = run
+	}
+
+	runs := []models.ScrapeRun{}
+	for _, kind := range sortedKeys(latest) {
+		run := latest[kind]
+		run.Routes = append([]models.ScrapeRouteResult(nil), run.Routes...)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+func (m *MemoryStore) GetRouteFreshness() []models.RouteFreshness {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	freshness := make(map[string]models.RouteFreshness) // kind + route code → freshness
+	for _, run := range m.scrapeRuns {
+		for _, result := range run.Routes {
+			key := run.Kind + "|" + result.RouteCode
+			route := freshness[key]
+			route.Kind = run.Kind
+			route.RouteCode = result.RouteCode
+			if run.FinishedAt.Before(route.LastAttemptAt) {
+				continue
+			}
+
+			route.LastAttemptAt = run.FinishedAt
+			route.LastError = ""
+			if result.Success {
+				finishedAt := run.FinishedAt
+				route.LastSuccessAt = &finishedAt
+			} else {
+				route.LastError = result.Error
+			}
+			freshness[key] = route
+		}
+	}
+
+	routes := []models.RouteFreshness{}
+	for _, key := range sortedKeys(freshness) {
+		routes = append(routes, freshness[key])
+	}
+	return routes
+}
+
+func (m *MemoryStore) DeleteScrapeRunsBefore(cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.scrapeRuns[:0]
+	for _, run := range m.scrapeRuns {
+		if !run.StartedAt.Before(cutoff) {
+			kept = append(kept, run)
+		}
+	}
+	deleted := int64(len(m.scrapeRuns) - len(kept))
+	m.scrapeRuns = kept
+	return deleted, nil
+}
+
+/************/
+/* Webhooks */
+/************/
+
+func (m *MemoryStore) CreateWebhookSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextWebhookID++
+	subscription.ID = m.nextWebhookID
+	subscription.CreatedAt = time.Now()
+	subscription = cloneWebhookSubscription(subscription)
+	m.webhooks[subscription.ID] = subscription
+	return cloneWebhookSubscription(subscription), nil
+}
+
+func (m *MemoryStore) UpdateWebhookSubscription(subscription models.WebhookSubscription) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.webhooks[subscription.ID]
+	if !ok {
+		return false, nil
+	}
+	subscription.Secret = existing.Secret
+	subscription.CreatedAt = existing.CreatedAt
+	m.webhooks[subscription.ID] = cloneWebhookSubscription(subscription)
+	return true, nil
+}
+
+func (m *MemoryStore) GetWebhookSubscriptions() []models.WebhookSubscription {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subscriptions := []models.WebhookSubscription{}
+	for _, subscription := range m.webhooks {
+		subscriptions = append(subscriptions, cloneWebhookSubscription(subscription))
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID < subscriptions[j].ID })
+	return subscriptions
+}
+
+func (m *MemoryStore) GetWebhookSubscription(id int64) *models.WebhookSubscription {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subscription, ok := m.webhooks[id]
+	if !ok {
+		return nil
+	}
+	subscription = cloneWebhookSubscription(subscription)
+	return &subscription
+}
+
+func (m *MemoryStore) DeleteWebhookSubscription(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[id]; !ok {
+		return false, nil
+	}
+	delete(m.webhooks, id)
+
+	kept := m.webhookDeliveries[:0]
+	for _, delivery := range m.webhookDeliveries {
+		if delivery.SubscriptionID != id {
+			kept = append(kept, delivery)
+		}
+	}
+	m.webhookDeliveries = kept
+	return true, nil
+}
+
+func (m *MemoryStore) SaveWebhookDelivery(delivery models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Attempts for a subscription deleted in the meantime are dropped, as by the foreign key
+	if _, ok := m.webhooks[delivery.SubscriptionID]; !ok {
+		return nil
+	}
+	m.nextDeliveryID++
+	delivery.ID = m.nextDeliveryID
+	m.webhookDeliveries = append(m.webhookDeliveries, delivery)
+	return nil
+}
+
+func (m *MemoryStore) GetWebhookDeliveries(subscriptionID int64, limit int) []models.WebhookDelivery {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for i := len(m.webhookDeliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if m.webhookDeliveries[i].SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, m.webhookDeliveries[i])
+		}
+	}
+	return deliveries
+}
+
+func (m *MemoryStore) DeleteWebhookDeliveriesBefore(cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.webhookDeliveries[:0]
+	for _, delivery := range m.webhookDeliveries {
+		if !delivery.DeliveredAt.Before(cutoff) {
+			kept = append(kept, delivery)
+		}
+	}
+	deleted := int64(len(m.webhookDeliveries) - len(kept))
+	m.webhookDeliveries = kept
+	return deleted, nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+/********************/
+/* Helper Functions */
+/********************/
+
+/*
+ * insertSnapshot
+ *
+ * Inserts a snapshot keeping the slice ordered by scrape time.
+ * A snapshot with the same scrape time as an existing one is ignored.
+ *
+ * @param []T snapshots - ordered oldest first
+ * @param T snapshot
+ * @param func(T) time.Time scrapedAt - returns a snapshot's scrape time
+ *
+ * @return []T
+ */
+func insertSnapshot[T any](snapshots []T, snapshot T, scrapedAt func(T) time.Time) []T {
+	at := scrapedAt(snapshot)
+	i := sort.Search(len(snapshots), func(i int) bool { return !scrapedAt(snapshots[i]).Before(at) })
+	if i < len(snapshots) && scrapedAt(snapshots[i]).Equal(at) {
+		return snapshots
+	}
+
+	snapshots = append(snapshots, snapshot)
+	copy(snapshots[i+1:], snapshots[i:])
+	snapshots[i] = snapshot
+	return snapshots
+}
+
+func snapshotKey(routeCode, serviceDate string) string {
+	return routeCode + "|" + serviceDate
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneCapacityRoute(route models.CapacityRoute) models.CapacityRoute {
+	sailings := route.Sailings
+	route.Sailings = nil
+	cloneJSON(sailings, &route.Sailings)
+	return route
+}
+
+func cloneNonCapacityRoute(route models.NonCapacityRoute) models.NonCapacityRoute {
+	sailings := route.Sailings
+	route.Sailings = nil
+	cloneJSON(sailings, &route.Sailings)
+	return route
+}
+
+func cloneWebhookSubscription(subscription models.WebhookSubscription) models.WebhookSubscription {
+	subscription.EventTypes = append([]string{}, subscription.EventTypes...)
+	subscription.RouteCodes = append([]string{}, subscription.RouteCodes...)
+	subscription.TerminalCodes = append([]string{}, subscription.TerminalCodes...)
+	if subscription.FillAbove != nil {
+		fillAbove := *subscription.FillAbove
+		subscription.FillAbove = &fillAbove
+	}
+	if subscription.CarFillAbove != nil {
+		carFillAbove := *subscription.CarFillAbove
+		subscription.CarFillAbove = &carFillAbove
+	}
+	return subscription
+}
+
+/*
+ * cloneJSON
+ *
+ * Deep-copies src into dst through a JSON round trip (the same encoding PostgreSQL stores).
+ *
+ * @param any src
+ * @param any dst - pointer to the destination
+ *
+ * @return void
+ */
+func cloneJSON(src, dst any) {
+	data, err := json.Marshal(src)
+	if err != nil {
+		log.Printf("cloneJSON: marshal failed: %v", err)
+		return
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		log.Printf("cloneJSON: unmarshal failed: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func nonCapacityRoute(routeCode, date string, departures ...string) models.NonCapacityRoute {
+	route := models.NonCapacityRoute{
+		Date:             date,
+		RouteCode:        routeCode,
+		FromTerminalCode: routeCode[:3],
+		ToTerminalCode:   routeCode[3:],
+		SailingDuration:  "1h 20m",
+		Sailings:         []models.NonCapacitySailing{},
+	}
+	for _, departure := range departures {
+		route.Sailings = append(route.Sailings, models.NonCapacitySailing{DepartureTime: departure})
+	}
+	return route
+}
+
+func TestMemoryStoreNonCapacityRoutes(t *testing.T) {
+	store := NewMemoryStore()
+
+	route := nonCapacityRoute("TSAPOB", "2025-10-20", "7:10 am")
+	if err := store.SaveNonCapacityRoute(route); err != nil {
+		t.Fatalf("SaveNonCapacityRoute: %v", err)
+	}
+	if err := store.SaveNonCapacityRoute(nonCapacityRoute("TSAPOB", "2025-10-21", "8:00 am")); err != nil {
+		t.Fatalf("SaveNonCapacityRoute: %v", err)
+	}
+	if err := store.SaveNonCapacityRoute(nonCapacityRoute("SWBPOB", "2025-10-20")); err != nil {
+		t.Fatalf("SaveNonCapacityRoute: %v", err)
+	}
+
+	// Mutating the saved value must not change the stored copy
+	route.Sailings[0].DepartureTime = "changed"
+
+	got := store.GetNonCapacityRoute("TSAPOB", "2025-10-20")
+	if got == nil || got.Sailings[0].DepartureTime != "7:10 am" {
+		t.Fatalf("GetNonCapacityRoute = %+v, want the 7:10 am sailing", got)
+	}
+	if store.GetNonCapacityRoute("TSAPOB", "2025-10-22") != nil {
+		t.Errorf("GetNonCapacityRoute for an unscraped date should be nil")
+	}
+
+	if routes := store.GetNonCapacitySailings("2025-10-20"); len(routes) != 2 || routes[0].RouteCode != "SWBPOB" || routes[1].RouteCode != "TSAPOB" {
+		t.Errorf("GetNonCapacitySailings = %+v, want SWBPOB and TSAPOB", routes)
+	}
+	if infos := store.GetNonCapacityRoutesInfo("2025-10-20", []string{"TSAPOB"}); len(infos) != 1 || infos[0].RouteCode != "TSAPOB" {
+		t.Errorf("GetNonCapacityRoutesInfo = %+v, want only TSAPOB", infos)
+	}
+
+	deleted, err := store.DeleteNonCapacityRoutesBefore("2025-10-21")
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteNonCapacityRoutesBefore = %d, %v, want 2 deleted", deleted, err)
+	}
+	if routes := store.GetNonCapacitySailings("2025-10-21"); len(routes) != 1 {
+		t.Errorf("routes on the cutoff date should be kept, got %+v", routes)
+	}
+}
+
+func TestMemoryStoreCapacityRoutes(t *testing.T) {
+	store := NewMemoryStore()
+
+	store.SaveCapacityRoute(models.CapacityRoute{Date: "2025-10-20", RouteCode: "TSASWB", FromTerminalCode: "TSA", ToTerminalCode: "SWB",
+		Sailings: []models.CapacitySailing{{DepartureTime: "9:00 am", CarFill: 40}}})
+
+	if got := store.GetCapacityRoute("TSASWB"); got == nil || len(got.Sailings) != 1 || got.Sailings[0].CarFill != 40 {
+		t.Errorf("GetCapacityRoute = %+v, want the 9:00 am sailing", got)
+	}
+	if store.GetCapacityRoute("SWBTSA") != nil {
+		t.Errorf("GetCapacityRoute for an unscraped route should be nil")
+	}
+}
+
+func TestMemoryStoreGetSailings(t *testing.T) {
+	store := NewMemoryStore()
+
+	route := nonCapacityRoute("TSASWB", "2025-10-20", "7:00 am", "9:00 am")
+	route.Sailings[0].ID = "TSASWB-2025-10-20-0700"
+	route.Sailings[1].ID = "TSASWB-2025-10-20-0900"
+	store.SaveNonCapacityRoute(route)
+	store.SaveCapacityRoute(models.CapacityRoute{Date: "2025-10-20", RouteCode: "TSASWB", FromTerminalCode: "TSA", ToTerminalCode: "SWB",
+		Sailings: []models.CapacitySailing{{ID: "TSASWB-2025-10-20-0900", CarFill: 40}}})
+
+	sailings := store.GetSailings([]string{"TSASWB-2025-10-20-0900", "TSASWB-2025-10-20-1100", "TSASWB-2025-10-20-0700", "bad", "TSASWB-2025-10-20-0900"})
+	if len(sailings) != 2 || sailings[0].ID != "TSASWB-2025-10-20-0900" || sailings[1].ID != "TSASWB-2025-10-20-0700" {
+		t.Fatalf("GetSailings = %+v, want the 0900 and 0700 sailings once each", sailings)
+	}
+	if first := sailings[0]; first.Capacity == nil || first.Capacity.CarFill != 40 || first.NonCapacity == nil || first.NonCapacity.DepartureTime != "9:00 am" || first.FromTerminalCode != "TSA" {
+		t.Errorf("sailing on both kinds of route = %+v", first)
+	}
+	if second := sailings[1]; second.Capacity != nil || second.NonCapacity == nil || second.Date != "2025-10-20" {
+		t.Errorf("non-capacity sailing = %+v", second)
+	}
+}
+
+func TestMemoryStoreFindSailings(t *testing.T) {
+	store := NewMemoryStore()
+
+	route := nonCapacityRoute("TSASWB", "2025-10-20", "7:00 am", "9:00 am")
+	route.Sailings[0].ID = "TSASWB-2025-10-20-0700"
+	route.Sailings[1].ID = "TSASWB-2025-10-20-0900"
+	vessel := "Queen of Nanaimo"
+	route.Sailings[0].Legs = []models.Leg{{LegNumber: 1, VesselName: &vessel}}
+	store.SaveNonCapacityRoute(route)
+	store.SaveCapacityRoute(models.CapacityRoute{Date: "2025-10-20", RouteCode: "SWBTSA", FromTerminalCode: "SWB", ToTerminalCode: "TSA",
+		Sailings: []models.CapacitySailing{
+			{ID: "SWBTSA-2025-10-20-0800", DepartureTime: "8:00 am", ScheduledTime: "8:00 am", SailingStatus: "future", VesselName: "Queen of Nanaimo"},
+			{ID: "SWBTSA-2025-10-20-1000", DepartureTime: "10:00 am", ScheduledTime: "10:00 am", SailingStatus: "cancelled"},
+		}})
+
+	ids := func(filter models.SailingFilter) string {
+		var ids []string
+		for _, sailing := range store.FindSailings(filter) {
+			ids = append(ids, sailing.ID[:6]+"@"+sailing.ID[len(sailing.ID)-4:])
+		}
+		return strings.Join(ids, ",")
+	}
+	at := func(minutes int) *time.Time {
+		t, _ := models.ServiceTime("2025-10-20", minutes)
+		return &t
+	}
+
+	tests := []struct {
+		name   string
+		filter models.SailingFilter
+		want   string
+	}{
+		{"all, soonest first", models.SailingFilter{Date: "2025-10-20"}, "TSASWB@0700,SWBTSA@0800,TSASWB@0900,SWBTSA@1000"},
+		{"other date", models.SailingFilter{Date: "2025-10-21"}, ""},
+		{"kind", models.SailingFilter{Date: "2025-10-20", Kind: "noncapacity"}, "TSASWB@0700,TSASWB@0900"},
+		{"from terminal", models.SailingFilter{Date: "2025-10-20", FromTerminalCode: "SWB"}, "SWBTSA@0800,SWBTSA@1000"},
+		{"vessel on either kind", models.SailingFilter{Date: "2025-10-20", VesselName: "queen of nanaimo"}, "TSASWB@0700,SWBTSA@0800"},
+		{"status", models.SailingFilter{Date: "2025-10-20", SailingStatus: "cancelled"}, "SWBTSA@1000"},
+		{"departure range", models.SailingFilter{Date: "2025-10-20", DepartAfter: at(8 * 60), DepartBefore: at(10 * 60)}, "SWBTSA@0800,TSASWB@0900"},
+		{"limit", models.SailingFilter{Date: "2025-10-20", RouteCodes: []string{"TSASWB"}, Limit: 1}, "TSASWB@0700"},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.filter); got != tt.want {
+			t.Errorf("%s: FindSailings = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSailingsWithoutIDs(t *testing.T) {
+	store := NewMemoryStore()
+
+	// Two routes that each have a sailing without an ID, plus a repeated ID
+	first := nonCapacityRoute("TSASWB", "2025-10-20", "", "7:00 am")
+	first.Sailings[1].ID = "TSASWB-2025-10-20-0700"
+	second := nonCapacityRoute("SWBTSA", "2025-10-20", "", "8:00 am", "8:00 am")
+	second.Sailings[1].ID = "SWBTSA-2025-10-20-0800"
+	second.Sailings[2].ID = "SWBTSA-2025-10-20-0800"
+
+	seen := make(map[string]bool)
+	var kept []string
+	for _, route := range []models.NonCapacityRoute{first, second} {
+		if err := store.SaveNonCapacityRoute(route); err != nil {
+			t.Fatalf("SaveNonCapacityRoute %s: %v", route.RouteCode, err)
+		}
+		for _, sailing := range route.Sailings {
+			if keepSailing(seen, sailing.ID) {
+				kept = append(kept, sailing.ID)
+			}
+		}
+	}
+	if got := strings.Join(kept, ","); got != "TSASWB-2025-10-20-0700,SWBTSA-2025-10-20-0800" {
+		t.Errorf("kept sailings %q, want each ID once and none empty", got)
+	}
+
+	var found []string
+	for _, sailing := range store.FindSailings(models.SailingFilter{Date: "2025-10-20"}) {
+		found = append(found, sailing.ID)
+	}
+	if got := strings.Join(found, ","); got != "TSASWB-2025-10-20-0700,SWBTSA-2025-10-20-0800" {
+		t.Errorf("FindSailings = %q, want only the sailings with IDs", got)
+	}
+	if sailings := store.GetSailings([]string{""}); len(sailings) != 0 {
+		t.Errorf("GetSailings(\"\") = %+v, want none", sailings)
+	}
+}
+
+func TestMemoryStoreSnapshots(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2025, time.October, 20, 12, 0, 0, 0, time.UTC)
+
+	saves := []struct {
+		route     models.NonCapacityRoute
+		scrapedAt time.Time
+	}{
+		{nonCapacityRoute("TSAPOB", "2025-10-20", "7:10 am"), start},
+		{nonCapacityRoute("TSAPOB", "2025-10-20", "7:10 am"), start.Add(time.Hour)}, // unchanged: still kept
+		{nonCapacityRoute("TSAPOB", "2025-10-20", "7:10 am", "2:15 pm"), start.Add(2 * time.Hour)},
+	}
+	for _, save := range saves {
+		if err := store.SaveNonCapacitySnapshot(save.route, save.scrapedAt); err != nil {
+			t.Fatalf("SaveNonCapacitySnapshot: %v", err)
+		}
+	}
+
+	if snapshots := store.GetNonCapacitySnapshots("TSAPOB", "2025-10-20"); len(snapshots) != 3 {
+		t.Fatalf("got %d snapshots, want one per scrape", len(snapshots))
+	}
+
+	latest := store.GetLatestNonCapacitySnapshot("TSAPOB", "2025-10-20")
+	if latest == nil || len(latest.Sailings) != 2 {
+		t.Errorf("GetLatestNonCapacitySnapshot = %+v, want 2 sailings", latest)
+	}
+
+	asOf := store.GetNonCapacitySnapshotAsOf("TSAPOB", "2025-10-20", start.Add(90*time.Minute))
+	if asOf == nil || !asOf.ScrapedAt.Equal(start.Add(time.Hour)) || len(asOf.Sailings) != 1 {
+		t.Errorf("GetNonCapacitySnapshotAsOf = %+v, want the second snapshot", asOf)
+	}
+	if store.GetNonCapacitySnapshotAsOf("TSAPOB", "2025-10-20", start.Add(-time.Minute)) != nil {
+		t.Errorf("GetNonCapacitySnapshotAsOf before the first scrape should be nil")
+	}
+
+	deleted, err := store.DeleteSnapshotsBefore(start.Add(time.Hour))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteSnapshotsBefore = %d, %v, want 1 deleted", deleted, err)
+	}
+	if snapshots := store.GetNonCapacitySnapshots("TSAPOB", "2025-10-20"); len(snapshots) != 2 {
+		t.Errorf("got %d snapshots after cleanup, want 2", len(snapshots))
+	}
+}
+
+func TestMemoryStoreScheduleSeasons(t *testing.T) {
+	store := NewMemoryStore()
+
+	if seasons := store.GetScheduleSeasons("TSAPOB"); seasons == nil || len(seasons) != 0 {
+		t.Errorf("GetScheduleSeasons for an unknown route = %#v, want an empty slice", seasons)
+	}
+
+	store.SaveScheduleSeasons("TSAPOB", []models.ScheduleSeason{
+		{Label: "Spring", EffectiveFrom: "2026-04-01", EffectiveTo: "2026-06-23"},
+		{Label: "Fall", EffectiveFrom: "2025-10-14", EffectiveTo: "2026-03-31"},
+	})
+	store.SaveScheduleSeasons("TSAPOB", []models.ScheduleSeason{
+		{Label: "Fall/Winter", EffectiveFrom: "2025-10-14", EffectiveTo: "2026-03-31"},
+	})
+
+	seasons := store.GetScheduleSeasons("TSAPOB")
+	if len(seasons) != 2 || seasons[0].Label != "Fall/Winter" || seasons[1].Label != "Spring" || seasons[0].RouteCode != "TSAPOB" {
+		t.Errorf("GetScheduleSeasons = %+v, want Fall/Winter then Spring", seasons)
+	}
+}
+
+func TestMemoryStoreScrapeRuns(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	runs := []models.ScrapeRun{
+		{Kind: models.ScrapeKindNonCapacity, StartedAt: start, FinishedAt: start.Add(time.Minute), Routes: []models.ScrapeRouteResult{
+			{RouteCode: "TSAPOB", Success: true, SailingCount: 5},
+			{RouteCode: "SWBPOB", Success: true, SailingCount: 3},
+		}},
+		{Kind: models.ScrapeKindNonCapacity, StartedAt: start.Add(time.Hour), FinishedAt: start.Add(time.Hour + time.Minute), Routes: []models.ScrapeRouteResult{
+			{RouteCode: "TSAPOB", Success: false, Error: "timeout"},
+			{RouteCode: "SWBPOB", Success: true, SailingCount: 3},
+		}},
+		{Kind: models.ScrapeKindCleanup, StartedAt: start.Add(30 * time.Minute), FinishedAt: start.Add(30 * time.Minute)},
+	}
+	for _, run := range runs {
+		if err := store.SaveScrapeRun(run); err != nil {
+			t.Fatalf("SaveScrapeRun: %v", err)
+		}
+	}
+
+	latest := store.GetLatestScrapeRuns()
+	if len(latest) != 2 || latest[0].Kind != models.ScrapeKindCleanup || latest[1].Kind != models.ScrapeKindNonCapacity {
+		t.Fatalf("GetLatestScrapeRuns = %+v, want the latest cleanup and noncapacity runs", latest)
+	}
+	if latest[1].ID != 2 || len(latest[1].Routes) != 2 || latest[1].Routes[0].RouteCode != "SWBPOB" {
+		t.Errorf("latest noncapacity run = %+v, want run 2 with routes sorted by code", latest[1])
+	}
+
+	freshness := store.GetRouteFreshness()
+	if len(freshness) != 2 {
+		t.Fatalf("GetRouteFreshness = %+v, want 2 routes", freshness)
+	}
+	swb, tsa := freshness[0], freshness[1]
+	if swb.RouteCode != "SWBPOB" || swb.LastSuccessAt == nil || !swb.LastSuccessAt.Equal(start.Add(time.Hour+time.Minute)) || swb.LastError != "" {
+		t.Errorf("SWBPOB freshness = %+v, want success at the second run", swb)
+	}
+	if tsa.RouteCode != "TSAPOB" || tsa.LastSuccessAt == nil || !tsa.LastSuccessAt.Equal(start.Add(time.Minute)) ||
+		!tsa.LastAttemptAt.Equal(start.Add(time.Hour+time.Minute)) || tsa.LastError != "timeout" {
+		t.Errorf("TSAPOB freshness = %+v, want last success at the first run and the second run's error", tsa)
+	}
+
+	deleted, err := store.DeleteScrapeRunsBefore(start.Add(time.Hour))
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteScrapeRunsBefore = %d, %v, want 2 deleted", deleted, err)
+	}
+	if freshness := store.GetRouteFreshness(); len(freshness) != 2 || freshness[1].LastSuccessAt != nil {
+		t.Errorf("after cleanup TSAPOB has no recorded success, got %+v", freshness)
+	}
+}
@@ -0,0 +1,361 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Key of the PostgreSQL advisory lock held while migrating, so that servers starting
+// together do not apply the same migration twice
+const migrationLockKey = 7_345_120_501
+
+// Migration file names: <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+/*
+ * Migration
+ *
+ * A versioned schema change, applied in a transaction together with its
+ * `schema_migrations` row.
+ */
+type Migration struct {
+	Version int
+	Name    string
+	Up      string // SQL applying the change
+	Down    string // SQL reverting it
+}
+
+/*
+ * MigrationStatus
+ *
+ * A migration of this build and whether the database has it.
+ */
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // nil if pending
+}
+
+/*
+ * Migrations
+ *
+ * Returns the migrations embedded in this build (cmd/db/migrations).
+ *
+ * @return []Migration - in version order
+ * @return error - if the files are not a valid sequence
+ */
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+/*
+ * LatestSchemaVersion
+ *
+ * Returns the schema version this build needs: the version of its last migration.
+ *
+ * @return int
+ */
+func LatestSchemaVersion() int {
+	migrations, err := Migrations()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+/*
+ * loadMigrations
+ *
+ * Reads the migrations in a directory. Versions must start at 1 and have no gaps,
+ * and every version needs both an up and a down file.
+ *
+ * @param fs.FS fsys
+ * @param string dir
+ *
+ * @return []Migration - in version order
+ * @return error
+ */
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: expected a name like 0001_name.up.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		name, direction := match[2], match[3]
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d: named both %q and %q", version, migration.Name, name)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d: expected version %d, versions must be 1, 2, 3...", migration.Version, i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing its up or down file", migration.Version, migration.Name)
+		}
+	}
+	return migrations, nil
+}
+
+/*
+ * planMigrations
+ *
+ * Works out which migrations take a database from one version to another.
+ *
+ * @param []Migration migrations - in version order
+ * @param int current - the database's version
+ * @param int target - the version wanted, 0 to revert everything
+ *
+ * @return []Migration - the migrations to run, in the order to run them
+ * @return bool - true if they are to be reverted (run down)
+ * @return error - if either version is unknown to this build
+ */
+func planMigrations(migrations []Migration, current, target int) ([]Migration, bool, error) {
+	latest := len(migrations)
+	if current > latest {
+		return nil, false, fmt.Errorf("database schema is at version %d, ahead of this build (version %d); run a newer build", current, latest)
+	}
+	if target < 0 || target > latest {
+		return nil, false, fmt.Errorf("unknown schema version %d, expected 0 to %d", target, latest)
+	}
+
+	if target >= current {
+		return migrations[current:target], false, nil
+	}
+
+	var steps []Migration
+	for i := current - 1; i >= target; i-- {
+		steps = append(steps, migrations[i])
+	}
+	return steps, true, nil
+}
+
+/*
+ * SchemaVersion
+ *
+ * Reads the version of the database schema: the last migration applied, 0 if none
+ * have been (including databases created before migrations were versioned).
+ *
+ * @return int
+ * @return error - if the database cannot be read
+ */
+func (s *PostgresStore) SchemaVersion() (int, error) {
+	var exists bool
+	if err := s.conn.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	err := s.conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+/*
+ * CheckSchemaVersion
+ *
+ * Checks that the database schema is the version this build needs.
+ *
+ * @return error - if it is behind (migrations pending) or ahead (a newer build migrated it)
+ */
+func (s *PostgresStore) CheckSchemaVersion() error {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	latest := LatestSchemaVersion()
+	if version > latest {
+		return fmt.Errorf("database schema is at version %d, ahead of this build (version %d); run a newer build", version, latest)
+	}
+	if version < latest {
+		return fmt.Errorf("database schema is at version %d, this build needs version %d; run the migrate command or set MIGRATE_ON_START=true", version, latest)
+	}
+	return nil
+}
+
+/*
+ * MigrationStatuses
+ *
+ * Lists this build's migrations with when each was applied.
+ *
+ * @return []MigrationStatus - in version order
+ * @return int - the database's schema version, which can be ahead of this build
+ * @return error
+ */
+func (s *PostgresStore) MigrationStatuses() ([]MigrationStatus, int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, 0, err
+	}
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	applied := make(map[int]time.Time)
+	if version > 0 {
+		rows, err := s.conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v int
+			var appliedAt time.Time
+			if err := rows.Scan(&v, &appliedAt); err != nil {
+				return nil, 0, err
+			}
+			applied[v] = appliedAt
+		}
+		if err := rows.Err(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i].Migration = migration
+		if appliedAt, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, version, nil
+}
+
+/*
+ * Migrate
+ *
+ * Applies or reverts migrations until the database schema is at the target version.
+ * Each migration runs in its own transaction with its `schema_migrations` row, so a
+ * failed migration leaves the schema at the previous version. An advisory lock is
+ * held throughout, so concurrent runs wait for each other.
+ *
+ * @param int target - schema version wanted, 0 to revert everything
+ * @param func(Migration, bool) progress - called before each migration runs, with
+ *        true if it is being reverted; may be nil
+ *
+ * @return int - the schema version reached
+ * @return error - if the target is unknown, the schema is ahead of this build, or a migration fails
+ */
+func (s *PostgresStore) Migrate(target int, progress func(Migration, bool)) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	conn, err := s.conn.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return 0, fmt.Errorf("locking schema_migrations: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return 0, fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, err
+	}
+
+	steps, down, err := planMigrations(migrations, current, target)
+	if err != nil {
+		return current, err
+	}
+
+	for _, migration := range steps {
+		if progress != nil {
+			progress(migration, down)
+		}
+		if err := runMigration(ctx, conn, migration, down); err != nil {
+			return current, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		current = migration.Version
+		if down {
+			current--
+		}
+	}
+	return current, nil
+}
+
+// runMigration applies or reverts one migration and records it, in a transaction
+func runMigration(ctx context.Context, conn *sql.Conn, migration Migration, down bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if down {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
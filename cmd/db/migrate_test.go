@@ -0,0 +1,90 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if len(migrations) == 0 || LatestSchemaVersion() != len(migrations) {
+		t.Fatalf("LatestSchemaVersion = %d with %d migrations", LatestSchemaVersion(), len(migrations))
+	}
+	for _, migration := range migrations {
+		// Each migration runs in a transaction the runner opens
+		for _, sql := range []string{migration.Up, migration.Down} {
+			if strings.Contains(sql, "BEGIN;") || strings.Contains(sql, "COMMIT;") {
+				t.Errorf("migration %d_%s manages its own transaction", migration.Version, migration.Name)
+			}
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+
+	migrations, err := loadMigrations(fstest.MapFS{
+		"m/0002_add_b.up.sql":   file("CREATE TABLE b ();"),
+		"m/0002_add_b.down.sql": file("DROP TABLE b;"),
+		"m/0001_add_a.up.sql":   file("CREATE TABLE a ();"),
+		"m/0001_add_a.down.sql": file("DROP TABLE a;"),
+	}, "m")
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "add_a" || migrations[1].Version != 2 || migrations[1].Down != "DROP TABLE b;" {
+		t.Errorf("migrations = %+v", migrations)
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"gap":          {"m/0001_a.up.sql": file("x"), "m/0001_a.down.sql": file("x"), "m/0003_c.up.sql": file("x"), "m/0003_c.down.sql": file("x")},
+		"missing down": {"m/0001_a.up.sql": file("x")},
+		"two names":    {"m/0001_a.up.sql": file("x"), "m/0001_b.down.sql": file("x")},
+		"bad name":     {"m/0001_a.sql": file("x")},
+	}
+	for name, fsys := range invalid {
+		if _, err := loadMigrations(fsys, "m"); err == nil {
+			t.Errorf("%s: loadMigrations succeeded, want an error", name)
+		}
+	}
+}
+
+func TestPlanMigrations(t *testing.T) {
+	migrations := []Migration{{Version: 1, Name: "a"}, {Version: 2, Name: "b"}, {Version: 3, Name: "c"}}
+	versions := func(steps []Migration) (got []int) {
+		for _, step := range steps {
+			got = append(got, step.Version)
+		}
+		return got
+	}
+
+	tests := []struct {
+		current, target int
+		want            string
+		down            bool
+	}{
+		{0, 3, "[1 2 3]", false},
+		{1, 2, "[2]", false},
+		{3, 3, "[]", false},
+		{3, 1, "[3 2]", true},
+		{2, 0, "[2 1]", true},
+	}
+	for _, tt := range tests {
+		steps, down, err := planMigrations(migrations, tt.current, tt.target)
+		if err != nil || fmt.Sprint(versions(steps)) != tt.want || down != tt.down {
+			t.Errorf("planMigrations(%d, %d) = %v down=%v err=%v, want %s down=%v", tt.current, tt.target, versions(steps), down, err, tt.want, tt.down)
+		}
+	}
+
+	if _, _, err := planMigrations(migrations, 4, 3); err == nil || !strings.Contains(err.Error(), "ahead of this build") {
+		t.Errorf("schema ahead of the build: err = %v", err)
+	}
+	if _, _, err := planMigrations(migrations, 1, 4); err == nil {
+		t.Error("unknown target version: want an error")
+	}
+}
@@ -0,0 +1,14 @@
+-- Drops every table, and all the data in them; the migrate command only reverts
+-- this migration when given both -to 0 and -drop-all
+
+DROP TABLE IF EXISTS sailing_departures;
+DROP TABLE IF EXISTS sailing_fill_samples;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS scrape_run_routes;
+DROP TABLE IF EXISTS scrape_runs;
+DROP TABLE IF EXISTS non_capacity_route_snapshots;
+DROP TABLE IF EXISTS capacity_route_snapshots;
+DROP TABLE IF EXISTS schedule_seasons;
+DROP TABLE IF EXISTS non_capacity_routes;
+DROP TABLE IF EXISTS capacity_routes;
@@ -0,0 +1,152 @@
+-- Schema as of the first versioned migration. Databases created by init.sql or updated
+-- by hand with migration.sql before then are brought to the same schema: every table
+-- and index is created only if missing, and columns added by migration.sql since the
+-- tables were first created are added only if missing.
+
+CREATE TABLE IF NOT EXISTS capacity_routes (
+    route_code VARCHAR(6) PRIMARY KEY,
+    from_terminal_code VARCHAR(3) NOT NULL,
+    to_terminal_code VARCHAR(3) NOT NULL,
+    date DATE NOT NULL DEFAULT CURRENT_DATE,
+    sailing_duration VARCHAR(7) NOT NULL,
+    sailings JSONB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS non_capacity_routes (
+    route_code VARCHAR(6) NOT NULL,
+    from_terminal_code VARCHAR(3) NOT NULL,
+    to_terminal_code VARCHAR(3) NOT NULL,
+    date DATE NOT NULL DEFAULT CURRENT_DATE,
+    sailing_duration VARCHAR(7) NOT NULL,
+    effective_from DATE,
+    effective_to DATE,
+    sailings JSONB NOT NULL,
+    PRIMARY KEY (route_code, date)
+);
+
+CREATE TABLE IF NOT EXISTS schedule_seasons (
+    route_code VARCHAR(6) NOT NULL,
+    effective_from DATE NOT NULL,
+    effective_to DATE NOT NULL,
+    label TEXT NOT NULL,
+    scraped_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (route_code, effective_from)
+);
+
+CREATE TABLE IF NOT EXISTS capacity_route_snapshots (
+    route_code VARCHAR(6) NOT NULL,
+    service_date DATE NOT NULL,
+    scraped_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    from_terminal_code VARCHAR(3) NOT NULL,
+    to_terminal_code VARCHAR(3) NOT NULL,
+    sailing_duration VARCHAR(7) NOT NULL,
+    sailings JSONB NOT NULL,
+    PRIMARY KEY (route_code, service_date, scraped_at)
+);
+
+CREATE INDEX IF NOT EXISTS capacity_route_snapshots_scraped_at_idx ON capacity_route_snapshots (scraped_at);
+
+CREATE TABLE IF NOT EXISTS non_capacity_route_snapshots (
+    route_code VARCHAR(6) NOT NULL,
+    service_date DATE NOT NULL,
+    scraped_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    from_terminal_code VARCHAR(3) NOT NULL,
+    to_terminal_code VARCHAR(3) NOT NULL,
+    sailing_duration VARCHAR(7) NOT NULL,
+    sailings JSONB NOT NULL,
+    PRIMARY KEY (route_code, service_date, scraped_at)
+);
+
+CREATE INDEX IF NOT EXISTS non_capacity_route_snapshots_scraped_at_idx ON non_capacity_route_snapshots (scraped_at);
+
+CREATE TABLE IF NOT EXISTS scrape_runs (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    route_count INTEGER NOT NULL,
+    success_count INTEGER NOT NULL,
+    sailing_count INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS scrape_runs_kind_started_at_idx ON scrape_runs (kind, started_at DESC);
+
+CREATE TABLE IF NOT EXISTS scrape_run_routes (
+    run_id BIGINT NOT NULL REFERENCES scrape_runs (id) ON DELETE CASCADE,
+    route_code VARCHAR(6) NOT NULL,
+    success BOOLEAN NOT NULL,
+    sailing_count INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL,
+    PRIMARY KEY (run_id, route_code)
+);
+
+CREATE INDEX IF NOT EXISTS scrape_run_routes_route_code_idx ON scrape_run_routes (route_code);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    route_codes TEXT[] NOT NULL DEFAULT '{}',
+    terminal_codes TEXT[] NOT NULL DEFAULT '{}',
+    fill_above INTEGER,
+    car_fill_above INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL,
+    success BOOLEAN NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL,
+    delivered_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_id_idx ON webhook_deliveries (subscription_id, delivered_at DESC);
+
+CREATE TABLE IF NOT EXISTS sailing_fill_samples (
+    sailing_id VARCHAR(32) NOT NULL,
+    route_code VARCHAR(6) NOT NULL,
+    service_date DATE NOT NULL,
+    scheduled_time VARCHAR(16) NOT NULL,
+    departure_minutes INTEGER NOT NULL,
+    sampled_at TIMESTAMPTZ NOT NULL,
+    minutes_before_departure INTEGER NOT NULL,
+    sailing_status VARCHAR(16) NOT NULL,
+    fill INTEGER NOT NULL,
+    car_fill INTEGER NOT NULL,
+    oversize_fill INTEGER NOT NULL,
+    PRIMARY KEY (sailing_id, sampled_at)
+);
+
+CREATE INDEX IF NOT EXISTS sailing_fill_samples_route_departure_idx ON sailing_fill_samples (route_code, departure_minutes, service_date);
+
+CREATE TABLE IF NOT EXISTS sailing_departures (
+    sailing_id VARCHAR(32) PRIMARY KEY,
+    route_code VARCHAR(6) NOT NULL,
+    service_date DATE NOT NULL,
+    vessel_name TEXT NOT NULL DEFAULT '',
+    scheduled_departure TIMESTAMPTZ NOT NULL,
+    actual_departure TIMESTAMPTZ NOT NULL,
+    eta TIMESTAMPTZ,
+    actual_arrival TIMESTAMPTZ,
+    delay_minutes INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS sailing_departures_service_date_idx ON sailing_departures (service_date, route_code);
+
+-- Columns and keys added to the route tables after they were first created
+ALTER TABLE capacity_routes ADD COLUMN IF NOT EXISTS date DATE NOT NULL DEFAULT CURRENT_DATE;
+ALTER TABLE non_capacity_routes ADD COLUMN IF NOT EXISTS date DATE NOT NULL DEFAULT CURRENT_DATE;
+ALTER TABLE non_capacity_routes ADD COLUMN IF NOT EXISTS effective_from DATE;
+ALTER TABLE non_capacity_routes ADD COLUMN IF NOT EXISTS effective_to DATE;
+ALTER TABLE non_capacity_routes DROP CONSTRAINT IF EXISTS non_capacity_routes_pkey;
+ALTER TABLE non_capacity_routes ADD PRIMARY KEY (route_code, date);
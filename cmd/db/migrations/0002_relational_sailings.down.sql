@@ -0,0 +1,67 @@
+-- Puts the sailings back into a JSONB array per route, then drops their tables. Leg
+-- terminals keep only their code and name, and typed times are set again by the next
+-- scrape of each route.
+
+ALTER TABLE capacity_routes ADD COLUMN IF NOT EXISTS sailings JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE non_capacity_routes ADD COLUMN IF NOT EXISTS sailings JSONB NOT NULL DEFAULT '[]';
+
+UPDATE capacity_routes r
+SET sailings = COALESCE((
+    SELECT jsonb_agg(jsonb_strip_nulls(jsonb_build_object(
+        'id', s.sailing_id,
+        'time', s.departure_time,
+        'scheduledTime', NULLIF(s.scheduled_time, ''),
+        'actualDepartureTime', NULLIF(s.actual_departure_time, ''),
+        'arrivalTime', s.arrival_time,
+        'eta', NULLIF(s.eta, ''),
+        'actualArrivalTime', NULLIF(s.actual_arrival_time, ''),
+        'sailingStatus', s.sailing_status,
+        'fill', s.fill,
+        'carFill', s.car_fill,
+        'oversizeFill', s.oversize_fill,
+        'vesselName', s.vessel_name,
+        'vesselStatus', s.vessel_status
+    )) ORDER BY s.position)
+    FROM sailings s
+    WHERE s.kind = 'capacity' AND s.route_code = r.route_code
+), '[]');
+
+UPDATE non_capacity_routes r
+SET sailings = COALESCE((
+    SELECT jsonb_agg(jsonb_strip_nulls(jsonb_build_object(
+        'id', s.sailing_id,
+        'time', s.departure_time,
+        'arrivalTime', s.arrival_time,
+        'sailingDuration', s.sailing_duration,
+        'isNonStop', s.is_non_stop,
+        'hasStops', s.has_stops,
+        'isThruFare', s.is_thru_fare,
+        'events', (
+            SELECT jsonb_agg(jsonb_build_object('type', e.event_type, 'terminalName', e.terminal_name) ORDER BY e.sequence)
+            FROM sailing_events e WHERE e.sailing_id = s.sailing_id AND e.kind = s.kind
+        ),
+        'legs', (
+            SELECT jsonb_agg(jsonb_build_object(
+                'leg_number', l.leg_number,
+                'origin_terminal', jsonb_build_object('Code', l.origin_terminal_code, 'Name', l.origin_terminal_name),
+                'destination_terminal', jsonb_build_object('Code', l.destination_terminal_code, 'Name', l.destination_terminal_name),
+                'distance_km', l.distance_km,
+                'avg_duration_min', l.avg_duration_min,
+                'vessel_name', l.vessel_name
+            ) ORDER BY l.leg_number)
+            FROM sailing_legs l WHERE l.sailing_id = s.sailing_id AND l.kind = s.kind
+        ),
+        'total_travel_min', s.total_travel_min,
+        'total_dwell_min', s.total_dwell_min,
+        'avg_dwell_per_stop_min', s.avg_dwell_per_stop_min
+    )) ORDER BY s.position)
+    FROM sailings s
+    WHERE s.kind = 'non_capacity' AND s.route_code = r.route_code AND s.service_date = r.date
+), '[]');
+
+ALTER TABLE capacity_routes ALTER COLUMN sailings DROP DEFAULT;
+ALTER TABLE non_capacity_routes ALTER COLUMN sailings DROP DEFAULT;
+
+DROP TABLE IF EXISTS sailing_legs;
+DROP TABLE IF EXISTS sailing_events;
+DROP TABLE IF EXISTS sailings;
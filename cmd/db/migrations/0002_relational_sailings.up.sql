@@ -0,0 +1,140 @@
+-- Stores sailings, their events and their legs as rows instead of a JSONB array per
+-- route, backfilled from those arrays. Typed times (departure_at etc.) are only filled
+-- in for routes scraped since they were added to the JSON, and are set on every route
+-- by its next scrape.
+
+CREATE TABLE IF NOT EXISTS sailings (
+    sailing_id VARCHAR(32) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    route_code VARCHAR(6) NOT NULL,
+    service_date DATE NOT NULL,
+    position INTEGER NOT NULL,
+    departure_time VARCHAR(32) NOT NULL DEFAULT '',
+    scheduled_time VARCHAR(32) NOT NULL DEFAULT '',
+    actual_departure_time VARCHAR(32) NOT NULL DEFAULT '',
+    arrival_time VARCHAR(32) NOT NULL DEFAULT '',
+    eta VARCHAR(32) NOT NULL DEFAULT '',
+    actual_arrival_time VARCHAR(32) NOT NULL DEFAULT '',
+    sailing_duration VARCHAR(16) NOT NULL DEFAULT '',
+    sailing_status VARCHAR(16) NOT NULL DEFAULT '',
+    fill INTEGER NOT NULL DEFAULT 0,
+    car_fill INTEGER NOT NULL DEFAULT 0,
+    oversize_fill INTEGER NOT NULL DEFAULT 0,
+    vessel_name TEXT NOT NULL DEFAULT '',
+    vessel_status TEXT NOT NULL DEFAULT '',
+    is_non_stop BOOLEAN NOT NULL DEFAULT FALSE,
+    has_stops BOOLEAN NOT NULL DEFAULT FALSE,
+    is_thru_fare BOOLEAN NOT NULL DEFAULT FALSE,
+    total_travel_min INTEGER NOT NULL DEFAULT 0,
+    total_dwell_min INTEGER NOT NULL DEFAULT 0,
+    avg_dwell_per_stop_min INTEGER,
+    scheduled_departure_at TIMESTAMPTZ,
+    departure_at TIMESTAMPTZ,
+    arrival_at TIMESTAMPTZ,
+    arrival_status VARCHAR(16) NOT NULL DEFAULT '',
+    duration_minutes INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (sailing_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS sailings_route_idx ON sailings (kind, route_code, service_date, position);
+CREATE INDEX IF NOT EXISTS sailings_departure_idx ON sailings (service_date, (COALESCE(departure_at, scheduled_departure_at)));
+CREATE INDEX IF NOT EXISTS sailings_vessel_idx ON sailings (lower(vessel_name), service_date) WHERE vessel_name <> '';
+CREATE INDEX IF NOT EXISTS sailings_status_idx ON sailings (sailing_status, service_date) WHERE sailing_status <> '';
+
+CREATE TABLE IF NOT EXISTS sailing_events (
+    sailing_id VARCHAR(32) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    sequence INTEGER NOT NULL,
+    event_type VARCHAR(16) NOT NULL,
+    terminal_name TEXT NOT NULL,
+    PRIMARY KEY (sailing_id, kind, sequence),
+    FOREIGN KEY (sailing_id, kind) REFERENCES sailings (sailing_id, kind) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS sailing_legs (
+    sailing_id VARCHAR(32) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    leg_number INTEGER NOT NULL,
+    origin_terminal_code VARCHAR(8) NOT NULL,
+    origin_terminal_name TEXT NOT NULL DEFAULT '',
+    destination_terminal_code VARCHAR(8) NOT NULL,
+    destination_terminal_name TEXT NOT NULL DEFAULT '',
+    distance_km DOUBLE PRECISION,
+    avg_duration_min INTEGER,
+    vessel_name TEXT,
+    PRIMARY KEY (sailing_id, kind, leg_number),
+    FOREIGN KEY (sailing_id, kind) REFERENCES sailings (sailing_id, kind) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS sailing_legs_vessel_idx ON sailing_legs (lower(vessel_name)) WHERE vessel_name IS NOT NULL;
+CREATE INDEX IF NOT EXISTS sailing_legs_terminals_idx ON sailing_legs (origin_terminal_code, destination_terminal_code);
+
+-- Databases that already had these tables before migrations were versioned have no
+-- sailing arrays left to backfill from
+ALTER TABLE capacity_routes ADD COLUMN IF NOT EXISTS sailings JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE non_capacity_routes ADD COLUMN IF NOT EXISTS sailings JSONB NOT NULL DEFAULT '[]';
+
+INSERT INTO sailings (
+    sailing_id, kind, route_code, service_date, position, departure_time, scheduled_time,
+    actual_departure_time, arrival_time, eta, actual_arrival_time, sailing_status, fill,
+    car_fill, oversize_fill, vessel_name, vessel_status, scheduled_departure_at,
+    departure_at, arrival_at, arrival_status, duration_minutes
+)
+SELECT s.value ->> 'id', 'capacity', r.route_code, r.date, s.ordinality - 1,
+    COALESCE(s.value ->> 'time', ''), COALESCE(s.value ->> 'scheduledTime', ''),
+    COALESCE(s.value ->> 'actualDepartureTime', ''), COALESCE(s.value ->> 'arrivalTime', ''),
+    COALESCE(s.value ->> 'eta', ''), COALESCE(s.value ->> 'actualArrivalTime', ''),
+    COALESCE(s.value ->> 'sailingStatus', ''), COALESCE((s.value ->> 'fill')::int, 0),
+    COALESCE((s.value ->> 'carFill')::int, 0), COALESCE((s.value ->> 'oversizeFill')::int, 0),
+    COALESCE(s.value ->> 'vesselName', ''), COALESCE(s.value ->> 'vesselStatus', ''),
+    (s.value ->> 'scheduledDepartureAt')::timestamptz, (s.value ->> 'departureAt')::timestamptz,
+    (s.value ->> 'arrivalAt')::timestamptz, COALESCE(s.value ->> 'arrivalStatus', ''),
+    COALESCE((s.value ->> 'durationMinutes')::int, 0)
+FROM capacity_routes r, jsonb_array_elements(r.sailings) WITH ORDINALITY AS s(value, ordinality)
+WHERE COALESCE(s.value ->> 'id', '') <> ''
+ON CONFLICT (sailing_id, kind) DO NOTHING;
+
+INSERT INTO sailings (
+    sailing_id, kind, route_code, service_date, position, departure_time, arrival_time,
+    sailing_duration, is_non_stop, has_stops, is_thru_fare, total_travel_min, total_dwell_min,
+    avg_dwell_per_stop_min, departure_at, arrival_at, arrival_status, duration_minutes
+)
+SELECT s.value ->> 'id', 'non_capacity', r.route_code, r.date, s.ordinality - 1,
+    COALESCE(s.value ->> 'time', ''), COALESCE(s.value ->> 'arrivalTime', ''),
+    COALESCE(s.value ->> 'sailingDuration', ''), COALESCE((s.value ->> 'isNonStop')::boolean, FALSE),
+    COALESCE((s.value ->> 'hasStops')::boolean, FALSE), COALESCE((s.value ->> 'isThruFare')::boolean, FALSE),
+    COALESCE((s.value ->> 'total_travel_min')::int, 0), COALESCE((s.value ->> 'total_dwell_min')::int, 0),
+    (s.value ->> 'avg_dwell_per_stop_min')::int, (s.value ->> 'departureAt')::timestamptz,
+    (s.value ->> 'arrivalAt')::timestamptz, COALESCE(s.value ->> 'arrivalStatus', ''),
+    COALESCE((s.value ->> 'durationMinutes')::int, 0)
+FROM non_capacity_routes r, jsonb_array_elements(r.sailings) WITH ORDINALITY AS s(value, ordinality)
+WHERE COALESCE(s.value ->> 'id', '') <> ''
+ON CONFLICT (sailing_id, kind) DO NOTHING;
+
+INSERT INTO sailing_events (sailing_id, kind, sequence, event_type, terminal_name)
+SELECT s.value ->> 'id', 'non_capacity', e.ordinality,
+    COALESCE(e.value ->> 'type', ''), COALESCE(e.value ->> 'terminalName', '')
+FROM non_capacity_routes r,
+    jsonb_array_elements(r.sailings) AS s(value),
+    jsonb_array_elements(COALESCE(s.value -> 'events', '[]')) WITH ORDINALITY AS e(value, ordinality)
+WHERE COALESCE(s.value ->> 'id', '') <> ''
+ON CONFLICT (sailing_id, kind, sequence) DO NOTHING;
+
+INSERT INTO sailing_legs (
+    sailing_id, kind, leg_number, origin_terminal_code, origin_terminal_name,
+    destination_terminal_code, destination_terminal_name, distance_km, avg_duration_min, vessel_name
+)
+SELECT s.value ->> 'id', 'non_capacity', (l.value ->> 'leg_number')::int,
+    COALESCE(l.value -> 'origin_terminal' ->> 'Code', ''), COALESCE(l.value -> 'origin_terminal' ->> 'Name', ''),
+    COALESCE(l.value -> 'destination_terminal' ->> 'Code', ''), COALESCE(l.value -> 'destination_terminal' ->> 'Name', ''),
+    (l.value ->> 'distance_km')::double precision, (l.value ->> 'avg_duration_min')::int, l.value ->> 'vessel_name'
+FROM non_capacity_routes r,
+    jsonb_array_elements(r.sailings) AS s(value),
+    jsonb_array_elements(COALESCE(s.value -> 'legs', '[]')) AS l(value)
+WHERE COALESCE(s.value ->> 'id', '') <> ''
+ON CONFLICT (sailing_id, kind, leg_number) DO NOTHING;
+
+-- Replaced by sailings, on databases where migration.sql created it
+DROP TABLE IF EXISTS sailing_index;
+ALTER TABLE capacity_routes DROP COLUMN IF EXISTS sailings;
+ALTER TABLE non_capacity_routes DROP COLUMN IF EXISTS sailings;
@@ -4,26 +4,48 @@ import (
 	"database/sql"
 
 	_ "github.com/lib/pq"
-
-	"github.com/jeffcstock/bc-ferries-api/cmd/config"
 )
 
-var Conn *sql.DB
+/*
+ * PostgresStore
+ *
+ * Store backed by PostgreSQL (schema in cmd/db/migrations, see Migrate).
+ */
+type PostgresStore struct {
+	conn *sql.DB
+}
 
 /*
- * Init
+ * NewPostgresStore
  *
- * Initializes the global PostgreSQL database connection using the DSN from config.DB.URL.
+ * Opens a PostgreSQL connection pool for the given DSN and checks that the
+ * database can be reached.
  *
- * Opens a connection pool and assigns it to the Conn variable.
- * Panics if the connection cannot be established.
+ * @param string url - DSN, e.g. config.DB.URL
  *
- * @return void
+ * @return *PostgresStore
+ * @return error - if the DSN is invalid or the database cannot be reached
  */
-func Init() {
-	var err error
-	Conn, err = sql.Open("postgres", config.DB.URL)
+func NewPostgresStore(url string) (*PostgresStore, error) {
+	conn, err := sql.Open("postgres", url)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, err
 	}
+
+	return &PostgresStore{conn: conn}, nil
+}
+
+/*
+ * Close
+ *
+ * Closes the connection pool.
+ *
+ * @return error
+ */
+func (s *PostgresStore) Close() error {
+	return s.conn.Close()
 }
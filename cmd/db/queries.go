@@ -2,8 +2,8 @@ package db
 
 import (
 	"database/sql"
-	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/jeffcstock/bc-ferries-api/cmd/models"
 	"github.com/lib/pq"
@@ -12,19 +12,19 @@ import (
 /*
  * GetCapacitySailings
  *
- * Retrieves all capacity route records from the database, including parsed sailing data.
+ * Retrieves all capacity route records from the database, including their sailings.
  *
- * Queries the `capacity_routes` table and unmarshals the `sailings` JSON column
- * into a slice of `models.CapacitySailing` for each route.
+ * Queries the `capacity_routes` table, and the `sailings` rows of each route in the
+ * order they were scraped.
  *
  * @return []models.CapacityRoute - a slice of capacity routes with their sailings
  */
-func GetCapacitySailings() []models.CapacityRoute {
+func (s *PostgresStore) GetCapacitySailings() []models.CapacityRoute {
 	var routes []models.CapacityRoute
 
-	sqlStatement := `SELECT * FROM capacity_routes`
+	sqlStatement := `SELECT route_code, from_terminal_code, to_terminal_code, to_char(date, 'YYYY-MM-DD'), sailing_duration FROM capacity_routes ORDER BY route_code`
 
-	rows, err := Conn.Query(sqlStatement)
+	rows, err := s.conn.Query(sqlStatement)
 	if err != nil {
 		log.Printf("GetCapacitySailings: query failed: %v", err)
 		return routes
@@ -33,21 +33,13 @@ func GetCapacitySailings() []models.CapacityRoute {
 
 	for rows.Next() {
 		var route models.CapacityRoute
-		var sailings []uint8
 
-		err := rows.Scan(&route.RouteCode, &route.FromTerminalCode, &route.ToTerminalCode, &route.Date, &route.SailingDuration, &sailings)
+		err := rows.Scan(&route.RouteCode, &route.FromTerminalCode, &route.ToTerminalCode, &route.Date, &route.SailingDuration)
 		if err != nil {
 			log.Printf("GetCapacitySailings: row scan failed: %v", err)
 			continue
 		}
 
-		var content []models.CapacitySailing
-		if err := json.Unmarshal(sailings, &content); err != nil {
-			log.Printf("GetCapacitySailings: JSON unmarshal failed: %v", err)
-			continue
-		}
-
-		route.Sailings = content
 		routes = append(routes, route)
 	}
 
@@ -55,25 +47,70 @@ func GetCapacitySailings() []models.CapacityRoute {
 		log.Printf("GetCapacitySailings: row iteration error: %v", err)
 	}
 
+	sailings := make(map[string][]models.CapacitySailing)
+	for _, row := range s.querySailings("GetCapacitySailings", `WHERE s.kind = $1 ORDER BY s.route_code, s.position`, sailingKindCapacity) {
+		sailings[row.details.RouteCode] = append(sailings[row.details.RouteCode], *row.capacity)
+	}
+
+	for i := range routes {
+		routes[i].Sailings = append([]models.CapacitySailing{}, sailings[routes[i].RouteCode]...)
+		// Typed times are not stored for sailings scraped before they existed
+		routes[i] = models.WithCapacityTimes(routes[i])
+	}
+
 	return routes
 }
 
+/*
+ * GetCapacityRoute
+ *
+ * Retrieves a single capacity route, including its sailings.
+ *
+ * @param string routeCode - e.g. "TSASWB"
+ *
+ * @return *models.CapacityRoute - nil if the route has no record
+ */
+func (s *PostgresStore) GetCapacityRoute(routeCode string) *models.CapacityRoute {
+	var route models.CapacityRoute
+
+	sqlStatement := `SELECT route_code, from_terminal_code, to_terminal_code, to_char(date, 'YYYY-MM-DD'), sailing_duration FROM capacity_routes WHERE route_code = $1`
+
+	err := s.conn.QueryRow(sqlStatement, routeCode).Scan(&route.RouteCode, &route.FromTerminalCode, &route.ToTerminalCode, &route.Date, &route.SailingDuration)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("GetCapacityRoute: query failed: %v", err)
+		return nil
+	}
+
+	route.Sailings = []models.CapacitySailing{}
+	for _, row := range s.querySailings("GetCapacityRoute", `WHERE s.kind = $1 AND s.route_code = $2 ORDER BY s.position`, sailingKindCapacity, routeCode) {
+		route.Sailings = append(route.Sailings, *row.capacity)
+	}
+
+	route = models.WithCapacityTimes(route)
+	return &route
+}
+
 /*
  * GetNonCapacitySailings
  *
- * Retrieves all non-capacity route records from the database, including parsed sailing data.
+ * Retrieves non-capacity route records for a service date, including their sailings.
+ *
+ * Queries the `non_capacity_routes` table, and the `sailings` rows of each route with
+ * their `sailing_events` and `sailing_legs`.
  *
- * Queries the `non_capacity_routes` table and unmarshals the `sailings` JSON column
- * into a slice of `models.NonCapacitySailing` for each route.
+ * @param string date - ISO service date (e.g. "2025-11-11")
  *
  * @return []models.NonCapacityRoute - a slice of non-capacity routes with their sailings
  */
-func GetNonCapacitySailings() []models.NonCapacityRoute {
+func (s *PostgresStore) GetNonCapacitySailings(date string) []models.NonCapacityRoute {
 	var routes []models.NonCapacityRoute
 
-	sqlStatement := `SELECT * FROM non_capacity_routes`
+	sqlStatement := `SELECT route_code, from_terminal_code, to_terminal_code, to_char(date, 'YYYY-MM-DD'), sailing_duration, COALESCE(to_char(effective_from, 'YYYY-MM-DD'), ''), COALESCE(to_char(effective_to, 'YYYY-MM-DD'), '') FROM non_capacity_routes WHERE date = $1 ORDER BY route_code`
 
-	rows, err := Conn.Query(sqlStatement)
+	rows, err := s.conn.Query(sqlStatement, date)
 	if err != nil {
 		log.Printf("GetNonCapacitySailings: query failed: %v", err)
 		return routes
@@ -82,21 +119,13 @@ func GetNonCapacitySailings() []models.NonCapacityRoute {
 
 	for rows.Next() {
 		var route models.NonCapacityRoute
-		var sailings []uint8
 
-		err := rows.Scan(&route.RouteCode, &route.FromTerminalCode, &route.ToTerminalCode, &route.Date, &route.SailingDuration, &sailings)
+		err := rows.Scan(&route.RouteCode, &route.FromTerminalCode, &route.ToTerminalCode, &route.Date, &route.SailingDuration, &route.EffectiveFrom, &route.EffectiveTo)
 		if err != nil {
 			log.Printf("GetNonCapacitySailings: row scan failed: %v", err)
 			continue
 		}
 
-		var content []models.NonCapacitySailing
-		if err := json.Unmarshal(sailings, &content); err != nil {
-			log.Printf("GetNonCapacitySailings: JSON unmarshal failed: %v", err)
-			continue
-		}
-
-		route.Sailings = content
 		routes = append(routes, route)
 	}
 
@@ -104,9 +133,52 @@ func GetNonCapacitySailings() []models.NonCapacityRoute {
 		log.Printf("GetNonCapacitySailings: row iteration error: %v", err)
 	}
 
+	sailings := make(map[string][]models.NonCapacitySailing)
+	for _, row := range s.querySailings("GetNonCapacitySailings", `WHERE s.kind = $1 AND s.service_date = $2 ORDER BY s.route_code, s.position`, sailingKindNonCapacity, date) {
+		sailings[row.details.RouteCode] = append(sailings[row.details.RouteCode], *row.nonCapacity)
+	}
+
+	for i := range routes {
+		routes[i].Sailings = append([]models.NonCapacitySailing{}, sailings[routes[i].RouteCode]...)
+		routes[i] = models.WithNonCapacityTimes(routes[i])
+	}
+
 	return routes
 }
 
+/*
+ * GetNonCapacityRoute
+ *
+ * Retrieves a single non-capacity route for a service date.
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param string date - ISO service date (e.g. "2025-11-11")
+ *
+ * @return *models.NonCapacityRoute - nil if the route has no record for that date
+ */
+func (s *PostgresStore) GetNonCapacityRoute(routeCode, date string) *models.NonCapacityRoute {
+	var route models.NonCapacityRoute
+
+	sqlStatement := `SELECT route_code, from_terminal_code, to_terminal_code, to_char(date, 'YYYY-MM-DD'), sailing_duration, COALESCE(to_char(effective_from, 'YYYY-MM-DD'), ''), COALESCE(to_char(effective_to, 'YYYY-MM-DD'), '') FROM non_capacity_routes WHERE route_code = $1 AND date = $2`
+
+	err := s.conn.QueryRow(sqlStatement, routeCode, date).Scan(&route.RouteCode, &route.FromTerminalCode, &route.ToTerminalCode, &route.Date, &route.SailingDuration, &route.EffectiveFrom, &route.EffectiveTo)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("GetNonCapacityRoute: query failed: %v", err)
+		return nil
+	}
+
+	route.Sailings = []models.NonCapacitySailing{}
+	for _, row := range s.querySailings("GetNonCapacityRoute", `WHERE s.kind = $1 AND s.route_code = $2 AND s.service_date = $3 ORDER BY s.position`, sailingKindNonCapacity, routeCode, date) {
+		route.Sailings = append(route.Sailings, *row.nonCapacity)
+	}
+
+	route = models.WithNonCapacityTimes(route)
+	return &route
+}
+
 /*
  * GetCapacityRoutesInfo
  *
@@ -117,7 +189,7 @@ func GetNonCapacitySailings() []models.NonCapacityRoute {
  *
  * @return []models.CapacityRouteInfo - a slice of capacity route metadata
  */
-func GetCapacityRoutesInfo(routeCodes []string) []models.CapacityRouteInfo {
+func (s *PostgresStore) GetCapacityRoutesInfo(routeCodes []string) []models.CapacityRouteInfo {
 	var routes []models.CapacityRouteInfo
 
 	sqlStatement := `SELECT route_code, from_terminal_code, to_terminal_code, date, sailing_duration FROM capacity_routes`
@@ -126,9 +198,9 @@ func GetCapacityRoutesInfo(routeCodes []string) []models.CapacityRouteInfo {
 
 	if len(routeCodes) > 0 {
 		sqlStatement += ` WHERE route_code = ANY($1)`
-		rows, err = Conn.Query(sqlStatement, pq.Array(routeCodes))
+		rows, err = s.conn.Query(sqlStatement, pq.Array(routeCodes))
 	} else {
-		rows, err = Conn.Query(sqlStatement)
+		rows, err = s.conn.Query(sqlStatement)
 	}
 
 	if err != nil {
@@ -159,25 +231,27 @@ func GetCapacityRoutesInfo(routeCodes []string) []models.CapacityRouteInfo {
 /*
  * GetNonCapacityRoutesInfo
  *
- * Retrieves non-capacity route metadata (without sailings) from the database.
+ * Retrieves non-capacity route metadata (without sailings) for a service date from the database.
+ * Routes are stored once per service date, so this lists each route at most once.
  * Optionally filters by specific route codes if provided.
  *
+ * @param date string - ISO service date (e.g. "2025-11-11")
  * @param routeCodes []string - optional list of route codes to filter by (empty slice = all routes)
  *
  * @return []models.NonCapacityRouteInfo - a slice of non-capacity route metadata
  */
-func GetNonCapacityRoutesInfo(routeCodes []string) []models.NonCapacityRouteInfo {
+func (s *PostgresStore) GetNonCapacityRoutesInfo(date string, routeCodes []string) []models.NonCapacityRouteInfo {
 	var routes []models.NonCapacityRouteInfo
 
-	sqlStatement := `SELECT route_code, from_terminal_code, to_terminal_code, date, sailing_duration FROM non_capacity_routes`
+	sqlStatement := `SELECT route_code, from_terminal_code, to_terminal_code, date, sailing_duration FROM non_capacity_routes WHERE date = $1`
 	var rows *sql.Rows
 	var err error
 
 	if len(routeCodes) > 0 {
-		sqlStatement += ` WHERE route_code = ANY($1)`
-		rows, err = Conn.Query(sqlStatement, pq.Array(routeCodes))
+		sqlStatement += ` AND route_code = ANY($2)`
+		rows, err = s.conn.Query(sqlStatement, date, pq.Array(routeCodes))
 	} else {
-		rows, err = Conn.Query(sqlStatement)
+		rows, err = s.conn.Query(sqlStatement, date)
 	}
 
 	if err != nil {
@@ -204,3 +278,14 @@ func GetNonCapacityRoutesInfo(routeCodes []string) []models.NonCapacityRouteInfo
 
 	return routes
 }
+
+/*
+ * CurrentServiceDate
+ *
+ * Returns today's date in Pacific Time (BC Ferries operates in PT) as an ISO date string.
+ *
+ * @return string - e.g. "2025-11-11"
+ */
+func CurrentServiceDate() string {
+	return time.Now().In(models.Pacific()).Format("2006-01-02")
+}
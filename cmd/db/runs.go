@@ -0,0 +1,209 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * SaveScrapeRun
+ *
+ * Records a finished scrape or cleanup run in `scrape_runs`, with one `scrape_run_routes`
+ * row per route it scraped.
+ *
+ * @param models.ScrapeRun run - run.ID is ignored and assigned by the database
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveScrapeRun(run models.ScrapeRun) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var runID int64
+	err = tx.QueryRow(`
+		INSERT INTO scrape_runs (kind, started_at, finished_at, route_count, success_count, sailing_count, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		run.Kind, run.StartedAt, run.FinishedAt, run.RouteCount, run.SuccessCount, run.SailingCount, run.Error,
+	).Scan(&runID)
+	if err != nil {
+		return err
+	}
+
+	for _, route := range run.Routes {
+		_, err := tx.Exec(`
+			INSERT INTO scrape_run_routes (run_id, route_code, success, sailing_count, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (run_id, route_code) DO NOTHING`,
+			runID, route.RouteCode, route.Success, route.SailingCount, route.Error, route.DurationMs,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+/*
+ * GetLatestScrapeRuns
+ *
+ * Retrieves the most recent run of each kind, with its per-route results.
+ *
+ * @return []models.ScrapeRun - ordered by kind
+ */
+func (s *PostgresStore) GetLatestScrapeRuns() []models.ScrapeRun {
+	runs := []models.ScrapeRun{}
+
+	sqlStatement := `
+		SELECT DISTINCT ON (kind) id, kind, started_at, finished_at, route_count, success_count, sailing_count, error
+		FROM scrape_runs
+		ORDER BY kind, started_at DESC`
+
+	rows, err := s.conn.Query(sqlStatement)
+	if err != nil {
+		log.Printf("GetLatestScrapeRuns: query failed: %v", err)
+		return runs
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var run models.ScrapeRun
+		if err := rows.Scan(&run.ID, &run.Kind, &run.StartedAt, &run.FinishedAt, &run.RouteCount, &run.SuccessCount, &run.SailingCount, &run.Error); err != nil {
+			log.Printf("GetLatestScrapeRuns: scan failed: %v", err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetLatestScrapeRuns: rows error: %v", err)
+	}
+
+	for i := range runs {
+		runs[i].Routes = s.getScrapeRunRoutes(runs[i].ID)
+	}
+
+	return runs
+}
+
+/*
+ * getScrapeRunRoutes
+ *
+ * Retrieves the per-route results of a run, ordered by route code.
+ *
+ * @param int64 runID
+ *
+ * @return []models.ScrapeRouteResult
+ */
+func (s *PostgresStore) getScrapeRunRoutes(runID int64) []models.ScrapeRouteResult {
+	var routes []models.ScrapeRouteResult
+
+	sqlStatement := `
+		SELECT route_code, success, sailing_count, error, duration_ms
+		FROM scrape_run_routes
+		WHERE run_id = $1
+		ORDER BY route_code`
+
+	rows, err := s.conn.Query(sqlStatement, runID)
+	if err != nil {
+		log.Printf("getScrapeRunRoutes: query failed: %v", err)
+		return routes
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var route models.ScrapeRouteResult
+		if err := rows.Scan(&route.RouteCode, &route.Success, &route.SailingCount, &route.Error, &route.DurationMs); err != nil {
+			log.Printf("getScrapeRunRoutes: scan failed: %v", err)
+			continue
+		}
+		routes = append(routes, route)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("getScrapeRunRoutes: rows error: %v", err)
+	}
+
+	return routes
+}
+
+/*
+ * GetRouteFreshness
+ *
+ * For every route with a recorded scrape, finds when it was last attempted and last
+ * scraped successfully, and the error of the last attempt if it failed. Only the
+ * store fields are filled in; Status and LastSuccessAgo are left to the caller.
+ *
+ * @return []models.RouteFreshness - ordered by kind and route code
+ */
+func (s *PostgresStore) GetRouteFreshness() []models.RouteFreshness {
+	routes := []models.RouteFreshness{}
+
+	sqlStatement := `
+		SELECT
+			latest.kind,
+			latest.route_code,
+			latest.finished_at,
+			CASE WHEN latest.success THEN '' ELSE latest.error END,
+			(
+				SELECT MAX(r.finished_at)
+				FROM scrape_run_routes rr
+				JOIN scrape_runs r ON r.id = rr.run_id
+				WHERE r.kind = latest.kind AND rr.route_code = latest.route_code AND rr.success
+			)
+		FROM (
+			SELECT DISTINCT ON (r.kind, rr.route_code) r.kind, rr.route_code, r.finished_at, rr.success, rr.error
+			FROM scrape_run_routes rr
+			JOIN scrape_runs r ON r.id = rr.run_id
+			ORDER BY r.kind, rr.route_code, r.finished_at DESC
+		) latest
+		ORDER BY latest.kind, latest.route_code`
+
+	rows, err := s.conn.Query(sqlStatement)
+	if err != nil {
+		log.Printf("GetRouteFreshness: query failed: %v", err)
+		return routes
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var route models.RouteFreshness
+		var lastSuccessAt sql.NullTime
+		if err := rows.Scan(&route.Kind, &route.RouteCode, &route.LastAttemptAt, &route.LastError, &lastSuccessAt); err != nil {
+			log.Printf("GetRouteFreshness: scan failed: %v", err)
+			continue
+		}
+		if lastSuccessAt.Valid {
+			route.LastSuccessAt = &lastSuccessAt.Time
+		}
+		routes = append(routes, route)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetRouteFreshness: rows error: %v", err)
+	}
+
+	return routes
+}
+
+/*
+ * DeleteScrapeRunsBefore
+ *
+ * Deletes runs (and their per-route results) that started before the cutoff.
+ *
+ * @param time.Time cutoff
+ *
+ * @return int64 - number of runs deleted
+ * @return error
+ */
+func (s *PostgresStore) DeleteScrapeRunsBefore(cutoff time.Time) (int64, error) {
+	result, err := s.conn.Exec(`DELETE FROM scrape_runs WHERE started_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,431 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+	"github.com/lib/pq"
+)
+
+// Kinds of route a sailing is stored under in `sailings`
+const (
+	sailingKindCapacity    = "capacity"
+	sailingKindNonCapacity = "non_capacity"
+)
+
+// Columns of a `sailings` row, its events and legs as JSON arrays, and its route's terminals and duration
+const sailingColumns = `
+	SELECT s.sailing_id, s.kind, s.route_code, to_char(s.service_date, 'YYYY-MM-DD'),
+		COALESCE(c.from_terminal_code, n.from_terminal_code, ''), COALESCE(c.to_terminal_code, n.to_terminal_code, ''),
+		COALESCE(c.sailing_duration, n.sailing_duration, ''),
+		s.departure_time, s.scheduled_time, s.actual_departure_time, s.arrival_time, s.eta,
+		s.actual_arrival_time, s.sailing_duration, s.sailing_status, s.fill, s.car_fill,
+		s.oversize_fill, s.vessel_name, s.vessel_status, s.is_non_stop, s.has_stops,
+		s.is_thru_fare, s.total_travel_min, s.total_dwell_min, s.avg_dwell_per_stop_min,
+		s.scheduled_departure_at, s.departure_at, s.arrival_at, s.arrival_status, s.duration_minutes,
+		COALESCE((
+			SELECT json_agg(json_build_object('type', e.event_type, 'terminalName', e.terminal_name) ORDER BY e.sequence)
+			FROM sailing_events e WHERE e.sailing_id = s.sailing_id AND e.kind = s.kind
+		), '[]'),
+		COALESCE((
+			SELECT json_agg(json_build_object(
+				'legNumber', l.leg_number,
+				'originCode', l.origin_terminal_code, 'originName', l.origin_terminal_name,
+				'destinationCode', l.destination_terminal_code, 'destinationName', l.destination_terminal_name,
+				'distanceKm', l.distance_km, 'avgDurationMin', l.avg_duration_min, 'vesselName', l.vessel_name
+			) ORDER BY l.leg_number)
+			FROM sailing_legs l WHERE l.sailing_id = s.sailing_id AND l.kind = s.kind
+		), '[]')
+	FROM sailings s
+	LEFT JOIN capacity_routes c
+		ON s.kind = 'capacity' AND c.route_code = s.route_code
+	LEFT JOIN non_capacity_routes n
+		ON s.kind = 'non_capacity' AND n.route_code = s.route_code AND n.date = s.service_date`
+
+// sailingRow is a sailing read back from `sailings`, `sailing_events` and `sailing_legs`
+type sailingRow struct {
+	kind        string
+	details     models.SailingDetails // Route context only
+	capacity    *models.CapacitySailing
+	nonCapacity *models.NonCapacitySailing
+}
+
+// legRow is a `sailing_legs` row as aggregated by sailingColumns
+type legRow struct {
+	LegNumber       int      `json:"legNumber"`
+	OriginCode      string   `json:"originCode"`
+	OriginName      string   `json:"originName"`
+	DestinationCode string   `json:"destinationCode"`
+	DestinationName string   `json:"destinationName"`
+	DistanceKm      *float64 `json:"distanceKm"`
+	AvgDurationMin  *int     `json:"avgDurationMin"`
+	VesselName      *string  `json:"vesselName"`
+}
+
+/*
+ * querySailings
+ *
+ * Reads sailings with their events and legs
+ *
+ * @param string caller - for log messages
+ * @param string clauses - WHERE and ORDER BY clauses on `sailings s`
+ * @param ...any args
+ *
+ * @return []sailingRow
+ */
+func (s *PostgresStore) querySailings(caller, clauses string, args ...any) []sailingRow {
+	var sailings []sailingRow
+
+	rows, err := s.conn.Query(sailingColumns+" "+clauses, args...)
+	if err != nil {
+		log.Printf("%s: sailings query failed: %v", caller, err)
+		return sailings
+	}
+	defer rows.Close()
+
+	location := models.Pacific()
+	inLocation := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		local := t.In(location)
+		return &local
+	}
+
+	for rows.Next() {
+		var row sailingRow
+		var c models.CapacitySailing
+		var n models.NonCapacitySailing
+		var id, departureTime, arrivalTime, arrivalStatus string
+		var avgDwell sql.NullInt64
+		var departureAt, arrivalAt *time.Time
+		var durationMinutes int
+		var events, legs []uint8
+
+		err := rows.Scan(
+			&id, &row.kind, &row.details.RouteCode, &row.details.Date,
+			&row.details.FromTerminalCode, &row.details.ToTerminalCode, &row.details.SailingDuration,
+			&departureTime, &c.ScheduledTime, &c.ActualDepartureTime, &arrivalTime, &c.ETA,
+			&c.ActualArrivalTime, &n.SailingDuration, &c.SailingStatus, &c.Fill, &c.CarFill,
+			&c.OversizeFill, &c.VesselName, &c.VesselStatus, &n.IsNonStop, &n.HasStops,
+			&n.IsThruFare, &n.TotalTravelMin, &n.TotalDwellMin, &avgDwell,
+			&c.ScheduledDepartureAt, &departureAt, &arrivalAt, &arrivalStatus, &durationMinutes,
+			&events, &legs,
+		)
+		if err != nil {
+			log.Printf("%s: sailing row scan failed: %v", caller, err)
+			continue
+		}
+
+		row.details.ID = id
+		if row.kind == sailingKindCapacity {
+			c.ID, c.DepartureTime, c.ArrivalTime = id, departureTime, arrivalTime
+			c.ScheduledDepartureAt = inLocation(c.ScheduledDepartureAt)
+			c.DepartureAt, c.ArrivalAt = inLocation(departureAt), inLocation(arrivalAt)
+			c.ArrivalStatus, c.DurationMinutes = arrivalStatus, durationMinutes
+			row.capacity = &c
+			sailings = append(sailings, row)
+			continue
+		}
+
+		n.ID, n.DepartureTime, n.ArrivalTime = id, departureTime, arrivalTime
+		n.DepartureAt, n.ArrivalAt = inLocation(departureAt), inLocation(arrivalAt)
+		n.ArrivalStatus, n.DurationMinutes = arrivalStatus, durationMinutes
+		if avgDwell.Valid {
+			dwell := int(avgDwell.Int64)
+			n.AvgDwellPerStopMin = &dwell
+		}
+		if err := json.Unmarshal(events, &n.Events); err != nil || len(n.Events) == 0 {
+			n.Events = nil
+		}
+		var legRows []legRow
+		if err := json.Unmarshal(legs, &legRows); err != nil {
+			log.Printf("%s: legs of %s: %v", caller, id, err)
+		}
+		for _, leg := range legRows {
+			n.Legs = append(n.Legs, leg.leg())
+		}
+		row.nonCapacity = &n
+		sailings = append(sailings, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("%s: sailing row iteration error: %v", caller, err)
+	}
+
+	return sailings
+}
+
+// leg rebuilds a models.Leg, with the catalogue's terminals (see models.BuildLegs)
+func (l legRow) leg() models.Leg {
+	terminal := func(code, name string) staticdata.Terminal {
+		if known, ok := staticdata.GetTerminals()[code]; ok {
+			return known
+		}
+		return staticdata.Terminal{Code: code, Name: name, ServiceArea: "UNKNOWN"}
+	}
+	return models.Leg{
+		LegNumber:           l.LegNumber,
+		OriginTerminal:      terminal(l.OriginCode, l.OriginName),
+		DestinationTerminal: terminal(l.DestinationCode, l.DestinationName),
+		DistanceKm:          l.DistanceKm,
+		AvgDurationMin:      l.AvgDurationMin,
+		VesselName:          l.VesselName,
+	}
+}
+
+// keepSailing reports whether a sailing gets a `sailings` row, and marks its ID seen.
+// Sailings without an ID (no scheduled time to build one from) cannot be looked up,
+// and they and repeated IDs would collide on the primary key.
+func keepSailing(seen map[string]bool, id string) bool {
+	if id == "" || seen[id] {
+		return false
+	}
+	seen[id] = true
+	return true
+}
+
+/*
+ * saveCapacitySailings
+ *
+ * Replaces the `sailings` rows of a capacity route. Capacity routes keep one row per
+ * route, so the sailings of its earlier dates go too. Called in the transaction that
+ * saves the route.
+ *
+ * @param *sql.Tx tx
+ * @param models.CapacityRoute route
+ *
+ * @return error
+ */
+func saveCapacitySailings(tx *sql.Tx, route models.CapacityRoute) error {
+	if _, err := tx.Exec(`DELETE FROM sailings WHERE kind = $1 AND route_code = $2`, sailingKindCapacity, route.RouteCode); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for position, sailing := range route.Sailings {
+		if !keepSailing(seen, sailing.ID) {
+			continue
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO sailings (
+				sailing_id, kind, route_code, service_date, position, departure_time, scheduled_time,
+				actual_departure_time, arrival_time, eta, actual_arrival_time, sailing_status, fill,
+				car_fill, oversize_fill, vessel_name, vessel_status, scheduled_departure_at,
+				departure_at, arrival_at, arrival_status, duration_minutes
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)`,
+			sailing.ID, sailingKindCapacity, route.RouteCode, route.Date, position, sailing.DepartureTime, sailing.ScheduledTime,
+			sailing.ActualDepartureTime, sailing.ArrivalTime, sailing.ETA, sailing.ActualArrivalTime, sailing.SailingStatus, sailing.Fill,
+			sailing.CarFill, sailing.OversizeFill, sailing.VesselName, sailing.VesselStatus, sailing.ScheduledDepartureAt,
+			sailing.DepartureAt, sailing.ArrivalAt, sailing.ArrivalStatus, sailing.DurationMinutes,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+ * saveNonCapacitySailings
+ *
+ * Replaces the `sailings` rows of a non-capacity route and date, with their
+ * `sailing_events` and `sailing_legs`. Called in the transaction that saves the route.
+ *
+ * @param *sql.Tx tx
+ * @param models.NonCapacityRoute route
+ *
+ * @return error
+ */
+func saveNonCapacitySailings(tx *sql.Tx, route models.NonCapacityRoute) error {
+	_, err := tx.Exec(`DELETE FROM sailings WHERE kind = $1 AND route_code = $2 AND service_date = $3`, sailingKindNonCapacity, route.RouteCode, route.Date)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for position, sailing := range route.Sailings {
+		if !keepSailing(seen, sailing.ID) {
+			continue
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO sailings (
+				sailing_id, kind, route_code, service_date, position, departure_time, arrival_time,
+				sailing_duration, is_non_stop, has_stops, is_thru_fare, total_travel_min, total_dwell_min,
+				avg_dwell_per_stop_min, departure_at, arrival_at, arrival_status, duration_minutes
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+			sailing.ID, sailingKindNonCapacity, route.RouteCode, route.Date, position, sailing.DepartureTime, sailing.ArrivalTime,
+			sailing.SailingDuration, sailing.IsNonStop, sailing.HasStops, sailing.IsThruFare, sailing.TotalTravelMin, sailing.TotalDwellMin,
+			sailing.AvgDwellPerStopMin, sailing.DepartureAt, sailing.ArrivalAt, sailing.ArrivalStatus, sailing.DurationMinutes,
+		)
+		if err != nil {
+			return err
+		}
+
+		for i, event := range sailing.Events {
+			_, err := tx.Exec(`
+				INSERT INTO sailing_events (sailing_id, kind, sequence, event_type, terminal_name)
+				VALUES ($1, $2, $3, $4, $5)`,
+				sailing.ID, sailingKindNonCapacity, i+1, event.Type, event.TerminalName,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, leg := range sailing.Legs {
+			_, err := tx.Exec(`
+				INSERT INTO sailing_legs (
+					sailing_id, kind, leg_number, origin_terminal_code, origin_terminal_name,
+					destination_terminal_code, destination_terminal_name, distance_km, avg_duration_min, vessel_name
+				)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+				sailing.ID, sailingKindNonCapacity, leg.LegNumber, leg.OriginTerminal.Code, leg.OriginTerminal.Name,
+				leg.DestinationTerminal.Code, leg.DestinationTerminal.Name, leg.DistanceKm, leg.AvgDurationMin, leg.VesselName,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+ * GetSailings
+ *
+ * Retrieves sailings by ID, with the route each is on
+ *
+ * @param []string sailingIDs
+ *
+ * @return []models.SailingDetails - the sailings found, in the order requested
+ */
+func (s *PostgresStore) GetSailings(sailingIDs []string) []models.SailingDetails {
+	found := make(map[string]*models.SailingDetails)
+	for _, row := range s.querySailings("GetSailings", `WHERE s.sailing_id = ANY($1::text[])`, pq.Array(nonNil(sailingIDs))) {
+		details := row.details
+		details.Capacity, details.NonCapacity = row.capacity, row.nonCapacity
+		mergeSailingDetails(found, details)
+	}
+
+	return orderSailingDetails(found, sailingIDs)
+}
+
+/*
+ * FindSailings
+ *
+ * Lists the sailings of a service date that match a filter, with every condition
+ * evaluated in SQL on the `sailings` and `sailing_legs` indexes
+ *
+ * @param models.SailingFilter filter
+ *
+ * @return []models.SailingDetails - soonest departure first, as returned by GetSailings
+ */
+func (s *PostgresStore) FindSailings(filter models.SailingFilter) []models.SailingDetails {
+	conditions := []string{"s.service_date = $1"}
+	args := []any{filter.Date}
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$?", fmt.Sprintf("$%d", len(args))))
+	}
+
+	switch filter.Kind {
+	case "capacity":
+		where("s.kind = $?", sailingKindCapacity)
+	case "noncapacity":
+		where("s.kind = $?", sailingKindNonCapacity)
+	}
+	if len(filter.RouteCodes) > 0 {
+		where("s.route_code = ANY($?::text[])", pq.Array(filter.RouteCodes))
+	}
+	if filter.FromTerminalCode != "" {
+		where("left(s.route_code, 3) = $?", filter.FromTerminalCode)
+	}
+	if filter.SailingStatus != "" {
+		where("s.sailing_status = $?", filter.SailingStatus)
+	}
+	if filter.VesselName != "" {
+		where(`(lower(s.vessel_name) = lower($?) OR EXISTS (
+			SELECT 1 FROM sailing_legs l
+			WHERE l.sailing_id = s.sailing_id AND l.kind = s.kind AND lower(l.vessel_name) = lower($?)
+		))`, filter.VesselName)
+	}
+	if filter.DepartAfter != nil {
+		where("COALESCE(s.departure_at, s.scheduled_departure_at) >= $?", *filter.DepartAfter)
+	}
+	if filter.DepartBefore != nil {
+		where("COALESCE(s.departure_at, s.scheduled_departure_at) < $?", *filter.DepartBefore)
+	}
+
+	sqlStatement := `
+		SELECT s.sailing_id
+		FROM sailings s
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY s.sailing_id
+		ORDER BY MIN(COALESCE(s.departure_at, s.scheduled_departure_at)) NULLS LAST, s.sailing_id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		sqlStatement += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.conn.Query(sqlStatement, args...)
+	if err != nil {
+		log.Printf("FindSailings: query failed: %v", err)
+		return []models.SailingDetails{}
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("FindSailings: row scan failed: %v", err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("FindSailings: row iteration error: %v", err)
+	}
+
+	return s.GetSailings(ids)
+}
+
+// mergeSailingDetails adds a sailing found on one kind of route to the ones found so far
+func mergeSailingDetails(found map[string]*models.SailingDetails, details models.SailingDetails) {
+	existing, ok := found[details.ID]
+	if !ok {
+		found[details.ID] = &details
+		return
+	}
+	if details.Capacity != nil {
+		existing.Capacity = details.Capacity
+	}
+	if details.NonCapacity != nil {
+		existing.NonCapacity = details.NonCapacity
+	}
+}
+
+// orderSailingDetails lists the sailings found in the order requested, once each
+func orderSailingDetails(found map[string]*models.SailingDetails, sailingIDs []string) []models.SailingDetails {
+	sailings := []models.SailingDetails{}
+	for _, id := range sailingIDs {
+		if details, ok := found[id]; ok {
+			sailings = append(sailings, *details)
+			delete(found, id)
+		}
+	}
+	return sailings
+}
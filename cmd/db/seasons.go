@@ -0,0 +1,85 @@
+package db
+
+import (
+	"log"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * SaveScheduleSeasons
+ *
+ * Upserts the schedule seasons advertised for a route into the `schedule_seasons` table,
+ * keyed by route code and effective-from date.
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param []models.ScheduleSeason seasons
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveScheduleSeasons(routeCode string, seasons []models.ScheduleSeason) error {
+	sqlStatement := `
+		INSERT INTO schedule_seasons (
+			route_code,
+			effective_from,
+			effective_to,
+			label,
+			scraped_at
+		)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (route_code, effective_from) DO UPDATE SET
+			effective_to = EXCLUDED.effective_to,
+			label = EXCLUDED.label,
+			scraped_at = EXCLUDED.scraped_at`
+
+	for _, season := range seasons {
+		if _, err := s.conn.Exec(sqlStatement, routeCode, season.EffectiveFrom, season.EffectiveTo, season.Label); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+ * GetScheduleSeasons
+ *
+ * Retrieves the known schedule seasons for a route, ordered by effective-from date.
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ *
+ * @return []models.ScheduleSeason
+ */
+func (s *PostgresStore) GetScheduleSeasons(routeCode string) []models.ScheduleSeason {
+	seasons := []models.ScheduleSeason{}
+
+	sqlStatement := `
+		SELECT route_code, label, to_char(effective_from, 'YYYY-MM-DD'), to_char(effective_to, 'YYYY-MM-DD')
+		FROM schedule_seasons
+		WHERE route_code = $1
+		ORDER BY effective_from`
+
+	rows, err := s.conn.Query(sqlStatement, routeCode)
+	if err != nil {
+		log.Printf("GetScheduleSeasons: query failed: %v", err)
+		return seasons
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var season models.ScheduleSeason
+
+		if err := rows.Scan(&season.RouteCode, &season.Label, &season.EffectiveFrom, &season.EffectiveTo); err != nil {
+			log.Printf("GetScheduleSeasons: row scan failed: %v", err)
+			continue
+		}
+
+		seasons = append(seasons, season)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("GetScheduleSeasons: row iteration error: %v", err)
+	}
+
+	return seasons
+}
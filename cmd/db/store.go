@@ -0,0 +1,85 @@
+package db
+
+import (
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * Store
+ *
+ * Persistence used by the router, scraper and cron jobs. Implemented by PostgresStore
+ * (production) and MemoryStore (tests, demos and running without a database).
+ *
+ * Getters log failures and return zero values (nil / empty slices), matching how the
+ * API treats missing data. Writers return errors so the scraper can report them.
+ */
+type Store interface {
+	// Capacity routes (one record per route, replaced on every scrape)
+	GetCapacitySailings() []models.CapacityRoute
+	GetCapacityRoute(routeCode string) *models.CapacityRoute
+	GetCapacityRoutesInfo(routeCodes []string) []models.CapacityRouteInfo
+	SaveCapacityRoute(route models.CapacityRoute) error
+	DeleteCapacityRoutesBefore(date string) (int64, error)
+
+	// Non-capacity routes (one record per route and service date)
+	GetNonCapacitySailings(date string) []models.NonCapacityRoute
+	GetNonCapacityRoute(routeCode, date string) *models.NonCapacityRoute
+	GetNonCapacityRoutesInfo(date string, routeCodes []string) []models.NonCapacityRouteInfo
+	SaveNonCapacityRoute(route models.NonCapacityRoute) error
+	DeleteNonCapacityRoutesBefore(date string) (int64, error)
+
+	// Single sailings of either kind of route, by ID
+	GetSailings(sailingIDs []string) []models.SailingDetails
+	FindSailings(filter models.SailingFilter) []models.SailingDetails
+
+	// Schedule seasons
+	GetScheduleSeasons(routeCode string) []models.ScheduleSeason
+	SaveScheduleSeasons(routeCode string, seasons []models.ScheduleSeason) error
+
+	// History snapshots
+	SaveCapacitySnapshot(route models.CapacityRoute, scrapedAt time.Time) error
+	GetLatestCapacitySnapshot(routeCode, serviceDate string) *models.CapacityRouteSnapshot
+	GetCapacitySnapshotAsOf(routeCode, serviceDate string, asOf time.Time) *models.CapacityRouteSnapshot
+	GetCapacitySnapshots(routeCode, serviceDate string) []models.CapacityRouteSnapshot
+	SaveNonCapacitySnapshot(route models.NonCapacityRoute, scrapedAt time.Time) error
+	GetLatestNonCapacitySnapshot(routeCode, serviceDate string) *models.NonCapacityRouteSnapshot
+	GetNonCapacitySnapshotAsOf(routeCode, serviceDate string, asOf time.Time) *models.NonCapacityRouteSnapshot
+	GetNonCapacitySnapshots(routeCode, serviceDate string) []models.NonCapacityRouteSnapshot
+	DeleteSnapshotsBefore(cutoff time.Time) (int64, error)
+
+	// Fill history of capacity sailings
+	SaveFillSamples(route models.CapacityRoute, sampledAt time.Time) error
+	GetFillHistory(sailingID string) *models.FillHistory
+	GetFillHistories(routeCode string, departureMinutes int, weekday time.Weekday, beforeDate string) []models.FillHistory
+	DeleteFillSamplesBefore(cutoff time.Time) (int64, error)
+
+	// Actual departures of capacity sailings
+	SaveSailingDepartures(route models.CapacityRoute) error
+	GetSailingDepartures(fromDate, toDate string, routeCodes []string) []models.SailingDeparture
+	DeleteSailingDeparturesBefore(date string) (int64, error)
+
+	// Scrape runs
+	SaveScrapeRun(run models.ScrapeRun) error
+	GetLatestScrapeRuns() []models.ScrapeRun
+	GetRouteFreshness() []models.RouteFreshness
+	DeleteScrapeRunsBefore(cutoff time.Time) (int64, error)
+
+	// Webhook subscriptions and their delivery log
+	CreateWebhookSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error)
+	UpdateWebhookSubscription(subscription models.WebhookSubscription) (bool, error)
+	GetWebhookSubscriptions() []models.WebhookSubscription
+	GetWebhookSubscription(id int64) *models.WebhookSubscription
+	DeleteWebhookSubscription(id int64) (bool, error)
+	SaveWebhookDelivery(delivery models.WebhookDelivery) error
+	GetWebhookDeliveries(subscriptionID int64, limit int) []models.WebhookDelivery
+	DeleteWebhookDeliveriesBefore(cutoff time.Time) (int64, error)
+
+	Close() error
+}
+
+var (
+	_ Store = (*PostgresStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
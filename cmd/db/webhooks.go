@@ -0,0 +1,241 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/lib/pq"
+)
+
+const webhookSubscriptionColumns = `id, url, secret, event_types, route_codes, terminal_codes, fill_above, car_fill_above, created_at`
+
+/*
+ * CreateWebhookSubscription
+ *
+ * Inserts a subscription into `webhook_subscriptions`.
+ *
+ * @param models.WebhookSubscription subscription - ID and CreatedAt are ignored
+ *
+ * @return models.WebhookSubscription - the subscription with its ID and CreatedAt
+ * @return error
+ */
+func (s *PostgresStore) CreateWebhookSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	err := s.conn.QueryRow(`
+		INSERT INTO webhook_subscriptions (url, secret, event_types, route_codes, terminal_codes, fill_above, car_fill_above)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		subscription.URL, subscription.Secret, pq.Array(nonNil(subscription.EventTypes)), pq.Array(nonNil(subscription.RouteCodes)),
+		pq.Array(nonNil(subscription.TerminalCodes)), subscription.FillAbove, subscription.CarFillAbove,
+	).Scan(&subscription.ID, &subscription.CreatedAt)
+	return subscription, err
+}
+
+/*
+ * UpdateWebhookSubscription
+ *
+ * Replaces the URL and filters of a subscription. Its secret is kept.
+ *
+ * @param models.WebhookSubscription subscription
+ *
+ * @return bool - false if there is no subscription with that ID
+ * @return error
+ */
+func (s *PostgresStore) UpdateWebhookSubscription(subscription models.WebhookSubscription) (bool, error) {
+	result, err := s.conn.Exec(`
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, route_codes = $4, terminal_codes = $5, fill_above = $6, car_fill_above = $7
+		WHERE id = $1`,
+		subscription.ID, subscription.URL, pq.Array(nonNil(subscription.EventTypes)), pq.Array(nonNil(subscription.RouteCodes)),
+		pq.Array(nonNil(subscription.TerminalCodes)), subscription.FillAbove, subscription.CarFillAbove,
+	)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+/*
+ * GetWebhookSubscriptions
+ *
+ * Retrieves all subscriptions, including their secrets.
+ *
+ * @return []models.WebhookSubscription - ordered by ID
+ */
+func (s *PostgresStore) GetWebhookSubscriptions() []models.WebhookSubscription {
+	subscriptions := []models.WebhookSubscription{}
+
+	rows, err := s.conn.Query(`SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		log.Printf("GetWebhookSubscriptions: query failed: %v", err)
+		return subscriptions
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			log.Printf("GetWebhookSubscriptions: scan failed: %v", err)
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetWebhookSubscriptions: rows error: %v", err)
+	}
+
+	return subscriptions
+}
+
+/*
+ * GetWebhookSubscription
+ *
+ * Retrieves a subscription, including its secret.
+ *
+ * @param int64 id
+ *
+ * @return *models.WebhookSubscription - nil if not found
+ */
+func (s *PostgresStore) GetWebhookSubscription(id int64) *models.WebhookSubscription {
+	row := s.conn.QueryRow(`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id)
+	subscription, err := scanWebhookSubscription(row)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("GetWebhookSubscription: scan failed: %v", err)
+		}
+		return nil
+	}
+	return &subscription
+}
+
+/*
+ * DeleteWebhookSubscription
+ *
+ * Deletes a subscription and its delivery log.
+ *
+ * @param int64 id
+ *
+ * @return bool - false if there is no subscription with that ID
+ * @return error
+ */
+func (s *PostgresStore) DeleteWebhookSubscription(id int64) (bool, error) {
+	result, err := s.conn.Exec(`DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+/*
+ * SaveWebhookDelivery
+ *
+ * Records a delivery attempt in `webhook_deliveries`.
+ *
+ * @param models.WebhookDelivery delivery - delivery.ID is ignored and assigned by the database
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveWebhookDelivery(delivery models.WebhookDelivery) error {
+	_, err := s.conn.Exec(`
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, attempt, status_code, success, error, duration_ms, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		delivery.SubscriptionID, delivery.EventID, delivery.EventType, delivery.Attempt, delivery.StatusCode,
+		delivery.Success, delivery.Error, delivery.DurationMs, delivery.DeliveredAt,
+	)
+	return err
+}
+
+/*
+ * GetWebhookDeliveries
+ *
+ * Retrieves the latest delivery attempts for a subscription.
+ *
+ * @param int64 subscriptionID
+ * @param int limit - maximum number of attempts to return
+ *
+ * @return []models.WebhookDelivery - newest first
+ */
+func (s *PostgresStore) GetWebhookDeliveries(subscriptionID int64, limit int) []models.WebhookDelivery {
+	deliveries := []models.WebhookDelivery{}
+
+	sqlStatement := `
+		SELECT id, subscription_id, event_id, event_type, attempt, status_code, success, error, duration_ms, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY delivered_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := s.conn.Query(sqlStatement, subscriptionID, limit)
+	if err != nil {
+		log.Printf("GetWebhookDeliveries: query failed: %v", err)
+		return deliveries
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Attempt, &d.StatusCode, &d.Success, &d.Error, &d.DurationMs, &d.DeliveredAt); err != nil {
+			log.Printf("GetWebhookDeliveries: scan failed: %v", err)
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("GetWebhookDeliveries: rows error: %v", err)
+	}
+
+	return deliveries
+}
+
+/*
+ * DeleteWebhookDeliveriesBefore
+ *
+ * Deletes delivery attempts made before the cutoff.
+ *
+ * @param time.Time cutoff
+ *
+ * @return int64 - number of attempts deleted
+ * @return error
+ */
+func (s *PostgresStore) DeleteWebhookDeliveriesBefore(cutoff time.Time) (int64, error) {
+	result, err := s.conn.Exec(`DELETE FROM webhook_deliveries WHERE delivered_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+/********************/
+/* Helper Functions */
+/********************/
+
+// scanWebhookSubscription scans a row of webhookSubscriptionColumns
+func scanWebhookSubscription(row interface{ Scan(...any) error }) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	var fillAbove, carFillAbove sql.NullInt64
+	err := row.Scan(
+		&subscription.ID, &subscription.URL, &subscription.Secret,
+		pq.Array(&subscription.EventTypes), pq.Array(&subscription.RouteCodes), pq.Array(&subscription.TerminalCodes),
+		&fillAbove, &carFillAbove, &subscription.CreatedAt,
+	)
+	if fillAbove.Valid {
+		value := int(fillAbove.Int64)
+		subscription.FillAbove = &value
+	}
+	if carFillAbove.Valid {
+		value := int(carFillAbove.Int64)
+		subscription.CarFillAbove = &value
+	}
+	return subscription, err
+}
+
+// nonNil turns a nil slice into an empty one, for NOT NULL array columns
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * SaveCapacityRoute
+ *
+ * Upserts a capacity route into the `capacity_routes` table, keyed by route code,
+ * and replaces its sailings in `sailings`.
+ *
+ * @param models.CapacityRoute route
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveCapacityRoute(route models.CapacityRoute) error {
+	sqlStatement := `
+		INSERT INTO capacity_routes (
+			route_code,
+			from_terminal_code,
+			to_terminal_code,
+			date,
+			sailing_duration
+		)
+		VALUES
+			($1, $2, $3, $4, $5) ON CONFLICT (route_code) DO
+		UPDATE
+		SET
+			route_code = EXCLUDED.route_code,
+			from_terminal_code = EXCLUDED.from_terminal_code,
+			to_terminal_code = EXCLUDED.to_terminal_code,
+			date = EXCLUDED.date,
+			sailing_duration = EXCLUDED.sailing_duration
+		WHERE
+			capacity_routes.route_code = EXCLUDED.route_code`
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(sqlStatement, route.RouteCode, route.FromTerminalCode, route.ToTerminalCode, route.Date, route.SailingDuration)
+	if err != nil {
+		return err
+	}
+	if err := saveCapacitySailings(tx, route); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+/*
+ * SaveNonCapacityRoute
+ *
+ * Upserts a non-capacity route into the `non_capacity_routes` table, keyed by (route_code, date),
+ * and replaces its sailings, their events and their legs.
+ *
+ * @param models.NonCapacityRoute route
+ *
+ * @return error
+ */
+func (s *PostgresStore) SaveNonCapacityRoute(route models.NonCapacityRoute) error {
+	sqlStatement := `
+		INSERT INTO non_capacity_routes (
+			route_code,
+			from_terminal_code,
+			to_terminal_code,
+			date,
+			sailing_duration,
+			effective_from,
+			effective_to
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::date, NULLIF($7, '')::date)
+		ON CONFLICT (route_code, date) DO UPDATE SET
+			from_terminal_code = EXCLUDED.from_terminal_code,
+			to_terminal_code = EXCLUDED.to_terminal_code,
+			sailing_duration = EXCLUDED.sailing_duration,
+			effective_from = EXCLUDED.effective_from,
+			effective_to = EXCLUDED.effective_to
+	`
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(sqlStatement,
+		route.RouteCode, route.FromTerminalCode, route.ToTerminalCode, route.Date, route.SailingDuration, route.EffectiveFrom, route.EffectiveTo,
+	)
+	if err != nil {
+		return err
+	}
+	if err := saveNonCapacitySailings(tx, route); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+/*
+ * DeleteCapacityRoutesBefore
+ *
+ * Deletes capacity routes whose service date is before the given date, and
+ * their sailings.
+ *
+ * @param string date - ISO date (e.g. "2025-11-09")
+ *
+ * @return int64 - number of routes deleted
+ * @return error
+ */
+func (s *PostgresStore) DeleteCapacityRoutesBefore(date string) (int64, error) {
+	if _, err := s.conn.Exec(`DELETE FROM sailings WHERE kind = $1 AND service_date < $2`, sailingKindCapacity, date); err != nil {
+		return 0, err
+	}
+
+	result, err := s.conn.Exec(`DELETE FROM capacity_routes WHERE date < $1`, date)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+/*
+ * DeleteNonCapacityRoutesBefore
+ *
+ * Deletes non-capacity routes whose service date is before the given date, and
+ * their sailings.
+ *
+ * @param string date - ISO date (e.g. "2025-11-09")
+ *
+ * @return int64 - number of routes deleted
+ * @return error
+ */
+func (s *PostgresStore) DeleteNonCapacityRoutesBefore(date string) (int64, error) {
+	if _, err := s.conn.Exec(`DELETE FROM sailings WHERE kind = $1 AND service_date < $2`, sailingKindNonCapacity, date); err != nil {
+		return 0, err
+	}
+
+	result, err := s.conn.Exec(`DELETE FROM non_capacity_routes WHERE date < $1`, date)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
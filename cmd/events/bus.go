@@ -0,0 +1,156 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Events a subscriber can fall behind by before it is dropped (and has to resume)
+const subscriberBuffer = 64
+
+/*
+ * Bus
+ *
+ * Fans change events out to subscribers and keeps the latest ones so a client that
+ * reconnects with Last-Event-ID gets what it missed. Event IDs increase across
+ * restarts: they start from the startup time in milliseconds.
+ */
+type Bus struct {
+	mu          sync.Mutex
+	nextID      int64
+	evictedID   int64 // Highest ID no longer in the buffer; IDs up to it cannot be replayed
+	buffer      []models.ChangeEvent
+	size        int
+	subscribers map[chan models.ChangeEvent]bool
+	listeners   []func(models.ChangeEvent)
+}
+
+/*
+ * NewBus
+ *
+ * Creates a bus that keeps the last `size` events for replay.
+ *
+ * @param int size
+ *
+ * @return *Bus
+ */
+func NewBus(size int) *Bus {
+	start := time.Now().UnixMilli() * 1000
+	return &Bus{
+		nextID:      start,
+		evictedID:   start - 1,
+		size:        max(size, 1),
+		subscribers: make(map[chan models.ChangeEvent]bool),
+	}
+}
+
+/*
+ * AddListener
+ *
+ * Registers a function called with every published event, after it has its ID. It is
+ * called from the publishing goroutine, so it must not block.
+ *
+ * @param func(models.ChangeEvent) listener
+ *
+ * @return void
+ */
+func (b *Bus) AddListener(listener func(models.ChangeEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, listener)
+}
+
+/*
+ * Publish
+ *
+ * Assigns IDs to events and sends them to every subscriber and listener. Subscribers that
+ * are too far behind are dropped: their channel is closed.
+ *
+ * @param []models.ChangeEvent events
+ *
+ * @return void
+ */
+func (b *Bus) Publish(events []models.ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	published := make([]models.ChangeEvent, 0, len(events))
+	for _, event := range events {
+		event.ID = b.nextID
+		b.nextID++
+
+		b.buffer = append(b.buffer, event)
+		if len(b.buffer) > b.size {
+			b.evictedID = b.buffer[0].ID
+			b.buffer = b.buffer[1:]
+		}
+
+		for subscriber := range b.subscribers {
+			select {
+			case subscriber <- event:
+			default:
+				delete(b.subscribers, subscriber)
+				close(subscriber)
+			}
+		}
+		published = append(published, event)
+	}
+	listeners := b.listeners
+	b.mu.Unlock()
+
+	for _, event := range published {
+		for _, listener := range listeners {
+			listener(event)
+		}
+	}
+}
+
+/*
+ * Subscribe
+ *
+ * Subscribes to new events, optionally replaying the ones after lastEventID. If some of
+ * those can no longer be replayed (they were evicted, or published before a restart),
+ * the replay is a single resync event instead, whose ID is the latest event's.
+ *
+ * @param int64 lastEventID - ID of the last event the client received
+ * @param bool resume - false for a new client, which gets no replay
+ *
+ * @return []models.ChangeEvent - events to send first
+ * @return <-chan models.ChangeEvent - new events; closed if the subscriber falls behind
+ * @return func() - unsubscribes
+ */
+func (b *Bus) Subscribe(lastEventID int64, resume bool) ([]models.ChangeEvent, <-chan models.ChangeEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []models.ChangeEvent
+	if resume {
+		if lastEventID < b.evictedID || lastEventID >= b.nextID {
+			replay = []models.ChangeEvent{{ID: b.nextID - 1, Type: models.ChangeResync, DetectedAt: time.Now()}}
+		} else {
+			for _, event := range b.buffer {
+				if event.ID > lastEventID {
+					replay = append(replay, event)
+				}
+			}
+		}
+	}
+
+	subscriber := make(chan models.ChangeEvent, subscriberBuffer)
+	b.subscribers[subscriber] = true
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.subscribers[subscriber] {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+
+	return replay, subscriber, unsubscribe
+}
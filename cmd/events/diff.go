@@ -0,0 +1,201 @@
+package events
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Order of the events a diff produces
+var changeTypes = []string{
+	models.ChangeScheduleChanged,
+	models.ChangeCancelled,
+	models.ChangeStatusChanged,
+	models.ChangeVesselChanged,
+	models.ChangeFillChanged,
+}
+
+/*
+ * changeSet
+ *
+ * Collects the changes found while diffing one route, by event type
+ */
+type changeSet map[string][]models.SailingChange
+
+func (c changeSet) add(changeType, sailingID, field string, from, to any) {
+	c[changeType] = append(c[changeType], models.SailingChange{SailingID: sailingID, Field: field, From: from, To: to})
+}
+
+// events makes one event per change type, in changeTypes order
+func (c changeSet) events(routeCode, date string, detectedAt time.Time) []models.ChangeEvent {
+	var events []models.ChangeEvent
+	for _, changeType := range changeTypes {
+		changes := c[changeType]
+		if len(changes) == 0 {
+			continue
+		}
+		event := models.ChangeEvent{Type: changeType, RouteCode: routeCode, Date: date, Changes: changes, DetectedAt: detectedAt}
+		for _, change := range changes {
+			if len(event.SailingIDs) == 0 || event.SailingIDs[len(event.SailingIDs)-1] != change.SailingID {
+				event.SailingIDs = append(event.SailingIDs, change.SailingID)
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+/*
+ * DiffCapacity
+ *
+ * Compares a freshly scraped capacity route with the one stored before it. Sailings are
+ * matched by ID, which stays the same once a sailing departs.
+ *   - schedule-changed: sailings added or removed, actual departure or arrival times
+ *   - cancelled: a sailing's status became "cancelled"
+ *   - status-changed: any other status change, e.g. future -> current
+ *   - vessel-changed: the vessel of a sailing changed
+ *   - fill-changed: fill, carFill or oversizeFill changed
+ *
+ * @param *models.CapacityRoute previous - nil if nothing was stored
+ * @param models.CapacityRoute current
+ * @param time.Time detectedAt
+ *
+ * @return []models.ChangeEvent - none when previous is nil or for another service date
+ */
+func DiffCapacity(previous *models.CapacityRoute, current models.CapacityRoute, detectedAt time.Time) []models.ChangeEvent {
+	if previous == nil || previous.Date != current.Date {
+		return nil
+	}
+
+	before := make(map[string]models.CapacitySailing, len(previous.Sailings))
+	for _, sailing := range previous.Sailings {
+		before[sailing.ID] = sailing
+	}
+
+	changes := changeSet{}
+	seen := make(map[string]bool, len(current.Sailings))
+	for _, sailing := range current.Sailings {
+		seen[sailing.ID] = true
+		old, ok := before[sailing.ID]
+		if !ok {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "sailing", nil, sailing.DepartureTime)
+			continue
+		}
+
+		if old.DepartureTime != sailing.DepartureTime {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "time", old.DepartureTime, sailing.DepartureTime)
+		}
+		if old.ArrivalTime != sailing.ArrivalTime {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "arrivalTime", old.ArrivalTime, sailing.ArrivalTime)
+		}
+
+		if old.SailingStatus != sailing.SailingStatus {
+			changeType := models.ChangeStatusChanged
+			if sailing.SailingStatus == "cancelled" {
+				changeType = models.ChangeCancelled
+			}
+			changes.add(changeType, sailing.ID, "sailingStatus", old.SailingStatus, sailing.SailingStatus)
+		}
+
+		// A vessel appearing or disappearing from the page is not a change of vessel
+		if old.VesselName != "" && sailing.VesselName != "" && old.VesselName != sailing.VesselName {
+			changes.add(models.ChangeVesselChanged, sailing.ID, "vesselName", old.VesselName, sailing.VesselName)
+		}
+
+		if old.Fill != sailing.Fill {
+			changes.add(models.ChangeFillChanged, sailing.ID, "fill", old.Fill, sailing.Fill)
+		}
+		if old.CarFill != sailing.CarFill {
+			changes.add(models.ChangeFillChanged, sailing.ID, "carFill", old.CarFill, sailing.CarFill)
+		}
+		if old.OversizeFill != sailing.OversizeFill {
+			changes.add(models.ChangeFillChanged, sailing.ID, "oversizeFill", old.OversizeFill, sailing.OversizeFill)
+		}
+	}
+
+	for _, sailing := range previous.Sailings {
+		if !seen[sailing.ID] {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "sailing", sailing.DepartureTime, nil)
+		}
+	}
+
+	return changes.events(current.RouteCode, current.Date, detectedAt)
+}
+
+/*
+ * DiffNonCapacity
+ *
+ * Compares a freshly scraped non-capacity route with the one stored before it for the
+ * same service date. Sailings are matched by ID.
+ *   - schedule-changed: sailings added or removed, arrival times, stops and transfers
+ *   - vessel-changed: the vessels of a sailing's legs changed
+ *
+ * @param *models.NonCapacityRoute previous - nil if nothing was stored
+ * @param models.NonCapacityRoute current
+ * @param time.Time detectedAt
+ *
+ * @return []models.ChangeEvent - none when previous is nil
+ */
+func DiffNonCapacity(previous *models.NonCapacityRoute, current models.NonCapacityRoute, detectedAt time.Time) []models.ChangeEvent {
+	if previous == nil || previous.Date != current.Date {
+		return nil
+	}
+
+	before := make(map[string]models.NonCapacitySailing, len(previous.Sailings))
+	for _, sailing := range previous.Sailings {
+		before[sailing.ID] = sailing
+	}
+
+	changes := changeSet{}
+	seen := make(map[string]bool, len(current.Sailings))
+	for _, sailing := range current.Sailings {
+		seen[sailing.ID] = true
+		old, ok := before[sailing.ID]
+		if !ok {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "sailing", nil, sailing.DepartureTime)
+			continue
+		}
+
+		if old.ArrivalTime != sailing.ArrivalTime {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "arrivalTime", old.ArrivalTime, sailing.ArrivalTime)
+		}
+		if oldStops, stops := describeEvents(old.Events), describeEvents(sailing.Events); oldStops != stops {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "events", oldStops, stops)
+		}
+
+		// Vessels are only known for today, so only report a change between two known ones
+		if oldVessels, vessels := legVessels(old.Legs), legVessels(sailing.Legs); oldVessels != "" && vessels != "" && oldVessels != vessels {
+			changes.add(models.ChangeVesselChanged, sailing.ID, "vesselName", oldVessels, vessels)
+		}
+	}
+
+	for _, sailing := range previous.Sailings {
+		if !seen[sailing.ID] {
+			changes.add(models.ChangeScheduleChanged, sailing.ID, "sailing", sailing.DepartureTime, nil)
+		}
+	}
+
+	return changes.events(current.RouteCode, current.Date, detectedAt)
+}
+
+// describeEvents lists a sailing's calls, e.g. "stop: Galiano Island (Sturdies Bay), transfer: ..."
+func describeEvents(events []models.SailingEvent) string {
+	parts := make([]string, len(events))
+	for i, event := range events {
+		parts[i] = event.Type + ": " + event.TerminalName
+	}
+	return strings.Join(parts, ", ")
+}
+
+// legVessels lists the vessels of a sailing's legs, empty unless all are known
+func legVessels(legs []models.Leg) string {
+	names := make([]string, len(legs))
+	for i, leg := range legs {
+		if leg.VesselName == nil || *leg.VesselName == "UNKNOWN" {
+			return ""
+		}
+		names[i] = *leg.VesselName
+	}
+	return strings.Join(names, ", ")
+}
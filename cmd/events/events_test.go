@@ -0,0 +1,157 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func TestDiffCapacity(t *testing.T) {
+	previous := models.CapacityRoute{Date: "2025-10-20", RouteCode: "TSASWB", Sailings: []models.CapacitySailing{
+		{ID: "TSASWB-2025-10-20-0900", DepartureTime: "9:00 am", ScheduledTime: "9:00 am", SailingStatus: "future", Fill: 40, CarFill: 50, VesselName: "Queen of New Westminster"},
+		{ID: "TSASWB-2025-10-20-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "future", Fill: 10},
+		{ID: "TSASWB-2025-10-20-1300", DepartureTime: "1:00 pm", ScheduledTime: "1:00 pm", SailingStatus: "future"},
+	}}
+	current := models.CapacityRoute{Date: "2025-10-20", RouteCode: "TSASWB", Sailings: []models.CapacitySailing{
+		{ID: "TSASWB-2025-10-20-0900", DepartureTime: "9:12 am", ScheduledTime: "9:00 am", ArrivalTime: "10:46 am", SailingStatus: "current", Fill: 40, CarFill: 50, VesselName: "Coastal Celebration"},
+		{ID: "TSASWB-2025-10-20-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "cancelled", Fill: 10},
+		{ID: "TSASWB-2025-10-20-1500", DepartureTime: "3:00 pm", ScheduledTime: "3:00 pm", SailingStatus: "future", Fill: 20},
+	}}
+	detectedAt := time.Date(2025, 10, 20, 16, 30, 0, 0, time.UTC)
+
+	events := DiffCapacity(&previous, current, detectedAt)
+
+	want := []struct {
+		changeType string
+		sailingIDs []string
+		changes    int
+	}{
+		// 9:00 departed (time, arrivalTime), 3:00 pm added, 1:00 pm removed
+		{models.ChangeScheduleChanged, []string{"TSASWB-2025-10-20-0900", "TSASWB-2025-10-20-1500", "TSASWB-2025-10-20-1300"}, 4},
+		{models.ChangeCancelled, []string{"TSASWB-2025-10-20-1100"}, 1},
+		{models.ChangeStatusChanged, []string{"TSASWB-2025-10-20-0900"}, 1},
+		{models.ChangeVesselChanged, []string{"TSASWB-2025-10-20-0900"}, 1},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("DiffCapacity = %+v, want %d events", events, len(want))
+	}
+	for i, w := range want {
+		event := events[i]
+		if event.Type != w.changeType || len(event.Changes) != w.changes || event.RouteCode != "TSASWB" || event.Date != "2025-10-20" || !event.DetectedAt.Equal(detectedAt) {
+			t.Errorf("event %d = %+v, want %s with %d changes", i, event, w.changeType, w.changes)
+			continue
+		}
+		if len(event.SailingIDs) != len(w.sailingIDs) {
+			t.Errorf("%s sailing IDs = %v, want %v", event.Type, event.SailingIDs, w.sailingIDs)
+			continue
+		}
+		for j := range w.sailingIDs {
+			if event.SailingIDs[j] != w.sailingIDs[j] {
+				t.Errorf("%s sailing IDs = %v, want %v", event.Type, event.SailingIDs, w.sailingIDs)
+			}
+		}
+	}
+
+	status := events[2].Changes[0]
+	if status.Field != "sailingStatus" || status.From != "future" || status.To != "current" {
+		t.Errorf("status change = %+v", status)
+	}
+
+	// Fill only
+	current.Sailings[2].Fill = 25
+	if events := DiffCapacity(&current, withFill(current, 2, 30), detectedAt); len(events) != 1 || events[0].Type != models.ChangeFillChanged || events[0].Changes[0].From != 25 || events[0].Changes[0].To != 30 {
+		t.Errorf("fill diff = %+v", events)
+	}
+
+	// First scrape of the day
+	if events := DiffCapacity(nil, current, detectedAt); events != nil {
+		t.Errorf("DiffCapacity(nil) = %+v, want none", events)
+	}
+	previous.Date = "2025-10-19"
+	if events := DiffCapacity(&previous, current, detectedAt); events != nil {
+		t.Errorf("DiffCapacity across days = %+v, want none", events)
+	}
+}
+
+func withFill(route models.CapacityRoute, i, fill int) models.CapacityRoute {
+	route.Sailings = append([]models.CapacitySailing(nil), route.Sailings...)
+	route.Sailings[i].Fill = fill
+	return route
+}
+
+func TestDiffNonCapacity(t *testing.T) {
+	cumberland, mayne := "Queen of Cumberland", "Mayne Queen"
+	previous := models.NonCapacityRoute{Date: "2025-10-20", RouteCode: "TSAPOB", Sailings: []models.NonCapacitySailing{
+		{ID: "TSAPOB-2025-10-20-0710", DepartureTime: "7:10 am", ArrivalTime: "9:25 am", Legs: []models.Leg{{VesselName: &cumberland}}},
+		{ID: "TSAPOB-2025-10-20-1500", DepartureTime: "3:00 pm", ArrivalTime: "4:20 pm", Legs: []models.Leg{{}}},
+	}}
+	current := models.NonCapacityRoute{Date: "2025-10-20", RouteCode: "TSAPOB", Sailings: []models.NonCapacitySailing{
+		{ID: "TSAPOB-2025-10-20-0710", DepartureTime: "7:10 am", ArrivalTime: "9:35 am", Legs: []models.Leg{{VesselName: &mayne}},
+			Events: []models.SailingEvent{{Type: "stop", TerminalName: "Galiano Island (Sturdies Bay)"}}},
+		// A vessel becoming known is not a change
+		{ID: "TSAPOB-2025-10-20-1500", DepartureTime: "3:00 pm", ArrivalTime: "4:20 pm", Legs: []models.Leg{{VesselName: &mayne}}},
+	}}
+
+	events := DiffNonCapacity(&previous, current, time.Now())
+	if len(events) != 2 || events[0].Type != models.ChangeScheduleChanged || events[1].Type != models.ChangeVesselChanged {
+		t.Fatalf("DiffNonCapacity = %+v", events)
+	}
+	if len(events[0].Changes) != 2 || events[0].Changes[1].To != "stop: Galiano Island (Sturdies Bay)" {
+		t.Errorf("schedule changes = %+v", events[0].Changes)
+	}
+	if len(events[1].SailingIDs) != 1 || events[1].Changes[0].To != "Mayne Queen" {
+		t.Errorf("vessel change = %+v", events[1])
+	}
+}
+
+func TestBus(t *testing.T) {
+	bus := NewBus(2)
+	var listened []models.ChangeEvent
+	bus.AddListener(func(event models.ChangeEvent) { listened = append(listened, event) })
+
+	// A new client gets no replay
+	replay, stream, unsubscribe := bus.Subscribe(0, false)
+	if len(replay) != 0 {
+		t.Errorf("replay for a new client = %+v", replay)
+	}
+
+	bus.Publish([]models.ChangeEvent{{Type: models.ChangeFillChanged, RouteCode: "TSASWB"}, {Type: models.ChangeCancelled, RouteCode: "SWBTSA"}})
+	first, second := <-stream, <-stream
+	if second.ID != first.ID+1 || first.Type != models.ChangeFillChanged {
+		t.Errorf("events = %+v, %+v", first, second)
+	}
+	if len(listened) != 2 || listened[0].ID != first.ID || listened[1].ID != second.ID {
+		t.Errorf("listener got %+v, want both events with their IDs", listened)
+	}
+
+	// Resuming replays what came after the last event
+	replay, _, unsubscribeResumed := bus.Subscribe(first.ID, true)
+	if len(replay) != 1 || replay[0].ID != second.ID {
+		t.Errorf("replay after %d = %+v", first.ID, replay)
+	}
+	unsubscribeResumed()
+
+	// Once evicted, a resync
+	bus.Publish([]models.ChangeEvent{{Type: models.ChangeFillChanged}, {Type: models.ChangeFillChanged}})
+	replay, _, unsubscribeResync := bus.Subscribe(first.ID, true)
+	if len(replay) != 1 || replay[0].Type != models.ChangeResync || replay[0].ID != second.ID+2 {
+		t.Errorf("replay after an evicted event = %+v", replay)
+	}
+	unsubscribeResync()
+
+	// A client from before a restart
+	if replay, _, unsubscribeOld := bus.Subscribe(42, true); len(replay) != 1 || replay[0].Type != models.ChangeResync {
+		t.Errorf("replay after an event from before a restart = %+v", replay)
+	} else {
+		unsubscribeOld()
+	}
+
+	// Subscribers that fall behind are dropped
+	for i := 0; i < subscriberBuffer; i++ {
+		bus.Publish([]models.ChangeEvent{{Type: models.ChangeFillChanged}})
+	}
+	for range stream {
+	}
+	unsubscribe()
+}
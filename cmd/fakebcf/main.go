@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/scraper"
+)
+
+/*
+ * fakebcf
+ *
+ * A local stand-in for www.bcferries.com that serves recorded pages from a directory,
+ * so the scrapers can run end-to-end without internet access:
+ *
+ *   go run ./cmd/fakebcf -addr :8081
+ *   BCF_BASE_URL=http://localhost:8081 STORE=memory go run ./cmd/server
+ *
+ * A request for /a/b is answered with <dir>/a/b.html. Pages that differ by query
+ * parameter are looked up as <dir>/a/b/<value>.html first (see scraper.RecordedPagePath,
+ * also used by the scraper's file fetcher). Pages that were not recorded return 404,
+ * like an unknown route on the real site.
+ */
+func main() {
+	addr := flag.String("addr", ":8081", "address to listen on")
+	dir := flag.String("dir", "cmd/fakebcf/site", "directory of recorded pages, laid out by URL path")
+	flag.Parse()
+
+	if info, err := os.Stat(*dir); err != nil || !info.IsDir() {
+		log.Fatalf("fakebcf: page directory %q not found", *dir)
+	}
+
+	log.Printf("fakebcf: serving %s on %s", *dir, *addr)
+	log.Fatal(http.ListenAndServe(*addr, newSiteHandler(*dir)))
+}
+
+/*
+ * newSiteHandler
+ *
+ * Serves recorded pages from dir, mapping request paths (and variant query parameters) to files.
+ *
+ * @param string dir - directory of recorded pages
+ *
+ * @return http.Handler
+ */
+func newSiteHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		file, ok := scraper.RecordedPagePath(dir, r.URL)
+		if !ok {
+			log.Printf("fakebcf: 404 %s", r.URL.RequestURI())
+			http.NotFound(w, r)
+			return
+		}
+
+		log.Printf("fakebcf: 200 %s -> %s", r.URL.RequestURI(), file)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeFile(w, r, file)
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/router"
+	"github.com/jeffcstock/bc-ferries-api/cmd/scraper"
+)
+
+// startSite serves the recorded site and points the scraper at it for the duration of the test
+func startSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	site := httptest.NewServer(newSiteHandler("site"))
+	t.Cleanup(site.Close)
+
+	previous := config.BCFBaseURL
+	config.BCFBaseURL = site.URL
+	t.Cleanup(func() { config.BCFBaseURL = previous })
+
+	return site
+}
+
+func TestSiteHandler(t *testing.T) {
+	site := startSite(t)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/current-conditions/TSA-SWB", http.StatusOK},
+		{"/current-conditions/vehicle-info?route=TSA-SWB&sailing=5:00%20pm", http.StatusOK},
+		{"/current-conditions/departures?terminalCode=TSA", http.StatusOK},
+		{"/current-conditions/departures?terminalCode=SWB", http.StatusNotFound},
+		{"/routes-fares/schedules/seasonal/TSA-POB", http.StatusOK},
+		{"/routes-fares/schedules/seasonal/TSA-POB?departureDate=2030-01-01", http.StatusOK},
+		// Unrecorded season falls back to the default page
+		{"/routes-fares/schedules/seasonal/TSA-POB?departureDate=2031-01-01", http.StatusOK},
+		{"/current-conditions/HSB-NAN", http.StatusNotFound},
+		{"/../main.go", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		response, err := http.Get(site.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.path, response.StatusCode, tt.code)
+		}
+	}
+}
+
+// Scrapes capacity routes from the stand-in site into a memory store and reads them back through the API
+func TestCapacityPipeline(t *testing.T) {
+	startSite(t)
+
+	store := db.NewMemoryStore()
+	newScraper(t, store, httpFetchers()).ScrapeCapacityRoutes()
+
+	api := httptest.NewServer(router.SetupRouter(store, nil))
+	defer api.Close()
+
+	response, err := http.Get(api.URL + "/v2/capacity/TSASWB")
+	if err != nil {
+		t.Fatalf("GET /v2/capacity/TSASWB: %v", err)
+	}
+	defer response.Body.Close()
+
+	var route models.CapacityRoute
+	if err := json.NewDecoder(response.Body).Decode(&route); err != nil {
+		t.Fatalf("decode route: %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || len(route.Sailings) != 8 {
+		t.Fatalf("GET /v2/capacity/TSASWB = %d with %d sailings, want 200 with 8", response.StatusCode, len(route.Sailings))
+	}
+
+	// The 5:00 pm fill comes from the vehicle-info page behind its "Details" link
+	if s := route.Sailings[6]; s.DepartureTime != "5:00 pm" || s.Fill != 65 || s.CarFill != 100 || s.OversizeFill != 20 {
+		t.Errorf("5:00 pm sailing = %+v, want fill 65/100/20 from the details page", s)
+	}
+
+	if snapshot := store.GetLatestCapacitySnapshot("TSASWB", route.Date); snapshot == nil {
+		t.Errorf("no history snapshot recorded for TSASWB")
+	}
+
+	// The run is recorded with TSASWB as the only route that exists on the stand-in site
+	var status models.StatusResponse
+	statusResponse, err := http.Get(api.URL + "/v2/status")
+	if err != nil {
+		t.Fatalf("GET /v2/status: %v", err)
+	}
+	defer statusResponse.Body.Close()
+	if err := json.NewDecoder(statusResponse.Body).Decode(&status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+
+	run, ok := status.LastRuns[models.ScrapeKindCapacity]
+	if !ok || run.SuccessCount != 1 || run.SailingCount != 8 || run.RouteCount != len(run.Routes) {
+		t.Errorf("capacity run = %+v, want 1 successful route with 8 sailings", run)
+	}
+	for _, r := range run.Routes {
+		if r.Success != (r.RouteCode == "TSASWB") || (!r.Success && r.Error == "") {
+			t.Errorf("route result %+v, want only TSASWB to succeed and failures to carry an error", r)
+		}
+	}
+}
+
+// Scrapes non-capacity routes from the stand-in site. The real site needs chromedp for
+// schedule pages, but the recorded ones don't run JavaScript, so plain HTTP works here.
+func TestNonCapacityPipeline(t *testing.T) {
+	startSite(t)
+	assertNonCapacityScrape(t, httpFetchers())
+}
+
+// Reads the recorded pages straight from disk, without the stand-in server
+func TestNonCapacityPipelineFromFiles(t *testing.T) {
+	fetcher := scraper.NewFileFetcher("site")
+	assertNonCapacityScrape(t, scraper.Fetchers{Capacity: fetcher, NonCapacity: fetcher})
+}
+
+func assertNonCapacityScrape(t *testing.T, fetchers scraper.Fetchers) {
+	t.Helper()
+
+	previous := config.ScheduleHorizonDays
+	config.ScheduleHorizonDays = 3
+	defer func() { config.ScheduleHorizonDays = previous }()
+
+	store := db.NewMemoryStore()
+	newScraper(t, store, fetchers).ScrapeNonCapacityRoutes()
+
+	if routes := store.GetNonCapacitySailings(db.CurrentServiceDate()); len(routes) != 1 || routes[0].RouteCode != "TSAPOB" {
+		t.Fatalf("today's non-capacity routes = %+v, want TSAPOB only", routes)
+	}
+
+	if seasons := store.GetScheduleSeasons("TSAPOB"); len(seasons) != 2 {
+		t.Errorf("got %d seasons for TSAPOB, want 2", len(seasons))
+	}
+}
+
+// newScraper creates a scraper without the upstream rate limit, which only slows down local pages
+func newScraper(t *testing.T, store db.Store, fetchers scraper.Fetchers) *scraper.Scraper {
+	t.Helper()
+
+	previous := config.Scrape
+	config.Scrape.RateLimit = 0
+	t.Cleanup(func() { config.Scrape = previous })
+
+	return scraper.New(store, fetchers, nil)
+}
+
+// httpFetchers fetches both route families over HTTP without retries
+func httpFetchers() scraper.Fetchers {
+	cfg := config.HTTPFetcher
+	cfg.Retries = 0
+	fetcher := scraper.NewHTTPFetcher(cfg)
+	return scraper.Fetchers{Capacity: fetcher, NonCapacity: fetcher}
+}
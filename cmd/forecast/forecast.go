@@ -0,0 +1,93 @@
+package forecast
+
+import (
+	"math"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Car deck fill at which a sailing counts as full
+const fullCarFill = 100
+
+/*
+ * Predict
+ *
+ * Estimates the fill a sailing will depart with from the fill curves of past sailings
+ * (same route, weekday and departure time). Each past sailing gives an estimate: the
+ * current fill plus how much that sailing filled up between the same time before
+ * departure and its departure, or just its fill at departure if it was not sampled that
+ * early. The prediction is the mean of those estimates.
+ *
+ * @param models.FillLevels current - the sailing's fill now
+ * @param int minutesBeforeDeparture - time left until the timetabled departure
+ * @param []models.FillHistory history - past sailings' curves
+ *
+ * @return models.FillPrediction - Current, MinutesBeforeDeparture, Predicted, SampleSize
+ *   and FullRate; Predicted is the current fill when there is no usable history
+ */
+func Predict(current models.FillLevels, minutesBeforeDeparture int, history []models.FillHistory) models.FillPrediction {
+	prediction := models.FillPrediction{
+		MinutesBeforeDeparture: minutesBeforeDeparture,
+		Current:                current,
+		Predicted:              current,
+	}
+
+	var fill, carFill, oversizeFill float64
+	full := 0
+	for _, curve := range history {
+		final, ok := levelsAt(curve, 0)
+		if !ok {
+			continue
+		}
+		estimate := final
+		if then, ok := levelsAt(curve, minutesBeforeDeparture); ok {
+			estimate = models.FillLevels{
+				Fill:         current.Fill + final.Fill - then.Fill,
+				CarFill:      current.CarFill + final.CarFill - then.CarFill,
+				OversizeFill: current.OversizeFill + final.OversizeFill - then.OversizeFill,
+			}
+		}
+
+		fill += float64(estimate.Fill)
+		carFill += float64(estimate.CarFill)
+		oversizeFill += float64(estimate.OversizeFill)
+		if final.CarFill >= fullCarFill {
+			full++
+		}
+		prediction.SampleSize++
+	}
+	if prediction.SampleSize == 0 {
+		return prediction
+	}
+
+	n := float64(prediction.SampleSize)
+	prediction.Predicted = models.FillLevels{
+		Fill:         percent(fill / n),
+		CarFill:      percent(carFill / n),
+		OversizeFill: percent(oversizeFill / n),
+	}
+	prediction.FullRate = math.Round(float64(full)/n*100) / 100
+	return prediction
+}
+
+/********************/
+/* Helper Functions */
+/********************/
+
+// levelsAt returns a curve's fill the given number of minutes before departure: its latest sample from then or earlier
+func levelsAt(curve models.FillHistory, minutesBeforeDeparture int) (models.FillLevels, bool) {
+	var levels models.FillLevels
+	found := false
+	for _, sample := range curve.Samples {
+		if sample.MinutesBeforeDeparture < minutesBeforeDeparture {
+			break
+		}
+		levels, found = sample.FillLevels, true
+	}
+	return levels, found
+}
+
+// percent rounds and clamps to 0-100
+func percent(v float64) int {
+	return min(max(int(math.Round(v)), 0), 100)
+}
@@ -0,0 +1,53 @@
+package forecast
+
+import (
+	"testing"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func curve(date string, samples ...[2]int) models.FillHistory {
+	history := models.FillHistory{RouteCode: "TSASWB", Date: date, ScheduledTime: "3:00 pm"}
+	for _, s := range samples {
+		history.Samples = append(history.Samples, models.FillSample{
+			MinutesBeforeDeparture: s[0],
+			FillLevels:             models.FillLevels{Fill: s[1], CarFill: s[1]},
+		})
+	}
+	return history
+}
+
+func TestPredict(t *testing.T) {
+	history := []models.FillHistory{
+		// 40% three hours out, full at departure: +60
+		curve("2025-10-06", [2]int{300, 20}, [2]int{180, 40}, [2]int{30, 100}, [2]int{-5, 100}),
+		// 50% three hours out, 80% at departure: +30
+		curve("2025-10-13", [2]int{240, 50}, [2]int{10, 80}),
+		// Only sampled from two hours out, so its departure fill is used as is
+		curve("2025-09-29", [2]int{120, 70}, [2]int{0, 90}),
+		// Never sampled before departure
+		curve("2025-09-22", [2]int{-10, 60}),
+	}
+
+	prediction := Predict(models.FillLevels{Fill: 30, CarFill: 30}, 180, history)
+	// (30+60 + 30+30 + 90) / 3
+	if prediction.SampleSize != 3 || prediction.Predicted.CarFill != 80 || prediction.Predicted.Fill != 80 {
+		t.Errorf("prediction = %+v, want 80%% from 3 sailings", prediction)
+	}
+	if prediction.FullRate != 0.33 {
+		t.Errorf("full rate = %v, want 0.33", prediction.FullRate)
+	}
+	if prediction.Current.CarFill != 30 || prediction.MinutesBeforeDeparture != 180 {
+		t.Errorf("prediction = %+v", prediction)
+	}
+
+	// Clamped to 100
+	if prediction := Predict(models.FillLevels{Fill: 90, CarFill: 90}, 180, history[:1]); prediction.Predicted.CarFill != 100 {
+		t.Errorf("predicted car fill = %d, want 100", prediction.Predicted.CarFill)
+	}
+
+	// No history: the current fill
+	if prediction := Predict(models.FillLevels{Fill: 30, CarFill: 45}, 180, nil); prediction.SampleSize != 0 || prediction.Predicted.CarFill != 45 {
+		t.Errorf("prediction without history = %+v", prediction)
+	}
+}
@@ -0,0 +1,146 @@
+package geo
+
+import (
+	"math"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+// Mean radius of the Earth
+const earthRadiusKm = 6371.0088
+
+/*
+ * DistanceKm
+ *
+ * Returns the great-circle distance between two points (haversine formula)
+ *
+ * @param float64 lat1
+ * @param float64 lon1
+ * @param float64 lat2
+ * @param float64 lon2
+ *
+ * @return float64 - kilometres
+ */
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+/*
+ * FeatureCollection
+ *
+ * A GeoJSON (RFC 7946) feature collection. Coordinates are [lon, lat].
+ */
+type FeatureCollection struct {
+	Type     string    `json:"type"` // Always "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"` // Always "Feature"
+	ID         string         `json:"id,omitempty"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type Geometry struct {
+	Type        string `json:"type"` // "Point" or "LineString"
+	Coordinates any    `json:"coordinates"`
+}
+
+/*
+ * NewFeatureCollection
+ *
+ * @param []Feature features
+ *
+ * @return FeatureCollection - with an empty features array rather than null
+ */
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// Point makes a point geometry
+func Point(lat, lon float64) Geometry {
+	return Geometry{Type: "Point", Coordinates: [2]float64{lon, lat}}
+}
+
+// LineString makes a line through terminals, in order
+func LineString(terminals ...staticdata.Terminal) Geometry {
+	coordinates := make([][2]float64, len(terminals))
+	for i, terminal := range terminals {
+		coordinates[i] = [2]float64{terminal.Lon, terminal.Lat}
+	}
+	return Geometry{Type: "LineString", Coordinates: coordinates}
+}
+
+/*
+ * TerminalFeature
+ *
+ * Makes a point feature for a terminal, with its code, name and service area
+ *
+ * @param staticdata.Terminal terminal
+ *
+ * @return Feature
+ */
+func TerminalFeature(terminal staticdata.Terminal) Feature {
+	return Feature{
+		Type:     "Feature",
+		ID:       terminal.Code,
+		Geometry: Point(terminal.Lat, terminal.Lon),
+		Properties: map[string]any{
+			"kind":        "terminal",
+			"code":        terminal.Code,
+			"name":        terminal.Name,
+			"serviceArea": terminal.ServiceArea,
+		},
+	}
+}
+
+/*
+ * TerminalsAndLegs
+ *
+ * Makes a feature collection of terminals (points) followed by legs (straight lines
+ * between their terminals), both in the given order
+ *
+ * @param []staticdata.Terminal terminals
+ * @param []staticdata.CatalogueLeg legs - legs whose terminals are not in `terminals` are left out
+ *
+ * @return FeatureCollection
+ */
+func TerminalsAndLegs(terminals []staticdata.Terminal, legs []staticdata.CatalogueLeg) FeatureCollection {
+	byCode := make(map[string]staticdata.Terminal, len(terminals))
+	var features []Feature
+	for _, terminal := range terminals {
+		byCode[terminal.Code] = terminal
+		features = append(features, TerminalFeature(terminal))
+	}
+
+	for _, leg := range legs {
+		from, okFrom := byCode[leg.From]
+		to, okTo := byCode[leg.To]
+		if !okFrom || !okTo {
+			continue
+		}
+		features = append(features, Feature{
+			Type:     "Feature",
+			ID:       leg.From + "-" + leg.To,
+			Geometry: LineString(from, to),
+			Properties: map[string]any{
+				"kind":             "leg",
+				"fromTerminalCode": leg.From,
+				"toTerminalCode":   leg.To,
+				"distanceKm":       leg.DistanceKm,
+				"avgDurationMin":   leg.AvgDurationMin,
+			},
+		})
+	}
+
+	return NewFeatureCollection(features)
+}
@@ -0,0 +1,57 @@
+package geo
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+func TestDistanceKm(t *testing.T) {
+	// Tsawwassen to Swartz Bay, about 41 km as the crow flies
+	tsa, swb := staticdata.GetTerminals()["TSA"], staticdata.GetTerminals()["SWB"]
+	if d := DistanceKm(tsa.Lat, tsa.Lon, swb.Lat, swb.Lon); math.Abs(d-40.8) > 0.1 {
+		t.Errorf("TSA-SWB = %.2f km, want about 40.8", d)
+	}
+	if d := DistanceKm(49, -123, 49, -123); d != 0 {
+		t.Errorf("same point = %v km", d)
+	}
+	// A quarter of the way around the equator
+	if d := DistanceKm(0, 0, 0, 90); math.Abs(d-earthRadiusKm*math.Pi/2) > 1e-6 {
+		t.Errorf("equator quarter = %v km", d)
+	}
+}
+
+func TestTerminalsAndLegs(t *testing.T) {
+	terminals := []staticdata.Terminal{
+		{Code: "AAA", Name: "Alpha", ServiceArea: "Somewhere", Lat: 49, Lon: -123},
+		{Code: "BBB", Name: "Bravo", ServiceArea: "Elsewhere", Lat: 48.5, Lon: -123.5},
+	}
+	legs := []staticdata.CatalogueLeg{
+		{From: "AAA", To: "BBB", DistanceKm: 10, AvgDurationMin: 30},
+		{From: "AAA", To: "ZZZ", DistanceKm: 5, AvgDurationMin: 15},
+	}
+
+	collection := TerminalsAndLegs(terminals, legs)
+	if len(collection.Features) != 3 {
+		t.Fatalf("features = %d, want 2 terminals and 1 leg", len(collection.Features))
+	}
+
+	data, _ := json.Marshal(collection)
+	for _, want := range []string{
+		`"type":"FeatureCollection"`,
+		`{"type":"Feature","id":"AAA","geometry":{"type":"Point","coordinates":[-123,49]}`,
+		`"geometry":{"type":"LineString","coordinates":[[-123,49],[-123.5,48.5]]}`,
+		`"avgDurationMin":30`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("GeoJSON does not contain %s:\n%s", want, data)
+		}
+	}
+
+	if data, _ := json.Marshal(NewFeatureCollection(nil)); string(data) != `{"type":"FeatureCollection","features":[]}` {
+		t.Errorf("empty collection = %s", data)
+	}
+}
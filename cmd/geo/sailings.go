@@ -0,0 +1,197 @@
+package geo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+// A terminal a sailing calls at, with when it is there (nil if unknown)
+type stop struct {
+	terminal    staticdata.Terminal
+	eventType   string // "origin", "destination", or the event at an intermediate terminal
+	arrivalAt   *time.Time
+	departureAt *time.Time
+	estimated   bool
+}
+
+/*
+ * NonCapacityRoute
+ *
+ * Makes a feature collection of every sailing of a route (see NonCapacitySailing), in
+ * the route's order
+ *
+ * @param models.NonCapacityRoute route - with typed times (see models.WithNonCapacityTimes)
+ *
+ * @return FeatureCollection
+ */
+func NonCapacityRoute(route models.NonCapacityRoute) FeatureCollection {
+	var features []Feature
+	for _, sailing := range route.Sailings {
+		features = append(features, NonCapacitySailing(route, sailing)...)
+	}
+	return NewFeatureCollection(features)
+}
+
+/*
+ * NonCapacitySailing
+ *
+ * Makes the features of a sailing's path: a line for each leg and a point for each
+ * terminal it calls at. Times at intermediate terminals are estimated as in
+ * models.EstimateStopTimes. Legs and stops at terminals missing from the catalogue
+ * are left out.
+ *
+ * @param models.NonCapacityRoute route - the sailing's route
+ * @param models.NonCapacitySailing sailing - with typed times (see models.WithNonCapacityTimes)
+ *
+ * @return []Feature - legs first, then stops, in order
+ */
+func NonCapacitySailing(route models.NonCapacityRoute, sailing models.NonCapacitySailing) []Feature {
+	legs := sailing.Legs
+	if len(legs) == 0 {
+		legs = directLeg(route.FromTerminalCode, route.ToTerminalCode, "")
+	}
+
+	stops := []stop{{terminal: legs[0].OriginTerminal, eventType: "origin"}}
+	for i, leg := range legs {
+		eventType := "destination"
+		if i < len(legs)-1 && i < len(sailing.Events) {
+			eventType = sailing.Events[i].Type
+		}
+		stops = append(stops, stop{terminal: leg.DestinationTerminal, eventType: eventType})
+	}
+
+	stopTimes, ok := models.EstimateStopTimes(route.FromTerminalCode, route.ToTerminalCode, sailing)
+	if ok && sailing.DepartureAt != nil {
+		// Offsets from the departure, so sailings after midnight stay on the next day
+		at := func(minutes int) *time.Time {
+			t := sailing.DepartureAt.Add(time.Duration(minutes-stopTimes[0].Departure) * time.Minute)
+			return &t
+		}
+		first, last := &stops[0], &stops[len(stops)-1]
+		first.departureAt = at(stopTimes[0].Departure)
+		last.arrivalAt = at(stopTimes[len(stopTimes)-1].Arrival)
+		if len(stopTimes) == len(stops) {
+			for i := 1; i < len(stops)-1; i++ {
+				stops[i].arrivalAt = at(stopTimes[i].Arrival)
+				stops[i].departureAt = at(stopTimes[i].Departure)
+				stops[i].estimated = stopTimes[i].Estimated
+			}
+		}
+	}
+
+	return sailingFeatures(sailing.ID, legs, stops)
+}
+
+/*
+ * CapacitySailing
+ *
+ * Makes the features of a capacity sailing's path: a line from the route's origin to
+ * its destination and a point at each end. Capacity sailings do not list their stops.
+ *
+ * @param models.CapacityRoute route - the sailing's route
+ * @param models.CapacitySailing sailing - with typed times (see models.WithCapacityTimes)
+ *
+ * @return []Feature - nil if the route's terminals are not in the catalogue
+ */
+func CapacitySailing(route models.CapacityRoute, sailing models.CapacitySailing) []Feature {
+	legs := directLeg(route.FromTerminalCode, route.ToTerminalCode, sailing.VesselName)
+	stops := []stop{
+		{terminal: legs[0].OriginTerminal, eventType: "origin", departureAt: sailing.DepartureAt},
+		{terminal: legs[0].DestinationTerminal, eventType: "destination", arrivalAt: sailing.ArrivalAt},
+	}
+	return sailingFeatures(sailing.ID, legs, stops)
+}
+
+// directLeg makes the single leg of a sailing that does not list its legs
+func directLeg(fromCode, toCode, vesselName string) []models.Leg {
+	terminals := staticdata.GetTerminals()
+	leg := models.Leg{
+		LegNumber:           1,
+		OriginTerminal:      terminals[fromCode],
+		DestinationTerminal: terminals[toCode],
+	}
+	if info := staticdata.GetLegInfo(fromCode, toCode); info != nil {
+		leg.DistanceKm = &info.DistanceKm
+		leg.AvgDurationMin = &info.AvgDurationMin
+	}
+	if vesselName != "" {
+		leg.VesselName = &vesselName
+	}
+	return []models.Leg{leg}
+}
+
+// sailingFeatures makes the leg lines and stop points of a sailing
+func sailingFeatures(sailingID string, legs []models.Leg, stops []stop) []Feature {
+	known := func(terminal staticdata.Terminal) bool {
+		_, exists := staticdata.GetTerminals()[terminal.Code]
+		return exists
+	}
+
+	var features []Feature
+	for _, leg := range legs {
+		if !known(leg.OriginTerminal) || !known(leg.DestinationTerminal) {
+			continue
+		}
+		var vesselName any
+		if leg.VesselName != nil && *leg.VesselName != "UNKNOWN" {
+			vesselName = *leg.VesselName
+		}
+		var durationMin, distanceKm any
+		if leg.AvgDurationMin != nil {
+			durationMin = *leg.AvgDurationMin
+		}
+		if leg.DistanceKm != nil {
+			distanceKm = *leg.DistanceKm
+		}
+		features = append(features, Feature{
+			Type:     "Feature",
+			ID:       fmt.Sprintf("%s/leg/%d", sailingID, leg.LegNumber),
+			Geometry: LineString(leg.OriginTerminal, leg.DestinationTerminal),
+			Properties: map[string]any{
+				"kind":             "leg",
+				"sailingId":        sailingID,
+				"legNumber":        leg.LegNumber,
+				"fromTerminalCode": leg.OriginTerminal.Code,
+				"toTerminalCode":   leg.DestinationTerminal.Code,
+				"vesselName":       vesselName,
+				"durationMin":      durationMin,
+				"distanceKm":       distanceKm,
+			},
+		})
+	}
+
+	for i, stop := range stops {
+		if !known(stop.terminal) {
+			continue
+		}
+		properties := map[string]any{
+			"kind":         "stop",
+			"sailingId":    sailingID,
+			"stopSequence": i + 1,
+			"terminalCode": stop.terminal.Code,
+			"name":         stop.terminal.Name,
+			"eventType":    stop.eventType,
+			"arrivalAt":    stop.arrivalAt,
+			"departureAt":  stop.departureAt,
+			"estimated":    stop.estimated,
+		}
+		if i > 0 && i < len(stops)-1 {
+			var dwellMin any
+			if stop.arrivalAt != nil && stop.departureAt != nil {
+				dwellMin = int(stop.departureAt.Sub(*stop.arrivalAt) / time.Minute)
+			}
+			properties["dwellMin"] = dwellMin
+		}
+		features = append(features, Feature{
+			Type:       "Feature",
+			ID:         fmt.Sprintf("%s/stop/%d", sailingID, i+1),
+			Geometry:   Point(stop.terminal.Lat, stop.terminal.Lon),
+			Properties: properties,
+		})
+	}
+
+	return features
+}
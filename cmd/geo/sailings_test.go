@@ -0,0 +1,63 @@
+package geo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func TestNonCapacitySailing(t *testing.T) {
+	// Tsawwassen to Otter Bay, stopping at Village Bay: 70 + 30 minutes of legs and a 10 minute stop
+	events := []models.SailingEvent{{Type: "stop", TerminalName: "Mayne Island (Village Bay)"}}
+	dwell := 10
+	sailing := models.NonCapacitySailing{
+		ID:                 "TSAPOB-2025-10-20-0710",
+		DepartureTime:      "7:10 am",
+		ArrivalTime:        "9:00 am",
+		Events:             events,
+		Legs:               models.BuildLegs("TSAPOB", events, "7:10 am", map[string]map[string]string{"TSA": {"7:10 am": "Queen of Nanaimo"}}, dwell),
+		AvgDwellPerStopMin: &dwell,
+	}
+	route := models.WithNonCapacityTimes(models.NonCapacityRoute{
+		Date: "2025-10-20", RouteCode: "TSAPOB", FromTerminalCode: "TSA", ToTerminalCode: "POB",
+		Sailings: []models.NonCapacitySailing{sailing},
+	})
+
+	features := NonCapacitySailing(route, route.Sailings[0])
+	if len(features) != 5 {
+		t.Fatalf("features = %d, want 2 legs and 3 stops", len(features))
+	}
+
+	data, _ := json.Marshal(NewFeatureCollection(features))
+	for _, want := range []string{
+		`"id":"TSAPOB-2025-10-20-0710/leg/2","geometry":{"type":"LineString"`,
+		`"legNumber":2,"sailingId":"TSAPOB-2025-10-20-0710","toTerminalCode":"POB","vesselName":"Queen of Nanaimo"`,
+		`"distanceKm":27.1,"durationMin":70`,
+		`"arrivalAt":"2025-10-20T08:20:00-07:00","departureAt":"2025-10-20T08:30:00-07:00","dwellMin":10,"estimated":true,"eventType":"stop"`,
+		`"arrivalAt":"2025-10-20T09:00:00-07:00","departureAt":null,"estimated":false,"eventType":"destination"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("GeoJSON does not contain %s:\n%s", want, data)
+		}
+	}
+}
+
+func TestCapacitySailing(t *testing.T) {
+	route := models.WithCapacityTimes(models.CapacityRoute{
+		Date: "2025-10-20", RouteCode: "SWBTSA", FromTerminalCode: "SWB", ToTerminalCode: "TSA", SailingDuration: "1h 35m",
+		Sailings: []models.CapacitySailing{{ID: "SWBTSA-2025-10-20-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "future", VesselName: "Spirit of Vancouver Island"}},
+	})
+
+	features := CapacitySailing(route, route.Sailings[0])
+	if len(features) != 3 || features[0].Geometry.Type != "LineString" {
+		t.Fatalf("features = %+v, want a leg and 2 stops", features)
+	}
+	if props := features[0].Properties; props["vesselName"] != "Spirit of Vancouver Island" || props["distanceKm"] != 46.6 {
+		t.Errorf("leg = %+v", props)
+	}
+	if data, _ := json.Marshal(features[2]); !strings.Contains(string(data), `"arrivalAt":"2025-10-20T12:35:00-07:00"`) {
+		t.Errorf("destination = %s", data)
+	}
+}
@@ -0,0 +1,451 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+const (
+	agencyID   = "BCF"
+	agencyName = "BC Ferries"
+	agencyURL  = "https://www.bcferries.com"
+	timezone   = "America/Vancouver"
+	routeType  = "4" // Ferry
+)
+
+var ErrNoSchedules = errors.New("no scraped schedules to export")
+
+/*
+ * Feed
+ *
+ * A GTFS static feed: the rows of each file, header first, in the order they are
+ * written to the zip
+ */
+type Feed struct {
+	Files []File
+}
+
+type File struct {
+	Name string // e.g. "stops.txt"
+	Rows [][]string
+}
+
+/*
+ * trip
+ *
+ * A sailing that runs with the same stop times on one or more dates
+ */
+type trip struct {
+	routeCode string
+	stopTimes []models.StopTime
+	dates     map[string]bool
+}
+
+// datedTrip is a capacity sailing, which runs on its date only
+type datedTrip struct {
+	id   string // Sailing ID
+	date string
+	trip trip
+}
+
+/*
+ * Export
+ *
+ * Builds the feed from the schedules and capacity sailings stored for `days` service
+ * dates starting at `start`, and writes it as a zip.
+ *
+ * @param db.Store store
+ * @param time.Time start - first service date
+ * @param int days
+ * @param io.Writer w
+ *
+ * @return error - ErrNoSchedules if nothing is stored for those dates
+ */
+func Export(store db.Store, start time.Time, days int, w io.Writer) error {
+	var routes []models.NonCapacityRoute
+	for offset := 0; offset < days; offset++ {
+		routes = append(routes, store.GetNonCapacitySailings(start.AddDate(0, 0, offset).Format("2006-01-02"))...)
+	}
+
+	var capacity []models.CapacityRoute
+	first, last := start.Format("2006-01-02"), start.AddDate(0, 0, days-1).Format("2006-01-02")
+	for _, route := range store.GetCapacitySailings() {
+		if route.Date >= first && route.Date <= last {
+			capacity = append(capacity, route)
+		}
+	}
+
+	if len(routes) == 0 && len(capacity) == 0 {
+		return ErrNoSchedules
+	}
+
+	return Build(routes, capacity).WriteZip(w)
+}
+
+/*
+ * Build
+ *
+ * Builds a feed from non-capacity routes over a range of service dates (one
+ * models.NonCapacityRoute per route and date):
+ *   - stops: the terminals in staticdata.GetTerminals() that sailings call at
+ *   - trips: one per sailing pattern (route and stop times); intermediate stop times
+ *     come from the legs and dwell times (see models.EstimateStopTimes) and are not
+ *     timepoints
+ *   - calendar: the weekdays a trip runs on most weeks, between the first and last
+ *     dates its route was scraped for
+ *   - calendar_dates: the dates it runs or does not run against that pattern. The
+ *     scraper applies "Only on" / "Except on" notes per date, so these exceptions are
+ *     exactly the dates the notes list.
+ *
+ * Capacity routes are only scraped for the current day, so each capacity sailing is a
+ * trip of its own, on a service that runs on its date alone. Its trip_id is the
+ * sailing ID, which GTFS-Realtime updates refer to (see BuildRealtime).
+ *
+ * Sailings that call at a terminal without coordinates are left out.
+ *
+ * @param []models.NonCapacityRoute routes
+ * @param []models.CapacityRoute capacity
+ *
+ * @return *Feed
+ */
+func Build(routes []models.NonCapacityRoute, capacity []models.CapacityRoute) *Feed {
+	terminals := staticdata.GetTerminals()
+
+	routeDates := make(map[string]map[string]bool)
+	routeEnds := make(map[string][2]string)
+	trips := make(map[string]*trip)
+	skipped := make(map[string]bool)
+
+	for _, route := range routes {
+		if routeDates[route.RouteCode] == nil {
+			routeDates[route.RouteCode] = make(map[string]bool)
+			routeEnds[route.RouteCode] = [2]string{route.FromTerminalCode, route.ToTerminalCode}
+		}
+		routeDates[route.RouteCode][route.Date] = true
+
+		for _, sailing := range route.Sailings {
+			stopTimes, ok := models.EstimateStopTimes(route.FromTerminalCode, route.ToTerminalCode, sailing)
+			if !ok {
+				continue
+			}
+			if code, known := knownTerminals(stopTimes, terminals); !known {
+				if !skipped[route.RouteCode+code] {
+					skipped[route.RouteCode+code] = true
+					log.Printf("gtfs.Build: skipping %s sailings calling at %s, which has no coordinates", route.RouteCode, code)
+				}
+				continue
+			}
+
+			key := tripKey(route.RouteCode, stopTimes)
+			if trips[key] == nil {
+				trips[key] = &trip{routeCode: route.RouteCode, stopTimes: stopTimes, dates: make(map[string]bool)}
+			}
+			trips[key].dates[route.Date] = true
+		}
+	}
+
+	var dated []datedTrip
+	for _, route := range capacity {
+		for _, t := range models.CapacitySchedule(route) {
+			if t.Arrival == 0 {
+				continue
+			}
+			stopTimes := []models.StopTime{
+				{TerminalCode: route.FromTerminalCode, Arrival: t.Departure, Departure: t.Departure},
+				{TerminalCode: route.ToTerminalCode, Arrival: t.Arrival, Departure: t.Arrival},
+			}
+			if code, known := knownTerminals(stopTimes, terminals); !known {
+				if !skipped[route.RouteCode+code] {
+					skipped[route.RouteCode+code] = true
+					log.Printf("gtfs.Build: skipping %s sailings calling at %s, which has no coordinates", route.RouteCode, code)
+				}
+				continue
+			}
+			routeEnds[route.RouteCode] = [2]string{route.FromTerminalCode, route.ToTerminalCode}
+			dated = append(dated, datedTrip{id: t.Sailing.ID, date: route.Date, trip: trip{routeCode: route.RouteCode, stopTimes: stopTimes}})
+		}
+	}
+	sort.Slice(dated, func(i, j int) bool { return dated[i].id < dated[j].id })
+
+	// Deterministic order: by route, then departure
+	keys := make([]string, 0, len(trips))
+	for key := range trips {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	agency := [][]string{
+		{"agency_id", "agency_name", "agency_url", "agency_timezone", "agency_lang"},
+		{agencyID, agencyName, agencyURL, timezone, "en"},
+	}
+	stopRows := [][]string{{"stop_id", "stop_name", "stop_lat", "stop_lon"}}
+	routeRows := [][]string{{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type"}}
+	tripRows := [][]string{{"route_id", "service_id", "trip_id", "trip_headsign"}}
+	stopTimeRows := [][]string{{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence", "timepoint"}}
+	calendarRows := [][]string{{"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"}}
+	calendarDateRows := [][]string{{"service_id", "date", "exception_type"}}
+
+	usedStops := make(map[string]bool)
+	usedRoutes := make(map[string]bool)
+	serviceIDs := make(map[string]string) // service signature -> service_id
+	tripIDs := make(map[string]bool)
+
+	addTrip := func(tripID, serviceID string, t trip) {
+		last := t.stopTimes[len(t.stopTimes)-1]
+		tripRows = append(tripRows, []string{t.routeCode, serviceID, tripID, stopName(terminals[last.TerminalCode])})
+
+		for i, stopTime := range t.stopTimes {
+			timepoint := "1"
+			if stopTime.Estimated {
+				timepoint = "0"
+			}
+			stopTimeRows = append(stopTimeRows, []string{
+				tripID, gtfsTime(stopTime.Arrival), gtfsTime(stopTime.Departure), stopTime.TerminalCode, strconv.Itoa(i + 1), timepoint,
+			})
+			usedStops[stopTime.TerminalCode] = true
+		}
+		usedRoutes[t.routeCode] = true
+	}
+
+	for _, key := range keys {
+		t := trips[key]
+
+		service := newService(sortedKeys(routeDates[t.routeCode]), t.dates)
+		signature := service.signature()
+		serviceID, exists := serviceIDs[signature]
+		if !exists {
+			serviceID = "S" + strconv.Itoa(len(serviceIDs)+1)
+			serviceIDs[signature] = serviceID
+			calendarRows = append(calendarRows, service.calendarRow(serviceID))
+			calendarDateRows = append(calendarDateRows, service.calendarDateRows(serviceID)...)
+		}
+
+		// e.g. TSAPOB-0710, or TSAPOB-0710-2 when the 7:10 sailing has another pattern
+		base := fmt.Sprintf("%s-%02d%02d", t.routeCode, t.stopTimes[0].Departure/60, t.stopTimes[0].Departure%60)
+		tripID := base
+		for n := 2; tripIDs[tripID]; n++ {
+			tripID = fmt.Sprintf("%s-%d", base, n)
+		}
+		tripIDs[tripID] = true
+
+		addTrip(tripID, serviceID, *t)
+	}
+
+	for _, d := range dated {
+		// e.g. D20251020
+		serviceID := "D" + gtfsDate(d.date)
+		if _, exists := serviceIDs[serviceID]; !exists {
+			serviceIDs[serviceID] = serviceID
+			calendarDateRows = append(calendarDateRows, []string{serviceID, gtfsDate(d.date), "1"})
+		}
+		addTrip(d.id, serviceID, d.trip)
+	}
+
+	for _, code := range sortedKeys(usedStops) {
+		terminal := terminals[code]
+		stopRows = append(stopRows, []string{
+			code, stopName(terminal), strconv.FormatFloat(terminal.Lat, 'f', 6, 64), strconv.FormatFloat(terminal.Lon, 'f', 6, 64),
+		})
+	}
+
+	for _, routeCode := range sortedKeys(usedRoutes) {
+		ends := routeEnds[routeCode]
+		longName := stopName(terminals[ends[0]]) + " - " + stopName(terminals[ends[1]])
+		routeRows = append(routeRows, []string{routeCode, agencyID, routeCode, longName, routeType})
+	}
+
+	return &Feed{Files: []File{
+		{Name: "agency.txt", Rows: agency},
+		{Name: "stops.txt", Rows: stopRows},
+		{Name: "routes.txt", Rows: routeRows},
+		{Name: "trips.txt", Rows: tripRows},
+		{Name: "stop_times.txt", Rows: stopTimeRows},
+		{Name: "calendar.txt", Rows: calendarRows},
+		{Name: "calendar_dates.txt", Rows: calendarDateRows},
+	}}
+}
+
+/*
+ * WriteZip
+ *
+ * Writes the feed as a GTFS zip, one CSV file per entry.
+ *
+ * @param io.Writer w
+ *
+ * @return error
+ */
+func (f *Feed) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range f.Files {
+		entry, err := archive.Create(file.Name)
+		if err != nil {
+			return err
+		}
+		writer := csv.NewWriter(entry)
+		if err := writer.WriteAll(file.Rows); err != nil {
+			return fmt.Errorf("writing %s: %w", file.Name, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+/*
+ * service
+ *
+ * When a trip runs: a weekly pattern between two dates plus exceptions
+ */
+type service struct {
+	weekdays [7]bool // Indexed by time.Weekday
+	start    string  // YYYY-MM-DD
+	end      string
+	added    []string // Dates the trip runs outside the weekly pattern
+	removed  []string // Dates the weekly pattern covers but the trip does not run
+}
+
+/*
+ * newService
+ *
+ * Works out a trip's weekly pattern from the dates its route was scraped for: the trip
+ * runs on a weekday when it ran on most of those dates that fall on that weekday.
+ * Dates that do not fit the pattern become exceptions.
+ *
+ * @param []string routeDates - sorted dates the route was scraped for
+ * @param map[string]bool tripDates - dates the trip runs on
+ *
+ * @return service
+ */
+func newService(routeDates []string, tripDates map[string]bool) service {
+	s := service{start: routeDates[0], end: routeDates[len(routeDates)-1]}
+
+	var total, runs [7]int
+	runDays := 0
+	for _, date := range routeDates {
+		weekday := weekdayOf(date)
+		total[weekday]++
+		if tripDates[date] {
+			runs[weekday]++
+			runDays++
+		}
+	}
+	// Ties (e.g. one of two Wednesdays) follow the trip overall: regular if it runs most days
+	regular := runDays*2 > len(routeDates)
+	for weekday := range s.weekdays {
+		s.weekdays[weekday] = runs[weekday]*2 > total[weekday] || (regular && runs[weekday]*2 == total[weekday])
+	}
+
+	for _, date := range routeDates {
+		inPattern := s.weekdays[weekdayOf(date)]
+		switch {
+		case tripDates[date] && !inPattern:
+			s.added = append(s.added, date)
+		case !tripDates[date] && inPattern:
+			s.removed = append(s.removed, date)
+		}
+	}
+
+	return s
+}
+
+func (s service) signature() string {
+	return fmt.Sprintf("%v|%s|%s|+%s|-%s", s.weekdays, s.start, s.end, strings.Join(s.added, ","), strings.Join(s.removed, ","))
+}
+
+func (s service) calendarRow(serviceID string) []string {
+	row := []string{serviceID}
+	// GTFS lists Monday first
+	for _, weekday := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if s.weekdays[weekday] {
+			row = append(row, "1")
+		} else {
+			row = append(row, "0")
+		}
+	}
+	return append(row, gtfsDate(s.start), gtfsDate(s.end))
+}
+
+func (s service) calendarDateRows(serviceID string) [][]string {
+	var rows [][]string
+	for _, date := range s.added {
+		rows = append(rows, []string{serviceID, gtfsDate(date), "1"})
+	}
+	for _, date := range s.removed {
+		rows = append(rows, []string{serviceID, gtfsDate(date), "2"})
+	}
+	return rows
+}
+
+/*
+ * knownTerminals
+ *
+ * Checks that every terminal a sailing calls at has coordinates.
+ *
+ * @param []models.StopTime stopTimes
+ * @param map[string]staticdata.Terminal terminals
+ *
+ * @return string - first unknown terminal code
+ * @return bool - true if all are known
+ */
+func knownTerminals(stopTimes []models.StopTime, terminals map[string]staticdata.Terminal) (string, bool) {
+	for _, stopTime := range stopTimes {
+		if _, ok := terminals[stopTime.TerminalCode]; !ok {
+			return stopTime.TerminalCode, false
+		}
+	}
+	return "", true
+}
+
+// tripKey identifies a sailing pattern; it sorts by route, then departure
+func tripKey(routeCode string, stopTimes []models.StopTime) string {
+	var b strings.Builder
+	b.WriteString(routeCode)
+	for _, stopTime := range stopTimes {
+		fmt.Fprintf(&b, "|%05d-%05d-%s", stopTime.Arrival, stopTime.Departure, stopTime.TerminalCode)
+	}
+	return b.String()
+}
+
+// stopName formats a terminal like the schedule pages do, e.g. "Victoria (Swartz Bay)"
+func stopName(terminal staticdata.Terminal) string {
+	return terminal.ServiceArea + " (" + terminal.Name + ")"
+}
+
+// gtfsTime formats minutes after midnight as HH:MM:SS, past 24:00:00 after midnight
+func gtfsTime(minutes int) string {
+	return fmt.Sprintf("%02d:%02d:00", minutes/60, minutes%60)
+}
+
+// gtfsDate converts YYYY-MM-DD to YYYYMMDD
+func gtfsDate(date string) string {
+	return strings.ReplaceAll(date, "-", "")
+}
+
+func weekdayOf(date string) time.Weekday {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Sunday
+	}
+	return t.Weekday()
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,172 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Two weeks of TSAPOB: a daily 7:10 am calling at Sturdies Bay and Village Bay (except on
+// Oct 22), and a 3:00 pm that only runs on Friday Oct 24
+func testRoutes() []models.NonCapacityRoute {
+	events := []models.SailingEvent{
+		{Type: "stop", TerminalName: "Galiano Island (Sturdies Bay)"},
+		{Type: "stop", TerminalName: "Mayne Island (Village Bay)"},
+	}
+	dwell := 10
+	morning := models.NonCapacitySailing{
+		DepartureTime:      "7:10 am",
+		ArrivalTime:        "9:25 am",
+		Events:             events,
+		Legs:               models.BuildLegs("TSAPOB", events, "7:10 am", nil, dwell),
+		AvgDwellPerStopMin: &dwell,
+	}
+	afternoon := models.NonCapacitySailing{DepartureTime: "3:00 pm", ArrivalTime: "4:20 pm", Legs: models.BuildLegs("TSAPOB", nil, "3:00 pm", nil, 0)}
+
+	var routes []models.NonCapacityRoute
+	start := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	for offset := 0; offset < 14; offset++ {
+		date := start.AddDate(0, 0, offset).Format("2006-01-02")
+		route := models.NonCapacityRoute{Date: date, RouteCode: "TSAPOB", FromTerminalCode: "TSA", ToTerminalCode: "POB"}
+		if date != "2025-10-22" {
+			route.Sailings = append(route.Sailings, morning)
+		}
+		if date == "2025-10-24" {
+			route.Sailings = append(route.Sailings, afternoon)
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func rows(t *testing.T, feed *Feed, name string) []string {
+	t.Helper()
+	for _, file := range feed.Files {
+		if file.Name == name {
+			var lines []string
+			for _, row := range file.Rows[1:] {
+				lines = append(lines, strings.Join(row, ","))
+			}
+			return lines
+		}
+	}
+	t.Fatalf("feed has no %s", name)
+	return nil
+}
+
+func assertRows(t *testing.T, feed *Feed, name string, want []string) {
+	t.Helper()
+	got := rows(t, feed, name)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s =\n%s\nwant\n%s", name, strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuild(t *testing.T) {
+	feed := Build(testRoutes(), nil)
+
+	assertRows(t, feed, "stops.txt", []string{
+		"POB,Pender Island (Otter Bay),48.800575,-123.315662",
+		"PSB,Galiano Island (Sturdies Bay),48.876705,-123.315124",
+		"PVB,Mayne Island (Village Bay),48.844692,-123.324577",
+		"TSA,Vancouver (Tsawwassen),49.006688,-123.131202",
+	})
+	assertRows(t, feed, "routes.txt", []string{"TSAPOB,BCF,TSAPOB,Vancouver (Tsawwassen) - Pender Island (Otter Bay),4"})
+	assertRows(t, feed, "trips.txt", []string{
+		"TSAPOB,S1,TSAPOB-0710,Pender Island (Otter Bay)",
+		"TSAPOB,S2,TSAPOB-1500,Pender Island (Otter Bay)",
+	})
+	assertRows(t, feed, "stop_times.txt", []string{
+		"TSAPOB-0710,07:10:00,07:10:00,TSA,1,1",
+		"TSAPOB-0710,08:05:00,08:15:00,PSB,2,0",
+		"TSAPOB-0710,08:45:00,08:55:00,PVB,3,0",
+		"TSAPOB-0710,09:25:00,09:25:00,POB,4,1",
+		"TSAPOB-1500,15:00:00,15:00:00,TSA,1,1",
+		"TSAPOB-1500,16:20:00,16:20:00,POB,2,1",
+	})
+	assertRows(t, feed, "calendar.txt", []string{
+		"S1,1,1,1,1,1,1,1,20251020,20251102",
+		"S2,0,0,0,0,0,0,0,20251020,20251102",
+	})
+	assertRows(t, feed, "calendar_dates.txt", []string{
+		"S1,20251022,2", // Except on Oct 22
+		"S2,20251024,1", // Only on Oct 24
+	})
+}
+
+func TestExport(t *testing.T) {
+	store := db.NewMemoryStore()
+	if err := Export(store, time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC), 14, &bytes.Buffer{}); err != ErrNoSchedules {
+		t.Errorf("Export with an empty store = %v, want ErrNoSchedules", err)
+	}
+
+	for _, route := range testRoutes() {
+		if err := store.SaveNonCapacityRoute(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Export(store, time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC), 14, &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		if file.Name != "trips.txt" {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil || len(records) != 3 || records[0][2] != "trip_id" {
+			t.Errorf("trips.txt = %v, %v, want a header and 2 trips", records, err)
+		}
+	}
+	if got := strings.Join(names, " "); got != "agency.txt stops.txt routes.txt trips.txt stop_times.txt calendar.txt calendar_dates.txt" {
+		t.Errorf("zip entries = %s", got)
+	}
+}
+
+func TestBuildCapacityTrips(t *testing.T) {
+	feed := Build(nil, []models.CapacityRoute{testCapacityRoute()})
+
+	assertRows(t, feed, "trips.txt", []string{
+		"TSASWB,D20251020,TSASWB-2025-10-20-0015,Victoria (Swartz Bay)",
+		"TSASWB,D20251020,TSASWB-2025-10-20-0700,Victoria (Swartz Bay)",
+		"TSASWB,D20251020,TSASWB-2025-10-20-0900,Victoria (Swartz Bay)",
+		"TSASWB,D20251020,TSASWB-2025-10-20-1000,Victoria (Swartz Bay)",
+		"TSASWB,D20251020,TSASWB-2025-10-20-1100,Victoria (Swartz Bay)",
+		"TSASWB,D20251020,TSASWB-2025-10-20-1300,Victoria (Swartz Bay)",
+	})
+	// Timetabled times, not the actual 7:04 am departure; 12:15 am is the next morning
+	assertRows(t, feed, "stop_times.txt", []string{
+		"TSASWB-2025-10-20-0015,24:15:00,24:15:00,TSA,1,1",
+		"TSASWB-2025-10-20-0015,25:50:00,25:50:00,SWB,2,1",
+		"TSASWB-2025-10-20-0700,07:00:00,07:00:00,TSA,1,1",
+		"TSASWB-2025-10-20-0700,08:35:00,08:35:00,SWB,2,1",
+		"TSASWB-2025-10-20-0900,09:00:00,09:00:00,TSA,1,1",
+		"TSASWB-2025-10-20-0900,10:35:00,10:35:00,SWB,2,1",
+		"TSASWB-2025-10-20-1000,10:00:00,10:00:00,TSA,1,1",
+		"TSASWB-2025-10-20-1000,11:35:00,11:35:00,SWB,2,1",
+		"TSASWB-2025-10-20-1100,11:00:00,11:00:00,TSA,1,1",
+		"TSASWB-2025-10-20-1100,12:35:00,12:35:00,SWB,2,1",
+		"TSASWB-2025-10-20-1300,13:00:00,13:00:00,TSA,1,1",
+		"TSASWB-2025-10-20-1300,14:35:00,14:35:00,SWB,2,1",
+	})
+	assertRows(t, feed, "calendar_dates.txt", []string{"D20251020,20251020,1"})
+}
@@ -0,0 +1,70 @@
+package gtfs
+
+import "math"
+
+/*
+ * protoBuffer
+ *
+ * A minimal protocol buffers (proto2) encoder, enough for the GTFS-Realtime messages
+ * in realtime.go: varints, strings and nested messages. Field numbers follow
+ * https://gtfs.org/realtime/proto/
+ */
+type protoBuffer struct {
+	b []byte
+}
+
+type protoMessage interface {
+	marshalProto(p *protoBuffer)
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (p *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		p.b = append(p.b, byte(v)|0x80)
+		v >>= 7
+	}
+	p.b = append(p.b, byte(v))
+}
+
+func (p *protoBuffer) tag(field, wireType int) {
+	p.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// uint writes a uint32/uint64 field
+func (p *protoBuffer) uint(field int, v uint64) {
+	p.tag(field, wireVarint)
+	p.varint(v)
+}
+
+// int writes an int32/int64 or enum field; negative values take ten bytes, as in proto
+func (p *protoBuffer) int(field int, v int64) {
+	p.tag(field, wireVarint)
+	p.varint(uint64(v))
+}
+
+// string writes a string field, skipping empty (unset) ones
+func (p *protoBuffer) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(s)))
+	p.b = append(p.b, s...)
+}
+
+func (p *protoBuffer) message(field int, m protoMessage) {
+	var nested protoBuffer
+	m.marshalProto(&nested)
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(nested.b)))
+	p.b = append(p.b, nested.b...)
+}
+
+// clampInt32 keeps delays within an int32 field
+func clampInt32(v int64) int32 {
+	return int32(max(math.MinInt32, min(math.MaxInt32, v)))
+}
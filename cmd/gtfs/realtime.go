@@ -0,0 +1,413 @@
+package gtfs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+// GTFS-Realtime enum values
+const (
+	incrementalityFullDataset = 0
+
+	scheduleRelationshipCanceled = 3
+
+	causeUnknown          = 1
+	causeOther            = 2
+	causeTechnicalProblem = 3
+	causeWeather          = 8
+	causeMaintenance      = 9
+	causePoliceActivity   = 11
+	causeMedicalEmergency = 12
+
+	effectNoService = 1
+)
+
+/*
+ * FeedMessage
+ *
+ * A GTFS-Realtime feed. Marshal encodes it as protobuf; the JSON tags follow the field
+ * names in gtfs-realtime.proto for the ?format=json debug view.
+ */
+type FeedMessage struct {
+	Header FeedHeader   `json:"header"`
+	Entity []FeedEntity `json:"entity"`
+}
+
+type FeedHeader struct {
+	GtfsRealtimeVersion string `json:"gtfs_realtime_version"`
+	Incrementality      int    `json:"incrementality"`
+	Timestamp           uint64 `json:"timestamp"`
+}
+
+type FeedEntity struct {
+	ID         string      `json:"id"`
+	TripUpdate *TripUpdate `json:"trip_update,omitempty"`
+	Alert      *Alert      `json:"alert,omitempty"`
+}
+
+type TripUpdate struct {
+	Trip           TripDescriptor     `json:"trip"`
+	Vehicle        *VehicleDescriptor `json:"vehicle,omitempty"`
+	StopTimeUpdate []StopTimeUpdate   `json:"stop_time_update,omitempty"`
+	Timestamp      uint64             `json:"timestamp"`
+}
+
+type TripDescriptor struct {
+	TripID               string `json:"trip_id"`
+	RouteID              string `json:"route_id"`
+	StartTime            string `json:"start_time,omitempty"`
+	StartDate            string `json:"start_date,omitempty"`
+	ScheduleRelationship int    `json:"schedule_relationship,omitempty"`
+}
+
+type VehicleDescriptor struct {
+	Label string `json:"label"`
+}
+
+type StopTimeUpdate struct {
+	StopSequence uint32         `json:"stop_sequence"`
+	StopID       string         `json:"stop_id"`
+	Arrival      *StopTimeEvent `json:"arrival,omitempty"`
+	Departure    *StopTimeEvent `json:"departure,omitempty"`
+}
+
+type StopTimeEvent struct {
+	Delay *int32 `json:"delay,omitempty"` // Seconds; unset when the scheduled time is unknown
+	Time  int64  `json:"time"`            // Unix time
+}
+
+type Alert struct {
+	ActivePeriod    []TimeRange      `json:"active_period"`
+	InformedEntity  []EntitySelector `json:"informed_entity"`
+	Cause           int              `json:"cause"`
+	Effect          int              `json:"effect"`
+	HeaderText      TranslatedString `json:"header_text"`
+	DescriptionText TranslatedString `json:"description_text"`
+}
+
+type TimeRange struct {
+	Start uint64 `json:"start,omitempty"`
+	End   uint64 `json:"end,omitempty"`
+}
+
+type EntitySelector struct {
+	AgencyID string          `json:"agency_id,omitempty"`
+	RouteID  string          `json:"route_id,omitempty"`
+	Trip     *TripDescriptor `json:"trip,omitempty"`
+}
+
+type TranslatedString struct {
+	Translation []Translation `json:"translation"`
+}
+
+type Translation struct {
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+}
+
+/*
+ * BuildRealtime
+ *
+ * Builds a GTFS-Realtime feed from capacity routes. Trip IDs are the sailing IDs, which
+ * are also the trip_ids of capacity sailings in the static feed (see Build).
+ *   - cancelled sailings: a CANCELED TripUpdate, and an Alert giving the reason from
+ *     VesselStatus
+ *   - departed sailings: a TripUpdate with the departure delay at the origin and, once
+ *     the page shows an ETA or arrival time, the arrival at the destination
+ *   - sailings that have not departed are running to schedule as far as the capacity
+ *     pages tell, so they get no update
+ *
+ * @param []models.CapacityRoute routes
+ * @param time.Time now - feed timestamp
+ *
+ * @return FeedMessage
+ */
+func BuildRealtime(routes []models.CapacityRoute, now time.Time) FeedMessage {
+	feed := FeedMessage{Header: FeedHeader{
+		GtfsRealtimeVersion: "2.0",
+		Incrementality:      incrementalityFullDataset,
+		Timestamp:           uint64(now.Unix()),
+	}}
+
+	terminals := staticdata.GetTerminals()
+	location := models.Pacific()
+
+	for _, route := range routes {
+		serviceDay, err := time.ParseInLocation("2006-01-02", route.Date, location)
+		if err != nil {
+			continue
+		}
+		// Minutes after midnight to Unix time, across DST changes
+		unix := func(minutes int) int64 {
+			return time.Date(serviceDay.Year(), serviceDay.Month(), serviceDay.Day(), 0, minutes, 0, 0, location).Unix()
+		}
+
+		for _, t := range models.CapacitySchedule(route) {
+			descriptor := TripDescriptor{
+				TripID:    t.Sailing.ID,
+				RouteID:   route.RouteCode,
+				StartTime: gtfsTime(t.Departure),
+				StartDate: gtfsDate(route.Date),
+			}
+			update := &TripUpdate{Trip: descriptor, Timestamp: uint64(now.Unix())}
+			if t.Sailing.VesselName != "" {
+				update.Vehicle = &VehicleDescriptor{Label: t.Sailing.VesselName}
+			}
+
+			switch t.Sailing.SailingStatus {
+			case "cancelled":
+				update.Trip.ScheduleRelationship = scheduleRelationshipCanceled
+				feed.Entity = append(feed.Entity, FeedEntity{ID: "trip-" + t.Sailing.ID, TripUpdate: update})
+				feed.Entity = append(feed.Entity, FeedEntity{ID: "alert-" + t.Sailing.ID, Alert: cancellationAlert(route, t, descriptor, terminals, unix)})
+
+			case "past", "current":
+				actual, ok := models.ParseClock(t.Sailing.DepartureTime)
+				if !ok {
+					continue
+				}
+				actual = nearest(actual, t.Departure)
+				update.StopTimeUpdate = append(update.StopTimeUpdate, StopTimeUpdate{
+					StopSequence: 1,
+					StopID:       route.FromTerminalCode,
+					Departure:    &StopTimeEvent{Delay: delay(actual, t.Departure), Time: unix(actual)},
+				})
+
+				// "..." or "Variable" until the page has an ETA
+				if arrival, ok := models.ParseClock(t.Sailing.ArrivalTime); ok {
+					for arrival < actual {
+						arrival += 1440
+					}
+					event := &StopTimeEvent{Time: unix(arrival)}
+					if t.Arrival > 0 {
+						event.Delay = delay(arrival, t.Arrival)
+					}
+					update.StopTimeUpdate = append(update.StopTimeUpdate, StopTimeUpdate{StopSequence: 2, StopID: route.ToTerminalCode, Arrival: event})
+				}
+				feed.Entity = append(feed.Entity, FeedEntity{ID: "trip-" + t.Sailing.ID, TripUpdate: update})
+			}
+		}
+	}
+
+	return feed
+}
+
+/*
+ * cancellationAlert
+ *
+ * Describes a cancelled sailing, e.g. "Cancelled: 11:00 am Vancouver (Tsawwassen) to
+ * Victoria (Swartz Bay)", with the reason the capacity page gives as the description.
+ *
+ * @param models.CapacityRoute route
+ * @param models.ScheduledSailing t
+ * @param TripDescriptor trip
+ * @param map[string]staticdata.Terminal terminals
+ * @param func(int) int64 unix - minutes after midnight to Unix time
+ *
+ * @return *Alert
+ */
+func cancellationAlert(route models.CapacityRoute, t models.ScheduledSailing, trip TripDescriptor, terminals map[string]staticdata.Terminal, unix func(int) int64) *Alert {
+	name := func(code string) string {
+		if terminal, ok := terminals[code]; ok {
+			return stopName(terminal)
+		}
+		return code
+	}
+
+	period := TimeRange{Start: uint64(unix(t.Departure))}
+	if t.Arrival > 0 {
+		period.End = uint64(unix(t.Arrival))
+	}
+
+	description := t.Sailing.VesselStatus
+	if description == "" {
+		description = "This sailing has been cancelled."
+	}
+
+	return &Alert{
+		ActivePeriod:    []TimeRange{period},
+		InformedEntity:  []EntitySelector{{AgencyID: agencyID, RouteID: route.RouteCode, Trip: &trip}},
+		Cause:           cancellationCause(t.Sailing.VesselStatus),
+		Effect:          effectNoService,
+		HeaderText:      text(fmt.Sprintf("Cancelled: %s %s to %s", models.FormatClock(t.Departure), name(route.FromTerminalCode), name(route.ToTerminalCode))),
+		DescriptionText: text(description),
+	}
+}
+
+/*
+ * cancellationCause
+ *
+ * Maps a cancellation reason such as "Due to a mechanical issue with the vessel." to a
+ * GTFS-Realtime cause.
+ *
+ * @param string reason
+ *
+ * @return int
+ */
+func cancellationCause(reason string) int {
+	reason = strings.ToLower(reason)
+	causes := []struct {
+		cause    int
+		keywords []string
+	}{
+		{causeWeather, []string{"weather", "wind", "sea condition", "fog", "storm", "tide"}},
+		{causeTechnicalProblem, []string{"mechanical", "technical"}},
+		{causeMedicalEmergency, []string{"medical"}},
+		{causeMaintenance, []string{"maintenance", "refit"}},
+		{causePoliceActivity, []string{"police", "rcmp"}},
+	}
+	for _, c := range causes {
+		for _, keyword := range c.keywords {
+			if strings.Contains(reason, keyword) {
+				return c.cause
+			}
+		}
+	}
+	if reason == "" {
+		return causeUnknown
+	}
+	return causeOther
+}
+
+// nearest moves a time of day to the day closest to `scheduled`, for sailings that leave late past midnight
+func nearest(minutes, scheduled int) int {
+	for minutes-scheduled > 720 {
+		minutes -= 1440
+	}
+	for scheduled-minutes > 720 {
+		minutes += 1440
+	}
+	return minutes
+}
+
+func delay(actual, scheduled int) *int32 {
+	seconds := clampInt32(int64(actual-scheduled) * 60)
+	return &seconds
+}
+
+func text(s string) TranslatedString {
+	return TranslatedString{Translation: []Translation{{Text: s, Language: "en"}}}
+}
+
+/*
+ * Marshal
+ *
+ * Encodes the feed in the protocol buffers wire format
+ *
+ * @return []byte
+ */
+func (m FeedMessage) Marshal() []byte {
+	var p protoBuffer
+	m.marshalProto(&p)
+	return p.b
+}
+
+func (m FeedMessage) marshalProto(p *protoBuffer) {
+	p.message(1, m.Header)
+	for _, entity := range m.Entity {
+		p.message(2, entity)
+	}
+}
+
+func (h FeedHeader) marshalProto(p *protoBuffer) {
+	p.string(1, h.GtfsRealtimeVersion)
+	p.int(2, int64(h.Incrementality))
+	p.uint(3, h.Timestamp)
+}
+
+func (e FeedEntity) marshalProto(p *protoBuffer) {
+	p.string(1, e.ID)
+	if e.TripUpdate != nil {
+		p.message(3, *e.TripUpdate)
+	}
+	if e.Alert != nil {
+		p.message(5, *e.Alert)
+	}
+}
+
+func (u TripUpdate) marshalProto(p *protoBuffer) {
+	p.message(1, u.Trip)
+	for _, update := range u.StopTimeUpdate {
+		p.message(2, update)
+	}
+	if u.Vehicle != nil {
+		p.message(3, *u.Vehicle)
+	}
+	p.uint(4, u.Timestamp)
+}
+
+func (d TripDescriptor) marshalProto(p *protoBuffer) {
+	p.string(1, d.TripID)
+	p.string(2, d.StartTime)
+	p.string(3, d.StartDate)
+	if d.ScheduleRelationship != 0 {
+		p.int(4, int64(d.ScheduleRelationship))
+	}
+	p.string(5, d.RouteID)
+}
+
+func (v VehicleDescriptor) marshalProto(p *protoBuffer) {
+	p.string(2, v.Label)
+}
+
+func (u StopTimeUpdate) marshalProto(p *protoBuffer) {
+	p.uint(1, uint64(u.StopSequence))
+	if u.Arrival != nil {
+		p.message(2, *u.Arrival)
+	}
+	if u.Departure != nil {
+		p.message(3, *u.Departure)
+	}
+	p.string(4, u.StopID)
+}
+
+func (e StopTimeEvent) marshalProto(p *protoBuffer) {
+	if e.Delay != nil {
+		p.int(1, int64(*e.Delay))
+	}
+	p.int(2, e.Time)
+}
+
+func (a Alert) marshalProto(p *protoBuffer) {
+	for _, period := range a.ActivePeriod {
+		p.message(1, period)
+	}
+	for _, entity := range a.InformedEntity {
+		p.message(5, entity)
+	}
+	p.int(6, int64(a.Cause))
+	p.int(7, int64(a.Effect))
+	p.message(10, a.HeaderText)
+	p.message(11, a.DescriptionText)
+}
+
+func (r TimeRange) marshalProto(p *protoBuffer) {
+	if r.Start != 0 {
+		p.uint(1, r.Start)
+	}
+	if r.End != 0 {
+		p.uint(2, r.End)
+	}
+}
+
+func (s EntitySelector) marshalProto(p *protoBuffer) {
+	p.string(1, s.AgencyID)
+	p.string(2, s.RouteID)
+	if s.Trip != nil {
+		p.message(4, *s.Trip)
+	}
+}
+
+func (s TranslatedString) marshalProto(p *protoBuffer) {
+	for _, translation := range s.Translation {
+		p.message(1, translation)
+	}
+}
+
+func (t Translation) marshalProto(p *protoBuffer) {
+	p.string(1, t.Text)
+	p.string(2, t.Language)
+}
@@ -0,0 +1,136 @@
+package gtfs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Monday Oct 20 on TSASWB, as the capacity page shows it at 10:30 am
+func testCapacityRoute() models.CapacityRoute {
+	return models.CapacityRoute{
+		Date:             "2025-10-20",
+		RouteCode:        "TSASWB",
+		FromTerminalCode: "TSA",
+		ToTerminalCode:   "SWB",
+		SailingDuration:  "1h 35m",
+		Sailings: []models.CapacitySailing{
+			{ID: "TSASWB-2025-10-20-0700", DepartureTime: "7:04 am", ScheduledTime: "7:00 am", ArrivalTime: "8:37 am", SailingStatus: "past", VesselName: "Spirit of Vancouver Island"},
+			{ID: "TSASWB-2025-10-20-0900", DepartureTime: "9:12 am", ScheduledTime: "9:00 am", ArrivalTime: "10:46 am", SailingStatus: "current", VesselName: "Queen of New Westminster"},
+			{ID: "TSASWB-2025-10-20-1000", DepartureTime: "10:01 am", ScheduledTime: "10:00 am", ArrivalTime: "...", SailingStatus: "current", VesselName: "Coastal Celebration"},
+			{ID: "TSASWB-2025-10-20-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "cancelled", VesselName: "Spirit of British Columbia", VesselStatus: "Due to a mechanical issue with the vessel."},
+			{ID: "TSASWB-2025-10-20-1300", DepartureTime: "1:00 pm", ScheduledTime: "1:00 pm", SailingStatus: "future", VesselName: "Spirit of Vancouver Island"},
+			{ID: "TSASWB-2025-10-20-0015", DepartureTime: "12:15 am", ScheduledTime: "12:15 am", SailingStatus: "future"},
+		},
+	}
+}
+
+func TestBuildRealtime(t *testing.T) {
+	now := time.Date(2025, 10, 20, 17, 30, 0, 0, time.UTC) // 10:30 am PDT
+	feed := BuildRealtime([]models.CapacityRoute{testCapacityRoute()}, now)
+
+	if feed.Header.GtfsRealtimeVersion != "2.0" || feed.Header.Timestamp != uint64(now.Unix()) {
+		t.Errorf("header = %+v", feed.Header)
+	}
+
+	var ids []string
+	for _, entity := range feed.Entity {
+		ids = append(ids, entity.ID)
+	}
+	want := []string{"trip-TSASWB-2025-10-20-0700", "trip-TSASWB-2025-10-20-0900", "trip-TSASWB-2025-10-20-1000", "trip-TSASWB-2025-10-20-1100", "alert-TSASWB-2025-10-20-1100"}
+	if len(ids) != len(want) {
+		t.Fatalf("entities = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("entities = %v, want %v", ids, want)
+		}
+	}
+
+	// Left 4 minutes late (7:04 am PDT), arrived 2 minutes late against 7:00 + 1h 35m
+	past := feed.Entity[0].TripUpdate
+	if past.Trip.TripID != "TSASWB-2025-10-20-0700" || past.Trip.StartTime != "07:00:00" || past.Trip.StartDate != "20251020" || past.Vehicle.Label != "Spirit of Vancouver Island" {
+		t.Errorf("trip = %+v, vehicle = %+v", past.Trip, past.Vehicle)
+	}
+	if len(past.StopTimeUpdate) != 2 {
+		t.Fatalf("stop time updates = %+v", past.StopTimeUpdate)
+	}
+	departure, arrival := past.StopTimeUpdate[0], past.StopTimeUpdate[1]
+	if departure.StopID != "TSA" || *departure.Departure.Delay != 240 || departure.Departure.Time != time.Date(2025, 10, 20, 14, 4, 0, 0, time.UTC).Unix() {
+		t.Errorf("departure = %+v %+v", departure, *departure.Departure)
+	}
+	if arrival.StopID != "SWB" || *arrival.Arrival.Delay != 120 || arrival.Arrival.Time != time.Date(2025, 10, 20, 15, 37, 0, 0, time.UTC).Unix() {
+		t.Errorf("arrival = %+v %+v", arrival, *arrival.Arrival)
+	}
+
+	// No ETA yet
+	if updates := feed.Entity[2].TripUpdate.StopTimeUpdate; len(updates) != 1 || *updates[0].Departure.Delay != 60 {
+		t.Errorf("10:00 am updates = %+v", updates)
+	}
+
+	cancelled := feed.Entity[3].TripUpdate
+	if cancelled.Trip.ScheduleRelationship != scheduleRelationshipCanceled || len(cancelled.StopTimeUpdate) != 0 {
+		t.Errorf("cancelled trip = %+v", cancelled)
+	}
+
+	alert := feed.Entity[4].Alert
+	if alert.Cause != causeTechnicalProblem || alert.Effect != effectNoService {
+		t.Errorf("alert cause, effect = %d, %d", alert.Cause, alert.Effect)
+	}
+	if got := alert.HeaderText.Translation[0].Text; got != "Cancelled: 11:00 am Vancouver (Tsawwassen) to Victoria (Swartz Bay)" {
+		t.Errorf("header = %q", got)
+	}
+	if got := alert.DescriptionText.Translation[0].Text; got != "Due to a mechanical issue with the vessel." {
+		t.Errorf("description = %q", got)
+	}
+	if alert.InformedEntity[0].Trip.TripID != "TSASWB-2025-10-20-1100" || alert.ActivePeriod[0].End-alert.ActivePeriod[0].Start != 95*60 {
+		t.Errorf("alert entity = %+v, period = %+v", alert.InformedEntity[0], alert.ActivePeriod[0])
+	}
+}
+
+func TestCancellationCause(t *testing.T) {
+	for reason, want := range map[string]int{
+		"Due to adverse weather conditions.":         causeWeather,
+		"Due to a mechanical issue with the vessel.": causeTechnicalProblem,
+		"Due to a medical emergency.":                causeMedicalEmergency,
+		"Due to crew availability.":                  causeOther,
+		"":                                           causeUnknown,
+	} {
+		if got := cancellationCause(reason); got != want {
+			t.Errorf("cancellationCause(%q) = %d, want %d", reason, got, want)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	delay := int32(-60)
+	feed := FeedMessage{
+		Header: FeedHeader{GtfsRealtimeVersion: "2.0", Timestamp: 300},
+		Entity: []FeedEntity{{ID: "a", TripUpdate: &TripUpdate{
+			Trip:           TripDescriptor{TripID: "t"},
+			StopTimeUpdate: []StopTimeUpdate{{StopSequence: 1, Departure: &StopTimeEvent{Delay: &delay, Time: 1}}},
+		}}},
+	}
+
+	want := []byte{
+		0x0a, 0x0a, // header
+		0x0a, 0x03, '2', '.', '0', // gtfs_realtime_version
+		0x10, 0x00, // incrementality
+		0x18, 0xac, 0x02, // timestamp 300
+		0x12, 0x1f, // entity
+		0x0a, 0x01, 'a', // id
+		0x1a, 0x1a, // trip_update
+		0x0a, 0x03, 0x0a, 0x01, 't', // trip
+		0x12, 0x11, // stop_time_update
+		0x08, 0x01, // stop_sequence
+		0x1a, 0x0d, // departure
+		0x08, 0xc4, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, // delay -60
+		0x10, 0x01, // time
+		0x20, 0x00, // timestamp
+	}
+	if got := feed.Marshal(); !bytes.Equal(got, want) {
+		t.Errorf("Marshal =\n% x\nwant\n% x", got, want)
+	}
+}
@@ -0,0 +1,297 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+const (
+	timezone = "America/Vancouver"
+	uidHost  = "bcferriesapi.ca"
+)
+
+// vtimezone describes America/Vancouver for calendar apps that do not know TZIDs
+const vtimezone = `BEGIN:VTIMEZONE
+TZID:America/Vancouver
+BEGIN:DAYLIGHT
+TZOFFSETFROM:-0800
+TZOFFSETTO:-0700
+TZNAME:PDT
+DTSTART:19700308T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+END:DAYLIGHT
+BEGIN:STANDARD
+TZOFFSETFROM:-0700
+TZOFFSETTO:-0800
+TZNAME:PST
+DTSTART:19701101T020000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+END:STANDARD
+END:VTIMEZONE`
+
+/*
+ * Calendar
+ *
+ * An iCalendar (RFC 5545) calendar of sailings
+ */
+type Calendar struct {
+	Name   string    // X-WR-CALNAME, shown by calendar apps when subscribing
+	Stamp  time.Time // DTSTAMP of every event: when the calendar was generated
+	Events []Event
+}
+
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time // Zero when the arrival is unknown
+	Cancelled   bool
+}
+
+/*
+ * NonCapacityEvents
+ *
+ * Makes an event per sailing of a non-capacity route, from its timetabled departure to
+ * its arrival. The description lists the vessels and the stops, transfers and thru
+ * fares from the sailing's events.
+ *
+ * @param models.NonCapacityRoute route
+ *
+ * @return []Event - sailings whose times cannot be parsed are left out
+ */
+func NonCapacityEvents(route models.NonCapacityRoute) []Event {
+	var events []Event
+	previous := 0
+	for _, sailing := range route.Sailings {
+		dep, arr, ok := models.SailingMinutes(sailing.DepartureTime, sailing.ArrivalTime, sailing.SailingDuration)
+		if !ok {
+			continue
+		}
+		// Sailings after midnight are listed last
+		for dep < previous {
+			dep, arr = dep+1440, arr+1440
+		}
+		previous = dep
+
+		var vessels []string
+		for _, leg := range sailing.Legs {
+			if leg.VesselName != nil && *leg.VesselName != "UNKNOWN" && !contains(vessels, *leg.VesselName) {
+				vessels = append(vessels, *leg.VesselName)
+			}
+		}
+
+		var lines []string
+		if len(vessels) > 0 {
+			lines = append(lines, "Vessel: "+strings.Join(vessels, ", "))
+		}
+		if len(sailing.Events) == 0 {
+			lines = append(lines, "Non-stop")
+		}
+		for _, event := range sailing.Events {
+			lines = append(lines, eventLabel(event.Type)+": "+event.TerminalName)
+		}
+
+		events = append(events, Event{
+			UID:         UID(sailing.ID),
+			Summary:     summary(route.FromTerminalCode, route.ToTerminalCode),
+			Description: strings.Join(lines, "\n"),
+			Location:    location(route.FromTerminalCode),
+			Start:       clock(route.Date, dep),
+			End:         clock(route.Date, arr),
+		})
+	}
+	return events
+}
+
+/*
+ * CapacityEvents
+ *
+ * Makes an event per sailing of a capacity route, at its timetabled times (see
+ * models.CapacitySchedule). Cancelled sailings are kept with STATUS:CANCELLED and the
+ * reason in their description, so subscribed calendars show the cancellation.
+ *
+ * @param models.CapacityRoute route
+ *
+ * @return []Event
+ */
+func CapacityEvents(route models.CapacityRoute) []Event {
+	var events []Event
+	for _, s := range models.CapacitySchedule(route) {
+		var lines []string
+		if s.Sailing.VesselName != "" {
+			lines = append(lines, "Vessel: "+s.Sailing.VesselName)
+		}
+
+		event := Event{
+			UID:      UID(s.Sailing.ID),
+			Summary:  summary(route.FromTerminalCode, route.ToTerminalCode),
+			Location: location(route.FromTerminalCode),
+			Start:    clock(route.Date, s.Departure),
+		}
+		if s.Arrival > 0 {
+			event.End = clock(route.Date, s.Arrival)
+		}
+		if s.Sailing.SailingStatus == "cancelled" {
+			event.Cancelled = true
+			event.Summary = "Cancelled: " + event.Summary
+			if s.Sailing.VesselStatus != "" {
+				lines = append(lines, s.Sailing.VesselStatus)
+			}
+		}
+		event.Description = strings.Join(lines, "\n")
+
+		events = append(events, event)
+	}
+	return events
+}
+
+/*
+ * UID
+ *
+ * Returns the UID of a sailing's event, which stays the same across downloads so
+ * calendar apps update the event instead of adding another
+ *
+ * @param string sailingID - e.g. "TSAPOB-2025-10-20-0710"
+ *
+ * @return string
+ */
+func UID(sailingID string) string {
+	return sailingID + "@" + uidHost
+}
+
+/*
+ * Marshal
+ *
+ * Encodes the calendar as text/calendar: CRLF line endings, lines folded at 75 octets
+ * and times in America/Vancouver
+ *
+ * @return []byte
+ */
+func (c Calendar) Marshal() []byte {
+	var b strings.Builder
+	line := func(name, value string) {
+		fold(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//BC Ferries API//Sailings//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	line("X-WR-TIMEZONE", timezone)
+	for _, l := range strings.Split(vtimezone, "\n") {
+		b.WriteString(l + "\r\n")
+	}
+
+	stamp := c.Stamp.UTC().Format("20060102T150405Z")
+	for _, event := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", event.UID)
+		line("DTSTAMP", stamp)
+		line("DTSTART;TZID="+timezone, localTime(event.Start))
+		if !event.End.IsZero() {
+			line("DTEND;TZID="+timezone, localTime(event.End))
+		}
+		line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION", escape(event.Location))
+		}
+		if event.Cancelled {
+			line("STATUS", "CANCELLED")
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return []byte(b.String())
+}
+
+// fold writes a content line, continuing it on lines starting with a space after 75 octets
+func fold(b *strings.Builder, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // The leading space counts
+	}
+	b.WriteString(s + "\r\n")
+}
+
+// escape escapes a TEXT value
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func localTime(t time.Time) string {
+	return t.In(models.Pacific()).Format("20060102T150405")
+}
+
+// clock converts minutes after midnight of a service date to a time, across DST changes
+func clock(date string, minutes int) time.Time {
+	location := models.Pacific()
+	day, err := time.ParseInLocation("2006-01-02", date, location)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, location)
+}
+
+// summary names a sailing by its terminals, e.g. "Tsawwassen to Otter Bay"
+func summary(fromTerminalCode, toTerminalCode string) string {
+	terminals := staticdata.GetTerminals()
+	name := func(code string) string {
+		if terminal, ok := terminals[code]; ok {
+			return terminal.Name
+		}
+		return code
+	}
+	return fmt.Sprintf("%s to %s", name(fromTerminalCode), name(toTerminalCode))
+}
+
+// location is the departure terminal, e.g. "Vancouver (Tsawwassen)"
+func location(terminalCode string) string {
+	terminal, ok := staticdata.GetTerminals()[terminalCode]
+	if !ok {
+		return terminalCode
+	}
+	return terminal.ServiceArea + " (" + terminal.Name + ")"
+}
+
+func eventLabel(eventType string) string {
+	switch eventType {
+	case "stop":
+		return "Stop"
+	case "transfer":
+		return "Transfer"
+	case "thruFare":
+		return "Thru fare"
+	}
+	return eventType
+}
+
+func contains(s []string, v string) bool {
+	for _, existing := range s {
+		if existing == v {
+			return true
+		}
+	}
+	return false
+}
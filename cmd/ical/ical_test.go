@@ -0,0 +1,126 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func TestNonCapacityEvents(t *testing.T) {
+	vessel := "Queen of Cumberland"
+	route := models.NonCapacityRoute{
+		Date:             "2025-10-20",
+		RouteCode:        "TSAPOB",
+		FromTerminalCode: "TSA",
+		ToTerminalCode:   "POB",
+		Sailings: []models.NonCapacitySailing{
+			{
+				ID:            "TSAPOB-2025-10-20-0710",
+				DepartureTime: "7:10 am",
+				ArrivalTime:   "9:25 am",
+				Events: []models.SailingEvent{
+					{Type: "stop", TerminalName: "Galiano Island (Sturdies Bay)"},
+					{Type: "transfer", TerminalName: "Mayne Island (Village Bay)"},
+				},
+				Legs: []models.Leg{{VesselName: &vessel}, {VesselName: &vessel}, {VesselName: &vessel}},
+			},
+			{ID: "TSAPOB-2025-10-20-2340", DepartureTime: "11:40 pm", ArrivalTime: "1:00 am"},
+			{ID: "TSAPOB-2025-10-20-0015", DepartureTime: "12:15 am", ArrivalTime: "1:35 am"},
+		},
+	}
+
+	events := NonCapacityEvents(route)
+	if len(events) != 3 {
+		t.Fatalf("NonCapacityEvents = %d events, want 3", len(events))
+	}
+
+	first := events[0]
+	if first.UID != "TSAPOB-2025-10-20-0710@bcferriesapi.ca" || first.Summary != "Tsawwassen to Otter Bay" || first.Location != "Vancouver (Tsawwassen)" {
+		t.Errorf("event = %+v", first)
+	}
+	// 7:10 am PDT
+	if !first.Start.Equal(time.Date(2025, 10, 20, 14, 10, 0, 0, time.UTC)) || !first.End.Equal(time.Date(2025, 10, 20, 16, 25, 0, 0, time.UTC)) {
+		t.Errorf("event times = %v - %v", first.Start, first.End)
+	}
+	if want := "Vessel: Queen of Cumberland\nStop: Galiano Island (Sturdies Bay)\nTransfer: Mayne Island (Village Bay)"; first.Description != want {
+		t.Errorf("description = %q, want %q", first.Description, want)
+	}
+
+	// Arrives after midnight
+	if !events[1].End.Equal(time.Date(2025, 10, 21, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("11:40 pm sailing ends %v", events[1].End)
+	}
+	// Listed after the 11:40 pm, so it leaves the next morning
+	if !events[2].Start.Equal(time.Date(2025, 10, 21, 7, 15, 0, 0, time.UTC)) {
+		t.Errorf("12:15 am sailing starts %v", events[2].Start)
+	}
+}
+
+func TestCapacityEvents(t *testing.T) {
+	route := models.CapacityRoute{
+		Date:             "2025-10-20",
+		RouteCode:        "TSASWB",
+		FromTerminalCode: "TSA",
+		ToTerminalCode:   "SWB",
+		SailingDuration:  "1h 35m",
+		Sailings: []models.CapacitySailing{
+			{ID: "TSASWB-2025-10-20-0700", DepartureTime: "7:04 am", ScheduledTime: "7:00 am", SailingStatus: "past", VesselName: "Spirit of Vancouver Island"},
+			{ID: "TSASWB-2025-10-20-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "cancelled", VesselName: "Spirit of British Columbia", VesselStatus: "Due to a mechanical issue with the vessel."},
+		},
+	}
+
+	events := CapacityEvents(route)
+	if len(events) != 2 {
+		t.Fatalf("CapacityEvents = %d events, want 2", len(events))
+	}
+	// Timetabled, not the actual 7:04 am departure
+	if !events[0].Start.Equal(time.Date(2025, 10, 20, 14, 0, 0, 0, time.UTC)) || events[0].End.Sub(events[0].Start) != 95*time.Minute || events[0].Cancelled {
+		t.Errorf("7:00 am event = %+v", events[0])
+	}
+	cancelled := events[1]
+	if !cancelled.Cancelled || cancelled.Summary != "Cancelled: Tsawwassen to Swartz Bay" || cancelled.Description != "Vessel: Spirit of British Columbia\nDue to a mechanical issue with the vessel." {
+		t.Errorf("cancelled event = %+v", cancelled)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	calendar := Calendar{
+		Name:  "BC Ferries TSAPOB",
+		Stamp: time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC),
+		Events: []Event{{
+			UID:         "TSAPOB-2025-10-20-0710@bcferriesapi.ca",
+			Summary:     "Tsawwassen to Otter Bay",
+			Description: "Vessel: Queen of Cumberland\nStop: Galiano Island (Sturdies Bay), then Mayne Island (Village Bay); transfer",
+			Start:       time.Date(2025, 10, 20, 14, 10, 0, 0, time.UTC),
+			End:         time.Date(2025, 10, 20, 16, 25, 0, 0, time.UTC),
+			Cancelled:   true,
+		}},
+	}
+
+	got := string(calendar.Marshal())
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(got, "\r\n ", "")
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:BC Ferries TSAPOB\r\n",
+		"TZID:America/Vancouver\r\n",
+		"UID:TSAPOB-2025-10-20-0710@bcferriesapi.ca\r\n",
+		"DTSTAMP:20251020T120000Z\r\n",
+		"DTSTART;TZID=America/Vancouver:20251020T071000\r\n",
+		"DTEND;TZID=America/Vancouver:20251020T092500\r\n",
+		`DESCRIPTION:Vessel: Queen of Cumberland\nStop: Galiano Island (Sturdies Bay)\, then Mayne Island (Village Bay)\; transfer` + "\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("calendar does not contain %q:\n%s", want, got)
+		}
+	}
+}
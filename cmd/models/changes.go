@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Change event types, as sent in the SSE "event:" field
+const (
+	ChangeFillChanged     = "fill-changed"     // fill, carFill or oversizeFill changed
+	ChangeStatusChanged   = "status-changed"   // e.g. future -> current -> past
+	ChangeCancelled       = "cancelled"        // a sailing was cancelled
+	ChangeScheduleChanged = "schedule-changed" // sailings added or removed, or their times changed
+	ChangeVesselChanged   = "vessel-changed"   // a sailing is now on another vessel
+	ChangeResync          = "resync"           // events were missed; refetch the routes
+)
+
+/*
+ * ChangeEvent
+ *
+ * Changes of one type to a route's sailings between two scrapes
+ */
+type ChangeEvent struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	RouteCode  string          `json:"routeCode"`
+	Date       string          `json:"date"`
+	SailingIDs []string        `json:"sailingIds"`
+	Changes    []SailingChange `json:"changes"`
+	DetectedAt time.Time       `json:"detectedAt"`
+}
+
+type SailingChange struct {
+	SailingID string `json:"sailingId"`
+	Field     string `json:"field"` // e.g. "carFill", "sailingStatus", or "sailing" when added or removed
+	From      any    `json:"from"`  // null when the sailing was added
+	To        any    `json:"to"`    // null when the sailing was removed
+}
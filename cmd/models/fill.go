@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+/*
+ * FillLevels
+ *
+ * How full a sailing is, in percent
+ */
+type FillLevels struct {
+	Fill         int `json:"fill"`
+	CarFill      int `json:"carFill"`
+	OversizeFill int `json:"oversizeFill"`
+}
+
+/*
+ * FillSample
+ *
+ * A sailing's fill as seen by one scrape
+ */
+type FillSample struct {
+	FillLevels
+	SampledAt              time.Time `json:"sampledAt"`
+	MinutesBeforeDeparture int       `json:"minutesBeforeDeparture"` // Before the timetabled departure, negative after it
+	SailingStatus          string    `json:"sailingStatus"`
+}
+
+/*
+ * FillHistory
+ *
+ * A sailing's fill curve: its samples, oldest first
+ */
+type FillHistory struct {
+	SailingID        string       `json:"sailingId"`
+	RouteCode        string       `json:"routeCode"`
+	Date             string       `json:"date"`
+	ScheduledTime    string       `json:"scheduledTime"`
+	DepartureMinutes int          `json:"-"` // Timetabled departure in minutes after midnight of Date
+	Samples          []FillSample `json:"samples"`
+}
+
+/*
+ * FillPrediction
+ *
+ * The fill a sailing is expected to depart with, from past sailings of the same route
+ * on the same weekday at the same time
+ */
+type FillPrediction struct {
+	SailingID              string     `json:"sailingId"`
+	RouteCode              string     `json:"routeCode"`
+	Date                   string     `json:"date"`
+	ScheduledTime          string     `json:"scheduledTime"`
+	MinutesBeforeDeparture int        `json:"minutesBeforeDeparture"`
+	Current                FillLevels `json:"current"`
+	Predicted              FillLevels `json:"predicted"`
+	SampleSize             int        `json:"sampleSize"` // Past sailings the prediction is based on
+	FullRate               float64    `json:"fullRate"`   // Share of those that departed with a full car deck
+}
+
+/*
+ * FillHistories
+ *
+ * Samples the fill of a capacity route's sailings that have not departed yet, for the
+ * fill history. Sailings whose time cannot be parsed are left out.
+ *
+ * @param CapacityRoute route
+ * @param time.Time sampledAt - when the route was scraped
+ *
+ * @return []FillHistory - one per sailing, each with a single sample
+ */
+func FillHistories(route CapacityRoute, sampledAt time.Time) []FillHistory {
+	var histories []FillHistory
+	for _, s := range CapacitySchedule(route) {
+		if s.Sailing.SailingStatus != "future" && s.Sailing.SailingStatus != "current" {
+			continue
+		}
+		scheduledTime := s.Sailing.ScheduledTime
+		if scheduledTime == "" {
+			scheduledTime = s.Sailing.DepartureTime
+		}
+
+		departure, ok := ServiceTime(route.Date, s.Departure)
+		if !ok {
+			continue
+		}
+		histories = append(histories, FillHistory{
+			SailingID:        s.Sailing.ID,
+			RouteCode:        route.RouteCode,
+			Date:             route.Date,
+			ScheduledTime:    scheduledTime,
+			DepartureMinutes: s.Departure,
+			Samples: []FillSample{{
+				FillLevels:             FillLevels{Fill: s.Sailing.Fill, CarFill: s.Sailing.CarFill, OversizeFill: s.Sailing.OversizeFill},
+				SampledAt:              sampledAt,
+				MinutesBeforeDeparture: int(departure.Sub(sampledAt).Minutes()),
+				SailingStatus:          s.Sailing.SailingStatus,
+			}},
+		})
+	}
+	return histories
+}
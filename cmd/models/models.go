@@ -1,7 +1,6 @@
 package models
 
 import (
-	"strings"
 	"time"
 
 	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
@@ -14,12 +13,13 @@ import (
 /**************/
 
 type CapacityRoute struct {
-	Date             string            `json:"date"`
-	RouteCode        string            `json:"routeCode"`
-	FromTerminalCode string            `json:"fromTerminalCode"`
-	ToTerminalCode   string            `json:"toTerminalCode"`
-	SailingDuration  string            `json:"sailingDuration"`
-	Sailings         []CapacitySailing `json:"sailings"`
+	Date                   string            `json:"date"`
+	RouteCode              string            `json:"routeCode"`
+	FromTerminalCode       string            `json:"fromTerminalCode"`
+	ToTerminalCode         string            `json:"toTerminalCode"`
+	SailingDuration        string            `json:"sailingDuration"`
+	SailingDurationMinutes int               `json:"sailingDurationMinutes,omitempty"`
+	Sailings               []CapacitySailing `json:"sailings"`
 }
 
 type CapacityRouteInfo struct {
@@ -31,15 +31,31 @@ type CapacityRouteInfo struct {
 }
 
 type CapacitySailing struct {
-	ID            string `json:"id"`
-	DepartureTime string `json:"time"`
-	ArrivalTime   string `json:"arrivalTime"`
-	SailingStatus string `json:"sailingStatus"`
-	Fill          int    `json:"fill"`
-	CarFill       int    `json:"carFill"`
-	OversizeFill  int    `json:"oversizeFill"`
-	VesselName    string `json:"vesselName"`
-	VesselStatus  string `json:"vesselStatus"`
+	ID                  string `json:"id"`
+	DepartureTime       string `json:"time"`
+	ScheduledTime       string `json:"scheduledTime,omitempty"`       // Timetabled departure; "time" is the actual departure once departed
+	ActualDepartureTime string `json:"actualDepartureTime,omitempty"` // Once departed
+	ArrivalTime         string `json:"arrivalTime"`                   // ETA while sailing, then the actual arrival
+	ETA                 string `json:"eta,omitempty"`                 // While sailing, e.g. "10:46 am" or "Variable"
+	ActualArrivalTime   string `json:"actualArrivalTime,omitempty"`   // Once arrived
+	SailingStatus       string `json:"sailingStatus"`
+	Fill                int    `json:"fill"`
+	CarFill             int    `json:"carFill"`
+	OversizeFill        int    `json:"oversizeFill"`
+	VesselName          string `json:"vesselName"`
+	VesselStatus        string `json:"vesselStatus"`
+
+	// Typed times (see WithCapacityTimes), in America/Vancouver
+	ScheduledDepartureAt *time.Time `json:"scheduledDepartureAt,omitempty"`
+	DepartureAt          *time.Time `json:"departureAt,omitempty"` // Actual departure once departed
+	ArrivalAt            *time.Time `json:"arrivalAt,omitempty"`   // Null unless arrivalStatus is scheduled, estimated or actual
+	ArrivalStatus        string     `json:"arrivalStatus,omitempty"`
+	DurationMinutes      int        `json:"durationMinutes,omitempty"`
+}
+
+type CapacityRouteSnapshot struct {
+	CapacityRoute
+	ScrapedAt time.Time `json:"scrapedAt"`
 }
 
 type NonCapacityResponse struct {
@@ -55,12 +71,15 @@ type NonCapacityRoutesResponse struct {
 }
 
 type NonCapacityRoute struct {
-	Date             string               `json:"date"`
-	RouteCode        string               `json:"routeCode"`
-	FromTerminalCode string               `json:"fromTerminalCode"`
-	ToTerminalCode   string               `json:"toTerminalCode"`
-	SailingDuration  string               `json:"sailingDuration"`
-	Sailings         []NonCapacitySailing `json:"sailings"`
+	Date                   string               `json:"date"`
+	RouteCode              string               `json:"routeCode"`
+	FromTerminalCode       string               `json:"fromTerminalCode"`
+	ToTerminalCode         string               `json:"toTerminalCode"`
+	SailingDuration        string               `json:"sailingDuration"`
+	SailingDurationMinutes int                  `json:"sailingDurationMinutes,omitempty"`
+	EffectiveFrom          string               `json:"effectiveFrom,omitempty"` // Start of the schedule season the sailings come from
+	EffectiveTo            string               `json:"effectiveTo,omitempty"`   // End of the schedule season the sailings come from
+	Sailings               []NonCapacitySailing `json:"sailings"`
 }
 
 type NonCapacityRouteInfo struct {
@@ -84,6 +103,29 @@ type NonCapacitySailing struct {
 	TotalTravelMin     int            `json:"total_travel_min"`              // Sum of leg sailing durations
 	TotalDwellMin      int            `json:"total_dwell_min"`               // Time spent at stops/terminals
 	AvgDwellPerStopMin *int           `json:"avg_dwell_per_stop_min,omitempty"` // Average dwell time per stop
+
+	// Typed times (see WithNonCapacityTimes), in America/Vancouver
+	DepartureAt     *time.Time `json:"departureAt,omitempty"`
+	ArrivalAt       *time.Time `json:"arrivalAt,omitempty"`
+	ArrivalStatus   string     `json:"arrivalStatus,omitempty"`
+	DurationMinutes int        `json:"durationMinutes,omitempty"`
+}
+
+type NonCapacityRouteSnapshot struct {
+	NonCapacityRoute
+	ScrapedAt time.Time `json:"scrapedAt"`
+}
+
+type ScheduleSeason struct {
+	RouteCode     string `json:"routeCode"`
+	Label         string `json:"label"`         // e.g. "Fall/Winter: Oct 14, 2025 - Mar 31, 2026"
+	EffectiveFrom string `json:"effectiveFrom"` // ISO date
+	EffectiveTo   string `json:"effectiveTo"`   // ISO date
+}
+
+type ScheduleSeasonsResponse struct {
+	RouteCode string           `json:"routeCode"`
+	Seasons   []ScheduleSeason `json:"seasons"`
 }
 
 type SailingEvent struct {
@@ -283,25 +325,11 @@ func findVesselByTimeWindow(vesselDB map[string]string, targetTime string, windo
  * Helper function to calculate estimated time by adding minutes to a base time
  */
 func calculateEstimatedTime(baseTime string, addMinutes int) string {
-	// Parse base time
-	layouts := []string{"3:04 pm", "3:04 PM", "03:04 pm", "03:04 PM", "3:04pm", "3:04PM"}
-	var baseParsed time.Time
-	var err error
-	for _, layout := range layouts {
-		baseParsed, err = time.Parse(layout, baseTime)
-		if err == nil {
-			break
-		}
-	}
-	if err != nil {
+	minutes, ok := ParseClock(baseTime)
+	if !ok {
 		return baseTime // Fallback to original time if parsing fails
 	}
-
-	// Add minutes
-	estimatedParsed := baseParsed.Add(time.Duration(addMinutes) * time.Minute)
-
-	// Format back to lowercase 12-hour format
-	return strings.ToLower(estimatedParsed.Format("3:04 pm"))
+	return FormatClock(minutes + addMinutes)
 }
 
 /**************/
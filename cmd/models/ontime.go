@@ -0,0 +1,107 @@
+package models
+
+import "time"
+
+/*
+ * SailingDeparture
+ *
+ * When a capacity sailing was timetabled to leave and when it actually left and
+ * arrived, kept for on-time statistics after the route is replaced by the next day's
+ */
+type SailingDeparture struct {
+	SailingID          string     `json:"sailingId"`
+	RouteCode          string     `json:"routeCode"`
+	Date               string     `json:"date"`
+	VesselName         string     `json:"vesselName"`
+	ScheduledDeparture time.Time  `json:"scheduledDeparture"`
+	ActualDeparture    time.Time  `json:"actualDeparture"`
+	ETA                *time.Time `json:"eta,omitempty"`           // Last ETA shown while sailing
+	ActualArrival      *time.Time `json:"actualArrival,omitempty"` // Once arrived
+	DelayMinutes       int        `json:"delayMinutes"`            // Actual minus scheduled departure, negative when early
+}
+
+/*
+ * OnTimeStats
+ *
+ * Departure delays over a date range, overall and per route, vessel and hour of day
+ */
+type OnTimeStats struct {
+	From          string       `json:"from"`
+	To            string       `json:"to"`
+	OnTimeMinutes int          `json:"onTimeMinutes"` // Sailings that left less than this late are on time
+	Overall       DelayStats   `json:"overall"`
+	ByRoute       []DelayStats `json:"byRoute"`
+	ByVessel      []DelayStats `json:"byVessel"`
+	ByHour        []DelayStats `json:"byHour"` // Hour of the timetabled departure, Pacific Time
+}
+
+type DelayStats struct {
+	Key                string        `json:"key,omitempty"` // Route code, vessel name or hour ("07")
+	Count              int           `json:"count"`
+	OnTimeRate         float64       `json:"onTimeRate"`
+	MeanDelayMinutes   float64       `json:"meanDelayMinutes"`
+	MedianDelayMinutes int           `json:"medianDelayMinutes"`
+	P90DelayMinutes    int           `json:"p90DelayMinutes"`
+	MaxDelayMinutes    int           `json:"maxDelayMinutes"`
+	Distribution       []DelayBucket `json:"distribution"`
+}
+
+type DelayBucket struct {
+	Label string `json:"label"` // e.g. "early", "0-4", "60+"
+	Count int    `json:"count"`
+}
+
+/*
+ * CapacityDepartures
+ *
+ * Works out the departures of a capacity route's sailings that have left: their
+ * timetabled and actual departure, ETA and arrival as times, and the delay. Times
+ * earlier on the clock than the one before them are the next day.
+ *
+ * @param CapacityRoute route
+ *
+ * @return []SailingDeparture - sailings without a parseable actual departure are left out
+ */
+func CapacityDepartures(route CapacityRoute) []SailingDeparture {
+	var departures []SailingDeparture
+	for _, s := range CapacitySchedule(route) {
+		actual, ok := actualMinutes(s.Departure, s.Sailing.ActualDepartureTime)
+		if !ok {
+			continue
+		}
+		delay := actual - s.Departure
+
+		scheduledAt, ok := ServiceTime(route.Date, s.Departure)
+		if !ok {
+			continue
+		}
+		actualAt, _ := ServiceTime(route.Date, actual)
+
+		departure := SailingDeparture{
+			SailingID:          s.Sailing.ID,
+			RouteCode:          route.RouteCode,
+			Date:               route.Date,
+			VesselName:         s.Sailing.VesselName,
+			ScheduledDeparture: scheduledAt,
+			ActualDeparture:    actualAt,
+			DelayMinutes:       delay,
+		}
+		if eta, ok := clockAfter(route.Date, actual, s.Sailing.ETA); ok {
+			departure.ETA = &eta
+		}
+		if arrival, ok := clockAfter(route.Date, actual, s.Sailing.ActualArrivalTime); ok {
+			departure.ActualArrival = &arrival
+		}
+		departures = append(departures, departure)
+	}
+	return departures
+}
+
+// clockAfter converts a clock time to the first time at or after `after` minutes of a service date
+func clockAfter(date string, after int, clock string) (time.Time, bool) {
+	minutes, ok := ParseClock(clock)
+	if !ok {
+		return time.Time{}, false
+	}
+	return ServiceTime(date, after+((minutes-after)%1440+1440)%1440)
+}
@@ -0,0 +1,44 @@
+package models
+
+// Travel modes accepted by /v2/plan
+const (
+	PlanModeWalkOn  = "walk"
+	PlanModeVehicle = "vehicle"
+)
+
+// Orders accepted by /v2/plan
+const (
+	PlanSortArrival   = "arrival"   // Earliest arrival first
+	PlanSortTransfers = "transfers" // Fewest transfers first
+)
+
+type PlanResponse struct {
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	Date        string      `json:"date"`
+	DepartAfter string      `json:"departAfter"` // e.g. "7:00 am"
+	Mode        string      `json:"mode"`        // PlanModeWalkOn or PlanModeVehicle
+	Sort        string      `json:"sort"`        // PlanSortArrival or PlanSortTransfers
+	Itineraries []Itinerary `json:"itineraries"`
+}
+
+type Itinerary struct {
+	DepartureTime string         `json:"departureTime"`
+	ArrivalTime   string         `json:"arrivalTime"`
+	DurationMin   int            `json:"durationMin"`
+	Transfers     int            `json:"transfers"` // Vessel changes
+	Legs          []ItineraryLeg `json:"legs"`
+}
+
+type ItineraryLeg struct {
+	SailingID        string   `json:"sailingId"`
+	RouteCode        string   `json:"routeCode"` // Route the sailing was scraped from
+	FromTerminalCode string   `json:"fromTerminalCode"`
+	ToTerminalCode   string   `json:"toTerminalCode"`
+	DepartureTime    string   `json:"departureTime"`
+	ArrivalTime      string   `json:"arrivalTime"`
+	Stops            []string `json:"stops,omitempty"`      // Terminals called at without changing vessel
+	VesselName       string   `json:"vesselName,omitempty"` // Empty if not known
+	WaitMin          int      `json:"waitMin"`              // Time at the terminal before boarding
+	Estimated        bool     `json:"estimated"`            // Times derived from average leg durations, not the timetable
+}
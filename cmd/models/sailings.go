@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+/*
+ * SailingDetails
+ *
+ * A single sailing, looked up by ID, with the route it is on. A sailing can be on both
+ * a capacity route (with fill levels) and a non-capacity route (with stops and legs).
+ */
+type SailingDetails struct {
+	ID               string              `json:"id"`
+	RouteCode        string              `json:"routeCode"`
+	Date             string              `json:"date"` // Service date of the route
+	FromTerminalCode string              `json:"fromTerminalCode"`
+	ToTerminalCode   string              `json:"toTerminalCode"`
+	SailingDuration  string              `json:"sailingDuration"`
+	Capacity         *CapacitySailing    `json:"capacity,omitempty"`
+	NonCapacity      *NonCapacitySailing `json:"nonCapacity,omitempty"`
+}
+
+type SailingsBatchGetRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type SailingsBatchGetResponse struct {
+	Sailings []SailingDetails `json:"sailings"` // In the order requested
+	NotFound []string         `json:"notFound"`
+}
+
+/*
+ * SailingFilter
+ *
+ * Which sailings of a service date to list (see db.Store.FindSailings). Empty fields
+ * match every sailing.
+ */
+type SailingFilter struct {
+	Date             string // Service date, YYYY-MM-DD (required)
+	Kind             string // "capacity" or "noncapacity"
+	RouteCodes       []string
+	FromTerminalCode string
+	VesselName       string     // Case-insensitive, on any leg of non-capacity sailings
+	SailingStatus    string     // Capacity sailings only, e.g. "future" or "cancelled"
+	DepartAfter      *time.Time // Inclusive, by departure time (the actual one once departed)
+	DepartBefore     *time.Time // Exclusive
+	Limit            int
+}
+
+type SailingsResponse struct {
+	Sailings []SailingDetails `json:"sailings"` // Soonest departure first
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Kinds of scrape runs
+const (
+	ScrapeKindCapacity    = "capacity"    // ScrapeCapacityRoutes
+	ScrapeKindNonCapacity = "noncapacity" // ScrapeNonCapacityRoutes
+	ScrapeKindCleanup     = "cleanup"     // CleanupOldSailings
+)
+
+// Health levels reported by /v2/status and /healthcheck
+const (
+	StatusOK        = "ok"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
+)
+
+type ScrapeRun struct {
+	ID           int64               `json:"id"`
+	Kind         string              `json:"kind"` // ScrapeKindCapacity, ScrapeKindNonCapacity or ScrapeKindCleanup
+	StartedAt    time.Time           `json:"startedAt"`
+	FinishedAt   time.Time           `json:"finishedAt"`
+	RouteCount   int                 `json:"routeCount"`
+	SuccessCount int                 `json:"successCount"`
+	SailingCount int                 `json:"sailingCount"`    // Sailings scraped today across all routes
+	Error        string              `json:"error,omitempty"` // Why the run as a whole failed, if it did
+	Routes       []ScrapeRouteResult `json:"routes,omitempty"`
+}
+
+type ScrapeRouteResult struct {
+	RouteCode    string `json:"routeCode"`
+	Success      bool   `json:"success"`
+	SailingCount int    `json:"sailingCount"` // Sailings scraped for today
+	Error        string `json:"error,omitempty"`
+	DurationMs   int64  `json:"durationMs"`
+}
+
+type RouteFreshness struct {
+	RouteCode      string     `json:"routeCode"`
+	Kind           string     `json:"kind"`
+	Status         string     `json:"status"` // StatusOK, StatusDegraded or StatusUnhealthy
+	LastAttemptAt  time.Time  `json:"lastAttemptAt"`
+	LastSuccessAt  *time.Time `json:"lastSuccessAt"`       // null if no recorded scrape succeeded
+	LastSuccessAgo string     `json:"lastSuccessAgo"`      // e.g. "3h ago" or "never"
+	LastError      string     `json:"lastError,omitempty"` // Error of the last attempt, if it failed
+}
+
+type StatusResponse struct {
+	Status    string               `json:"status"` // StatusOK, StatusDegraded or StatusUnhealthy
+	Reasons   []string             `json:"reasons,omitempty"`
+	CheckedAt time.Time            `json:"checkedAt"`
+	LastRuns  map[string]ScrapeRun `json:"lastRuns"` // Latest run of each kind
+	Routes    []RouteFreshness     `json:"routes"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+/*
+ * TerminalInfo
+ *
+ * A terminal from the catalogue, as returned by /v2/terminals
+ */
+type TerminalInfo struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name"`
+	ServiceArea string  `json:"serviceArea"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+}
+
+type TerminalsResponse struct {
+	Terminals []TerminalInfo `json:"terminals"`
+}
+
+/*
+ * TerminalDetails
+ *
+ * A terminal with the routes that serve it and its next departures
+ */
+type TerminalDetails struct {
+	TerminalInfo
+	Routes         []TerminalRoute     `json:"routes"`
+	NextDepartures []TerminalDeparture `json:"nextDepartures"`
+}
+
+type TerminalRoute struct {
+	RouteCode        string `json:"routeCode"`
+	FromTerminalCode string `json:"fromTerminalCode"`
+	ToTerminalCode   string `json:"toTerminalCode"`
+	Capacity         bool   `json:"capacity"` // Capacity route (fill levels), otherwise a scheduled non-capacity route
+}
+
+type TerminalDeparture struct {
+	SailingID      string     `json:"sailingId"`
+	RouteCode      string     `json:"routeCode"`
+	ToTerminalCode string     `json:"toTerminalCode"`
+	Time           string     `json:"time"` // e.g. "7:10 am"
+	DepartureAt    time.Time  `json:"departureAt"`
+	ArrivalAt      *time.Time `json:"arrivalAt,omitempty"`
+	SailingStatus  string     `json:"sailingStatus,omitempty"` // Capacity sailings only
+	VesselName     string     `json:"vesselName,omitempty"`
+	Fill           *int       `json:"fill,omitempty"`    // Capacity sailings only
+	CarFill        *int       `json:"carFill,omitempty"` // Capacity sailings only
+}
+
+/*
+ * NearbyTerminal
+ *
+ * A terminal with its great-circle distance from the point searched from
+ */
+type NearbyTerminal struct {
+	TerminalInfo
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+type NearbyTerminalsResponse struct {
+	Lat       float64          `json:"lat"`
+	Lon       float64          `json:"lon"`
+	RadiusKm  float64          `json:"radiusKm"`
+	Terminals []NearbyTerminal `json:"terminals"`
+}
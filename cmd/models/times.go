@@ -0,0 +1,184 @@
+package models
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// How a sailing's arrival is known, as in "arrivalStatus"
+const (
+	ArrivalScheduled = "scheduled" // Timetabled arrival
+	ArrivalEstimated = "estimated" // ETA of a sailing under way
+	ArrivalActual    = "actual"    // The sailing has arrived
+	ArrivalVariable  = "variable"  // BC Ferries shows "Variable" instead of an ETA
+	ArrivalUnknown   = "unknown"   // No arrival time, e.g. "..." or no sailing duration
+)
+
+/*
+ * Pacific
+ *
+ * Returns the America/Vancouver time zone, in which BC Ferries publishes its times
+ * and service dates are counted. Falls back to UTC without a time zone database.
+ *
+ * @return *time.Location
+ */
+func Pacific() *time.Location {
+	return pacific()
+}
+
+var pacific = sync.OnceValue(func() *time.Location {
+	location, err := time.LoadLocation("America/Vancouver")
+	if err != nil {
+		return time.UTC
+	}
+	return location
+})
+
+/*
+ * ServiceTime
+ *
+ * Converts minutes after midnight of a service date (Pacific Time) to a time
+ *
+ * @param string date - YYYY-MM-DD
+ * @param int minutes - past 1440 for the next morning
+ *
+ * @return time.Time
+ * @return bool - false if the date cannot be parsed
+ */
+func ServiceTime(date string, minutes int) (time.Time, bool) {
+	location := Pacific()
+	day, err := time.ParseInLocation("2006-01-02", date, location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, location), true
+}
+
+/*
+ * WithCapacityTimes
+ *
+ * Fills in the typed times of a capacity route's sailings from their "7:10 am"
+ * strings: timetabled and actual departure and the arrival as timestamps in
+ * America/Vancouver, the arrival status and the duration in minutes. Sailings after
+ * midnight are the next day (see CapacitySchedule). The strings are left as they are.
+ *
+ * @param CapacityRoute route
+ *
+ * @return CapacityRoute - a copy; sailings whose times cannot be parsed get no typed times
+ */
+func WithCapacityTimes(route CapacityRoute) CapacityRoute {
+	durationMin := ParseDurationMin(route.SailingDuration)
+	route.SailingDurationMinutes = durationMin
+
+	sailings := make([]CapacitySailing, len(route.Sailings))
+	copy(sailings, route.Sailings)
+	route.Sailings = sailings
+
+	for _, s := range CapacitySchedule(route) {
+		sailing := &sailings[s.Index]
+
+		scheduledAt, ok := ServiceTime(route.Date, s.Departure)
+		if !ok {
+			continue
+		}
+		departure := s.Departure
+		if actual, ok := actualMinutes(s.Departure, sailing.ActualDepartureTime); ok {
+			departure = actual
+		}
+		departureAt, _ := ServiceTime(route.Date, departure)
+		sailing.ScheduledDepartureAt = &scheduledAt
+		sailing.DepartureAt = &departureAt
+
+		sailing.ArrivalAt = nil
+		sailing.ArrivalStatus = ArrivalUnknown
+		switch sailing.SailingStatus {
+		case "past":
+			if arrival, ok := clockAfter(route.Date, departure, sailing.ActualArrivalTime); ok {
+				sailing.ArrivalAt, sailing.ArrivalStatus = &arrival, ArrivalActual
+			}
+		case "current":
+			if arrival, ok := clockAfter(route.Date, departure, sailing.ETA); ok {
+				sailing.ArrivalAt, sailing.ArrivalStatus = &arrival, ArrivalEstimated
+			} else if isVariable(sailing.ETA) || isVariable(sailing.ArrivalTime) {
+				sailing.ArrivalStatus = ArrivalVariable
+			}
+		default:
+			if s.Arrival > 0 {
+				arrival, _ := ServiceTime(route.Date, s.Arrival)
+				sailing.ArrivalAt, sailing.ArrivalStatus = &arrival, ArrivalScheduled
+			}
+		}
+
+		sailing.DurationMinutes = durationMin
+		if sailing.ArrivalAt != nil {
+			sailing.DurationMinutes = int(sailing.ArrivalAt.Sub(departureAt).Minutes())
+		}
+	}
+	return route
+}
+
+/*
+ * WithNonCapacityTimes
+ *
+ * Fills in the typed times of a non-capacity route's sailings: departure and arrival
+ * as timestamps in America/Vancouver, the arrival status and the duration in minutes.
+ * Sailings are listed in departure order, so one that departs earlier than the
+ * sailing before it is after midnight. The strings are left as they are.
+ *
+ * @param NonCapacityRoute route
+ *
+ * @return NonCapacityRoute - a copy; sailings whose departure cannot be parsed get no typed times
+ */
+func WithNonCapacityTimes(route NonCapacityRoute) NonCapacityRoute {
+	route.SailingDurationMinutes = ParseDurationMin(route.SailingDuration)
+
+	sailings := make([]NonCapacitySailing, len(route.Sailings))
+	copy(sailings, route.Sailings)
+	route.Sailings = sailings
+
+	previous := 0
+	for i := range sailings {
+		sailing := &sailings[i]
+
+		dep, ok := ParseClock(sailing.DepartureTime)
+		if !ok {
+			continue
+		}
+		for dep < previous {
+			dep += 1440
+		}
+		previous = dep
+
+		departureAt, ok := ServiceTime(route.Date, dep)
+		if !ok {
+			continue
+		}
+		sailing.DepartureAt = &departureAt
+		sailing.ArrivalAt = nil
+		sailing.ArrivalStatus = ArrivalUnknown
+		sailing.DurationMinutes = ParseDurationMin(sailing.SailingDuration)
+
+		if listed, arr, ok := SailingMinutes(sailing.DepartureTime, sailing.ArrivalTime, sailing.SailingDuration); ok {
+			arrivalAt, _ := ServiceTime(route.Date, dep+arr-listed)
+			sailing.ArrivalAt, sailing.ArrivalStatus = &arrivalAt, ArrivalScheduled
+			sailing.DurationMinutes = int(arrivalAt.Sub(departureAt).Minutes())
+		} else if isVariable(sailing.ArrivalTime) {
+			sailing.ArrivalStatus = ArrivalVariable
+		}
+	}
+	return route
+}
+
+// actualMinutes places an actual departure clock time within 12 hours of the timetabled one
+func actualMinutes(scheduled int, clock string) (int, bool) {
+	actual, ok := ParseClock(clock)
+	if !ok {
+		return 0, false
+	}
+	return scheduled + ((actual-scheduled)%1440+1440+720)%1440 - 720, true
+}
+
+func isVariable(s string) bool {
+	return strings.EqualFold(strings.TrimSpace(s), "variable")
+}
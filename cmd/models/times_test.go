@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithCapacityTimes(t *testing.T) {
+	route := WithCapacityTimes(CapacityRoute{
+		Date:            "2025-10-20",
+		SailingDuration: "1h 35m",
+		Sailings: []CapacitySailing{
+			{ID: "a", ScheduledTime: "7:00 am", ActualDepartureTime: "7:04 am", ActualArrivalTime: "8:37 am", SailingStatus: "past"},
+			{ID: "b", ScheduledTime: "9:00 am", ActualDepartureTime: "9:12 am", ETA: "Variable", ArrivalTime: "Variable", SailingStatus: "current"},
+			{ID: "c", ScheduledTime: "10:00 am", ActualDepartureTime: "10:01 am", ArrivalTime: "...", SailingStatus: "current"},
+			{ID: "d", ScheduledTime: "11:55 pm", SailingStatus: "future"},
+			{ID: "e", ScheduledTime: "12:15 am", SailingStatus: "future"},
+		},
+	})
+
+	if route.SailingDurationMinutes != 95 {
+		t.Errorf("sailingDurationMinutes = %d, want 95", route.SailingDurationMinutes)
+	}
+
+	// 7:00 am PDT
+	past := route.Sailings[0]
+	if !past.ScheduledDepartureAt.Equal(time.Date(2025, 10, 20, 14, 0, 0, 0, time.UTC)) || !past.DepartureAt.Equal(time.Date(2025, 10, 20, 14, 4, 0, 0, time.UTC)) {
+		t.Errorf("past departure = %v, %v", past.ScheduledDepartureAt, past.DepartureAt)
+	}
+	if past.ArrivalStatus != ArrivalActual || !past.ArrivalAt.Equal(time.Date(2025, 10, 20, 15, 37, 0, 0, time.UTC)) || past.DurationMinutes != 93 {
+		t.Errorf("past arrival = %v, %s, %d min", past.ArrivalAt, past.ArrivalStatus, past.DurationMinutes)
+	}
+
+	if s := route.Sailings[1]; s.ArrivalStatus != ArrivalVariable || s.ArrivalAt != nil {
+		t.Errorf("variable ETA = %v, %s", s.ArrivalAt, s.ArrivalStatus)
+	}
+	if s := route.Sailings[2]; s.ArrivalStatus != ArrivalUnknown || s.ArrivalAt != nil || s.DurationMinutes != 95 {
+		t.Errorf("unknown ETA = %v, %s, %d min", s.ArrivalAt, s.ArrivalStatus, s.DurationMinutes)
+	}
+
+	// Arrives after midnight
+	if s := route.Sailings[3]; s.ArrivalStatus != ArrivalScheduled || !s.ArrivalAt.Equal(time.Date(2025, 10, 21, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("11:55 pm arrival = %v, %s", s.ArrivalAt, s.ArrivalStatus)
+	}
+	// Listed after the 11:55 pm, so it leaves the next morning
+	if s := route.Sailings[4]; !s.DepartureAt.Equal(time.Date(2025, 10, 21, 7, 15, 0, 0, time.UTC)) {
+		t.Errorf("12:15 am departure = %v", s.DepartureAt)
+	}
+
+	// The strings are kept
+	if past.ScheduledTime != "7:00 am" || past.ActualArrivalTime != "8:37 am" {
+		t.Errorf("legacy times changed: %+v", past)
+	}
+}
+
+func TestWithNonCapacityTimes(t *testing.T) {
+	route := WithNonCapacityTimes(NonCapacityRoute{
+		Date:            "2025-10-20",
+		SailingDuration: "1h 20m",
+		Sailings: []NonCapacitySailing{
+			{DepartureTime: "7:10 am", ArrivalTime: "9:25 am", SailingDuration: "2h 15m"},
+			{DepartureTime: "11:40 pm", ArrivalTime: "1:00 am", SailingDuration: "1h 20m"},
+			{DepartureTime: "12:15 am", ArrivalTime: "Variable"},
+			{DepartureTime: "..."},
+		},
+	})
+
+	if route.SailingDurationMinutes != 80 {
+		t.Errorf("sailingDurationMinutes = %d, want 80", route.SailingDurationMinutes)
+	}
+
+	first := route.Sailings[0]
+	if !first.DepartureAt.Equal(time.Date(2025, 10, 20, 14, 10, 0, 0, time.UTC)) || !first.ArrivalAt.Equal(time.Date(2025, 10, 20, 16, 25, 0, 0, time.UTC)) {
+		t.Errorf("7:10 am sailing = %v - %v", first.DepartureAt, first.ArrivalAt)
+	}
+	if first.ArrivalStatus != ArrivalScheduled || first.DurationMinutes != 135 {
+		t.Errorf("7:10 am sailing = %s, %d min", first.ArrivalStatus, first.DurationMinutes)
+	}
+
+	if late := route.Sailings[1]; !late.ArrivalAt.Equal(time.Date(2025, 10, 21, 8, 0, 0, 0, time.UTC)) || late.DurationMinutes != 80 {
+		t.Errorf("11:40 pm sailing arrives %v after %d min", late.ArrivalAt, late.DurationMinutes)
+	}
+
+	variable := route.Sailings[2]
+	if !variable.DepartureAt.Equal(time.Date(2025, 10, 21, 7, 15, 0, 0, time.UTC)) || variable.ArrivalStatus != ArrivalVariable || variable.ArrivalAt != nil {
+		t.Errorf("12:15 am sailing = %v, %v, %s", variable.DepartureAt, variable.ArrivalAt, variable.ArrivalStatus)
+	}
+
+	if s := route.Sailings[3]; s.DepartureAt != nil || s.ArrivalStatus != "" {
+		t.Errorf("unparseable sailing got times: %+v", s)
+	}
+}
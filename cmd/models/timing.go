@@ -0,0 +1,278 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ * StopTime
+ *
+ * When a sailing is at one of the terminals it calls at, in minutes after midnight of
+ * the service date (past 1440 after midnight)
+ */
+type StopTime struct {
+	TerminalCode string
+	Arrival      int
+	Departure    int
+	EventType    string // Event at an intermediate terminal ("stop", "transfer" or "thruFare"), empty at the ends
+	Estimated    bool   // Derived from average leg durations rather than the timetable
+}
+
+/*
+ * EstimateStopTimes
+ *
+ * Works out when a sailing is at each terminal. The timetable only gives the first
+ * departure and the last arrival; times in between follow the legs' average durations
+ * and the sailing's average dwell per stop (or the spare time spread over the stops
+ * and transfers when that is unknown). Durations that add up to more than the
+ * timetable allows are scaled down. Sailings whose legs are not all known only get
+ * their two ends.
+ *
+ * @param string fromTerminalCode - route origin
+ * @param string toTerminalCode - route destination
+ * @param NonCapacitySailing sailing
+ *
+ * @return []StopTime - one per terminal, in order
+ * @return bool - false if the sailing's times cannot be parsed
+ */
+func EstimateStopTimes(fromTerminalCode, toTerminalCode string, sailing NonCapacitySailing) ([]StopTime, bool) {
+	dep, arr, ok := SailingMinutes(sailing.DepartureTime, sailing.ArrivalTime, sailing.SailingDuration)
+	if !ok {
+		return nil, false
+	}
+
+	legs := sailing.Legs
+	known := len(legs) > 1 && len(legs) == len(sailing.Events)+1
+	travelMin := 0
+	for _, leg := range legs {
+		if leg.AvgDurationMin == nil || leg.OriginTerminal.Code == "UNKNOWN" || leg.DestinationTerminal.Code == "UNKNOWN" {
+			known = false
+			break
+		}
+		travelMin += *leg.AvgDurationMin
+	}
+
+	if !known {
+		return []StopTime{
+			{TerminalCode: fromTerminalCode, Arrival: dep, Departure: dep},
+			{TerminalCode: toTerminalCode, Arrival: arr, Departure: arr},
+		}, true
+	}
+
+	totalMin := arr - dep
+	dwellMin := 0
+	if sailing.AvgDwellPerStopMin != nil {
+		dwellMin = *sailing.AvgDwellPerStopMin
+	} else {
+		calls := 0
+		for _, event := range sailing.Events {
+			if event.Type == "stop" || event.Type == "transfer" {
+				calls++
+			}
+		}
+		if slack := totalMin - travelMin; calls > 0 && slack > 0 {
+			dwellMin = slack / calls
+		}
+	}
+
+	stopTimes := []StopTime{{TerminalCode: legs[0].OriginTerminal.Code, Arrival: dep, Departure: dep}}
+	current := dep
+	for i, leg := range legs {
+		legMin := *leg.AvgDurationMin
+		if travelMin > totalMin {
+			legMin = legMin * totalMin / travelMin
+		}
+		current += legMin
+
+		if i == len(legs)-1 {
+			stopTimes = append(stopTimes, StopTime{TerminalCode: leg.DestinationTerminal.Code, Arrival: arr, Departure: arr})
+			break
+		}
+
+		event := sailing.Events[i]
+		call := StopTime{TerminalCode: leg.DestinationTerminal.Code, Arrival: current, EventType: event.Type, Estimated: true}
+		if event.Type == "stop" || event.Type == "transfer" {
+			current += dwellMin
+		}
+		// Never leave an intermediate terminal after the sailing is due at its destination
+		current = min(current, arr)
+		call.Arrival = min(call.Arrival, arr)
+		call.Departure = current
+		stopTimes = append(stopTimes, call)
+	}
+
+	return stopTimes, true
+}
+
+/*
+ * ScheduledSailing
+ *
+ * A capacity sailing with its timetabled times in minutes after midnight of the
+ * service date
+ */
+type ScheduledSailing struct {
+	Sailing   CapacitySailing
+	Index     int // Position in the route's sailings
+	Departure int
+	Arrival   int // 0 when the route has no sailing duration
+}
+
+/*
+ * CapacitySchedule
+ *
+ * Works out the timetabled times of a capacity route's sailings. Times come from
+ * ScheduledTime (DepartureTime for data scraped before it existed) plus the route's
+ * sailing duration. Sailings are listed in departure order, so one that departs
+ * earlier than the sailing before it (e.g. "12:15 am (Tomorrow)") is after midnight.
+ *
+ * @param CapacityRoute route
+ *
+ * @return []ScheduledSailing - sailings whose times cannot be parsed are left out
+ */
+func CapacitySchedule(route CapacityRoute) []ScheduledSailing {
+	durationMin := ParseDurationMin(route.SailingDuration)
+
+	var scheduled []ScheduledSailing
+	previous := 0
+	for i, sailing := range route.Sailings {
+		clock := sailing.ScheduledTime
+		if clock == "" {
+			clock = sailing.DepartureTime
+		}
+		departure, ok := ParseClock(clock)
+		if !ok {
+			continue
+		}
+		for departure < previous {
+			departure += 1440
+		}
+		previous = departure
+
+		s := ScheduledSailing{Sailing: sailing, Index: i, Departure: departure}
+		if durationMin > 0 {
+			s.Arrival = departure + durationMin
+		}
+		scheduled = append(scheduled, s)
+	}
+	return scheduled
+}
+
+/*
+ * SailingMinutes
+ *
+ * Returns a sailing's departure and arrival in minutes after midnight. When the arrival
+ * is missing (capacity pages show "..." for some sailings) it is derived from the
+ * duration. Arrivals after midnight are moved to the next day.
+ *
+ * @param string departure - e.g. "7:10 am"
+ * @param string arrival - e.g. "8:30 am"
+ * @param string duration - e.g. "1h 20m"
+ *
+ * @return int - departure
+ * @return int - arrival
+ * @return bool - false if the times cannot be worked out
+ */
+func SailingMinutes(departure, arrival, duration string) (int, int, bool) {
+	dep, ok := ParseClock(departure)
+	if !ok {
+		return 0, 0, false
+	}
+
+	arr, ok := ParseClock(arrival)
+	if !ok {
+		durationMin := ParseDurationMin(duration)
+		if durationMin <= 0 {
+			return 0, 0, false
+		}
+		arr = dep + durationMin
+	}
+	if arr < dep {
+		arr += 1440
+	}
+
+	return dep, arr, true
+}
+
+/*
+ * ParseClock
+ *
+ * Parses a time of day such as "7:10 am", "07:10 AM" or "19:10". Times with a
+ * "(Tomorrow)" suffix, as shown for sailings after midnight, are the next day.
+ *
+ * @param string s
+ *
+ * @return int - minutes after midnight, past 1440 for "(Tomorrow)"
+ * @return bool - false if s is not a time of day
+ */
+func ParseClock(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	day := 0
+	if trimmed := strings.TrimSuffix(s, "(Tomorrow)"); trimmed != s {
+		s = strings.TrimSpace(trimmed)
+		day = 1440
+	}
+
+	layouts := []string{"3:04 pm", "3:04 PM", "3:04pm", "3:04PM", "15:04"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return day + t.Hour()*60 + t.Minute(), true
+		}
+	}
+	return 0, false
+}
+
+/*
+ * FormatClock
+ *
+ * Formats minutes after midnight like the scraped times, e.g. "7:10 am". Times past
+ * midnight wrap around.
+ *
+ * @param int minutes
+ *
+ * @return string
+ */
+func FormatClock(minutes int) string {
+	minutes = ((minutes % 1440) + 1440) % 1440
+	t := time.Date(2000, 1, 1, minutes/60, minutes%60, 0, 0, time.UTC)
+	return strings.ToLower(t.Format("3:04 pm"))
+}
+
+// "1h 20m" and "35m"; tolerates "1h20m"
+var (
+	durationHoursRe   = regexp.MustCompile(`(\d+)\s*h`)
+	durationMinutesRe = regexp.MustCompile(`(\d+)\s*m`)
+)
+
+/*
+ * ParseDurationMin
+ *
+ * Parses a sailing duration such as "1h 20m", "35m", "2h" or "01:40".
+ *
+ * @param string duration
+ *
+ * @return int - minutes, 0 if there is no duration
+ */
+func ParseDurationMin(duration string) int {
+	total := 0
+	if m := durationHoursRe.FindStringSubmatch(duration); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		total += hours * 60
+	}
+	if m := durationMinutesRe.FindStringSubmatch(duration); m != nil {
+		minutes, _ := strconv.Atoi(m[1])
+		total += minutes
+	}
+
+	// "01:40"
+	if hours, minutes, ok := strings.Cut(strings.TrimSpace(duration), ":"); total == 0 && ok {
+		h, errH := strconv.Atoi(hours)
+		m, errM := strconv.Atoi(minutes)
+		if errH == nil && errM == nil {
+			total = h*60 + m
+		}
+	}
+	return total
+}
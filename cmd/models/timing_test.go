@@ -0,0 +1,137 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	for _, s := range []string{"7:05 am", "7:05 AM", "07:05", "7:05am"} {
+		if minutes, ok := ParseClock(s); !ok || minutes != 7*60+5 {
+			t.Errorf("ParseClock(%q) = %d, %v", s, minutes, ok)
+		}
+	}
+	if _, ok := ParseClock("soon"); ok {
+		t.Error("ParseClock(soon) succeeded")
+	}
+	if got := FormatClock(24*60 + 30); got != "12:30 am" {
+		t.Errorf("FormatClock = %q, want 12:30 am", got)
+	}
+	if minutes, ok := ParseClock("12:15 am (Tomorrow)"); !ok || minutes != 1440+15 {
+		t.Errorf("ParseClock(12:15 am (Tomorrow)) = %d, %v", minutes, ok)
+	}
+
+	durations := map[string]int{
+		"1h 35m": 95,
+		"2h 05m": 125,
+		"1h35m":  95,
+		"45m":    45,
+		"2h":     120,
+		"01:40":  100,
+		"":       0,
+	}
+	for in, want := range durations {
+		if got := ParseDurationMin(in); got != want {
+			t.Errorf("ParseDurationMin(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestEstimateStopTimes(t *testing.T) {
+	events := []SailingEvent{
+		{Type: "stop", TerminalName: "Galiano Island (Sturdies Bay)"},
+		{Type: "stop", TerminalName: "Mayne Island (Village Bay)"},
+	}
+	dwell := 10
+	sailing := NonCapacitySailing{
+		DepartureTime:      "7:10 am",
+		ArrivalTime:        "9:25 am",
+		Events:             events,
+		Legs:               BuildLegs("TSAPOB", events, "7:10 am", nil, dwell),
+		AvgDwellPerStopMin: &dwell,
+	}
+
+	stopTimes, ok := EstimateStopTimes("TSA", "POB", sailing)
+	if !ok || len(stopTimes) != 4 {
+		t.Fatalf("EstimateStopTimes = %+v, %v, want 4 stop times", stopTimes, ok)
+	}
+
+	// TSA-PSB 55m, PSB-PVB 30m, PVB-POB 30m (pinned to the timetabled arrival)
+	want := []StopTime{
+		{TerminalCode: "TSA", Arrival: 7*60 + 10, Departure: 7*60 + 10},
+		{TerminalCode: "PSB", Arrival: 8*60 + 5, Departure: 8*60 + 15, EventType: "stop", Estimated: true},
+		{TerminalCode: "PVB", Arrival: 8*60 + 45, Departure: 8*60 + 55, EventType: "stop", Estimated: true},
+		{TerminalCode: "POB", Arrival: 9*60 + 25, Departure: 9*60 + 25},
+	}
+	for i := range want {
+		if stopTimes[i] != want[i] {
+			t.Errorf("stop %d = %+v, want %+v", i, stopTimes[i], want[i])
+		}
+	}
+
+	// Without usable legs only the ends are known
+	sailing.Legs = nil
+	if stopTimes, ok := EstimateStopTimes("TSA", "POB", sailing); !ok || len(stopTimes) != 2 || stopTimes[1].Arrival != 9*60+25 {
+		t.Errorf("EstimateStopTimes without legs = %+v, %v", stopTimes, ok)
+	}
+}
+
+func TestCapacitySchedule(t *testing.T) {
+	route := CapacityRoute{
+		SailingDuration: "1h 35m",
+		Sailings: []CapacitySailing{
+			{DepartureTime: "7:04 am", ScheduledTime: "7:00 am"},
+			{DepartureTime: "1:00 pm"},
+			{DepartureTime: "12:15 am"},
+		},
+	}
+
+	// The actual departure does not move the timetable; 12:15 am after 1:00 pm is the next morning's
+	want := [][2]int{{7 * 60, 8*60 + 35}, {13 * 60, 14*60 + 35}, {24*60 + 15, 25*60 + 50}}
+	scheduled := CapacitySchedule(route)
+	if len(scheduled) != len(want) {
+		t.Fatalf("CapacitySchedule = %+v", scheduled)
+	}
+	for i, s := range scheduled {
+		if s.Departure != want[i][0] || s.Arrival != want[i][1] {
+			t.Errorf("sailing %d = %d-%d, want %d-%d", i, s.Departure, s.Arrival, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestCapacityDepartures(t *testing.T) {
+	route := CapacityRoute{
+		Date:            "2025-10-20",
+		RouteCode:       "TSASWB",
+		SailingDuration: "1h 35m",
+		Sailings: []CapacitySailing{
+			{ID: "TSASWB-2025-10-20-0700", ScheduledTime: "7:00 am", ActualDepartureTime: "7:04 am", ActualArrivalTime: "8:37 am", VesselName: "Spirit of Vancouver Island"},
+			{ID: "TSASWB-2025-10-20-0900", ScheduledTime: "9:00 am", ActualDepartureTime: "8:58 am", ETA: "10:33 am"},
+			{ID: "TSASWB-2025-10-20-2345", ScheduledTime: "11:45 pm", ActualDepartureTime: "12:20 am", ETA: "Variable"},
+			{ID: "TSASWB-2025-10-21-0100", ScheduledTime: "1:00 am"},
+		},
+	}
+
+	departures := CapacityDepartures(route)
+	if len(departures) != 3 {
+		t.Fatalf("CapacityDepartures = %+v, want the 3 departed sailings", departures)
+	}
+
+	first := departures[0]
+	if first.DelayMinutes != 4 || !first.ScheduledDeparture.Equal(time.Date(2025, 10, 20, 14, 0, 0, 0, time.UTC)) || !first.ActualDeparture.Equal(time.Date(2025, 10, 20, 14, 4, 0, 0, time.UTC)) {
+		t.Errorf("7:00 am departure = %+v", first)
+	}
+	if first.ActualArrival == nil || !first.ActualArrival.Equal(time.Date(2025, 10, 20, 15, 37, 0, 0, time.UTC)) || first.ETA != nil {
+		t.Errorf("7:00 am arrival = %v, ETA = %v", first.ActualArrival, first.ETA)
+	}
+
+	if early := departures[1]; early.DelayMinutes != -2 || early.ETA == nil || !early.ETA.Equal(time.Date(2025, 10, 20, 17, 33, 0, 0, time.UTC)) {
+		t.Errorf("9:00 am departure = %+v", early)
+	}
+
+	// Left after midnight, 35 minutes late
+	late := departures[2]
+	if late.DelayMinutes != 35 || !late.ActualDeparture.Equal(time.Date(2025, 10, 21, 7, 20, 0, 0, time.UTC)) || late.ETA != nil {
+		t.Errorf("11:45 pm departure = %+v", late)
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+/*
+ * WebhookSubscription
+ *
+ * A URL to POST change events to. Empty filters match everything; a fill-changed event
+ * only matches a threshold when a sailing's fill crosses it, e.g. from 88% to 92% for
+ * carFillAbove 90.
+ */
+type WebhookSubscription struct {
+	ID            int64     `json:"id"`
+	URL           string    `json:"url"`
+	Secret        string    `json:"secret,omitempty"` // HMAC key for X-Webhook-Signature, only returned on creation
+	EventTypes    []string  `json:"eventTypes"`       // e.g. ["cancelled"]
+	RouteCodes    []string  `json:"routeCodes"`       // e.g. ["TSASWB"]
+	TerminalCodes []string  `json:"terminalCodes"`    // Routes from or to these terminals, e.g. ["SGI"]
+	FillAbove     *int      `json:"fillAbove"`        // Percent full, 0-100
+	CarFillAbove  *int      `json:"carFillAbove"`     // Percent of car space full, 0-100
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+/*
+ * WebhookDelivery
+ *
+ * One attempt at delivering an event to a subscription
+ */
+type WebhookDelivery struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscriptionId"`
+	EventID        int64     `json:"eventId"`
+	EventType      string    `json:"eventType"`
+	Attempt        int       `json:"attempt"`    // 1 for the first attempt
+	StatusCode     int       `json:"statusCode"` // 0 if no response was received
+	Success        bool      `json:"success"`
+	Error          string    `json:"error,omitempty"`
+	DurationMs     int64     `json:"durationMs"`
+	DeliveredAt    time.Time `json:"deliveredAt"`
+}
+
+/*
+ * WebhookPayload
+ *
+ * The body POSTed to a subscription. Event only holds the changes that matched it.
+ */
+type WebhookPayload struct {
+	SubscriptionID int64       `json:"subscriptionId"`
+	Event          ChangeEvent `json:"event"`
+}
@@ -0,0 +1,487 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+/*
+ * Query
+ *
+ * What to plan: a trip between two terminals on one service date
+ */
+type Query struct {
+	From         string // Terminal code, e.g. "SWB"
+	To           string // Terminal code, e.g. "PST"
+	DepartAfter  int    // Minutes after midnight of the service date
+	Mode         string // models.PlanModeWalkOn or models.PlanModeVehicle
+	Sort         string // models.PlanSortArrival or models.PlanSortTransfers
+	MaxTransfers int    // Vessel changes allowed per itinerary
+	Limit        int    // Itineraries to return
+}
+
+/*
+ * hop
+ *
+ * An edge of the time-expanded graph: a vessel sailing between two consecutive
+ * terminals at a given time. Sailings that call at several terminals become a
+ * chain of hops.
+ */
+type hop struct {
+	from      string
+	to        string
+	dep       int // Minutes after midnight of the service date
+	arr       int
+	sailingID string
+	routeCode string
+	vessel    string
+	estimated bool
+	stay      []int // Next hops on the same vessel ("stop" events)
+	transfer  []int // Next hops the timetable connects to on another vessel ("transfer" and "thruFare" events)
+}
+
+/*
+ * Graph
+ *
+ * The day's sailings as a time-expanded graph. Hops that appear in several scraped
+ * routes (e.g. TSA-PSB on both TSAPSB and TSAPOB) are merged.
+ */
+type Graph struct {
+	hops  []hop
+	index map[string]int // "from-to-dep-arr" -> index in hops
+	order []int          // hops sorted by departure, then arrival
+}
+
+/*
+ * NewGraph
+ *
+ * Builds the graph for one service date. Cancelled sailings are left out, and so are
+ * sailings whose car deck is full when planning for a vehicle.
+ *
+ * @param []models.NonCapacityRoute nonCapacity - the date's non-capacity routes
+ * @param []models.CapacityRoute capacity - the date's capacity routes
+ * @param string mode - models.PlanModeWalkOn or models.PlanModeVehicle
+ *
+ * @return *Graph
+ */
+func NewGraph(nonCapacity []models.NonCapacityRoute, capacity []models.CapacityRoute, mode string) *Graph {
+	g := &Graph{index: make(map[string]int)}
+
+	for _, route := range nonCapacity {
+		for _, sailing := range route.Sailings {
+			g.addNonCapacitySailing(route, sailing)
+		}
+	}
+
+	for _, route := range capacity {
+		for _, sailing := range route.Sailings {
+			if sailing.SailingStatus == "cancelled" {
+				continue
+			}
+			if mode == models.PlanModeVehicle && sailing.CarFill >= 100 {
+				continue
+			}
+
+			dep, arr, ok := models.SailingMinutes(sailing.DepartureTime, sailing.ArrivalTime, route.SailingDuration)
+			if !ok {
+				continue
+			}
+			g.addHop(hop{
+				from:      route.FromTerminalCode,
+				to:        route.ToTerminalCode,
+				dep:       dep,
+				arr:       arr,
+				sailingID: sailing.ID,
+				routeCode: route.RouteCode,
+				vessel:    sailing.VesselName,
+			})
+		}
+	}
+
+	g.order = make([]int, len(g.hops))
+	for i := range g.order {
+		g.order[i] = i
+	}
+	sort.SliceStable(g.order, func(i, j int) bool {
+		a, b := g.hops[g.order[i]], g.hops[g.order[j]]
+		if a.dep != b.dep {
+			return a.dep < b.dep
+		}
+		return a.arr < b.arr
+	})
+
+	return g
+}
+
+/*
+ * addNonCapacitySailing
+ *
+ * Adds a sailing as one hop per leg, timed by models.EstimateStopTimes. Sailings whose
+ * legs are not all known become a single hop from the route's origin to its destination.
+ *
+ * @param models.NonCapacityRoute route
+ * @param models.NonCapacitySailing sailing
+ *
+ * @return void
+ */
+func (g *Graph) addNonCapacitySailing(route models.NonCapacityRoute, sailing models.NonCapacitySailing) {
+	stopTimes, ok := models.EstimateStopTimes(route.FromTerminalCode, route.ToTerminalCode, sailing)
+	if !ok {
+		return
+	}
+
+	previous := -1
+	for i := 0; i < len(stopTimes)-1; i++ {
+		from, to := stopTimes[i], stopTimes[i+1]
+
+		vessel := ""
+		if len(sailing.Legs) == len(stopTimes)-1 {
+			vessel = legVessel(sailing.Legs[i])
+		}
+
+		current := g.addHop(hop{
+			from:      from.TerminalCode,
+			to:        to.TerminalCode,
+			dep:       from.Departure,
+			arr:       to.Arrival,
+			sailingID: sailing.ID,
+			routeCode: route.RouteCode,
+			vessel:    vessel,
+			estimated: from.Estimated || to.Estimated,
+		})
+
+		if previous >= 0 {
+			if from.EventType == "stop" {
+				g.hops[previous].stay = appendUnique(g.hops[previous].stay, current)
+			} else {
+				g.hops[previous].transfer = appendUnique(g.hops[previous].transfer, current)
+			}
+		}
+		previous = current
+	}
+}
+
+/*
+ * addHop
+ *
+ * Adds a hop unless the same sailing between the same terminals is already in the
+ * graph. A hop with timetable times replaces the vessel and sailing of an estimated one.
+ *
+ * @param hop h
+ *
+ * @return int - index of the hop
+ */
+func (g *Graph) addHop(h hop) int {
+	key := fmt.Sprintf("%s-%s-%d-%d", h.from, h.to, h.dep, h.arr)
+
+	if i, exists := g.index[key]; exists {
+		existing := &g.hops[i]
+		if existing.estimated && !h.estimated {
+			existing.sailingID, existing.routeCode, existing.estimated = h.sailingID, h.routeCode, false
+		}
+		if existing.vessel == "" {
+			existing.vessel = h.vessel
+		}
+		return i
+	}
+
+	g.hops = append(g.hops, h)
+	g.index[key] = len(g.hops) - 1
+	return len(g.hops) - 1
+}
+
+/*
+ * Plan
+ *
+ * Finds itineraries from q.From to q.To leaving at or after q.DepartAfter. Each search
+ * returns the itineraries that are best for some number of transfers (fewer transfers or
+ * an earlier arrival); the search is repeated from just after the earliest departure
+ * found until q.Limit departures have been tried. Itineraries that leave earlier but
+ * arrive no sooner, with no fewer transfers, than another are dropped.
+ *
+ * @param *Graph g
+ * @param Query q
+ *
+ * @return []models.Itinerary - ordered by q.Sort, at most q.Limit
+ */
+func Plan(g *Graph, q Query) []models.Itinerary {
+	var candidates []candidate
+	seen := make(map[string]bool)
+
+	departAfter := q.DepartAfter
+	for i := 0; i < q.Limit; i++ {
+		found := g.search(q, departAfter)
+		if len(found) == 0 {
+			break
+		}
+
+		earliest := math.MaxInt
+		for _, c := range found {
+			earliest = min(earliest, c.dep)
+			if !seen[c.key] {
+				seen[c.key] = true
+				candidates = append(candidates, c)
+			}
+		}
+		departAfter = earliest + 1
+	}
+
+	var kept []candidate
+	for _, c := range candidates {
+		dominated := false
+		for _, other := range candidates {
+			if other.dominates(c) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			kept = append(kept, c)
+		}
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		a, b := kept[i], kept[j]
+		if q.Sort == models.PlanSortTransfers && a.transfers != b.transfers {
+			return a.transfers < b.transfers
+		}
+		if a.arr != b.arr {
+			return a.arr < b.arr
+		}
+		if a.transfers != b.transfers {
+			return a.transfers < b.transfers
+		}
+		return a.dep > b.dep
+	})
+
+	itineraries := []models.Itinerary{}
+	for i := 0; i < len(kept) && i < q.Limit; i++ {
+		itineraries = append(itineraries, kept[i].itinerary)
+	}
+	return itineraries
+}
+
+/*
+ * candidate
+ *
+ * An itinerary with the numbers it is ranked by
+ */
+type candidate struct {
+	dep       int
+	arr       int
+	transfers int
+	key       string // Hops taken, to spot the same itinerary found twice
+	itinerary models.Itinerary
+}
+
+func (c candidate) dominates(other candidate) bool {
+	if c.dep < other.dep || c.arr > other.arr || c.transfers > other.transfers {
+		return false
+	}
+	return c.dep > other.dep || c.arr < other.arr || c.transfers < other.transfers
+}
+
+// label is the earliest known arrival at a terminal
+type label struct {
+	arr   int
+	hop   int // Last hop taken, -1 at the origin
+	round int // Round the label was set in
+}
+
+// step records how a hop was reached in a round
+type step struct {
+	prev      int  // Previous hop, -1 at the origin
+	prevRound int  // Round prev was taken in
+	boarded   bool // A vessel was boarded for this hop
+}
+
+/*
+ * search
+ *
+ * Round-based scan of the graph: round k finds the earliest arrival at every terminal
+ * using at most k vessels. A vessel can be boarded from a terminal reached in the
+ * previous round once the terminal's minimum connection time has passed (none at the
+ * origin), or through a connection the timetable schedules within a sailing. Staying
+ * aboard through a "stop" does not start a new round.
+ *
+ * @param Query q
+ * @param int departAfter - minutes after midnight
+ *
+ * @return []candidate - for each number of transfers, the itinerary arriving earliest,
+ *         if it arrives before every itinerary with fewer transfers
+ */
+func (g *Graph) search(q Query, departAfter int) []candidate {
+	rounds := q.MaxTransfers + 1
+	vehicle := q.Mode == models.PlanModeVehicle
+
+	labels := make([]map[string]label, rounds+1)
+	labels[0] = map[string]label{q.From: {arr: departAfter, hop: -1}}
+
+	steps := make([][]step, rounds+1)
+	transferIn := make([][]int, rounds+2)
+	for k := range transferIn {
+		transferIn[k] = filled(len(g.hops), -1)
+	}
+
+	var found []candidate
+	bestArr := math.MaxInt
+
+	for k := 1; k <= rounds; k++ {
+		labels[k] = make(map[string]label, len(labels[k-1]))
+		for code, l := range labels[k-1] {
+			labels[k][code] = l
+		}
+		steps[k] = make([]step, len(g.hops))
+		stayIn := filled(len(g.hops), -1)
+
+		for _, i := range g.order {
+			h := &g.hops[i]
+			if h.dep < departAfter || h.from == q.To {
+				continue
+			}
+
+			switch {
+			case stayIn[i] >= 0:
+				steps[k][i] = step{prev: stayIn[i], prevRound: k}
+			case transferIn[k][i] >= 0:
+				steps[k][i] = step{prev: transferIn[k][i], prevRound: k - 1, boarded: true}
+			default:
+				l, ok := labels[k-1][h.from]
+				if !ok {
+					continue
+				}
+				ready := l.arr
+				if l.hop >= 0 {
+					ready += staticdata.GetMinConnectionMin(h.from, vehicle)
+				}
+				if h.dep < ready {
+					continue
+				}
+				steps[k][i] = step{prev: l.hop, prevRound: l.round, boarded: true}
+			}
+
+			for _, next := range h.stay {
+				if stayIn[next] < 0 {
+					stayIn[next] = i
+				}
+			}
+			for _, next := range h.transfer {
+				if transferIn[k+1][next] < 0 {
+					transferIn[k+1][next] = i
+				}
+			}
+
+			if l, ok := labels[k][h.to]; !ok || h.arr < l.arr {
+				labels[k][h.to] = label{arr: h.arr, hop: i, round: k}
+			}
+		}
+
+		if l, ok := labels[k][q.To]; ok && l.round == k && l.arr < bestArr {
+			bestArr = l.arr
+			found = append(found, g.buildItinerary(steps, l.hop, k))
+		}
+	}
+
+	return found
+}
+
+/*
+ * buildItinerary
+ *
+ * Walks the recorded steps back from the last hop, grouping hops taken on the same
+ * vessel into one leg.
+ *
+ * @param [][]step steps
+ * @param int last - last hop
+ * @param int round - round the last hop was taken in
+ *
+ * @return candidate
+ */
+func (g *Graph) buildItinerary(steps [][]step, last, round int) candidate {
+	var path []int
+	var boarded []bool
+	for i, k := last, round; i >= 0; {
+		s := steps[k][i]
+		path = append(path, i)
+		boarded = append(boarded, s.boarded)
+		i, k = s.prev, s.prevRound
+	}
+
+	var legs []models.ItineraryLeg
+	var keys []string
+	previousArr := 0
+	for j := len(path) - 1; j >= 0; j-- {
+		h := g.hops[path[j]]
+		keys = append(keys, strconv.Itoa(path[j]))
+
+		if boarded[j] || len(legs) == 0 {
+			wait := 0
+			if len(legs) > 0 {
+				wait = h.dep - previousArr
+			}
+			legs = append(legs, models.ItineraryLeg{
+				SailingID:        h.sailingID,
+				RouteCode:        h.routeCode,
+				FromTerminalCode: h.from,
+				ToTerminalCode:   h.to,
+				DepartureTime:    models.FormatClock(h.dep),
+				ArrivalTime:      models.FormatClock(h.arr),
+				VesselName:       h.vessel,
+				WaitMin:          wait,
+				Estimated:        h.estimated,
+			})
+		} else {
+			leg := &legs[len(legs)-1]
+			leg.Stops = append(leg.Stops, leg.ToTerminalCode)
+			leg.ToTerminalCode = h.to
+			leg.ArrivalTime = models.FormatClock(h.arr)
+			leg.Estimated = leg.Estimated || h.estimated
+		}
+		previousArr = h.arr
+	}
+
+	first, final := g.hops[path[len(path)-1]], g.hops[last]
+	return candidate{
+		dep:       first.dep,
+		arr:       final.arr,
+		transfers: len(legs) - 1,
+		key:       strings.Join(keys, ","),
+		itinerary: models.Itinerary{
+			DepartureTime: models.FormatClock(first.dep),
+			ArrivalTime:   models.FormatClock(final.arr),
+			DurationMin:   final.arr - first.dep,
+			Transfers:     len(legs) - 1,
+			Legs:          legs,
+		},
+	}
+}
+
+func legVessel(leg models.Leg) string {
+	if leg.VesselName == nil || *leg.VesselName == "UNKNOWN" {
+		return ""
+	}
+	return *leg.VesselName
+}
+
+func appendUnique(s []int, v int) []int {
+	for _, existing := range s {
+		if existing == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
+
+func filled(n, v int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = v
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func sailing(routeCode, id, dep, arr string, events ...models.SailingEvent) models.NonCapacitySailing {
+	return models.NonCapacitySailing{
+		ID:            id,
+		DepartureTime: dep,
+		ArrivalTime:   arr,
+		Events:        events,
+		Legs:          models.BuildLegs(routeCode, events, dep, nil, 0),
+	}
+}
+
+func route(routeCode string, sailings ...models.NonCapacitySailing) models.NonCapacityRoute {
+	return models.NonCapacityRoute{
+		RouteCode:        routeCode,
+		FromTerminalCode: routeCode[:3],
+		ToTerminalCode:   routeCode[3:],
+		Sailings:         sailings,
+	}
+}
+
+// SWB to PST either with a change at Otter Bay, or on one vessel calling at Village Bay
+func testRoutes() []models.NonCapacityRoute {
+	return []models.NonCapacityRoute{
+		route("SWBPOB", sailing("SWBPOB", "SWBPOB-0700", "7:00 am", "7:40 am")),
+		route("POBPST",
+			sailing("POBPST", "POBPST-0755", "7:55 am", "8:35 am"),
+			sailing("POBPST", "POBPST-1000", "10:00 am", "10:40 am")),
+		route("SWBPST", sailing("SWBPST", "SWBPST-0730", "7:30 am", "9:30 am",
+			models.SailingEvent{Type: "stop", TerminalName: "Mayne Island (Village Bay)"})),
+		route("PVBTSA", sailing("PVBTSA", "PVBTSA-0900", "9:00 am", "12:00 pm",
+			models.SailingEvent{Type: "transfer", TerminalName: "Victoria (Swartz Bay)"})),
+	}
+}
+
+func summary(itineraries []models.Itinerary) []string {
+	var s []string
+	for _, itinerary := range itineraries {
+		s = append(s, itinerary.DepartureTime+"-"+itinerary.ArrivalTime)
+	}
+	return s
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  []string // departure-arrival of each itinerary
+	}{
+		{"walk-on earliest arrival", Query{MaxTransfers: 3, From: "SWB", To: "PST", Mode: models.PlanModeWalkOn, Sort: models.PlanSortArrival}, []string{"7:00 am-8:35 am", "7:30 am-9:30 am"}},
+		{"walk-on fewest transfers", Query{MaxTransfers: 3, From: "SWB", To: "PST", Mode: models.PlanModeWalkOn, Sort: models.PlanSortTransfers}, []string{"7:30 am-9:30 am", "7:00 am-8:35 am"}},
+		{"vehicle misses the Otter Bay connection", Query{MaxTransfers: 3, From: "SWB", To: "PST", Mode: models.PlanModeVehicle, Sort: models.PlanSortArrival}, []string{"7:30 am-9:30 am"}},
+		{"no transfers allowed", Query{From: "SWB", To: "PST", Mode: models.PlanModeWalkOn}, []string{"7:30 am-9:30 am"}},
+		{"depart after the first sailings", Query{MaxTransfers: 3, From: "SWB", To: "PST", Mode: models.PlanModeWalkOn, DepartAfter: 7*60 + 45}, nil},
+		{"get off at an intermediate stop", Query{MaxTransfers: 3, From: "SWB", To: "PVB", Mode: models.PlanModeWalkOn}, []string{"7:30 am-8:25 am"}},
+		{"scheduled transfer within a sailing", Query{MaxTransfers: 3, From: "PVB", To: "TSA", Mode: models.PlanModeWalkOn}, []string{"9:00 am-12:00 pm"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.Limit = 5
+
+			got := summary(Plan(NewGraph(testRoutes(), nil, q.Mode), q))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Plan = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Plan = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPlanLegs(t *testing.T) {
+	graph := NewGraph(testRoutes(), nil, models.PlanModeWalkOn)
+
+	itineraries := Plan(graph, Query{From: "SWB", To: "PST", MaxTransfers: 3, Limit: 5, Sort: models.PlanSortArrival})
+	if len(itineraries) != 2 {
+		t.Fatalf("got %d itineraries, want 2", len(itineraries))
+	}
+
+	connecting := itineraries[0]
+	if connecting.Transfers != 1 || len(connecting.Legs) != 2 || connecting.DurationMin != 95 {
+		t.Fatalf("connecting itinerary = %+v", connecting)
+	}
+	if leg := connecting.Legs[1]; leg.SailingID != "POBPST-0755" || leg.FromTerminalCode != "POB" || leg.WaitMin != 15 || leg.Estimated {
+		t.Errorf("second leg = %+v, want POBPST-0755 after a 15 minute wait", leg)
+	}
+
+	direct := itineraries[1]
+	if direct.Transfers != 0 || len(direct.Legs) != 1 {
+		t.Fatalf("direct itinerary = %+v", direct)
+	}
+	if leg := direct.Legs[0]; leg.ToTerminalCode != "PST" || len(leg.Stops) != 1 || leg.Stops[0] != "PVB" || !leg.Estimated {
+		t.Errorf("direct leg = %+v, want one estimated leg calling at PVB", leg)
+	}
+
+	// The timetabled transfer at Swartz Bay changes vessel
+	transfer := Plan(graph, Query{From: "PVB", To: "TSA", MaxTransfers: 1, Limit: 1})
+	if len(transfer) != 1 || transfer[0].Transfers != 1 || transfer[0].Legs[1].FromTerminalCode != "SWB" {
+		t.Errorf("PVB to TSA = %+v, want one change at SWB", transfer)
+	}
+	if none := Plan(graph, Query{From: "PVB", To: "TSA", MaxTransfers: 0, Limit: 1}); len(none) != 0 {
+		t.Errorf("PVB to TSA without transfers = %+v, want none", none)
+	}
+}
+
+func TestPlanSkipsFullCarDecks(t *testing.T) {
+	capacity := []models.CapacityRoute{{
+		RouteCode: "TSASWB", FromTerminalCode: "TSA", ToTerminalCode: "SWB", SailingDuration: "1h 35m",
+		Sailings: []models.CapacitySailing{
+			{ID: "TSASWB-0700", DepartureTime: "7:00 am", ArrivalTime: "8:35 am", CarFill: 100},
+			{ID: "TSASWB-0900", DepartureTime: "9:00 am", ArrivalTime: "...", CarFill: 40},
+			{ID: "TSASWB-1100", DepartureTime: "11:00 am", ArrivalTime: "12:35 pm", SailingStatus: "cancelled"},
+		},
+	}}
+
+	q := Query{From: "TSA", To: "SWB", MaxTransfers: 1, Limit: 5, Mode: models.PlanModeWalkOn}
+	if got := summary(Plan(NewGraph(nil, capacity, q.Mode), q)); len(got) != 2 || got[0] != "7:00 am-8:35 am" || got[1] != "9:00 am-10:35 am" {
+		t.Errorf("walk-on = %v, want the 7:00 am and 9:00 am sailings", got)
+	}
+
+	q.Mode = models.PlanModeVehicle
+	if got := summary(Plan(NewGraph(nil, capacity, q.Mode), q)); len(got) != 1 || got[0] != "9:00 am-10:35 am" {
+		t.Errorf("vehicle = %v, want only the 9:00 am sailing", got)
+	}
+}
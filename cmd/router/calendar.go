@@ -0,0 +1,64 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/ical"
+)
+
+/*
+ * writeCalendar
+ *
+ * Writes events as a text/calendar download
+ *
+ * @param http.ResponseWriter w
+ * @param string name - calendar name
+ * @param string filename - e.g. "TSAPOB.ics"
+ * @param []ical.Event events
+ *
+ * @return void
+ */
+func writeCalendar(w http.ResponseWriter, name, filename string, events []ical.Event) {
+	calendar := ical.Calendar{Name: name, Stamp: time.Now(), Events: events}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Write(calendar.Marshal())
+}
+
+// horizonDates lists the service dates non-capacity schedules are scraped for, today first
+func horizonDates() []string {
+	today, _ := time.Parse("2006-01-02", db.CurrentServiceDate())
+	dates := []string{today.Format("2006-01-02")}
+	for offset := 1; offset < config.ScheduleHorizonDays; offset++ {
+		dates = append(dates, today.AddDate(0, 0, offset).Format("2006-01-02"))
+	}
+	return dates
+}
+
+/*
+ * sailingDate
+ *
+ * Reads the service date from a sailing ID
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param string sailingID - e.g. "TSAPOB-2025-10-20-0710"
+ *
+ * @return string - e.g. "2025-10-20"
+ * @return bool - false if the ID is not one of the route's
+ */
+func sailingDate(routeCode, sailingID string) (string, bool) {
+	rest, ok := strings.CutPrefix(sailingID, routeCode+"-")
+	if !ok || len(rest) < len("2006-01-02") {
+		return "", false
+	}
+	date := rest[:len("2006-01-02")]
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return "", false
+	}
+	return date, true
+}
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * findCapacitySailing
+ *
+ * Finds a sailing in the stored capacity routes, with its timetabled times
+ *
+ * @param []models.CapacityRoute routes
+ * @param string routeCode
+ * @param string sailingID
+ *
+ * @return models.CapacityRoute - the sailing's route
+ * @return models.ScheduledSailing
+ * @return bool - false if not found
+ */
+func findCapacitySailing(routes []models.CapacityRoute, routeCode, sailingID string) (models.CapacityRoute, models.ScheduledSailing, bool) {
+	for _, route := range routes {
+		if route.RouteCode != routeCode {
+			continue
+		}
+		for _, s := range models.CapacitySchedule(route) {
+			if s.Sailing.ID == sailingID {
+				return route, s, true
+			}
+		}
+	}
+	return models.CapacityRoute{}, models.ScheduledSailing{}, false
+}
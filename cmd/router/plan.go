@@ -0,0 +1,105 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/planner"
+)
+
+const (
+	defaultPlanLimit        = 3
+	maxPlanLimit            = 10
+	defaultPlanMaxTransfers = 3
+	maxPlanMaxTransfers     = 5
+)
+
+/*
+ * parsePlanQuery
+ *
+ * Reads and validates the /v2/plan query parameters (see GetPlan), filling in defaults.
+ *
+ * @param url.Values values
+ * @param time.Time now
+ *
+ * @return planner.Query
+ * @return string - service date
+ * @return error - message for a 400 response
+ */
+func parsePlanQuery(values url.Values, now time.Time) (planner.Query, string, error) {
+	q := planner.Query{
+		From:         strings.ToUpper(strings.TrimSpace(values.Get("from"))),
+		To:           strings.ToUpper(strings.TrimSpace(values.Get("to"))),
+		Mode:         values.Get("mode"),
+		Sort:         values.Get("sort"),
+		MaxTransfers: defaultPlanMaxTransfers,
+		Limit:        defaultPlanLimit,
+	}
+
+	if q.From == "" || q.To == "" {
+		return q, "", errors.New("Missing from or to terminal code")
+	}
+	if q.From == q.To {
+		return q, "", errors.New("From and to must be different terminals")
+	}
+
+	today := db.CurrentServiceDate()
+	date := values.Get("date")
+	if date == "" {
+		date = today
+	} else if _, err := time.Parse("2006-01-02", date); err != nil {
+		return q, "", errors.New("Invalid date, expected YYYY-MM-DD")
+	}
+
+	switch departAfter := values.Get("departAfter"); {
+	case departAfter != "":
+		minutes, ok := models.ParseClock(departAfter)
+		if !ok {
+			return q, "", errors.New("Invalid departAfter, expected a time such as 7:00 am or 07:00")
+		}
+		q.DepartAfter = minutes
+	case date == today:
+		now = now.In(models.Pacific())
+		q.DepartAfter = now.Hour()*60 + now.Minute()
+	}
+
+	switch q.Mode {
+	case "":
+		q.Mode = models.PlanModeWalkOn
+	case models.PlanModeWalkOn, models.PlanModeVehicle:
+	default:
+		return q, "", fmt.Errorf("Invalid mode, expected %s or %s", models.PlanModeWalkOn, models.PlanModeVehicle)
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = models.PlanSortArrival
+	case models.PlanSortArrival, models.PlanSortTransfers:
+	default:
+		return q, "", fmt.Errorf("Invalid sort, expected %s or %s", models.PlanSortArrival, models.PlanSortTransfers)
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPlanLimit {
+			return q, "", fmt.Errorf("Invalid limit, expected 1 to %d", maxPlanLimit)
+		}
+		q.Limit = n
+	}
+
+	if maxTransfers := values.Get("maxTransfers"); maxTransfers != "" {
+		n, err := strconv.Atoi(maxTransfers)
+		if err != nil || n < 0 || n > maxPlanMaxTransfers {
+			return q, "", fmt.Errorf("Invalid maxTransfers, expected 0 to %d", maxPlanMaxTransfers)
+		}
+		q.MaxTransfers = n
+	}
+
+	return q, date, nil
+}
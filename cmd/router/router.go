@@ -3,6 +3,8 @@ package router
 import (
 	"net/http"
 
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -12,43 +14,102 @@ import (
  * Initializes the HTTP router and registers all API endpoints.
  * Also serves static files for not-found routes.
  *
+ * @param db.Store store - where handlers read route data from
+ * @param *events.Bus bus - changes streamed by /v2/stream, may be nil
+ *
  * @return *httprouter.Router - configured router instance
  */
-func SetupRouter() *httprouter.Router {
+func SetupRouter(store db.Store, bus *events.Bus) *httprouter.Router {
 	router := httprouter.New()
+	h := NewHandler(store, bus)
 
 	// V2 Routes (with and without trailing slash)
-	router.GET("/v2", GetCapacityAndNonCapacitySailings)
-	router.GET("/v2/", GetCapacityAndNonCapacitySailings)
+	router.GET("/v2", h.GetCapacityAndNonCapacitySailings)
+	router.GET("/v2/", h.GetCapacityAndNonCapacitySailings)
 
 	// Routes list endpoints (moved to avoid conflict with :routeCode wildcard)
-	router.GET("/v2/routes/capacity", GetCapacityRoutesList)
-	router.GET("/v2/routes/capacity/", GetCapacityRoutesList)
-	router.GET("/v2/routes/noncapacity", GetNonCapacityRoutesList)
-	router.GET("/v2/routes/noncapacity/", GetNonCapacityRoutesList)
+	router.GET("/v2/routes/capacity", h.GetCapacityRoutesList)
+	router.GET("/v2/routes/capacity/", h.GetCapacityRoutesList)
+	router.GET("/v2/routes/noncapacity", h.GetNonCapacityRoutesList)
+	router.GET("/v2/routes/noncapacity/", h.GetNonCapacityRoutesList)
 
 	// Capacity routes
-	router.GET("/v2/capacity", GetCapacitySailings)
-	router.GET("/v2/capacity/", GetCapacitySailings)
-	router.GET("/v2/capacity/:routeCode", GetSingleCapacityRoute)
-	router.GET("/v2/capacity/:routeCode/", GetSingleCapacityRoute)
+	router.GET("/v2/capacity", h.GetCapacitySailings)
+	router.GET("/v2/capacity/", h.GetCapacitySailings)
+	router.GET("/v2/capacity/:routeCode", h.GetSingleCapacityRoute)
+	router.GET("/v2/capacity/:routeCode/", h.GetSingleCapacityRoute)
+	router.GET("/v2/capacity/:routeCode/calendar.ics", h.GetCapacityCalendar)
+	router.GET("/v2/capacity/:routeCode/sailings/:sailingId/calendar.ics", h.GetCapacitySailingCalendar)
+	router.GET("/v2/capacity/:routeCode/sailings/:sailingId/fill", h.GetCapacitySailingFill)
+	router.GET("/v2/capacity/:routeCode/sailings/:sailingId/prediction", h.GetCapacitySailingPrediction)
 
 	// Non-capacity routes
-	router.GET("/v2/noncapacity", GetNonCapacitySailings)
-	router.GET("/v2/noncapacity/", GetNonCapacitySailings)
-	router.GET("/v2/noncapacity/:routeCode", GetSingleNonCapacityRoute)
-	router.GET("/v2/noncapacity/:routeCode/", GetSingleNonCapacityRoute)
+	router.GET("/v2/noncapacity", h.GetNonCapacitySailings)
+	router.GET("/v2/noncapacity/", h.GetNonCapacitySailings)
+	router.GET("/v2/noncapacity/:routeCode", h.GetSingleNonCapacityRoute)
+	router.GET("/v2/noncapacity/:routeCode/", h.GetSingleNonCapacityRoute)
+	router.GET("/v2/noncapacity/:routeCode/calendar.ics", h.GetNonCapacityCalendar)
+	router.GET("/v2/noncapacity/:routeCode/geojson", h.GetNonCapacityRouteGeoJSON)
+	router.GET("/v2/noncapacity/:routeCode/sailings/:sailingId/calendar.ics", h.GetNonCapacitySailingCalendar)
+
+	// Sailings of either kind of route, listed or by ID
+	router.GET("/v2/sailings", h.ListSailings)
+	router.GET("/v2/sailings/", h.ListSailings)
+	router.GET("/v2/sailings/:id", h.GetSailing)
+	router.GET("/v2/sailings/:id/", h.GetSailing)
+	router.GET("/v2/sailings/:id/geojson", h.GetSailingGeoJSON)
+	// httprouter reads ":batchGet" as a parameter matching any suffix, which
+	// BatchGetSailings checks is the literal ":batchGet"
+	router.POST("/v2/sailings:batchGet", h.BatchGetSailings)
+
+	// GTFS static and realtime feeds
+	router.GET("/v2/gtfs.zip", h.GetGTFS)
+	router.GET("/v2/gtfs-rt", h.GetGTFSRealtime)
+
+	// Server-Sent Events stream of sailing changes
+	router.GET("/v2/stream", h.GetStream)
+	router.GET("/v2/stream/", h.GetStream)
+
+	// Webhook subscriptions (admin bearer token)
+	router.POST("/v2/webhooks", h.CreateWebhook)
+	router.GET("/v2/webhooks", h.GetWebhooks)
+	router.GET("/v2/webhooks/:id", h.GetWebhook)
+	router.PUT("/v2/webhooks/:id", h.UpdateWebhook)
+	router.DELETE("/v2/webhooks/:id", h.DeleteWebhook)
+	router.GET("/v2/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+
+	// Journey planner
+	router.GET("/v2/plan", h.GetPlan)
+	router.GET("/v2/plan/", h.GetPlan)
+
+	// On-time statistics
+	router.GET("/v2/stats/ontime", h.GetOnTimeStats)
+	router.GET("/v2/stats/ontime/", h.GetOnTimeStats)
+
+	// Scraper status
+	router.GET("/v2/status", h.GetStatus)
+	router.GET("/v2/status/", h.GetStatus)
+
+	// Terminals
+	router.GET("/v2/terminals", h.GetTerminals)
+	router.GET("/v2/terminals/", h.GetTerminals)
+	router.GET("/v2/terminals/:code", h.GetTerminal)
+	router.GET("/v2/terminals/:code/", h.GetTerminal)
+
+	// Schedule seasons
+	router.GET("/v2/schedules/:routeCode/seasons", h.GetScheduleSeasons)
+	router.GET("/v2/schedules/:routeCode/seasons/", h.GetScheduleSeasons)
 
 	// V1 Routes (with and without trailing slash)
-	router.GET("/api", GetAllSailings)
-	router.GET("/api/", GetAllSailings)
-	router.GET("/api/:departureTerminal", GetSailingsByDepartureTerminal)
-	router.GET("/api/:departureTerminal/", GetSailingsByDepartureTerminal)
-	router.GET("/api/:departureTerminal/:destinationTerminal", GetSailingsByDepartureAndDestinationTerminals)
-	router.GET("/api/:departureTerminal/:destinationTerminal/", GetSailingsByDepartureAndDestinationTerminals)
-
-	router.GET("/healthcheck", HealthCheck)
-	router.GET("/healthcheck/", HealthCheck)
+	router.GET("/api", h.GetAllSailings)
+	router.GET("/api/", h.GetAllSailings)
+	router.GET("/api/:departureTerminal", h.GetSailingsByDepartureTerminal)
+	router.GET("/api/:departureTerminal/", h.GetSailingsByDepartureTerminal)
+	router.GET("/api/:departureTerminal/:destinationTerminal", h.GetSailingsByDepartureAndDestinationTerminals)
+	router.GET("/api/:departureTerminal/:destinationTerminal/", h.GetSailingsByDepartureAndDestinationTerminals)
+
+	router.GET("/healthcheck", h.HealthCheck)
+	router.GET("/healthcheck/", h.HealthCheck)
 
 	router.NotFound = http.FileServer(http.Dir("./static"))
 
@@ -1,16 +1,52 @@
 package router
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
 	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/events"
+	"github.com/jeffcstock/bc-ferries-api/cmd/forecast"
+	"github.com/jeffcstock/bc-ferries-api/cmd/geo"
+	"github.com/jeffcstock/bc-ferries-api/cmd/gtfs"
+	"github.com/jeffcstock/bc-ferries-api/cmd/ical"
 	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/planner"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+	"github.com/jeffcstock/bc-ferries-api/cmd/stats"
 )
 
+/*
+ * Handler
+ *
+ * Serves the API endpoints from a Store, and streams change events from a Bus.
+ */
+type Handler struct {
+	store  db.Store
+	events *events.Bus
+}
+
+/*
+ * NewHandler
+ *
+ * Creates a handler that reads from the given store.
+ *
+ * @param db.Store store
+ * @param *events.Bus bus - changes found by the scraper; nil disables /v2/stream
+ *
+ * @return *Handler
+ */
+func NewHandler(store db.Store, bus *events.Bus) *Handler {
+	return &Handler{store: store, events: bus}
+}
+
 /**************/
 /* V2 Structs */
 /**************/
@@ -39,9 +75,9 @@ type CapacityResponse struct {
  *
  * @return void
  */
-func GetCapacityAndNonCapacitySailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	capacityRoute := db.GetCapacitySailings()
-	nonCapacityRoute := db.GetNonCapacitySailings()
+func (h *Handler) GetCapacityAndNonCapacitySailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	capacityRoute := h.store.GetCapacitySailings()
+	nonCapacityRoute := h.store.GetNonCapacitySailings(db.CurrentServiceDate())
 
 	response := AllDataResponse{
 		CapacityRoutes:    capacityRoute,
@@ -67,14 +103,14 @@ func GetCapacityAndNonCapacitySailings(w http.ResponseWriter, r *http.Request, p
  *
  * @return void
  */
-func GetCapacitySailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	routes := db.GetCapacitySailings()
+func (h *Handler) GetCapacitySailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	routes := h.store.GetCapacitySailings()
 
 	response := CapacityResponse{
 		Routes: routes,
 	}
 
-	if len(response.Routes[0].Sailings) == 0 {
+	if len(response.Routes) == 0 || len(response.Routes[0].Sailings) == 0 {
 		jsonString, _ := json.Marshal("BC Ferries Data Currently Down")
 
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -100,9 +136,9 @@ func GetCapacitySailings(w http.ResponseWriter, r *http.Request, ps httprouter.P
  *
  * @return void
  */
-func GetSingleCapacityRoute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *Handler) GetSingleCapacityRoute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	routeCode := ps.ByName("routeCode")
-	routes := db.GetCapacitySailings()
+	routes := h.store.GetCapacitySailings()
 
 	// Find the route matching the route code
 	var foundRoute *models.CapacityRoute
@@ -137,8 +173,8 @@ func GetSingleCapacityRoute(w http.ResponseWriter, r *http.Request, ps httproute
  *
  * @return void
  */
-func GetNonCapacitySailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	routes := db.GetNonCapacitySailings()
+func (h *Handler) GetNonCapacitySailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	routes := h.store.GetNonCapacitySailings(db.CurrentServiceDate())
 
 	response := models.NonCapacityResponse{
 		Routes: routes,
@@ -155,7 +191,11 @@ func GetNonCapacitySailings(w http.ResponseWriter, r *http.Request, ps httproute
 /*
  * GetSingleNonCapacityRoute
  *
- * Returns sailing data for a specific non-capacity route by route code
+ * Returns sailing data for a specific non-capacity route by route code.
+ * Defaults to today's sailings (Pacific Time).
+ *
+ * Query params:
+ *   - date: service date in YYYY-MM-DD format (within the scraped schedule horizon)
  *
  * @param http.ResponseWriter w
  * @param *http.Request r
@@ -163,22 +203,24 @@ func GetNonCapacitySailings(w http.ResponseWriter, r *http.Request, ps httproute
  *
  * @return void
  */
-func GetSingleNonCapacityRoute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *Handler) GetSingleNonCapacityRoute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	routeCode := ps.ByName("routeCode")
-	routes := db.GetNonCapacitySailings()
-
-	// Find the route matching the route code
-	var foundRoute *models.NonCapacityRoute
-	for _, route := range routes {
-		if route.RouteCode == routeCode {
-			foundRoute = &route
-			break
-		}
-	}
+	date := r.URL.Query().Get("date")
 
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
 
+	if date == "" {
+		date = db.CurrentServiceDate()
+	} else if _, err := time.Parse("2006-01-02", date); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Invalid date, expected YYYY-MM-DD"})
+		w.Write(jsonString)
+		return
+	}
+
+	foundRoute := h.store.GetNonCapacityRoute(routeCode, date)
+
 	if foundRoute != nil {
 		jsonString, _ := json.Marshal(foundRoute)
 		w.Write(jsonString)
@@ -204,7 +246,7 @@ func GetSingleNonCapacityRoute(w http.ResponseWriter, r *http.Request, ps httpro
  *
  * @return void
  */
-func GetCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *Handler) GetCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Parse query parameter
 	routeCodesParam := r.URL.Query().Get("routeCodes")
 	var routeCodes []string
@@ -217,7 +259,7 @@ func GetCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprouter
 		}
 	}
 
-	routes := db.GetCapacityRoutesInfo(routeCodes)
+	routes := h.store.GetCapacityRoutesInfo(routeCodes)
 
 	response := models.CapacityRoutesResponse{
 		Routes: routes,
@@ -233,8 +275,9 @@ func GetCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprouter
 /*
  * GetNonCapacityRoutesList
  *
- * Returns lightweight metadata for non-capacity routes (without sailings).
- * Optionally filters by route codes via query parameter.
+ * Returns lightweight metadata for non-capacity routes (without sailings), from
+ * today's (Pacific Time) schedule, so each route is listed once although several
+ * service dates are stored. Optionally filters by route codes via query parameter.
  *
  * Query params:
  *   - routeCodes: comma-separated route codes (e.g., "FULSWB,BOWHSB")
@@ -245,7 +288,7 @@ func GetCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprouter
  *
  * @return void
  */
-func GetNonCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *Handler) GetNonCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Parse query parameter
 	routeCodesParam := r.URL.Query().Get("routeCodes")
 	var routeCodes []string
@@ -258,7 +301,7 @@ func GetNonCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprou
 		}
 	}
 
-	routes := db.GetNonCapacityRoutesInfo(routeCodes)
+	routes := h.store.GetNonCapacityRoutesInfo(db.CurrentServiceDate(), routeCodes)
 
 	response := models.NonCapacityRoutesResponse{
 		Routes: routes,
@@ -271,24 +314,95 @@ func GetNonCapacityRoutesList(w http.ResponseWriter, r *http.Request, ps httprou
 	w.Write(jsonString)
 }
 
-/**************/
-/* V1 Structs */
-/**************/
+/*
+ * GetScheduleSeasons
+ *
+ * Returns the known schedule seasons (effective date ranges) for a non-capacity route
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetScheduleSeasons(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	routeCode := ps.ByName("routeCode")
 
-type Response struct {
-	Schedule  map[string]map[string]models.Route `json:"schedule"`
-	ScrapedAt time.Time                          `json:"scrapedAt"`
+	response := models.ScheduleSeasonsResponse{
+		RouteCode: routeCode,
+		Seasons:   h.store.GetScheduleSeasons(routeCode),
+	}
+
+	jsonString, _ := json.Marshal(response)
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonString)
 }
 
-/*************/
-/* V1 Routes */
-/*************/
-// V1 routes return data in a different format and only contain upcoming sailings for specific routes
+/*
+ * GetPlan
+ *
+ * Plans journeys between two terminals over the day's sailings, including connections
+ * between routes (see planner.Plan).
+ *
+ * Query params:
+ *   - from, to: terminal codes (required), e.g. from=SWB&to=PST
+ *   - date: service date in YYYY-MM-DD format (defaults to today, Pacific Time)
+ *   - departAfter: earliest departure, e.g. "7:00 am" or "07:00" (defaults to now for
+ *     today, otherwise midnight)
+ *   - mode: "walk" (default) or "vehicle"; vehicles need longer connections and skip
+ *     sailings with a full car deck
+ *   - sort: "arrival" (default, earliest arrival first) or "transfers" (fewest first)
+ *   - limit: itineraries to return, 1 to 10 (default 3)
+ *   - maxTransfers: vessel changes allowed, 0 to 5 (default 3)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetPlan(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	query, date, err := parsePlanQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	var capacityRoutes []models.CapacityRoute
+	for _, route := range h.store.GetCapacitySailings() {
+		if route.Date == date {
+			capacityRoutes = append(capacityRoutes, route)
+		}
+	}
+
+	graph := planner.NewGraph(h.store.GetNonCapacitySailings(date), capacityRoutes, query.Mode)
+
+	response := models.PlanResponse{
+		From:        query.From,
+		To:          query.To,
+		Date:        date,
+		DepartAfter: models.FormatClock(query.DepartAfter),
+		Mode:        query.Mode,
+		Sort:        query.Sort,
+		Itineraries: planner.Plan(graph, query),
+	}
+
+	jsonString, _ := json.Marshal(response)
+	w.Write(jsonString)
+}
 
 /*
- * GetAllSailings
+ * GetGTFS
  *
- * Returns all sailing data
+ * Returns the scraped schedules and capacity sailings for the next config.ScheduleHorizonDays
+ * days as a GTFS static feed (see gtfs.Build)
  *
  * @param http.ResponseWriter w
  * @param *http.Request r
@@ -296,26 +410,179 @@ type Response struct {
  *
  * @return void
  */
-func GetAllSailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	capacityRoute := db.GetCapacitySailings()
-	nonCapacityRoute := db.GetNonCapacitySailings()
+func (h *Handler) GetGTFS(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	response := AllDataResponse{
-		CapacityRoutes:    capacityRoute,
-		NonCapacityRoutes: nonCapacityRoute,
+	start, _ := time.Parse("2006-01-02", db.CurrentServiceDate())
+
+	var feed bytes.Buffer
+	if err := gtfs.Export(h.store, start, config.ScheduleHorizonDays, &feed); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
 	}
 
-	jsonString, _ := json.Marshal(ConvertV1ResponseToV2Response(response))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="gtfs.zip"`)
+	w.Write(feed.Bytes())
+}
+
+/*
+ * GetGTFSRealtime
+ *
+ * Returns today's capacity data as a GTFS-Realtime feed of trip updates and
+ * cancellation alerts (see gtfs.BuildRealtime), in protobuf, or as JSON with
+ * ?format=json for debugging
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetGTFSRealtime(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	today := db.CurrentServiceDate()
+	var routes []models.CapacityRoute
+	for _, route := range h.store.GetCapacitySailings() {
+		if route.Date == today {
+			routes = append(routes, route)
+		}
+	}
+
+	feed := gtfs.BuildRealtime(routes, time.Now())
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		jsonString, _ := json.Marshal(feed)
+		w.Write(jsonString)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(feed.Marshal())
+}
+
+/*
+ * GetStream
+ *
+ * Streams changes to sailings as Server-Sent Events, as the scraper finds them. Each
+ * event's "event:" field is its type (see models.ChangeEvent) and its data the event as
+ * JSON. A comment is sent every streamKeepAlive so proxies keep the connection open.
+ *
+ * Query params:
+ *   - routeCodes: comma-separated route codes to receive events for (default: all)
+ *   - lastEventId: resume after this event, for clients that cannot set the
+ *     Last-Event-ID header
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetStream(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	flusher, ok := w.(http.Flusher)
+	if h.events == nil || !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Streaming is not available"})
+		w.Write(jsonString)
+		return
+	}
+
+	lastEventID, resume, err := parseLastEventID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+	routeCodes := parseRouteCodes(r.URL.Query().Get("routeCodes"))
+
+	replay, stream, unsubscribe := h.events.Subscribe(lastEventID, resume)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Don't let nginx buffer the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+	for _, event := range replay {
+		writeStreamEvent(w, event, routeCodes)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-stream:
+			// Closed when this client fell behind; it reconnects and resumes
+			if !ok {
+				return
+			}
+			writeStreamEvent(w, event, routeCodes)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
 
+/*
+ * GetOnTimeStats
+ *
+ * Reports how late capacity sailings departed compared with the timetable, overall and
+ * per route, vessel and hour of day, with the mean, median, 90th percentile and a
+ * histogram of delays (see stats.OnTime)
+ *
+ * Query params:
+ *   - from, to: service dates in YYYY-MM-DD format (default: the 30 days up to today)
+ *   - routeCodes: comma-separated route codes (default: all)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetOnTimeStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
+
+	from, to, routeCodes, err := parseStatsQuery(r.URL.Query(), db.CurrentServiceDate())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	jsonString, _ := json.Marshal(stats.OnTime(h.store.GetSailingDepartures(from, to, routeCodes), from, to))
 	w.Write(jsonString)
 }
 
 /*
- * GetSailingsByDepartureTerminal
+ * GetNonCapacityCalendar
  *
- * Returns sailing data for given departure
+ * Returns a non-capacity route's sailings as an iCalendar feed (see
+ * ical.NonCapacityEvents), for the whole scraped schedule horizon by default.
+ *
+ * Query params:
+ *   - date: only this service date, in YYYY-MM-DD format
  *
  * @param http.ResponseWriter w
  * @param *http.Request r
@@ -323,27 +590,78 @@ func GetAllSailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params
  *
  * @return void
  */
-func GetSailingsByDepartureTerminal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	departureTerminal := ps.ByName("departureTerminal")
-	capacityRoute := db.GetCapacitySailings()
-	nonCapacityRoute := db.GetNonCapacitySailings()
+func (h *Handler) GetNonCapacityCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	allDataResponse := AllDataResponse{
-		CapacityRoutes:    capacityRoute,
-		NonCapacityRoutes: nonCapacityRoute,
+	routeCode := ps.ByName("routeCode")
+	dates := []string{r.URL.Query().Get("date")}
+	if dates[0] == "" {
+		dates = horizonDates()
+	} else if _, err := time.Parse("2006-01-02", dates[0]); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Invalid date, expected YYYY-MM-DD"})
+		w.Write(jsonString)
+		return
 	}
 
-	jsonString, _ := json.Marshal(ConvertV1ResponseToV2Response(allDataResponse)[departureTerminal])
+	var events []ical.Event
+	found := false
+	for _, date := range dates {
+		if route := h.store.GetNonCapacityRoute(routeCode, date); route != nil {
+			found = true
+			events = append(events, ical.NonCapacityEvents(*route)...)
+		}
+	}
+	if !found {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Route not found"})
+		w.Write(jsonString)
+		return
+	}
 
+	writeCalendar(w, "BC Ferries "+routeCode, routeCode+".ics", events)
+}
+
+/*
+ * GetNonCapacitySailingCalendar
+ *
+ * Returns a single non-capacity sailing, by ID, as an iCalendar file
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetNonCapacitySailingCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	routeCode, sailingID := ps.ByName("routeCode"), ps.ByName("sailingId")
+	if date, ok := sailingDate(routeCode, sailingID); ok {
+		if route := h.store.GetNonCapacityRoute(routeCode, date); route != nil {
+			// From the whole route so a sailing after midnight is still placed on the next day
+			for _, event := range ical.NonCapacityEvents(*route) {
+				if event.UID == ical.UID(sailingID) {
+					writeCalendar(w, "BC Ferries "+routeCode, sailingID+".ics", []ical.Event{event})
+					return
+				}
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	jsonString, _ := json.Marshal(map[string]string{"error": "Sailing not found"})
 	w.Write(jsonString)
 }
 
 /*
- * GetSailingsByDepartureAndDestinationTerminals
+ * GetCapacityCalendar
  *
- * Returns sailing data for given departure and destination terminal
+ * Returns a capacity route's sailings (today's, as capacity is only scraped for the
+ * current day) as an iCalendar feed, including cancellations (see ical.CapacityEvents)
  *
  * @param http.ResponseWriter w
  * @param *http.Request r
@@ -351,32 +669,96 @@ func GetSailingsByDepartureTerminal(w http.ResponseWriter, r *http.Request, ps h
  *
  * @return void
  */
-func GetSailingsByDepartureAndDestinationTerminals(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	departureTerminal := ps.ByName("departureTerminal")
-	destinationTerminal := ps.ByName("destinationTerminal")
-	capacityRoute := db.GetCapacitySailings()
-	nonCapacityRoute := db.GetNonCapacitySailings()
+func (h *Handler) GetCapacityCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	allDataResponse := AllDataResponse{
-		CapacityRoutes:    capacityRoute,
-		NonCapacityRoutes: nonCapacityRoute,
+	routeCode := ps.ByName("routeCode")
+	var events []ical.Event
+	found := false
+	for _, route := range h.store.GetCapacitySailings() {
+		if route.RouteCode == routeCode {
+			found = true
+			events = append(events, ical.CapacityEvents(route)...)
+		}
+	}
+	if !found {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Route not found"})
+		w.Write(jsonString)
+		return
 	}
 
-	jsonString, _ := json.Marshal(ConvertV1ResponseToV2Response(allDataResponse)[departureTerminal][destinationTerminal])
+	writeCalendar(w, "BC Ferries "+routeCode, routeCode+".ics", events)
+}
 
+/*
+ * GetCapacitySailingCalendar
+ *
+ * Returns a single capacity sailing, by ID, as an iCalendar file
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetCapacitySailingCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	routeCode, sailingID := ps.ByName("routeCode"), ps.ByName("sailingId")
+	for _, route := range h.store.GetCapacitySailings() {
+		if route.RouteCode != routeCode {
+			continue
+		}
+		for _, event := range ical.CapacityEvents(route) {
+			if event.UID == ical.UID(sailingID) {
+				writeCalendar(w, "BC Ferries "+routeCode, sailingID+".ics", []ical.Event{event})
+				return
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	jsonString, _ := json.Marshal(map[string]string{"error": "Sailing not found"})
 	w.Write(jsonString)
 }
 
-/****************/
-/* Other Routes */
-/****************/
+/*
+ * GetCapacitySailingFill
+ *
+ * Returns a capacity sailing's fill curve: its fill, car fill and oversize fill as
+ * sampled by every scrape until it departed (samples are only added when they change)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetCapacitySailingFill(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	history := h.store.GetFillHistory(ps.ByName("sailingId"))
+	if history == nil || history.RouteCode != ps.ByName("routeCode") {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "No fill history for this sailing"})
+		w.Write(jsonString)
+		return
+	}
+
+	jsonString, _ := json.Marshal(history)
+	w.Write(jsonString)
+}
 
 /*
- * HealthCheck
+ * GetCapacitySailingPrediction
  *
- * Returns a simple response indicating the server is running.
+ * Predicts the fill one of today's capacity sailings will depart with, from the fill
+ * curves of the route's sailings at the same time on the same weekday in past weeks
+ * (see forecast.Predict)
  *
  * @param http.ResponseWriter w
  * @param *http.Request r
@@ -384,11 +766,751 @@ func GetSailingsByDepartureAndDestinationTerminals(w http.ResponseWriter, r *htt
  *
  * @return void
  */
-func HealthCheck(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	jsonString, _ := json.Marshal("Server OK")
+func (h *Handler) GetCapacitySailingPrediction(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	route, s, ok := findCapacitySailing(h.store.GetCapacitySailings(), ps.ByName("routeCode"), ps.ByName("sailingId"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Sailing not found"})
+		w.Write(jsonString)
+		return
+	}
+	departure, ok := models.ServiceTime(route.Date, s.Departure)
+	if !ok || s.Sailing.SailingStatus != "future" {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Sailing has already departed or was cancelled"})
+		w.Write(jsonString)
+		return
+	}
+
+	serviceDate, _ := time.Parse("2006-01-02", route.Date)
+	history := h.store.GetFillHistories(route.RouteCode, s.Departure, serviceDate.Weekday(), route.Date)
+
+	current := models.FillLevels{Fill: s.Sailing.Fill, CarFill: s.Sailing.CarFill, OversizeFill: s.Sailing.OversizeFill}
+	prediction := forecast.Predict(current, max(int(time.Until(departure).Minutes()), 0), history)
+	prediction.SailingID = s.Sailing.ID
+	prediction.RouteCode = route.RouteCode
+	prediction.Date = route.Date
+	prediction.ScheduledTime = models.FormatClock(s.Departure)
+
+	jsonString, _ := json.Marshal(prediction)
+	w.Write(jsonString)
+}
+
+/*
+ * CreateWebhook
+ *
+ * Subscribes a URL to change events (see parseWebhookSubscription for the body). The
+ * response includes the generated secret payloads are signed with, which is not
+ * returned again. Requires the admin bearer token.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !authorizeWebhooks(w, r) {
+		return
+	}
+
+	subscription, err := parseWebhookSubscription(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	subscription.Secret, err = newWebhookSecret()
+	if err == nil {
+		subscription, err = h.store.CreateWebhookSubscription(subscription)
+	}
+	if err != nil {
+		log.Printf("CreateWebhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Failed to save webhook"})
+		w.Write(jsonString)
+		return
+	}
+
+	jsonString, _ := json.Marshal(subscription)
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonString)
+}
+
+/*
+ * GetWebhooks
+ *
+ * Lists webhook subscriptions, without their secrets. Requires the admin bearer token.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetWebhooks(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !authorizeWebhooks(w, r) {
+		return
+	}
+
+	subscriptions := h.store.GetWebhookSubscriptions()
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	jsonString, _ := json.Marshal(subscriptions)
+	w.Write(jsonString)
+}
+
+/*
+ * GetWebhook
+ *
+ * Returns a webhook subscription, without its secret. Requires the admin bearer token.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !authorizeWebhooks(w, r) {
+		return
+	}
+
+	id, err := parseWebhookID(ps.ByName("id"))
+	var subscription *models.WebhookSubscription
+	if err == nil {
+		subscription = h.store.GetWebhookSubscription(id)
+	}
+	if subscription == nil {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Webhook not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	subscription.Secret = ""
+	jsonString, _ := json.Marshal(subscription)
+	w.Write(jsonString)
+}
+
+/*
+ * UpdateWebhook
+ *
+ * Replaces a webhook subscription's URL and filters, with the same body as
+ * CreateWebhook. The secret stays the same. Requires the admin bearer token.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !authorizeWebhooks(w, r) {
+		return
+	}
+
+	id, err := parseWebhookID(ps.ByName("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Webhook not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	subscription, err := parseWebhookSubscription(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+	subscription.ID = id
+
+	updated, err := h.store.UpdateWebhookSubscription(subscription)
+	if err != nil {
+		log.Printf("UpdateWebhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Failed to save webhook"})
+		w.Write(jsonString)
+		return
+	}
+	if !updated {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Webhook not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	h.GetWebhook(w, r, ps)
+}
+
+/*
+ * DeleteWebhook
+ *
+ * Deletes a webhook subscription and its delivery log. Requires the admin bearer token.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !authorizeWebhooks(w, r) {
+		return
+	}
+
+	id, err := parseWebhookID(ps.ByName("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Webhook not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	deleted, err := h.store.DeleteWebhookSubscription(id)
+	if err != nil {
+		log.Printf("DeleteWebhook: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Failed to delete webhook"})
+		w.Write(jsonString)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Webhook not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+ * GetWebhookDeliveries
+ *
+ * Returns a webhook subscription's latest delivery attempts, newest first. Requires the
+ * admin bearer token.
+ *
+ * Query params:
+ *   - limit: number of attempts to return (default 50, at most 500)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !authorizeWebhooks(w, r) {
+		return
+	}
+
+	id, err := parseWebhookID(ps.ByName("id"))
+	if err != nil || h.store.GetWebhookSubscription(id) == nil {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Webhook not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"), defaultDeliveryLimit, maxDeliveryLimit)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	jsonString, _ := json.Marshal(h.store.GetWebhookDeliveries(id, limit))
+	w.Write(jsonString)
+}
+
+/*
+ * GetTerminals
+ *
+ * Returns the terminals in the catalogue, sorted by code
+ *
+ * Query params:
+ *   - format: "geojson" for a GeoJSON feature collection of the terminals and the
+ *     legs between them, for mapping
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetTerminals(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	terminals := sortedTerminals()
+
+	if r.URL.Query().Get("format") == "geojson" {
+		w.Header().Set("Content-Type", "application/geo+json")
+		jsonString, _ := json.Marshal(geo.TerminalsAndLegs(terminals, staticdata.GetLegs()))
+		w.Write(jsonString)
+		return
+	}
+
+	response := models.TerminalsResponse{Terminals: []models.TerminalInfo{}}
+	for _, terminal := range terminals {
+		response.Terminals = append(response.Terminals, terminalInfo(terminal))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	jsonString, _ := json.Marshal(response)
+	w.Write(jsonString)
+}
+
+/*
+ * GetTerminal
+ *
+ * Returns a terminal with the routes that serve it and its next departures today.
+ * /v2/terminals/nearby is served by GetNearbyTerminals.
+ *
+ * Query params:
+ *   - limit: maximum number of departures (default 10, at most 100)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetTerminal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// httprouter cannot have a static segment next to :code
+	if ps.ByName("code") == "nearby" {
+		h.GetNearbyTerminals(w, r, ps)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	terminal, ok := staticdata.GetTerminals()[strings.ToUpper(ps.ByName("code"))]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Terminal not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"), defaultDepartureLimit, maxDepartureLimit)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	response := models.TerminalDetails{
+		TerminalInfo:   terminalInfo(terminal),
+		Routes:         terminalRoutes(terminal.Code),
+		NextDepartures: nextDepartures(h.store.GetCapacitySailings(), h.store.GetNonCapacitySailings(db.CurrentServiceDate()), terminal.Code, time.Now(), limit),
+	}
+
+	jsonString, _ := json.Marshal(response)
+	w.Write(jsonString)
+}
+
+/*
+ * GetNearbyTerminals
+ *
+ * Returns the terminals within a radius of a point, closest first by great-circle
+ * distance
+ *
+ * Query params:
+ *   - lat, lon: the point, in degrees (required)
+ *   - radiusKm: search radius (default 25, at most 500)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetNearbyTerminals(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	lat, lon, radiusKm, err := parseNearbyQuery(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	response := models.NearbyTerminalsResponse{
+		Lat:       lat,
+		Lon:       lon,
+		RadiusKm:  radiusKm,
+		Terminals: nearbyTerminals(lat, lon, radiusKm),
+	}
+
+	jsonString, _ := json.Marshal(response)
+	w.Write(jsonString)
+}
+
+/*
+ * GetNonCapacityRouteGeoJSON
+ *
+ * Returns a non-capacity route's sailings as a GeoJSON feature collection, with a
+ * line for each leg and a point for each terminal called at (see geo.NonCapacitySailing)
+ *
+ * Query params:
+ *   - date: service date in YYYY-MM-DD format (defaults to today)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetNonCapacityRouteGeoJSON(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	routeCode := ps.ByName("routeCode")
+	date := r.URL.Query().Get("date")
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	if date == "" {
+		date = db.CurrentServiceDate()
+	} else if _, err := time.Parse("2006-01-02", date); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Invalid date, expected YYYY-MM-DD"})
+		w.Write(jsonString)
+		return
+	}
+
+	route := h.store.GetNonCapacityRoute(routeCode, date)
+	if route == nil {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Route not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	jsonString, _ := json.Marshal(geo.NonCapacityRoute(models.WithNonCapacityTimes(*route)))
+	w.Write(jsonString)
+}
+
+/*
+ * ListSailings
+ *
+ * Lists the sailings of a service date on either kind of route, soonest departure
+ * first, each as returned by GetSailing. Filters are applied by the store (in SQL for
+ * PostgreSQL) rather than by loading every route.
+ *
+ * Query params:
+ *   - date: service date in YYYY-MM-DD format (default: today)
+ *   - kind: "capacity" or "noncapacity" (default: both)
+ *   - routeCodes: comma-separated route codes (default: all)
+ *   - from: departure terminal code
+ *   - vessel: vessel name, on any leg for non-capacity sailings
+ *   - status: capacity sailing status, e.g. "future" or "cancelled"
+ *   - departAfter, departBefore: times of day, e.g. "7:00 am" or "13:30"
+ *   - limit: at most this many sailings (default: 50, max: 500)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) ListSailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseSailingsQuery(r.URL.Query(), db.CurrentServiceDate())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	response := models.SailingsResponse{Sailings: h.store.FindSailings(filter)}
+	jsonString, _ := json.Marshal(response)
+	w.Write(jsonString)
+}
+
+/*
+ * GetSailing
+ *
+ * Returns a single sailing by ID, with its route's code, date, terminals and duration.
+ * `capacity` holds it as listed on a capacity route (with fill levels) and
+ * `nonCapacity` as listed on a non-capacity route (with stops and legs); a sailing
+ * can be on both.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetSailing(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	sailings := h.store.GetSailings([]string{ps.ByName("id")})
+	if len(sailings) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Sailing not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	jsonString, _ := json.Marshal(sailings[0])
+	w.Write(jsonString)
+}
+
+/*
+ * BatchGetSailings
+ *
+ * Returns up to 100 sailings by ID (see parseBatchGetSailings for the body), in the
+ * order requested, each as returned by GetSailing. IDs that are not found are listed
+ * in `notFound` rather than failing the request.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) BatchGetSailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	// The route's ":batchGet" is a parameter, so any POST /v2/sailings<suffix> lands here
+	if ps.ByName("batchGet") != ":batchGet" {
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	ids, err := parseBatchGetSailings(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonString, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(jsonString)
+		return
+	}
+
+	sailings := h.store.GetSailings(ids)
+	response := models.SailingsBatchGetResponse{
+		Sailings: sailings,
+		NotFound: notFoundSailings(ids, sailings),
+	}
+
+	jsonString, _ := json.Marshal(response)
+	w.Write(jsonString)
+}
+
+/*
+ * GetSailingGeoJSON
+ *
+ * Returns a sailing's path, by ID, as a GeoJSON feature collection. Non-capacity
+ * sailings get their legs and stops; sailings only found on a capacity route get a
+ * line between the route's terminals.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetSailingGeoJSON(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	sailings := h.store.GetSailings([]string{ps.ByName("id")})
+	if len(sailings) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Sailing not found"})
+		w.Write(jsonString)
+		return
+	}
+
+	sailing := sailings[0]
+	var features []geo.Feature
+	if sailing.NonCapacity != nil {
+		route := models.NonCapacityRoute{Date: sailing.Date, RouteCode: sailing.RouteCode, FromTerminalCode: sailing.FromTerminalCode, ToTerminalCode: sailing.ToTerminalCode}
+		features = geo.NonCapacitySailing(route, *sailing.NonCapacity)
+	} else {
+		route := models.CapacityRoute{Date: sailing.Date, RouteCode: sailing.RouteCode, FromTerminalCode: sailing.FromTerminalCode, ToTerminalCode: sailing.ToTerminalCode}
+		features = geo.CapacitySailing(route, *sailing.Capacity)
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	jsonString, _ := json.Marshal(geo.NewFeatureCollection(features))
+	w.Write(jsonString)
+}
+
+/**************/
+/* V1 Structs */
+/**************/
+
+type Response struct {
+	Schedule  map[string]map[string]models.Route `json:"schedule"`
+	ScrapedAt time.Time                          `json:"scrapedAt"`
+}
+
+/*************/
+/* V1 Routes */
+/*************/
+// V1 routes return data in a different format and only contain upcoming sailings for specific routes
+
+/*
+ * GetAllSailings
+ *
+ * Returns all sailing data
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetAllSailings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	capacityRoute := h.store.GetCapacitySailings()
+	nonCapacityRoute := h.store.GetNonCapacitySailings(db.CurrentServiceDate())
+
+	response := AllDataResponse{
+		CapacityRoutes:    capacityRoute,
+		NonCapacityRoutes: nonCapacityRoute,
+	}
+
+	jsonString, _ := json.Marshal(ConvertV1ResponseToV2Response(response))
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonString)
+}
+
+/*
+ * GetSailingsByDepartureTerminal
+ *
+ * Returns sailing data for given departure
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetSailingsByDepartureTerminal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	departureTerminal := ps.ByName("departureTerminal")
+	capacityRoute := h.store.GetCapacitySailings()
+	nonCapacityRoute := h.store.GetNonCapacitySailings(db.CurrentServiceDate())
+
+	allDataResponse := AllDataResponse{
+		CapacityRoutes:    capacityRoute,
+		NonCapacityRoutes: nonCapacityRoute,
+	}
+
+	jsonString, _ := json.Marshal(ConvertV1ResponseToV2Response(allDataResponse)[departureTerminal])
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonString)
+}
+
+/*
+ * GetSailingsByDepartureAndDestinationTerminals
+ *
+ * Returns sailing data for given departure and destination terminal
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetSailingsByDepartureAndDestinationTerminals(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	departureTerminal := ps.ByName("departureTerminal")
+	destinationTerminal := ps.ByName("destinationTerminal")
+	capacityRoute := h.store.GetCapacitySailings()
+	nonCapacityRoute := h.store.GetNonCapacitySailings(db.CurrentServiceDate())
+
+	allDataResponse := AllDataResponse{
+		CapacityRoutes:    capacityRoute,
+		NonCapacityRoutes: nonCapacityRoute,
+	}
+
+	jsonString, _ := json.Marshal(ConvertV1ResponseToV2Response(allDataResponse)[departureTerminal][destinationTerminal])
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonString)
+}
+
+/****************/
+/* Other Routes */
+/****************/
+
+/*
+ * GetStatus
+ *
+ * Returns the health of the scraped data: the latest run of each scraper and, for every
+ * scraped route, when it was last scraped successfully (see buildStatus)
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	jsonString, _ := json.Marshal(h.buildStatus(time.Now()))
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonString)
+}
+
+/*
+ * HealthCheck
+ *
+ * Returns "Server OK" while the scraped data is fresh. When it is stale (see buildStatus)
+ * returns "Degraded: <reasons>" with 200, or "Unhealthy: <reasons>" with 503 so uptime
+ * monitors notice.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ * @param httprouter.Params ps
+ *
+ * @return void
+ */
+func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := h.buildStatus(time.Now())
+
+	message := "Server OK"
+	code := http.StatusOK
+	switch status.Status {
+	case models.StatusDegraded:
+		message = "Degraded: " + strings.Join(status.Reasons, "; ")
+	case models.StatusUnhealthy:
+		message = "Unhealthy: " + strings.Join(status.Reasons, "; ")
+		code = http.StatusServiceUnavailable
+	}
+
+	jsonString, _ := json.Marshal(message)
 
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	w.Write(jsonString)
 }
 
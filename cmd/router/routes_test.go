@@ -0,0 +1,705 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/events"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func newTestRouter(t *testing.T) (http.Handler, *db.MemoryStore) {
+	t.Helper()
+
+	store := db.NewMemoryStore()
+	today := db.CurrentServiceDate()
+
+	routes := []models.NonCapacityRoute{
+		{Date: today, RouteCode: "TSAPOB", FromTerminalCode: "TSA", ToTerminalCode: "POB", SailingDuration: "1h 20m",
+			Sailings: []models.NonCapacitySailing{{ID: "TSAPOB-" + today + "-0710", DepartureTime: "7:10 am", ArrivalTime: "8:30 am"}}},
+		{Date: today, RouteCode: "FULSWB", FromTerminalCode: "FUL", ToTerminalCode: "SWB", SailingDuration: "35m",
+			Sailings: []models.NonCapacitySailing{{ID: "FULSWB-" + today + "-0900", DepartureTime: "9:00 am", ArrivalTime: "9:35 am"}}},
+		{Date: "2025-10-20", RouteCode: "TSAPOB", FromTerminalCode: "TSA", ToTerminalCode: "POB", SailingDuration: "1h 20m",
+			Sailings: []models.NonCapacitySailing{}},
+	}
+	for _, route := range routes {
+		if err := store.SaveNonCapacityRoute(route); err != nil {
+			t.Fatalf("SaveNonCapacityRoute: %v", err)
+		}
+	}
+
+	return SetupRouter(store, nil), store
+}
+
+func get(t *testing.T, handler http.Handler, path string, v any) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: invalid JSON %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestNonCapacityEndpoints(t *testing.T) {
+	handler, _ := newTestRouter(t)
+
+	var all models.NonCapacityResponse
+	if code := get(t, handler, "/v2/noncapacity", &all); code != http.StatusOK || len(all.Routes) != 2 {
+		t.Errorf("/v2/noncapacity = %d with %d routes, want 200 with today's 2 routes", code, len(all.Routes))
+	}
+
+	var route models.NonCapacityRoute
+	if code := get(t, handler, "/v2/noncapacity/TSAPOB", &route); code != http.StatusOK || len(route.Sailings) != 1 {
+		t.Errorf("/v2/noncapacity/TSAPOB = %d %+v, want today's route", code, route)
+	}
+	if code := get(t, handler, "/v2/noncapacity/TSAPOB?date=2025-10-20", &route); code != http.StatusOK || route.Date != "2025-10-20" {
+		t.Errorf("/v2/noncapacity/TSAPOB?date=2025-10-20 = %d %+v, want that date's route", code, route)
+	}
+	if code := get(t, handler, "/v2/noncapacity/TSAPOB?date=20-10-2025", nil); code != http.StatusBadRequest {
+		t.Errorf("invalid date = %d, want 400", code)
+	}
+	if code := get(t, handler, "/v2/noncapacity/NOPE", nil); code != http.StatusNotFound {
+		t.Errorf("unknown route = %d, want 404", code)
+	}
+
+	var list models.NonCapacityRoutesResponse
+	if code := get(t, handler, "/v2/routes/noncapacity", &list); code != http.StatusOK || len(list.Routes) != 2 {
+		t.Errorf("/v2/routes/noncapacity = %d %+v, want today's 2 routes, once each", code, list)
+	}
+	if code := get(t, handler, "/v2/routes/noncapacity?routeCodes=FULSWB", &list); code != http.StatusOK || len(list.Routes) != 1 || list.Routes[0].RouteCode != "FULSWB" {
+		t.Errorf("/v2/routes/noncapacity?routeCodes=FULSWB = %d %+v, want only FULSWB", code, list)
+	}
+}
+
+func TestV1Endpoints(t *testing.T) {
+	handler, _ := newTestRouter(t)
+
+	var route models.Route
+	if code := get(t, handler, "/api/FUL/SWB", &route); code != http.StatusOK || len(route.Sailings) != 1 || route.Sailings[0].DepartureTime != "9:00 am" {
+		t.Errorf("/api/FUL/SWB = %d %+v, want the 9:00 am sailing", code, route)
+	}
+}
+
+func TestCapacityEndpointsWithoutData(t *testing.T) {
+	handler, _ := newTestRouter(t)
+
+	var message string
+	if code := get(t, handler, "/v2/capacity", &message); code != http.StatusOK || message != "BC Ferries Data Currently Down" {
+		t.Errorf("/v2/capacity with no data = %d %q", code, message)
+	}
+	if code := get(t, handler, "/v2/capacity/TSASWB", nil); code != http.StatusNotFound {
+		t.Errorf("/v2/capacity/TSASWB with no data = %d, want 404", code)
+	}
+}
+
+func TestStatusAndHealthCheck(t *testing.T) {
+	handler, store := newTestRouter(t)
+
+	var message string
+	var status models.StatusResponse
+
+	// Nothing scraped yet
+	if code := get(t, handler, "/healthcheck", &message); code != http.StatusOK || message != "Degraded: no scrape has finished yet" {
+		t.Errorf("/healthcheck before any scrape = %d %q", code, message)
+	}
+
+	now := time.Now()
+	saveRun := func(finishedAt time.Time, results ...models.ScrapeRouteResult) {
+		t.Helper()
+		run := models.ScrapeRun{Kind: models.ScrapeKindNonCapacity, StartedAt: finishedAt.Add(-time.Minute), FinishedAt: finishedAt, Routes: results}
+		if err := store.SaveScrapeRun(run); err != nil {
+			t.Fatalf("SaveScrapeRun: %v", err)
+		}
+	}
+
+	// Fresh data
+	saveRun(now.Add(-10*time.Minute),
+		models.ScrapeRouteResult{RouteCode: "TSAPOB", Success: true, SailingCount: 1},
+		models.ScrapeRouteResult{RouteCode: "FULSWB", Success: true, SailingCount: 1})
+	if code := get(t, handler, "/healthcheck", &message); code != http.StatusOK || message != "Server OK" {
+		t.Errorf("/healthcheck with fresh data = %d %q", code, message)
+	}
+	if code := get(t, handler, "/v2/status", &status); code != http.StatusOK || status.Status != models.StatusOK || len(status.Routes) != 2 {
+		t.Fatalf("/v2/status with fresh data = %d %+v", code, status)
+	}
+	if route := status.Routes[1]; route.RouteCode != "TSAPOB" || route.LastSuccessAgo != "10m ago" || route.Status != models.StatusOK {
+		t.Errorf("TSAPOB freshness = %+v, want ok, last success 10m ago", route)
+	}
+	if run := status.LastRuns[models.ScrapeKindNonCapacity]; len(run.Routes) != 2 {
+		t.Errorf("last noncapacity run = %+v, want 2 route results", run)
+	}
+
+	// One route keeps failing: degraded once its last success is older than the threshold
+	saveRun(now.Add(-time.Minute),
+		models.ScrapeRouteResult{RouteCode: "TSAPOB", Success: false, Error: "invalid page: interstitial page"},
+		models.ScrapeRouteResult{RouteCode: "FULSWB", Success: true, SailingCount: 1})
+	if code := get(t, handler, "/v2/status", &status); code != http.StatusOK || status.Status != models.StatusOK {
+		t.Errorf("/v2/status with one failure inside the threshold = %d %+v", code, status)
+	}
+
+	store = db.NewMemoryStore()
+	handler = SetupRouter(store, nil)
+	saveRun(now.Add(-5*time.Hour),
+		models.ScrapeRouteResult{RouteCode: "TSAPOB", Success: true},
+		models.ScrapeRouteResult{RouteCode: "FULSWB", Success: true})
+	saveRun(now.Add(-time.Minute),
+		models.ScrapeRouteResult{RouteCode: "TSAPOB", Success: false, Error: "timeout"},
+		models.ScrapeRouteResult{RouteCode: "FULSWB", Success: true})
+	if code := get(t, handler, "/v2/status", &status); code != http.StatusOK || status.Status != models.StatusDegraded {
+		t.Fatalf("/v2/status with a stale route = %d %+v", code, status)
+	}
+	if route := status.Routes[1]; route.Status != models.StatusDegraded || route.LastSuccessAgo != "5h ago" || route.LastError != "timeout" {
+		t.Errorf("stale TSAPOB = %+v", route)
+	}
+	if code := get(t, handler, "/healthcheck", &message); code != http.StatusOK || !strings.HasPrefix(message, "Degraded: 1/2 route(s)") {
+		t.Errorf("/healthcheck with a stale route = %d %q", code, message)
+	}
+
+	// Every scrape failing for over a day
+	store = db.NewMemoryStore()
+	handler = SetupRouter(store, nil)
+	saveRun(now.Add(-30*time.Hour), models.ScrapeRouteResult{RouteCode: "TSAPOB", Success: true})
+	saveRun(now.Add(-time.Minute), models.ScrapeRouteResult{RouteCode: "TSAPOB", Success: false, Error: "timeout"})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthcheck/", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "Unhealthy") {
+		t.Errorf("/healthcheck with day-old data = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPlanEndpoint(t *testing.T) {
+	handler, _ := newTestRouter(t)
+
+	var plan models.PlanResponse
+	if code := get(t, handler, "/v2/plan?from=ful&to=SWB&departAfter=8:00+am", &plan); code != http.StatusOK || len(plan.Itineraries) != 1 {
+		t.Fatalf("/v2/plan FUL to SWB = %d %+v, want one itinerary", code, plan)
+	}
+	if itinerary := plan.Itineraries[0]; itinerary.DepartureTime != "9:00 am" || itinerary.Legs[0].SailingID != "FULSWB-"+db.CurrentServiceDate()+"-0900" {
+		t.Errorf("itinerary = %+v, want the 9:00 am FULSWB sailing", itinerary)
+	}
+	if plan.Mode != models.PlanModeWalkOn || plan.Sort != models.PlanSortArrival || plan.DepartAfter != "8:00 am" {
+		t.Errorf("defaults = %+v", plan)
+	}
+
+	if code := get(t, handler, "/v2/plan?from=FUL&to=SWB&departAfter=10:00", &plan); code != http.StatusOK || len(plan.Itineraries) != 0 {
+		t.Errorf("/v2/plan after the last sailing = %d %+v, want no itineraries", code, plan)
+	}
+
+	for _, path := range []string{
+		"/v2/plan?from=FUL",
+		"/v2/plan?from=FUL&to=FUL",
+		"/v2/plan?from=FUL&to=SWB&date=tomorrow",
+		"/v2/plan?from=FUL&to=SWB&departAfter=soon",
+		"/v2/plan?from=FUL&to=SWB&mode=bicycle",
+		"/v2/plan?from=FUL&to=SWB&limit=50",
+	} {
+		if code := get(t, handler, path, nil); code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", path, code)
+		}
+	}
+}
+
+func TestGTFSEndpoint(t *testing.T) {
+	previous := config.ScheduleHorizonDays
+	config.ScheduleHorizonDays = 14
+	defer func() { config.ScheduleHorizonDays = previous }()
+
+	handler, _ := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/gtfs.zip", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" || !strings.HasPrefix(rec.Body.String(), "PK") {
+		t.Errorf("/v2/gtfs.zip = %d %s, want a zip", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	rec = httptest.NewRecorder()
+	SetupRouter(db.NewMemoryStore(), nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/gtfs.zip", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/v2/gtfs.zip with no schedules = %d, want 503", rec.Code)
+	}
+}
+
+func TestGTFSRealtimeEndpoint(t *testing.T) {
+	handler, store := newTestRouter(t)
+	today := db.CurrentServiceDate()
+	store.SaveCapacityRoute(models.CapacityRoute{Date: today, RouteCode: "TSASWB", FromTerminalCode: "TSA", ToTerminalCode: "SWB", SailingDuration: "1h 35m",
+		Sailings: []models.CapacitySailing{{ID: "TSASWB-" + today + "-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "cancelled"}}})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/gtfs-rt", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-protobuf" || !strings.Contains(rec.Body.String(), "TSASWB-"+today+"-1100") {
+		t.Errorf("/v2/gtfs-rt = %d %s %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	var feed struct {
+		Entity []struct {
+			ID string `json:"id"`
+		} `json:"entity"`
+	}
+	if code := get(t, handler, "/v2/gtfs-rt?format=json", &feed); code != http.StatusOK || len(feed.Entity) != 2 || feed.Entity[1].ID != "alert-TSASWB-"+today+"-1100" {
+		t.Errorf("/v2/gtfs-rt?format=json = %d %+v, want a trip update and an alert", code, feed)
+	}
+}
+
+func TestCalendarEndpoints(t *testing.T) {
+	handler, store := newTestRouter(t)
+	today := db.CurrentServiceDate()
+	store.SaveCapacityRoute(models.CapacityRoute{Date: today, RouteCode: "TSASWB", FromTerminalCode: "TSA", ToTerminalCode: "SWB", SailingDuration: "1h 35m",
+		Sailings: []models.CapacitySailing{{ID: "TSASWB-" + today + "-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "future"}}})
+
+	tests := []struct {
+		path string
+		code int
+		uid  string
+	}{
+		{"/v2/noncapacity/TSAPOB/calendar.ics", http.StatusOK, "TSAPOB-" + today + "-0710"},
+		{"/v2/noncapacity/TSAPOB/calendar.ics?date=" + today, http.StatusOK, "TSAPOB-" + today + "-0710"},
+		{"/v2/noncapacity/TSAPOB/sailings/TSAPOB-" + today + "-0710/calendar.ics", http.StatusOK, "TSAPOB-" + today + "-0710"},
+		{"/v2/capacity/TSASWB/calendar.ics", http.StatusOK, "TSASWB-" + today + "-1100"},
+		{"/v2/capacity/TSASWB/sailings/TSASWB-" + today + "-1100/calendar.ics", http.StatusOK, "TSASWB-" + today + "-1100"},
+		{"/v2/noncapacity/TSAPOB/calendar.ics?date=tomorrow", http.StatusBadRequest, ""},
+		{"/v2/noncapacity/XXXYYY/calendar.ics", http.StatusNotFound, ""},
+		{"/v2/noncapacity/TSAPOB/sailings/TSAPOB-" + today + "-2359/calendar.ics", http.StatusNotFound, ""},
+		{"/v2/capacity/TSASWB/sailings/nonsense/calendar.ics", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s = %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		body := rec.Body.String()
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar") || !strings.Contains(body, "UID:"+tt.uid+"@bcferriesapi.ca\r\n") || strings.Count(body, "BEGIN:VEVENT") != 1 {
+			t.Errorf("%s = %s\n%s", tt.path, rec.Header().Get("Content-Type"), body)
+		}
+	}
+}
+
+func TestStreamEndpoint(t *testing.T) {
+	store := db.NewMemoryStore()
+	bus := events.NewBus(10)
+	server := httptest.NewServer(SetupRouter(store, bus))
+	defer server.Close()
+
+	bus.Publish([]models.ChangeEvent{{Type: models.ChangeFillChanged, RouteCode: "TSASWB", SailingIDs: []string{"TSASWB-2025-10-20-0900"}}})
+
+	// Resume from before the first event, for SWBTSA and TSASWB
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/v2/stream?routeCodes=SWBTSA,TSASWB", nil)
+	request.Header.Set("Last-Event-ID", "0")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("/v2/stream = %d %s", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	lines := bufio.NewScanner(response.Body)
+	next := func() string {
+		t.Helper()
+		for lines.Scan() {
+			if line := lines.Text(); strings.HasPrefix(line, "event: ") || strings.HasPrefix(line, "data: ") {
+				return line
+			}
+		}
+		t.Fatalf("stream ended: %v", lines.Err())
+		return ""
+	}
+
+	// 0 is from before this bus started
+	if line := next(); line != "event: resync" {
+		t.Errorf("first event = %q, want a resync", line)
+	}
+	next() // Its data
+
+	bus.Publish([]models.ChangeEvent{
+		{Type: models.ChangeCancelled, RouteCode: "DUKTSA", SailingIDs: []string{"DUKTSA-2025-10-20-1030"}},
+		{Type: models.ChangeCancelled, RouteCode: "SWBTSA", SailingIDs: []string{"SWBTSA-2025-10-20-1100"}},
+	})
+	if line := next(); line != "event: cancelled" {
+		t.Errorf("event = %q, want cancelled", line)
+	}
+	var event models.ChangeEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(next(), "data: ")), &event); err != nil || event.RouteCode != "SWBTSA" || event.SailingIDs[0] != "SWBTSA-2025-10-20-1100" {
+		t.Errorf("event = %+v, %v, want the SWBTSA cancellation", event, err)
+	}
+
+	// Without a bus there is nothing to stream
+	rec := httptest.NewRecorder()
+	SetupRouter(store, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/stream", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/v2/stream without a bus = %d, want 503", rec.Code)
+	}
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	store := db.NewMemoryStore()
+	handler := SetupRouter(store, nil)
+
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Disabled without an admin token
+	config.Webhooks.AdminToken = ""
+	if rec := request(http.MethodGet, "/v2/webhooks", "", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /v2/webhooks without a configured token = %d, want 503", rec.Code)
+	}
+	config.Webhooks.AdminToken = "admin"
+	defer func() { config.Webhooks.AdminToken = "" }()
+	if rec := request(http.MethodGet, "/v2/webhooks", "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /v2/webhooks with a wrong token = %d, want 401", rec.Code)
+	}
+
+	for _, body := range []string{
+		`{"url": "ftp://example.com/hook"}`,
+		`{"url": "https://example.com/hook", "eventTypes": ["resync"]}`,
+		`{"url": "https://example.com/hook", "carFillAbove": 120}`,
+		`{"url": "https://example.com/hook", "routeCode": "TSASWB"}`,
+	} {
+		if rec := request(http.MethodPost, "/v2/webhooks", "admin", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s = %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec := request(http.MethodPost, "/v2/webhooks", "admin", `{"url": "https://example.com/hook", "eventTypes": ["fill-changed"], "routeCodes": ["tsaswb"], "carFillAbove": 90}`)
+	var created models.WebhookSubscription
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &created) != nil {
+		t.Fatalf("POST /v2/webhooks = %d %s", rec.Code, rec.Body.String())
+	}
+	if created.ID == 0 || created.Secret == "" || created.RouteCodes[0] != "TSASWB" || created.CarFillAbove == nil || *created.CarFillAbove != 90 {
+		t.Errorf("created = %+v", created)
+	}
+
+	// The secret is only returned on creation
+	var fetched models.WebhookSubscription
+	rec = request(http.MethodGet, "/v2/webhooks/1", "admin", "")
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &fetched) != nil || fetched.Secret != "" || fetched.URL != "https://example.com/hook" {
+		t.Errorf("GET /v2/webhooks/1 = %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = request(http.MethodPut, "/v2/webhooks/1", "admin", `{"url": "https://example.com/other", "eventTypes": ["cancelled"], "terminalCodes": ["SGI"]}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"terminalCodes":["SGI"]`) {
+		t.Errorf("PUT /v2/webhooks/1 = %d %s", rec.Code, rec.Body.String())
+	}
+	if stored := store.GetWebhookSubscription(1); stored == nil || stored.Secret != created.Secret || stored.CarFillAbove != nil {
+		t.Errorf("stored after update = %+v, want the same secret and no threshold", stored)
+	}
+
+	store.SaveWebhookDelivery(models.WebhookDelivery{SubscriptionID: 1, EventID: 7, EventType: models.ChangeCancelled, Attempt: 1, StatusCode: 204, Success: true})
+	var deliveries []models.WebhookDelivery
+	rec = request(http.MethodGet, "/v2/webhooks/1/deliveries?limit=10", "admin", "")
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &deliveries) != nil || len(deliveries) != 1 {
+		t.Errorf("GET /v2/webhooks/1/deliveries = %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := request(http.MethodDelete, "/v2/webhooks/1", "admin", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE /v2/webhooks/1 = %d", rec.Code)
+	}
+	for _, path := range []string{"/v2/webhooks/1", "/v2/webhooks/1/deliveries", "/v2/webhooks/abc"} {
+		if rec := request(http.MethodGet, path, "admin", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s after delete = %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestFillEndpoints(t *testing.T) {
+	store := db.NewMemoryStore()
+	handler := SetupRouter(store, nil)
+
+	today := db.CurrentServiceDate()
+	day, _ := time.Parse("2006-01-02", today)
+	lastWeek := day.AddDate(0, 0, -7).Format("2006-01-02")
+
+	route := func(date string, carFill int) models.CapacityRoute {
+		return models.CapacityRoute{Date: date, RouteCode: "TSASWB", FromTerminalCode: "TSA", ToTerminalCode: "SWB", SailingDuration: "1h 35m",
+			Sailings: []models.CapacitySailing{{ID: "TSASWB-" + date + "-2300", DepartureTime: "11:00 pm", ScheduledTime: "11:00 pm", SailingStatus: "future", Fill: carFill, CarFill: carFill}}}
+	}
+
+	// Last week's 11:00 pm sailing filled up to 100% car fill
+	departure, _ := models.ServiceTime(lastWeek, 23*60)
+	for _, sample := range []struct {
+		before  time.Duration
+		carFill int
+	}{{6 * time.Hour, 20}, {2 * time.Hour, 60}, {10 * time.Minute, 100}} {
+		store.SaveFillSamples(route(lastWeek, sample.carFill), departure.Add(-sample.before))
+	}
+	store.SaveCapacityRoute(route(today, 10))
+
+	var history models.FillHistory
+	if code := get(t, handler, "/v2/capacity/TSASWB/sailings/TSASWB-"+lastWeek+"-2300/fill", &history); code != http.StatusOK || len(history.Samples) != 3 || history.Samples[2].MinutesBeforeDeparture != 10 {
+		t.Errorf("fill history = %d %+v", code, history)
+	}
+	if code := get(t, handler, "/v2/capacity/SWBTSA/sailings/TSASWB-"+lastWeek+"-2300/fill", nil); code != http.StatusNotFound {
+		t.Errorf("fill history on another route = %d, want 404", code)
+	}
+
+	var prediction models.FillPrediction
+	if code := get(t, handler, "/v2/capacity/TSASWB/sailings/TSASWB-"+today+"-2300/prediction", &prediction); code != http.StatusOK {
+		t.Fatalf("prediction = %d", code)
+	}
+	if prediction.SampleSize != 1 || prediction.Current.CarFill != 10 || prediction.Predicted.CarFill <= 10 || prediction.FullRate != 1 || prediction.ScheduledTime != "11:00 pm" {
+		t.Errorf("prediction = %+v", prediction)
+	}
+	if code := get(t, handler, "/v2/capacity/TSASWB/sailings/TSASWB-"+today+"-0700/prediction", nil); code != http.StatusNotFound {
+		t.Errorf("prediction for an unknown sailing = %d, want 404", code)
+	}
+}
+
+func TestOnTimeStatsEndpoint(t *testing.T) {
+	store := db.NewMemoryStore()
+	handler := SetupRouter(store, nil)
+
+	today := db.CurrentServiceDate()
+	store.SaveSailingDepartures(models.CapacityRoute{Date: today, RouteCode: "TSASWB", SailingDuration: "1h 35m", Sailings: []models.CapacitySailing{
+		{ID: "TSASWB-" + today + "-0700", ScheduledTime: "7:00 am", ActualDepartureTime: "7:04 am", ActualArrivalTime: "8:37 am", SailingStatus: "past", VesselName: "Spirit of Vancouver Island"},
+		{ID: "TSASWB-" + today + "-0900", ScheduledTime: "9:00 am", ActualDepartureTime: "9:12 am", ETA: "10:46 am", SailingStatus: "current", VesselName: "Coastal Celebration"},
+		{ID: "TSASWB-" + today + "-1100", ScheduledTime: "11:00 am", SailingStatus: "future"},
+	}})
+	store.SaveSailingDepartures(models.CapacityRoute{Date: today, RouteCode: "SWBTSA", Sailings: []models.CapacitySailing{
+		{ID: "SWBTSA-" + today + "-0700", ScheduledTime: "7:00 am", ActualDepartureTime: "7:00 am", SailingStatus: "past"},
+	}})
+
+	var stats models.OnTimeStats
+	if code := get(t, handler, "/v2/stats/ontime", &stats); code != http.StatusOK {
+		t.Fatalf("/v2/stats/ontime = %d", code)
+	}
+	if stats.To != today || stats.Overall.Count != 3 || stats.Overall.MaxDelayMinutes != 12 || len(stats.ByRoute) != 2 || len(stats.ByVessel) != 2 || len(stats.ByHour) != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	stats = models.OnTimeStats{}
+	if code := get(t, handler, "/v2/stats/ontime/?routeCodes=tsaswb", &stats); code != http.StatusOK || stats.Overall.Count != 2 || stats.Overall.OnTimeRate != 0.5 {
+		t.Errorf("TSASWB stats = %d %+v", code, stats.Overall)
+	}
+
+	for _, query := range []string{"?from=2025-10-21&to=2025-10-20", "?from=2024-01-01&to=2025-10-20", "?to=yesterday"} {
+		if code := get(t, handler, "/v2/stats/ontime"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("/v2/stats/ontime%s = %d, want 400", query, code)
+		}
+	}
+}
+
+func TestTerminalEndpoints(t *testing.T) {
+	handler, _ := newTestRouter(t)
+
+	var list models.TerminalsResponse
+	if code := get(t, handler, "/v2/terminals", &list); code != http.StatusOK || len(list.Terminals) != 8 || list.Terminals[0].Code != "FUL" {
+		t.Errorf("/v2/terminals = %d %+v", code, list)
+	}
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if code := get(t, handler, "/v2/terminals?format=geojson", &collection); code != http.StatusOK || collection.Type != "FeatureCollection" || len(collection.Features) != 8+32 {
+		t.Errorf("/v2/terminals?format=geojson = %d with %d features", code, len(collection.Features))
+	}
+
+	var details models.TerminalDetails
+	if code := get(t, handler, "/v2/terminals/ful", &details); code != http.StatusOK || details.Name != "Fulford Harbour" {
+		t.Fatalf("/v2/terminals/ful = %d %+v", code, details)
+	}
+	// SWB-FUL capacity, then the non-capacity routes to and from SWB
+	if len(details.Routes) != 3 || details.Routes[0].RouteCode != "SWBFUL" || !details.Routes[0].Capacity || details.Routes[2].Capacity {
+		t.Errorf("FUL routes = %+v", details.Routes)
+	}
+	if details.NextDepartures == nil {
+		t.Error("nextDepartures is null, want an array")
+	}
+	if code := get(t, handler, "/v2/terminals/XXX", nil); code != http.StatusNotFound {
+		t.Errorf("/v2/terminals/XXX = %d, want 404", code)
+	}
+
+	// Otter Bay, then Lyall Harbour, from a point on Pender Island; Village Bay is just out of range
+	var nearby models.NearbyTerminalsResponse
+	if code := get(t, handler, "/v2/terminals/nearby?lat=48.79&lon=-123.29&radiusKm=6.58", &nearby); code != http.StatusOK || len(nearby.Terminals) != 2 || nearby.Terminals[0].Code != "POB" || nearby.Terminals[1].Code != "PST" {
+		t.Fatalf("nearby = %d %+v", code, nearby)
+	}
+	if nearby.Terminals[0].DistanceKm <= 0 || nearby.Terminals[0].DistanceKm > nearby.Terminals[1].DistanceKm {
+		t.Errorf("nearby distances = %+v", nearby.Terminals)
+	}
+	for _, query := range []string{"", "?lat=91&lon=-123", "?lat=48&lon=west", "?lat=48&lon=-123&radiusKm=0", "?lat=48&lon=-123&radiusKm=501"} {
+		if code := get(t, handler, "/v2/terminals/nearby"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("/v2/terminals/nearby%s = %d, want 400", query, code)
+		}
+	}
+}
+
+func TestNextDepartures(t *testing.T) {
+	date := "2025-10-20"
+	capacity := []models.CapacityRoute{{Date: date, RouteCode: "SWBTSA", FromTerminalCode: "SWB", ToTerminalCode: "TSA", SailingDuration: "1h 35m",
+		Sailings: []models.CapacitySailing{
+			{ID: "SWBTSA-2025-10-20-0700", DepartureTime: "7:02 am", ScheduledTime: "7:00 am", ActualDepartureTime: "7:02 am", SailingStatus: "past"},
+			{ID: "SWBTSA-2025-10-20-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "future", CarFill: 80},
+			{ID: "SWBTSA-2025-10-20-1300", DepartureTime: "1:00 pm", ScheduledTime: "1:00 pm", SailingStatus: "cancelled"},
+		}}}
+	nonCapacity := []models.NonCapacityRoute{
+		{Date: date, RouteCode: "SWBTSA", FromTerminalCode: "SWB", ToTerminalCode: "TSA",
+			Sailings: []models.NonCapacitySailing{{ID: "SWBTSA-2025-10-20-1100", DepartureTime: "11:00 am", ArrivalTime: "12:35 pm"}}},
+		{Date: date, RouteCode: "SWBPOB", FromTerminalCode: "SWB", ToTerminalCode: "POB",
+			Sailings: []models.NonCapacitySailing{
+				{ID: "SWBPOB-2025-10-20-0900", DepartureTime: "9:00 am", ArrivalTime: "9:40 am"},
+				{ID: "SWBPOB-2025-10-20-1200", DepartureTime: "12:00 pm", ArrivalTime: "12:40 pm"},
+			}},
+		{Date: date, RouteCode: "POBSWB", FromTerminalCode: "POB", ToTerminalCode: "SWB",
+			Sailings: []models.NonCapacitySailing{{ID: "POBSWB-2025-10-20-1000", DepartureTime: "10:00 am", ArrivalTime: "10:40 am"}}},
+	}
+
+	now, _ := models.ServiceTime(date, 10*60)
+	departures := nextDepartures(capacity, nonCapacity, "SWB", now, 10)
+
+	var ids []string
+	for _, departure := range departures {
+		ids = append(ids, departure.SailingID)
+	}
+	if want := "SWBTSA-2025-10-20-1100 SWBPOB-2025-10-20-1200 SWBTSA-2025-10-20-1300"; strings.Join(ids, " ") != want {
+		t.Fatalf("next departures = %v, want %s", ids, want)
+	}
+	if first := departures[0]; first.CarFill == nil || *first.CarFill != 80 || first.ArrivalAt == nil || first.SailingStatus != "future" {
+		t.Errorf("capacity departure = %+v", first)
+	}
+	if departures[1].Fill != nil || departures[2].SailingStatus != "cancelled" {
+		t.Errorf("departures = %+v", departures)
+	}
+
+	if limited := nextDepartures(capacity, nonCapacity, "SWB", now, 1); len(limited) != 1 {
+		t.Errorf("limit 1 = %d departures", len(limited))
+	}
+}
+
+func TestGeoJSONEndpoints(t *testing.T) {
+	handler, store := newTestRouter(t)
+	today := db.CurrentServiceDate()
+	store.SaveCapacityRoute(models.CapacityRoute{Date: today, RouteCode: "TSASWB", FromTerminalCode: "TSA", ToTerminalCode: "SWB", SailingDuration: "1h 35m",
+		Sailings: []models.CapacitySailing{{ID: "TSASWB-" + today + "-1100", DepartureTime: "11:00 am", ScheduledTime: "11:00 am", SailingStatus: "future"}}})
+
+	type collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID       string         `json:"id"`
+			Geometry map[string]any `json:"geometry"`
+		} `json:"features"`
+	}
+
+	// Sailings without legs get a line between the route's terminals and a point at each end
+	var route collection
+	if code := get(t, handler, "/v2/noncapacity/TSAPOB/geojson", &route); code != http.StatusOK || route.Type != "FeatureCollection" || len(route.Features) != 3 {
+		t.Fatalf("route GeoJSON = %d %+v", code, route)
+	}
+	if id := route.Features[0].ID; id != "TSAPOB-"+today+"-0710/leg/1" {
+		t.Errorf("first feature = %s", id)
+	}
+
+	var sailing collection
+	if code := get(t, handler, "/v2/sailings/FULSWB-"+today+"-0900/geojson", &sailing); code != http.StatusOK || len(sailing.Features) != 3 || sailing.Features[2].ID != "FULSWB-"+today+"-0900/stop/2" {
+		t.Errorf("sailing GeoJSON = %d %+v", code, sailing)
+	}
+	if code := get(t, handler, "/v2/sailings/TSASWB-"+today+"-1100/geojson", &sailing); code != http.StatusOK || len(sailing.Features) != 3 {
+		t.Errorf("capacity sailing GeoJSON = %d %+v", code, sailing)
+	}
+
+	for path, want := range map[string]int{
+		"/v2/noncapacity/TSAPOB/geojson?date=10-20":   http.StatusBadRequest,
+		"/v2/noncapacity/XXXYYY/geojson":              http.StatusNotFound,
+		"/v2/sailings/TSAPOB-2025-10-20-2359/geojson": http.StatusNotFound,
+		"/v2/sailings/nonsense/geojson":               http.StatusNotFound,
+	} {
+		if code := get(t, handler, path, nil); code != want {
+			t.Errorf("%s = %d, want %d", path, code, want)
+		}
+	}
+}
+
+func TestSailingEndpoints(t *testing.T) {
+	handler, store := newTestRouter(t)
+	today := db.CurrentServiceDate()
+	store.SaveCapacityRoute(models.CapacityRoute{Date: today, RouteCode: "TSAPOB", FromTerminalCode: "TSA", ToTerminalCode: "POB", SailingDuration: "1h 20m",
+		Sailings: []models.CapacitySailing{{ID: "TSAPOB-" + today + "-0710", DepartureTime: "7:10 am", ScheduledTime: "7:10 am", CarFill: 55}}})
+
+	var sailing models.SailingDetails
+	if code := get(t, handler, "/v2/sailings/TSAPOB-"+today+"-0710", &sailing); code != http.StatusOK || sailing.RouteCode != "TSAPOB" || sailing.Date != today {
+		t.Fatalf("GET sailing = %d %+v", code, sailing)
+	}
+	if sailing.Capacity == nil || sailing.Capacity.CarFill != 55 || sailing.NonCapacity == nil || sailing.NonCapacity.ArrivalTime != "8:30 am" {
+		t.Errorf("sailing on both kinds of route = %+v", sailing)
+	}
+	if code := get(t, handler, "/v2/sailings/TSAPOB-"+today+"-2359", nil); code != http.StatusNotFound {
+		t.Errorf("unknown sailing = %d, want 404", code)
+	}
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+	batchGet := func(body string) *httptest.ResponseRecorder { return post("/v2/sailings:batchGet", body) }
+
+	rec := batchGet(`{"ids": ["FULSWB-` + today + `-0900", "TSAPOB-2025-10-20-0710", "TSAPOB-` + today + `-0710", "FULSWB-` + today + `-0900"]}`)
+	var batch models.SailingsBatchGetResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &batch); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("batchGet = %d %s", rec.Code, rec.Body)
+	}
+	if len(batch.Sailings) != 2 || batch.Sailings[0].RouteCode != "FULSWB" || batch.Sailings[1].RouteCode != "TSAPOB" {
+		t.Errorf("batchGet sailings = %+v", batch.Sailings)
+	}
+	if len(batch.NotFound) != 1 || batch.NotFound[0] != "TSAPOB-2025-10-20-0710" {
+		t.Errorf("batchGet notFound = %v", batch.NotFound)
+	}
+
+	var list models.SailingsResponse
+	if code := get(t, handler, "/v2/sailings", &list); code != http.StatusOK || len(list.Sailings) != 2 || list.Sailings[0].ID != "TSAPOB-"+today+"-0710" {
+		t.Errorf("/v2/sailings = %d %+v, want today's 2 sailings, soonest first", code, list.Sailings)
+	}
+	if code := get(t, handler, "/v2/sailings?kind=noncapacity&from=ful&departAfter=8:00+am", &list); code != http.StatusOK || len(list.Sailings) != 1 || list.Sailings[0].RouteCode != "FULSWB" {
+		t.Errorf("/v2/sailings filtered = %d %+v, want the FULSWB sailing", code, list.Sailings)
+	}
+	for _, query := range []string{"date=tomorrow", "kind=ferry", "departAfter=noon", "limit=0"} {
+		if code := get(t, handler, "/v2/sailings?"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("/v2/sailings?%s = %d, want 400", query, code)
+		}
+	}
+
+	tooMany := `{"ids": [` + strings.Repeat(`"x", `, 100) + `"x"]}`
+	for _, body := range []string{`{}`, `{"ids": []}`, `{"ids": [""]}`, `{"id": ["x"]}`, tooMany, `not json`} {
+		if rec := batchGet(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("batchGet %.40s = %d, want 400", body, rec.Code)
+		}
+	}
+
+	// Only the literal ":batchGet" suffix is the batch endpoint
+	for _, path := range []string{"/v2/sailingsfoo", "/v2/sailings:delete", "/v2/sailings:batchget"} {
+		if rec := post(path, `{"ids": ["x"]}`); rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s = %d, want 404", path, rec.Code)
+		}
+	}
+}
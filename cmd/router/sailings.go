@@ -0,0 +1,150 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+const (
+	// Most sailings one POST /v2/sailings:batchGet can look up
+	maxBatchGetSailings = 100
+
+	defaultListSailings = 50
+	maxListSailings     = 500
+)
+
+/*
+ * parseSailingsQuery
+ *
+ * Reads the GET /v2/sailings query parameters (see ListSailings), filling in defaults
+ *
+ * @param url.Values values
+ * @param string today - service date, YYYY-MM-DD
+ *
+ * @return models.SailingFilter
+ * @return error - message for a 400 response
+ */
+func parseSailingsQuery(values url.Values, today string) (models.SailingFilter, error) {
+	filter := models.SailingFilter{
+		Date:             values.Get("date"),
+		FromTerminalCode: strings.ToUpper(strings.TrimSpace(values.Get("from"))),
+		VesselName:       strings.TrimSpace(values.Get("vessel")),
+		SailingStatus:    strings.ToLower(strings.TrimSpace(values.Get("status"))),
+	}
+
+	if filter.Date == "" {
+		filter.Date = today
+	}
+	if _, err := time.Parse("2006-01-02", filter.Date); err != nil {
+		return filter, errors.New("Invalid date, expected YYYY-MM-DD")
+	}
+
+	switch kind := values.Get("kind"); kind {
+	case "", "capacity", "noncapacity":
+		filter.Kind = kind
+	default:
+		return filter, errors.New("Invalid kind, expected capacity or noncapacity")
+	}
+
+	for _, code := range strings.Split(values.Get("routeCodes"), ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			filter.RouteCodes = append(filter.RouteCodes, code)
+		}
+	}
+
+	// Clock times on the service date, e.g. "7:00 am" or "13:30"
+	serviceTime := func(param string) (*time.Time, error) {
+		if values.Get(param) == "" {
+			return nil, nil
+		}
+		minutes, ok := models.ParseClock(values.Get(param))
+		if !ok {
+			return nil, fmt.Errorf("Invalid %s, expected a time like 7:00 am or 13:30", param)
+		}
+		at, _ := models.ServiceTime(filter.Date, minutes)
+		return &at, nil
+	}
+	var err error
+	if filter.DepartAfter, err = serviceTime("departAfter"); err != nil {
+		return filter, err
+	}
+	if filter.DepartBefore, err = serviceTime("departBefore"); err != nil {
+		return filter, err
+	}
+
+	if filter.Limit, err = parseLimit(values.Get("limit"), defaultListSailings, maxListSailings); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+/*
+ * parseBatchGetSailings
+ *
+ * Reads the body of POST /v2/sailings:batchGet:
+ *
+ *   {"ids": ["TSAPOB-2025-10-20-0710", "SWBTSA-2025-10-20-1100"]}
+ *
+ * @param io.Reader body
+ *
+ * @return []string - the IDs, without duplicates
+ * @return error - message for a 400 response
+ */
+func parseBatchGetSailings(body io.Reader) ([]string, error) {
+	var request models.SailingsBatchGetRequest
+	decoder := json.NewDecoder(io.LimitReader(body, 64<<10))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("Invalid request body: %v", err)
+	}
+
+	if len(request.IDs) == 0 {
+		return nil, errors.New("Invalid ids, expected at least one sailing ID")
+	}
+	if len(request.IDs) > maxBatchGetSailings {
+		return nil, fmt.Errorf("Invalid ids, expected at most %d sailing IDs", maxBatchGetSailings)
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range request.IDs {
+		if id == "" {
+			return nil, errors.New("Invalid ids, expected non-empty sailing IDs")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+/*
+ * notFoundSailings
+ *
+ * @param []string ids - IDs looked up
+ * @param []models.SailingDetails sailings - the ones found
+ *
+ * @return []string - the IDs not found, in order
+ */
+func notFoundSailings(ids []string, sailings []models.SailingDetails) []string {
+	found := make(map[string]bool, len(sailings))
+	for _, sailing := range sailings {
+		found[sailing.ID] = true
+	}
+	notFound := []string{}
+	for _, id := range ids {
+		if !found[id] {
+			notFound = append(notFound, id)
+		}
+	}
+	return notFound
+}
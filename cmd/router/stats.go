@@ -0,0 +1,61 @@
+package router
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+)
+
+/*
+ * parseStatsQuery
+ *
+ * Reads the /v2/stats/ontime query parameters (see GetOnTimeStats), filling in defaults
+ *
+ * @param url.Values values
+ * @param string today - service date, YYYY-MM-DD
+ *
+ * @return string - first service date
+ * @return string - last service date
+ * @return []string - route codes, nil for all routes
+ * @return error - message for a 400 response
+ */
+func parseStatsQuery(values url.Values, today string) (string, string, []string, error) {
+	to := values.Get("to")
+	if to == "" {
+		to = today
+	}
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return "", "", nil, errors.New("Invalid to, expected YYYY-MM-DD")
+	}
+
+	from := values.Get("from")
+	if from == "" {
+		from = toDate.AddDate(0, 0, 1-defaultStatsDays).Format("2006-01-02")
+	}
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return "", "", nil, errors.New("Invalid from, expected YYYY-MM-DD")
+	}
+	if fromDate.After(toDate) {
+		return "", "", nil, errors.New("From must not be after to")
+	}
+	if toDate.Sub(fromDate) >= maxStatsDays*24*time.Hour {
+		return "", "", nil, errors.New("Date range too long, at most 366 days")
+	}
+
+	var routeCodes []string
+	for _, code := range strings.Split(values.Get("routeCodes"), ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			routeCodes = append(routeCodes, code)
+		}
+	}
+
+	return from, to, routeCodes, nil
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * buildStatus
+ *
+ * Rates the freshness of every route with a recorded scrape against config.Status:
+ *   - ok: last successful scrape within DegradedAfter
+ *   - degraded: last successful scrape within UnhealthyAfter
+ *   - unhealthy: older, or never successful
+ *
+ * Overall, the status is unhealthy when no route is fresher than UnhealthyAfter, and
+ * degraded when any route (or the latest cleanup) is not ok or nothing has been
+ * scraped yet. Routes that were never attempted (e.g. capacity routes while the
+ * capacity scraper is disabled) are not rated.
+ *
+ * @param time.Time now
+ *
+ * @return models.StatusResponse
+ */
+func (h *Handler) buildStatus(now time.Time) models.StatusResponse {
+	status := models.StatusResponse{
+		Status:    models.StatusOK,
+		CheckedAt: now,
+		LastRuns:  make(map[string]models.ScrapeRun),
+		Routes:    h.store.GetRouteFreshness(),
+	}
+
+	for _, run := range h.store.GetLatestScrapeRuns() {
+		status.LastRuns[run.Kind] = run
+	}
+
+	staleRoutes := 0
+	unhealthyRoutes := 0
+	for i := range status.Routes {
+		route := &status.Routes[i]
+
+		if route.LastSuccessAt == nil {
+			route.Status = models.StatusUnhealthy
+			route.LastSuccessAgo = "never"
+		} else {
+			age := now.Sub(*route.LastSuccessAt)
+			route.LastSuccessAgo = formatAgo(age)
+			switch {
+			case age > config.Status.UnhealthyAfter:
+				route.Status = models.StatusUnhealthy
+			case age > config.Status.DegradedAfter:
+				route.Status = models.StatusDegraded
+			default:
+				route.Status = models.StatusOK
+			}
+		}
+
+		if route.Status != models.StatusOK {
+			staleRoutes++
+		}
+		if route.Status == models.StatusUnhealthy {
+			unhealthyRoutes++
+		}
+	}
+
+	switch {
+	case len(status.Routes) == 0:
+		status.Status = models.StatusDegraded
+		status.Reasons = append(status.Reasons, "no scrape has finished yet")
+	case unhealthyRoutes == len(status.Routes):
+		status.Status = models.StatusUnhealthy
+		status.Reasons = append(status.Reasons, fmt.Sprintf("no route scraped successfully in the last %s", config.Status.UnhealthyAfter))
+	case staleRoutes > 0:
+		status.Status = models.StatusDegraded
+		status.Reasons = append(status.Reasons, fmt.Sprintf("%d/%d route(s) not scraped successfully in the last %s", staleRoutes, len(status.Routes), config.Status.DegradedAfter))
+	}
+
+	if cleanup, ok := status.LastRuns[models.ScrapeKindCleanup]; ok && cleanup.Error != "" {
+		if status.Status == models.StatusOK {
+			status.Status = models.StatusDegraded
+		}
+		status.Reasons = append(status.Reasons, "last cleanup failed: "+cleanup.Error)
+	}
+
+	return status
+}
+
+/*
+ * formatAgo
+ *
+ * Formats an age for humans, e.g. "just now", "5m ago", "3h ago", "2d ago".
+ *
+ * @param time.Duration age
+ *
+ * @return string
+ */
+func formatAgo(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age/time.Minute))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(age/(24*time.Hour)))
+	}
+}
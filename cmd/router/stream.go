@@ -0,0 +1,79 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+const (
+	streamKeepAlive = 30 * time.Second // Comment interval on idle streams
+	streamRetry     = 5 * time.Second  // Reconnection delay suggested to clients
+)
+
+/*
+ * parseLastEventID
+ *
+ * Reads the event a client is resuming after, from the Last-Event-ID header that
+ * EventSource sends on reconnect or from the lastEventId query parameter.
+ *
+ * @param *http.Request r
+ *
+ * @return int64 - last event ID
+ * @return bool - false for a new client
+ * @return error - if the ID is not a number
+ */
+func parseLastEventID(r *http.Request) (int64, bool, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, false, errors.New("Invalid Last-Event-ID, expected an event id")
+	}
+	return id, true, nil
+}
+
+// parseRouteCodes splits a comma-separated routeCodes parameter into a set, nil for all routes
+func parseRouteCodes(param string) map[string]bool {
+	if param == "" {
+		return nil
+	}
+	routeCodes := make(map[string]bool)
+	for _, code := range strings.Split(param, ",") {
+		routeCodes[strings.TrimSpace(code)] = true
+	}
+	return routeCodes
+}
+
+/*
+ * writeStreamEvent
+ *
+ * Writes a change event in the SSE format, unless it is for a route the client did not
+ * ask for. Resync events go to every client.
+ *
+ * @param io.Writer w
+ * @param models.ChangeEvent event
+ * @param map[string]bool routeCodes - nil for all routes
+ *
+ * @return void
+ */
+func writeStreamEvent(w io.Writer, event models.ChangeEvent, routeCodes map[string]bool) {
+	if routeCodes != nil && event.Type != models.ChangeResync && !routeCodes[event.RouteCode] {
+		return
+	}
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
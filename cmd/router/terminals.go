@@ -0,0 +1,213 @@
+package router
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/geo"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+)
+
+const (
+	defaultDepartureLimit = 10
+	maxDepartureLimit     = 100
+
+	defaultNearbyRadiusKm = 25
+	maxNearbyRadiusKm     = 500
+)
+
+// sortedTerminals lists the catalogue's terminals by code
+func sortedTerminals() []staticdata.Terminal {
+	terminals := make([]staticdata.Terminal, 0, len(staticdata.GetTerminals()))
+	for _, terminal := range staticdata.GetTerminals() {
+		terminals = append(terminals, terminal)
+	}
+	sort.Slice(terminals, func(i, j int) bool { return terminals[i].Code < terminals[j].Code })
+	return terminals
+}
+
+func terminalInfo(terminal staticdata.Terminal) models.TerminalInfo {
+	return models.TerminalInfo{
+		Code:        terminal.Code,
+		Name:        terminal.Name,
+		ServiceArea: terminal.ServiceArea,
+		Lat:         terminal.Lat,
+		Lon:         terminal.Lon,
+	}
+}
+
+/*
+ * terminalRoutes
+ *
+ * Lists the catalogue routes that depart from or arrive at a terminal, capacity
+ * routes first
+ *
+ * @param string terminalCode
+ *
+ * @return []models.TerminalRoute
+ */
+func terminalRoutes(terminalCode string) []models.TerminalRoute {
+	routes := []models.TerminalRoute{}
+	add := func(departures []string, destinations [][]string, capacity bool) {
+		for i := 0; i < len(departures) && i < len(destinations); i++ {
+			for _, destination := range destinations[i] {
+				if departures[i] == terminalCode || destination == terminalCode {
+					routes = append(routes, models.TerminalRoute{
+						RouteCode:        departures[i] + destination,
+						FromTerminalCode: departures[i],
+						ToTerminalCode:   destination,
+						Capacity:         capacity,
+					})
+				}
+			}
+		}
+	}
+	add(staticdata.GetCapacityDepartureTerminals(), staticdata.GetCapacityDestinationTerminals(), true)
+	add(staticdata.GetNonCapacityDepartureTerminals(), staticdata.GetNonCapacityDestinationTerminals(), false)
+	return routes
+}
+
+/*
+ * nextDepartures
+ *
+ * Lists the sailings leaving a terminal at or after now, soonest first. Capacity
+ * sailings that have departed are left out; cancelled ones are kept with their
+ * status. A sailing on both a capacity and a non-capacity route is listed once, with
+ * its fill.
+ *
+ * @param []models.CapacityRoute capacityRoutes
+ * @param []models.NonCapacityRoute nonCapacityRoutes - today's
+ * @param string terminalCode
+ * @param time.Time now
+ * @param int limit
+ *
+ * @return []models.TerminalDeparture
+ */
+func nextDepartures(capacityRoutes []models.CapacityRoute, nonCapacityRoutes []models.NonCapacityRoute, terminalCode string, now time.Time, limit int) []models.TerminalDeparture {
+	departures := []models.TerminalDeparture{}
+	seen := make(map[string]bool)
+
+	for _, route := range capacityRoutes {
+		if route.FromTerminalCode != terminalCode {
+			continue
+		}
+		for _, sailing := range models.WithCapacityTimes(route).Sailings {
+			if sailing.DepartureAt == nil || sailing.DepartureAt.Before(now) || sailing.SailingStatus == "past" || sailing.SailingStatus == "current" {
+				continue
+			}
+			fill, carFill := sailing.Fill, sailing.CarFill
+			seen[sailing.ID] = true
+			departures = append(departures, models.TerminalDeparture{
+				SailingID:      sailing.ID,
+				RouteCode:      route.RouteCode,
+				ToTerminalCode: route.ToTerminalCode,
+				Time:           sailing.DepartureTime,
+				DepartureAt:    *sailing.DepartureAt,
+				ArrivalAt:      sailing.ArrivalAt,
+				SailingStatus:  sailing.SailingStatus,
+				VesselName:     sailing.VesselName,
+				Fill:           &fill,
+				CarFill:        &carFill,
+			})
+		}
+	}
+
+	for _, route := range nonCapacityRoutes {
+		if route.FromTerminalCode != terminalCode {
+			continue
+		}
+		for _, sailing := range models.WithNonCapacityTimes(route).Sailings {
+			if sailing.DepartureAt == nil || sailing.DepartureAt.Before(now) || seen[sailing.ID] {
+				continue
+			}
+			departure := models.TerminalDeparture{
+				SailingID:      sailing.ID,
+				RouteCode:      route.RouteCode,
+				ToTerminalCode: route.ToTerminalCode,
+				Time:           sailing.DepartureTime,
+				DepartureAt:    *sailing.DepartureAt,
+				ArrivalAt:      sailing.ArrivalAt,
+			}
+			if len(sailing.Legs) > 0 && sailing.Legs[0].VesselName != nil && *sailing.Legs[0].VesselName != "UNKNOWN" {
+				departure.VesselName = *sailing.Legs[0].VesselName
+			}
+			departures = append(departures, departure)
+		}
+	}
+
+	sort.SliceStable(departures, func(i, j int) bool {
+		if !departures[i].DepartureAt.Equal(departures[j].DepartureAt) {
+			return departures[i].DepartureAt.Before(departures[j].DepartureAt)
+		}
+		return departures[i].RouteCode < departures[j].RouteCode
+	})
+	if len(departures) > limit {
+		departures = departures[:limit]
+	}
+	return departures
+}
+
+/*
+ * parseNearbyQuery
+ *
+ * Reads the /v2/terminals/nearby query parameters (see GetNearbyTerminals)
+ *
+ * @param url.Values values
+ *
+ * @return float64 - latitude
+ * @return float64 - longitude
+ * @return float64 - radius in km
+ * @return error - message for a 400 response
+ */
+func parseNearbyQuery(values url.Values) (float64, float64, float64, error) {
+	lat, err := strconv.ParseFloat(values.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, 0, errors.New("Invalid lat, expected a latitude between -90 and 90")
+	}
+	lon, err := strconv.ParseFloat(values.Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, 0, errors.New("Invalid lon, expected a longitude between -180 and 180")
+	}
+
+	radiusKm := float64(defaultNearbyRadiusKm)
+	if param := values.Get("radiusKm"); param != "" {
+		radiusKm, err = strconv.ParseFloat(param, 64)
+		if err != nil || radiusKm <= 0 || radiusKm > maxNearbyRadiusKm {
+			return 0, 0, 0, errors.New("Invalid radiusKm, expected a distance up to 500")
+		}
+	}
+
+	return lat, lon, radiusKm, nil
+}
+
+/*
+ * nearbyTerminals
+ *
+ * Ranks the catalogue's terminals within a radius of a point by great-circle distance
+ *
+ * @param float64 lat
+ * @param float64 lon
+ * @param float64 radiusKm
+ *
+ * @return []models.NearbyTerminal - closest first, distances rounded to 10 m
+ */
+func nearbyTerminals(lat, lon, radiusKm float64) []models.NearbyTerminal {
+	nearby := []models.NearbyTerminal{}
+	for _, terminal := range sortedTerminals() {
+		distanceKm := geo.DistanceKm(lat, lon, terminal.Lat, terminal.Lon)
+		if distanceKm > radiusKm {
+			continue
+		}
+		nearby = append(nearby, models.NearbyTerminal{
+			TerminalInfo: terminalInfo(terminal),
+			DistanceKm:   math.Round(distanceKm*100) / 100,
+		})
+	}
+	sort.SliceStable(nearby, func(i, j int) bool { return nearby[i].DistanceKm < nearby[j].DistanceKm })
+	return nearby
+}
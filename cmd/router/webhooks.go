@@ -0,0 +1,169 @@
+package router
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// Event types a subscription can filter on
+var webhookEventTypes = []string{
+	models.ChangeFillChanged,
+	models.ChangeStatusChanged,
+	models.ChangeCancelled,
+	models.ChangeScheduleChanged,
+	models.ChangeVesselChanged,
+}
+
+/*
+ * authorizeWebhooks
+ *
+ * Checks the request's bearer token against config.Webhooks.AdminToken, writing the
+ * error response if it does not match. The endpoints are disabled when no token is set.
+ *
+ * @param http.ResponseWriter w
+ * @param *http.Request r
+ *
+ * @return bool - false if the request was rejected
+ */
+func authorizeWebhooks(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Content-Type", "application/json")
+
+	if config.Webhooks.AdminToken == "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Webhooks are not enabled"})
+		w.Write(jsonString)
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(config.Webhooks.AdminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		jsonString, _ := json.Marshal(map[string]string{"error": "Invalid or missing bearer token"})
+		w.Write(jsonString)
+		return false
+	}
+	return true
+}
+
+/*
+ * parseWebhookSubscription
+ *
+ * Reads and validates the subscription in a create or update request body:
+ *
+ *   {"url": "https://...", "eventTypes": ["fill-changed"], "routeCodes": ["TSASWB"],
+ *    "terminalCodes": [], "fillAbove": null, "carFillAbove": 90}
+ *
+ * @param io.Reader body
+ *
+ * @return models.WebhookSubscription - codes upper-cased, lists never nil
+ * @return error - describes the first invalid field
+ */
+func parseWebhookSubscription(body io.Reader) (models.WebhookSubscription, error) {
+	var request struct {
+		URL           string   `json:"url"`
+		EventTypes    []string `json:"eventTypes"`
+		RouteCodes    []string `json:"routeCodes"`
+		TerminalCodes []string `json:"terminalCodes"`
+		FillAbove     *int     `json:"fillAbove"`
+		CarFillAbove  *int     `json:"carFillAbove"`
+	}
+	decoder := json.NewDecoder(io.LimitReader(body, 64<<10))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("Invalid request body: %v", err)
+	}
+
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return models.WebhookSubscription{}, errors.New("Invalid url, expected an http or https URL")
+	}
+
+	subscription := models.WebhookSubscription{
+		URL:           request.URL,
+		EventTypes:    []string{},
+		RouteCodes:    []string{},
+		TerminalCodes: []string{},
+		FillAbove:     request.FillAbove,
+		CarFillAbove:  request.CarFillAbove,
+	}
+	for _, eventType := range request.EventTypes {
+		if !contains(webhookEventTypes, eventType) {
+			return models.WebhookSubscription{}, fmt.Errorf("Invalid event type %q, expected one of %s", eventType, strings.Join(webhookEventTypes, ", "))
+		}
+		subscription.EventTypes = append(subscription.EventTypes, eventType)
+	}
+	for _, code := range request.RouteCodes {
+		if len(code) != 6 {
+			return models.WebhookSubscription{}, fmt.Errorf("Invalid route code %q", code)
+		}
+		subscription.RouteCodes = append(subscription.RouteCodes, strings.ToUpper(code))
+	}
+	for _, code := range request.TerminalCodes {
+		if len(code) != 3 {
+			return models.WebhookSubscription{}, fmt.Errorf("Invalid terminal code %q", code)
+		}
+		subscription.TerminalCodes = append(subscription.TerminalCodes, strings.ToUpper(code))
+	}
+	for name, threshold := range map[string]*int{"fillAbove": request.FillAbove, "carFillAbove": request.CarFillAbove} {
+		if threshold != nil && (*threshold < 0 || *threshold > 100) {
+			return models.WebhookSubscription{}, fmt.Errorf("Invalid %s, expected a percentage from 0 to 100", name)
+		}
+	}
+
+	return subscription, nil
+}
+
+/*
+ * parseWebhookID
+ *
+ * @param string param - the :id path parameter
+ *
+ * @return int64
+ * @return error
+ */
+func parseWebhookID(param string) (int64, error) {
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("Invalid webhook id")
+	}
+	return id, nil
+}
+
+// parseLimit reads a limit query parameter, capped at maxLimit
+func parseLimit(param string, defaultLimit, maxLimit int) (int, error) {
+	if param == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(param)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("Invalid limit, expected a positive number")
+	}
+	return min(limit, maxLimit), nil
+}
+
+// newWebhookSecret generates the HMAC key a subscription's payloads are signed with
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
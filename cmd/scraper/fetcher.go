@@ -0,0 +1,486 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+)
+
+/*
+ * Fetcher
+ *
+ * Fetches a page and parses it into a goquery document. Implemented by HTTPFetcher
+ * (plain HTTP), ChromedpFetcher (headless Chrome, for pages that need JavaScript) and
+ * FileFetcher (recorded pages on disk).
+ *
+ * Every page is checked with validatePage before it is returned: interstitial, empty and
+ * error pages, and pages the validator rejects, fail with an *InvalidPageError.
+ */
+type Fetcher interface {
+	Fetch(ctx context.Context, link string, validate PageValidator) (*goquery.Document, error)
+}
+
+/*
+ * Fetchers
+ *
+ * The fetcher used by each route family.
+ */
+type Fetchers struct {
+	Capacity    Fetcher // current conditions and vehicle info pages
+	NonCapacity Fetcher // seasonal schedule and departures pages
+}
+
+/*
+ * NewFetchersFromConfig
+ *
+ * Builds the fetchers selected by config.CapacityFetcher and config.NonCapacityFetcher.
+ * Route families that use the same backend share one instance (e.g. one browser).
+ *
+ * @return Fetchers
+ * @return error - if a backend name is unknown
+ */
+func NewFetchersFromConfig() (Fetchers, error) {
+	backends := make(map[string]Fetcher)
+
+	get := func(backend string) (Fetcher, error) {
+		if fetcher, ok := backends[backend]; ok {
+			return fetcher, nil
+		}
+		fetcher, err := NewFetcher(backend)
+		if err != nil {
+			return nil, err
+		}
+		backends[backend] = fetcher
+		return fetcher, nil
+	}
+
+	capacity, err := get(config.CapacityFetcher)
+	if err != nil {
+		return Fetchers{}, err
+	}
+	nonCapacity, err := get(config.NonCapacityFetcher)
+	if err != nil {
+		return Fetchers{}, err
+	}
+
+	return Fetchers{Capacity: capacity, NonCapacity: nonCapacity}, nil
+}
+
+/*
+ * NewFetcher
+ *
+ * Creates a fetcher for a backend name, configured from config.
+ *
+ * @param string backend - config.FetcherHTTP, config.FetcherChromedp or config.FetcherFile
+ *
+ * @return Fetcher
+ * @return error - if the backend name is unknown
+ */
+func NewFetcher(backend string) (Fetcher, error) {
+	switch backend {
+	case config.FetcherHTTP:
+		return NewHTTPFetcher(config.HTTPFetcher), nil
+	case config.FetcherChromedp:
+		return NewChromedpFetcher(config.ChromedpFetcher), nil
+	case config.FetcherFile:
+		return NewFileFetcher(config.FixtureDir), nil
+	}
+	return nil, fmt.Errorf("unknown fetcher backend %q", backend)
+}
+
+/*
+ * releaseFetcher
+ *
+ * Frees resources a fetcher holds between requests (e.g. a browser) at the end of a scrape run.
+ * They are acquired again on the next fetch.
+ *
+ * @param Fetcher fetcher
+ *
+ * @return void
+ */
+func releaseFetcher(fetcher Fetcher) {
+	if r, ok := fetcher.(interface{ Release() }); ok {
+		r.Release()
+	}
+}
+
+/****************/
+/* HTTP backend */
+/****************/
+
+/*
+ * HTTPFetcher
+ *
+ * Fetches pages with plain HTTP GET requests. Does not run JavaScript.
+ */
+type HTTPFetcher struct {
+	client *http.Client
+	config config.FetcherConfig
+}
+
+/*
+ * NewHTTPFetcher
+ *
+ * @param config.FetcherConfig cfg - timeout, user agent and retry policy
+ *
+ * @return *HTTPFetcher
+ */
+func NewHTTPFetcher(cfg config.FetcherConfig) *HTTPFetcher {
+	// Shared HTTP client to prevent memory leaks from creating new clients
+	// HTTP clients maintain connection pools, so reusing one is more efficient
+	return &HTTPFetcher{client: &http.Client{}, config: cfg}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, link string, validate PageValidator) (*goquery.Document, error) {
+	return withRetry(ctx, f.config, link, validate, func(ctx context.Context) (*goquery.Document, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+		if err != nil {
+			return nil, permanent(err)
+		}
+		if f.config.UserAgent != "" {
+			req.Header.Set("User-Agent", f.config.UserAgent)
+		}
+
+		response, err := f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			err := &StatusError{URL: link, StatusCode: response.StatusCode}
+			if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500 {
+				return nil, err
+			}
+			return nil, permanent(err)
+		}
+
+		return goquery.NewDocumentFromReader(response.Body)
+	})
+}
+
+// StatusError is returned by HTTPFetcher for non-2xx responses
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: HTTP %d", e.URL, e.StatusCode)
+}
+
+/********************/
+/* Chromedp backend */
+/********************/
+
+/*
+ * ChromedpFetcher
+ *
+ * Fetches pages with headless Chrome, so JavaScript runs like in a real browser. This is
+ * used to get past JavaScript-based protections like Queue-it.
+ *
+ * The browser is started on the first fetch and shared by every fetch (one tab each)
+ * until Release, which the scraper calls at the end of each run.
+ */
+type ChromedpFetcher struct {
+	config config.FetcherConfig
+
+	mu         sync.Mutex
+	browserCtx context.Context
+	cancel     context.CancelFunc
+}
+
+/*
+ * NewChromedpFetcher
+ *
+ * @param config.FetcherConfig cfg - timeout, user agent and retry policy
+ *
+ * @return *ChromedpFetcher
+ */
+func NewChromedpFetcher(cfg config.FetcherConfig) *ChromedpFetcher {
+	return &ChromedpFetcher{config: cfg}
+}
+
+func (f *ChromedpFetcher) Fetch(ctx context.Context, link string, validate PageValidator) (*goquery.Document, error) {
+	return withRetry(ctx, f.config, link, validate, func(ctx context.Context) (*goquery.Document, error) {
+		browserCtx, err := f.browser()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start browser: %w", err)
+		}
+
+		// Open a tab for this fetch, closed when ctx is done
+		tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+		defer cancelTab()
+		stop := context.AfterFunc(ctx, cancelTab)
+		defer stop()
+
+		var html string
+		err = chromedp.Run(tabCtx,
+			chromedp.Navigate(link),
+			chromedp.WaitReady("body", chromedp.ByQuery),
+			chromedp.OuterHTML("html", &html),
+		)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("chromedp fetch failed for %s: %w", link, ctx.Err())
+			}
+			return nil, fmt.Errorf("chromedp fetch failed for %s: %w", link, err)
+		}
+
+		document, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			return nil, permanent(fmt.Errorf("failed to parse HTML for %s: %w", link, err))
+		}
+		return document, nil
+	})
+}
+
+/*
+ * browser
+ *
+ * Returns the shared browser context, starting the browser if needed.
+ *
+ * @return context.Context
+ * @return error
+ */
+func (f *ChromedpFetcher) browser() (context.Context, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.browserCtx != nil {
+		return f.browserCtx, nil
+	}
+
+	options := chromedp.DefaultExecAllocatorOptions[:]
+	if f.config.UserAgent != "" {
+		options = append(options, chromedp.UserAgent(f.config.UserAgent))
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), options...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		return nil, err
+	}
+
+	f.browserCtx = browserCtx
+	f.cancel = func() {
+		cancelBrowser()
+		cancelAlloc()
+	}
+	return f.browserCtx, nil
+}
+
+/*
+ * Release
+ *
+ * Closes the browser. The next fetch starts a new one.
+ *
+ * @return void
+ */
+func (f *ChromedpFetcher) Release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.browserCtx = nil
+	f.cancel = nil
+}
+
+/****************/
+/* File backend */
+/****************/
+
+/*
+ * FileFetcher
+ *
+ * Reads recorded pages from a directory laid out by URL path instead of fetching them
+ * (see RecordedPagePath). The host of the requested URL is ignored.
+ */
+type FileFetcher struct {
+	dir string
+}
+
+/*
+ * NewFileFetcher
+ *
+ * @param string dir - directory of recorded pages, e.g. cmd/fakebcf/site
+ *
+ * @return *FileFetcher
+ */
+func NewFileFetcher(dir string) *FileFetcher {
+	return &FileFetcher{dir: dir}
+}
+
+func (f *FileFetcher) Fetch(ctx context.Context, link string, validate PageValidator) (*goquery.Document, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	file, ok := RecordedPagePath(f.dir, u)
+	if !ok {
+		return nil, fmt.Errorf("no recorded page for %s in %s: %w", link, f.dir, os.ErrNotExist)
+	}
+
+	reader, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	document, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recorded pages don't change, so there is nothing to retry
+	if err := validatePage(document, link, validate); err != nil {
+		var permanentErr *permanentError
+		if errors.As(err, &permanentErr) {
+			return nil, permanentErr.err
+		}
+		return nil, err
+	}
+	return document, nil
+}
+
+// Query parameters that select a different recorded page for the same path
+var recordedPageParams = []string{
+	"departureDate", // seasonal schedules, e.g. /routes-fares/schedules/seasonal/TSA-POB?departureDate=2026-04-01
+	"terminalCode",  // departures, e.g. /current-conditions/departures?terminalCode=TSA
+}
+
+/*
+ * RecordedPagePath
+ *
+ * Maps a URL to a recorded page: /a/b is read from <dir>/a/b.html, and pages that differ
+ * by query parameter (departureDate, terminalCode) from <dir>/a/b/<value>.html first.
+ *
+ * @param string dir - directory of recorded pages
+ * @param *url.URL u
+ *
+ * @return string - file path
+ * @return bool - false if no page was recorded for the URL
+ */
+func RecordedPagePath(dir string, u *url.URL) (string, bool) {
+	// Clean the path so URLs can't escape the page directory
+	urlPath := strings.TrimPrefix(path.Clean("/"+u.Path), "/")
+	if urlPath == "" {
+		urlPath = "index"
+	}
+
+	var candidates []string
+	query := u.Query()
+	for _, param := range recordedPageParams {
+		if value := query.Get(param); value != "" && !strings.ContainsAny(value, `/\`) && value != ".." {
+			candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(urlPath), value+".html"))
+		}
+	}
+	candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(urlPath)+".html"))
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+/*********/
+/* Retry */
+/*********/
+
+// permanentError marks a failure that retrying won't fix (e.g. HTTP 404)
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+/*
+ * withRetry
+ *
+ * Runs a fetch attempt with the configured per-request timeout and validates the page,
+ * retrying failed attempts and invalid pages up to cfg.Retries times. The delay before
+ * each retry starts at cfg.RetryBackoff and doubles every time, plus up to 25% jitter so
+ * concurrent workers don't retry in lockstep. Permanent errors and cancellation of ctx are
+ * not retried. Every attempt first waits for the rate limiter in ctx, if there is one
+ * (see limitedFetcher).
+ *
+ * @param context.Context ctx
+ * @param config.FetcherConfig cfg
+ * @param string link - used in log messages
+ * @param PageValidator validate - page-specific check, may be nil
+ * @param func(context.Context) (*goquery.Document, error) attempt
+ *
+ * @return *goquery.Document
+ * @return error - the last attempt's error
+ */
+func withRetry(ctx context.Context, cfg config.FetcherConfig, link string, validate PageValidator, attempt func(context.Context) (*goquery.Document, error)) (*goquery.Document, error) {
+	backoff := cfg.RetryBackoff
+
+	for try := 0; ; try++ {
+		if err := rateLimiterFrom(ctx).Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		}
+		document, err := attempt(attemptCtx)
+		cancel()
+
+		if err == nil {
+			err = validatePage(document, link, validate)
+		}
+
+		if err == nil {
+			return document, nil
+		}
+
+		var permanentErr *permanentError
+		if errors.As(err, &permanentErr) {
+			return nil, permanentErr.err
+		}
+		if try >= cfg.Retries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := backoff
+		if backoff > 0 {
+			delay += time.Duration(rand.Int63n(int64(backoff)/4 + 1))
+		}
+
+		log.Printf("Fetch: attempt %d for %s failed: %v, retrying in %s", try+1, link, err, delay.Round(time.Millisecond))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,145 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+)
+
+func TestHTTPFetcherRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "test-agent" {
+			t.Errorf("User-Agent = %q, want test-agent", r.Header.Get("User-Agent"))
+		}
+		switch r.URL.Path {
+		case "/flaky":
+			// Fails twice, then succeeds
+			if requests.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("<html><body><h1>ok</h1></body></html>"))
+		default:
+			requests.Add(1)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(config.FetcherConfig{
+		Timeout:      time.Second,
+		UserAgent:    "test-agent",
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+	})
+
+	document, err := fetcher.Fetch(context.Background(), server.URL+"/flaky", nil)
+	if err != nil {
+		t.Fatalf("Fetch /flaky: %v", err)
+	}
+	if got := document.Find("h1").Text(); got != "ok" {
+		t.Errorf("h1 = %q, want ok", got)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("got %d requests for /flaky, want 3", got)
+	}
+
+	// 404 is not retried
+	requests.Store(0)
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/missing", nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Fetch /missing error = %v, want a 404 StatusError", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests for /missing, want 1", got)
+	}
+}
+
+func TestHTTPFetcherGivesUp(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(config.FetcherConfig{Timeout: time.Second, Retries: 1, RetryBackoff: time.Millisecond})
+
+	_, err := fetcher.Fetch(context.Background(), server.URL, nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Fetch error = %v, want a 429 StatusError", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
+
+func TestFileFetcher(t *testing.T) {
+	dir := t.TempDir()
+	writePage(t, filepath.Join(dir, "current-conditions", "TSA-SWB.html"), "default")
+	writePage(t, filepath.Join(dir, "routes-fares", "schedules", "seasonal", "TSA-POB.html"), "default")
+	writePage(t, filepath.Join(dir, "routes-fares", "schedules", "seasonal", "TSA-POB", "2026-04-01.html"), "spring")
+
+	fetcher := NewFileFetcher(dir)
+
+	tests := []struct {
+		link string
+		want string // h1 text, empty if the page should be missing
+	}{
+		{"https://www.bcferries.com/current-conditions/TSA-SWB", "default"},
+		{"https://www.bcferries.com/routes-fares/schedules/seasonal/TSA-POB?departureDate=2026-04-01", "spring"},
+		{"https://www.bcferries.com/routes-fares/schedules/seasonal/TSA-POB?departureDate=2030-01-01", "default"},
+		{"https://www.bcferries.com/current-conditions/HSB-NAN", ""},
+		{"https://www.bcferries.com/../../etc/passwd", ""},
+	}
+
+	for _, tt := range tests {
+		document, err := fetcher.Fetch(context.Background(), tt.link, nil)
+		if tt.want == "" {
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Fetch(%s) error = %v, want os.ErrNotExist", tt.link, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Fetch(%s): %v", tt.link, err)
+			continue
+		}
+		if got := document.Find("h1").Text(); got != tt.want {
+			t.Errorf("Fetch(%s) h1 = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestRecordedPagePathIgnoresUnsafeVariants(t *testing.T) {
+	dir := t.TempDir()
+	writePage(t, filepath.Join(dir, "departures.html"), "default")
+
+	u, _ := url.Parse("/departures?terminalCode=../departures")
+	file, ok := RecordedPagePath(dir, u)
+	if !ok || file != filepath.Join(dir, "departures.html") {
+		t.Errorf("RecordedPagePath = %q, %v, want the default page", file, ok)
+	}
+}
+
+func writePage(t *testing.T, file, heading string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("<html><body><h1>"+heading+"</h1></body></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
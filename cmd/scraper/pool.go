@@ -0,0 +1,264 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * routePair
+ *
+ * A departure/destination pair scraped as one unit of work.
+ */
+type routePair struct {
+	From string
+	To   string
+}
+
+func (p routePair) Code() string {
+	return p.From + p.To
+}
+
+/*
+ * routePairs
+ *
+ * Flattens the static terminal lists into departure/destination pairs.
+ *
+ * @param []string departures - e.g. staticdata.GetCapacityDepartureTerminals()
+ * @param [][]string destinations - destinations for each departure terminal
+ *
+ * @return []routePair
+ */
+func routePairs(departures []string, destinations [][]string) []routePair {
+	var pairs []routePair
+	for i := 0; i < len(departures) && i < len(destinations); i++ {
+		for _, destination := range destinations[i] {
+			pairs = append(pairs, routePair{From: departures[i], To: destination})
+		}
+	}
+	return pairs
+}
+
+/*
+ * runParallel
+ *
+ * Calls work for each of n jobs on a pool of at most `workers` goroutines and waits
+ * for them to finish.
+ *
+ * @param int n - number of jobs
+ * @param int workers - maximum number of jobs running at once
+ * @param func(int) work - called with the job index
+ *
+ * @return void
+ */
+func runParallel(n, workers int, work func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+/*
+ * scrapeRoutes
+ *
+ * Scrapes route pairs on a pool of config.Scrape.Concurrency workers. Each route gets its
+ * own context with the config.Scrape.RouteTimeout deadline, covering every request made
+ * for it (retries included). Logs a summary line per route and a total for the run, and
+ * records the run in the store.
+ *
+ * @param string kind - models.ScrapeKindCapacity or models.ScrapeKindNonCapacity
+ * @param string name - caller name for log messages, e.g. "ScrapeCapacityRoutes"
+ * @param []routePair pairs
+ * @param func(context.Context, routePair) (int, error) scrape - returns the number of sailings saved for today
+ *
+ * @return models.ScrapeRun
+ */
+func (s *Scraper) scrapeRoutes(kind, name string, pairs []routePair, scrape func(ctx context.Context, pair routePair) (int, error)) models.ScrapeRun {
+	run := models.ScrapeRun{
+		Kind:       kind,
+		StartedAt:  time.Now(),
+		RouteCount: len(pairs),
+		Routes:     make([]models.ScrapeRouteResult, len(pairs)),
+	}
+
+	runParallel(len(pairs), config.Scrape.Concurrency, func(i int) {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if config.Scrape.RouteTimeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), config.Scrape.RouteTimeout)
+		}
+		defer cancel()
+
+		routeStart := time.Now()
+		sailings, err := scrape(ctx, pairs[i])
+		if ctx.Err() == context.DeadlineExceeded {
+			log.Printf("%s: route %s hit its %s deadline", name, pairs[i].Code(), config.Scrape.RouteTimeout)
+			err = fmt.Errorf("route deadline of %s exceeded: %w", config.Scrape.RouteTimeout, ctx.Err())
+		}
+
+		result := models.ScrapeRouteResult{
+			RouteCode:    pairs[i].Code(),
+			Success:      err == nil,
+			SailingCount: sailings,
+			DurationMs:   time.Since(routeStart).Milliseconds(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		run.Routes[i] = result
+	})
+
+	for _, r := range run.Routes {
+		status := "ok"
+		if r.Success {
+			run.SuccessCount++
+			run.SailingCount += r.SailingCount
+		} else {
+			status = "FAILED"
+		}
+		log.Printf("%s: %-8s %-6s %4d sailing(s) %s", name, r.RouteCode, status, r.SailingCount, time.Duration(r.DurationMs)*time.Millisecond)
+	}
+	if len(pairs) > 0 && run.SuccessCount == 0 {
+		run.Error = "no route was scraped successfully"
+	}
+	run.FinishedAt = time.Now()
+
+	log.Printf("%s: Completed! Successfully scraped %d/%d routes in %s (concurrency %d)", name, run.SuccessCount, len(pairs), run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond), config.Scrape.Concurrency)
+	s.saveScrapeRun(run)
+	return run
+}
+
+/*
+ * saveScrapeRun
+ *
+ * Records a run in the store, logging failures.
+ *
+ * @param models.ScrapeRun run
+ *
+ * @return void
+ */
+func (s *Scraper) saveScrapeRun(run models.ScrapeRun) {
+	if err := s.store.SaveScrapeRun(run); err != nil {
+		log.Printf("saveScrapeRun: failed to record %s run: %v", run.Kind, err)
+	}
+}
+
+/*
+ * rateLimiter
+ *
+ * Spaces out requests to the upstream site so they start at most `perSecond` times per
+ * second, shared by every worker. A nil limiter does not limit.
+ */
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+/*
+ * newRateLimiter
+ *
+ * @param int perSecond - requests per second, 0 for no limit
+ *
+ * @return *rateLimiter - nil when perSecond is 0
+ */
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+/*
+ * Wait
+ *
+ * Blocks until the caller may send a request, or ctx is done.
+ *
+ * @param context.Context ctx
+ *
+ * @return error - ctx.Err() if ctx was done first
+ */
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type rateLimiterKey struct{}
+
+// withRateLimiter returns a copy of ctx whose fetch attempts wait for limiter
+func withRateLimiter(ctx context.Context, limiter *rateLimiter) context.Context {
+	return context.WithValue(ctx, rateLimiterKey{}, limiter)
+}
+
+// rateLimiterFrom returns the rate limiter in ctx, nil (no limit) if there is none
+func rateLimiterFrom(ctx context.Context) *rateLimiter {
+	limiter, _ := ctx.Value(rateLimiterKey{}).(*rateLimiter)
+	return limiter
+}
+
+/*
+ * limitedFetcher
+ *
+ * Hands the shared rate limiter to the fetcher in ctx, so that every request it sends,
+ * retries included, waits for the limiter (see withRetry).
+ */
+type limitedFetcher struct {
+	fetcher Fetcher
+	limiter *rateLimiter
+}
+
+func (f *limitedFetcher) Fetch(ctx context.Context, link string, validate PageValidator) (*goquery.Document, error) {
+	return f.fetcher.Fetch(withRateLimiter(ctx, f.limiter), link, validate)
+}
+
+func (f *limitedFetcher) Release() {
+	releaseFetcher(f.fetcher)
+}
@@ -0,0 +1,106 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+)
+
+func TestRoutePairs(t *testing.T) {
+	got := routePairs([]string{"TSA", "SWB"}, [][]string{{"SWB", "DUK"}, {"TSA"}})
+	want := []routePair{{"TSA", "SWB"}, {"TSA", "DUK"}, {"SWB", "TSA"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("routePairs = %v, want %v", got, want)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	var running, maxRunning, done atomic.Int32
+
+	runParallel(20, 3, func(i int) {
+		n := running.Add(1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		done.Add(1)
+	})
+
+	if done.Load() != 20 {
+		t.Errorf("ran %d jobs, want 20", done.Load())
+	}
+	if max := maxRunning.Load(); max > 3 || max < 2 {
+		t.Errorf("max concurrent jobs = %d, want up to 3", max)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(50) // one request every 20ms
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("5 requests took %s, want at least 80ms", elapsed)
+	}
+
+	// Cancelled callers stop waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.Wait(ctx)
+	if err := limiter.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait with cancelled context = %v, want context.Canceled", err)
+	}
+
+	// No limit
+	if err := newRateLimiter(0).Wait(context.Background()); err != nil {
+		t.Errorf("unlimited Wait = %v", err)
+	}
+}
+
+func TestLimitedFetcherRetriesWaitForLimiter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Rate limited twice, then succeeds
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("<html><body><h1>ok</h1></body></html>"))
+	}))
+	defer server.Close()
+
+	limiter := newRateLimiter(10) // one request every 100ms
+	fetcher := &limitedFetcher{
+		fetcher: NewHTTPFetcher(config.FetcherConfig{Timeout: time.Second, Retries: 2, RetryBackoff: time.Millisecond}),
+		limiter: limiter,
+	}
+
+	start := time.Now()
+	if _, err := fetcher.Fetch(context.Background(), server.URL, nil); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Fatalf("got %d requests, want 3", got)
+	}
+	// Each of the 3 attempts took a slot: the first at once, the retries 100ms apart
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("3 attempts took %s, want at least 200ms", elapsed)
+	}
+	if next := limiter.next.Sub(start); next < 300*time.Millisecond {
+		t.Errorf("limiter's next slot is %s after the start, want at least 300ms", next)
+	}
+}
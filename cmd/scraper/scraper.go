@@ -2,9 +2,8 @@ package scraper
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,54 +12,140 @@ import (
 	"log"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/chromedp/chromedp"
 
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
 	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/events"
 	"github.com/jeffcstock/bc-ferries-api/cmd/models"
 	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
 )
 
-// Shared HTTP client to prevent memory leaks from creating new clients
-// HTTP clients maintain connection pools, so reusing one is more efficient
-var httpClient = &http.Client{
-	Timeout: 30 * time.Second,
+// DocumentFetcher fetches and parses a page, e.g. a capacity "Details" page
+type DocumentFetcher func(link string) (*goquery.Document, error)
+
+/*
+ * Scraper
+ *
+ * Scrapes BC Ferries pages and saves the results to a Store.
+ */
+type Scraper struct {
+	store    db.Store
+	fetchers Fetchers
+	events   *events.Bus
+}
+
+/*
+ * New
+ *
+ * Creates a scraper that fetches pages with the given fetchers and saves to the given store.
+ * Requests are limited to config.Scrape.RateLimit per second. Every saved route is
+ * compared with the one it replaces and the changes are published to the bus.
+ *
+ * @param db.Store store
+ * @param Fetchers fetchers - e.g. from NewFetchersFromConfig
+ * @param *events.Bus bus - nil to not look for changes
+ *
+ * @return *Scraper
+ */
+func New(store db.Store, fetchers Fetchers, bus *events.Bus) *Scraper {
+	// Both route families scrape the same site, so they share one rate limiter
+	limiter := newRateLimiter(config.Scrape.RateLimit)
+	return &Scraper{
+		store:  store,
+		events: bus,
+		fetchers: Fetchers{
+			Capacity:    &limitedFetcher{fetcher: fetchers.Capacity, limiter: limiter},
+			NonCapacity: &limitedFetcher{fetcher: fetchers.NonCapacity, limiter: limiter},
+		},
+	}
 }
 
 /*
  * CleanupOldSailings
  *
- * Deletes sailing records older than 48 hours from both capacity and non-capacity tables.
+ * Applies the retention policy from config.Retention:
+ *   - Deletes route records whose service date is older than the sailing retention window.
+ *   - Deletes history snapshots scraped before the snapshot retention window.
+ *   - Deletes scrape run records started before the scrape run retention window.
+ *
  * This prevents the database from growing indefinitely and consuming memory.
+ * The cleanup itself is recorded as a scrape run of kind models.ScrapeKindCleanup.
  *
  * @return void
  */
-func CleanupOldSailings() {
-	// Calculate the cutoff date (48 hours ago)
-	cutoffDate := time.Now().Add(-48 * time.Hour).Format("2006-01-02")
+func (s *Scraper) CleanupOldSailings() {
+	run := models.ScrapeRun{Kind: models.ScrapeKindCleanup, StartedAt: time.Now()}
+	var errs []error
+
+	// Calculate the cutoff date for route records
+	cutoffDate := time.Now().Add(-config.Retention.Sailings).Format("2006-01-02")
 
 	// Delete old capacity routes
-	sqlCapacity := `DELETE FROM capacity_routes WHERE date < $1`
-	result, err := db.Conn.Exec(sqlCapacity, cutoffDate)
-	if err != nil {
+	if rowsAffected, err := s.store.DeleteCapacityRoutesBefore(cutoffDate); err != nil {
 		log.Printf("CleanupOldSailings: failed to delete old capacity routes: %v", err)
-	} else {
-		rowsAffected, _ := result.RowsAffected()
-		if rowsAffected > 0 {
-			log.Printf("CleanupOldSailings: deleted %d old capacity route(s)", rowsAffected)
-		}
+		errs = append(errs, fmt.Errorf("capacity routes: %w", err))
+	} else if rowsAffected > 0 {
+		log.Printf("CleanupOldSailings: deleted %d old capacity route(s)", rowsAffected)
 	}
 
 	// Delete old non-capacity routes
-	sqlNonCapacity := `DELETE FROM non_capacity_routes WHERE date < $1`
-	result, err = db.Conn.Exec(sqlNonCapacity, cutoffDate)
-	if err != nil {
+	if rowsAffected, err := s.store.DeleteNonCapacityRoutesBefore(cutoffDate); err != nil {
 		log.Printf("CleanupOldSailings: failed to delete old non-capacity routes: %v", err)
-	} else {
-		rowsAffected, _ := result.RowsAffected()
-		if rowsAffected > 0 {
-			log.Printf("CleanupOldSailings: deleted %d old non-capacity route(s)", rowsAffected)
-		}
+		errs = append(errs, fmt.Errorf("non-capacity routes: %w", err))
+	} else if rowsAffected > 0 {
+		log.Printf("CleanupOldSailings: deleted %d old non-capacity route(s)", rowsAffected)
+	}
+
+	// Delete history snapshots outside the retention window
+	snapshotsDeleted, err := s.store.DeleteSnapshotsBefore(time.Now().Add(-config.Retention.Snapshots))
+	if err != nil {
+		log.Printf("CleanupOldSailings: failed to delete old snapshots: %v", err)
+		errs = append(errs, fmt.Errorf("snapshots: %w", err))
+	} else if snapshotsDeleted > 0 {
+		log.Printf("CleanupOldSailings: deleted %d old snapshot(s)", snapshotsDeleted)
+	}
+
+	// Delete departure records outside the retention window
+	departuresCutoff := time.Now().Add(-config.Retention.Departures).Format("2006-01-02")
+	if rowsAffected, err := s.store.DeleteSailingDeparturesBefore(departuresCutoff); err != nil {
+		log.Printf("CleanupOldSailings: failed to delete old departures: %v", err)
+		errs = append(errs, fmt.Errorf("departures: %w", err))
+	} else if rowsAffected > 0 {
+		log.Printf("CleanupOldSailings: deleted %d old departure(s)", rowsAffected)
+	}
+
+	// Delete fill samples outside the retention window
+	samplesDeleted, err := s.store.DeleteFillSamplesBefore(time.Now().Add(-config.Retention.FillHistory))
+	if err != nil {
+		log.Printf("CleanupOldSailings: failed to delete old fill samples: %v", err)
+		errs = append(errs, fmt.Errorf("fill samples: %w", err))
+	} else if samplesDeleted > 0 {
+		log.Printf("CleanupOldSailings: deleted %d old fill sample(s)", samplesDeleted)
+	}
+
+	// Delete scrape run records outside the retention window
+	runsDeleted, err := s.store.DeleteScrapeRunsBefore(time.Now().Add(-config.Retention.ScrapeRuns))
+	if err != nil {
+		log.Printf("CleanupOldSailings: failed to delete old scrape runs: %v", err)
+		errs = append(errs, fmt.Errorf("scrape runs: %w", err))
+	} else if runsDeleted > 0 {
+		log.Printf("CleanupOldSailings: deleted %d old scrape run(s)", runsDeleted)
 	}
+
+	// Delete webhook delivery attempts outside the retention window
+	deliveriesDeleted, err := s.store.DeleteWebhookDeliveriesBefore(time.Now().Add(-config.Retention.WebhookDeliveries))
+	if err != nil {
+		log.Printf("CleanupOldSailings: failed to delete old webhook deliveries: %v", err)
+		errs = append(errs, fmt.Errorf("webhook deliveries: %w", err))
+	} else if deliveriesDeleted > 0 {
+		log.Printf("CleanupOldSailings: deleted %d old webhook delivery attempt(s)", deliveriesDeleted)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		run.Error = err.Error()
+	}
+	run.FinishedAt = time.Now()
+	s.saveScrapeRun(run)
 }
 
 /*
@@ -74,7 +159,7 @@ func CleanupOldSailings() {
  * @return string
  */
 func MakeCurrentConditionsLink(departure, destination string) string {
-	return "https://www.bcferries.com/current-conditions/" + departure + "-" + destination
+	return config.BCFBaseURL + "/current-conditions/" + departure + "-" + destination
 }
 
 /*
@@ -89,70 +174,117 @@ func MakeCurrentConditionsLink(departure, destination string) string {
  * @return string
  */
 func MakeScheduleLink(departure, destination string) string {
-    return "https://www.bcferries.com/routes-fares/schedules/seasonal/" + departure + "-" + destination
+	return config.BCFBaseURL + "/routes-fares/schedules/seasonal/" + departure + "-" + destination
 }
 
 /*
  * ScrapeCapacityRoutes
  *
- * Scrapes capacity routes
+ * Scrapes capacity routes on the worker pool (see scrapeRoutes)
  *
  * @return void
  */
-func ScrapeCapacityRoutes() {
-	departureTerminals := staticdata.GetCapacityDepartureTerminals()
-	destinationTerminals := staticdata.GetCapacityDestinationTerminals()
-
-	for i := 0; i < len(departureTerminals); i++ {
-		for j := 0; j < len(destinationTerminals[i]); j++ {
-			link := MakeCurrentConditionsLink(departureTerminals[i], destinationTerminals[i][j])
-
-			// Make HTTP GET request using shared client
-			req, err := http.NewRequest("GET", link, nil)
-			if err != nil {
-				log.Printf("ScrapeCapacityRoutes: failed to create request for %s: %v", link, err)
-				continue
-			}
+func (s *Scraper) ScrapeCapacityRoutes() {
+	defer releaseFetcher(s.fetchers.Capacity)
 
-			req.Header.Add("User-Agent", "Mozilla")
-			response, err := httpClient.Do(req)
-			if err != nil {
-				log.Printf("ScrapeCapacityRoutes: failed to fetch %s: %v", link, err)
-				continue
-			}
-
-			defer response.Body.Close()
+	pairs := routePairs(staticdata.GetCapacityDepartureTerminals(), staticdata.GetCapacityDestinationTerminals())
 
-			document, err := goquery.NewDocumentFromReader(response.Body)
-			if err != nil {
-				log.Printf("ScrapeCapacityRoutes: failed to parse response from %s: %v", link, err)
-				continue
-			}
+	s.scrapeRoutes(models.ScrapeKindCapacity, "ScrapeCapacityRoutes", pairs, func(ctx context.Context, pair routePair) (int, error) {
+		link := MakeCurrentConditionsLink(pair.From, pair.To)
 
-			ScrapeCapacityRoute(document, departureTerminals[i], destinationTerminals[i][j])
+		document, err := s.fetchers.Capacity.Fetch(ctx, link, validateCurrentConditions)
+		if err != nil {
+			log.Printf("ScrapeCapacityRoutes: failed to fetch %s: %v", link, err)
+			return 0, err
 		}
-	}
+
+		return s.ScrapeCapacityRoute(ctx, document, pair.From, pair.To)
+	})
 }
 
 /*
  * ScrapeCapacityRoute
  *
- * Scrapes capacity data for a given route
+ * Scrapes capacity data for a given route and saves it. A page without sailings does not
+ * replace sailings already saved for the same day.
  *
+ * @param context.Context ctx - used for "Details" page requests
  * @param *goquery.Document document
  * @param string fromTerminalCode
  * @param string toTerminalCode
  *
- * @return void
+ * @return int - number of sailings saved
+ * @return error - if the route was not saved
  */
-func ScrapeCapacityRoute(document *goquery.Document, fromTerminalCode string, toTerminalCode string) {
-	// Get current date in Pacific Time (BC Ferries operates in PT)
-	loc, err := time.LoadLocation("America/Vancouver")
-	if err != nil {
-		log.Printf("ScrapeCapacityRoute: failed to load PT location: %v", err)
-		loc = time.UTC
+func (s *Scraper) ScrapeCapacityRoute(ctx context.Context, document *goquery.Document, fromTerminalCode string, toTerminalCode string) (int, error) {
+	scrapedAt := time.Now()
+	fetchDetails := func(link string) (*goquery.Document, error) {
+		return s.fetchers.Capacity.Fetch(ctx, link, validateVehicleInfo)
 	}
-	currentDate := time.Now().In(loc).Format("2006-01-02")
+	route := ParseCapacityRoute(document, fromTerminalCode, toTerminalCode, scrapedAt, fetchDetails)
+
+	// An empty table is most likely a partial render; keep today's last known sailings
+	if len(route.Sailings) == 0 && s.hasCapacitySailings(route.RouteCode, route.Date) {
+		log.Printf("ScrapeCapacityRoute: page for %s has no sailings, keeping the last saved ones", route.RouteCode)
+		return 0, errors.New("page has no sailings, kept the last saved ones")
+	}
+
+	if err := s.saveCapacityRoute(route, scrapedAt); err != nil {
+		log.Printf("ScrapeCapacityRoute: failed to insert route %s: %v", route.RouteCode, err)
+		return 0, err
+	}
+	return len(route.Sailings), nil
+}
+
+/*
+ * hasCapacitySailings
+ *
+ * Reports whether the store has sailings for a capacity route on a service date.
+ *
+ * @param string routeCode
+ * @param string date - YYYY-MM-DD
+ *
+ * @return bool
+ */
+func (s *Scraper) hasCapacitySailings(routeCode, date string) bool {
+	route := s.storedCapacityRoute(routeCode, date)
+	return route != nil && len(route.Sailings) > 0
+}
+
+/*
+ * storedCapacityRoute
+ *
+ * Returns the capacity route saved for a service date.
+ *
+ * @param string routeCode
+ * @param string date - YYYY-MM-DD
+ *
+ * @return *models.CapacityRoute - nil if there is none
+ */
+func (s *Scraper) storedCapacityRoute(routeCode, date string) *models.CapacityRoute {
+	route := s.store.GetCapacityRoute(routeCode)
+	if route == nil || route.Date != date {
+		return nil
+	}
+	return route
+}
+
+/*
+ * ParseCapacityRoute
+ *
+ * Parses a current conditions page into a capacity route. Does not touch the database.
+ *
+ * @param *goquery.Document document - current conditions page
+ * @param string fromTerminalCode
+ * @param string toTerminalCode
+ * @param time.Time now - scrape time, used for the service date (Pacific Time) and sailing IDs
+ * @param DocumentFetcher fetchDetails - fetches the vehicle info page behind "Details" links
+ *
+ * @return models.CapacityRoute
+ */
+func ParseCapacityRoute(document *goquery.Document, fromTerminalCode string, toTerminalCode string, now time.Time, fetchDetails DocumentFetcher) models.CapacityRoute {
+	// Get current date in Pacific Time (BC Ferries operates in PT)
+	currentDate := now.In(models.Pacific()).Format("2006-01-02")
 
 	route := models.CapacityRoute{
 		Date:             currentDate,
@@ -162,793 +294,911 @@ func ScrapeCapacityRoute(document *goquery.Document, fromTerminalCode string, to
 		Sailings:         []models.CapacitySailing{},
 	}
 
-	document.Find("table.detail-departure-table").Each(func(i int, table *goquery.Selection) {
-		table.Find("tbody").Each(func(j int, tbody *goquery.Selection) {
-                tbody.Find("tr.mobile-friendly-row").Each(func(k int, row *goquery.Selection) {
-                    // Init sailing
-                    sailing := models.CapacitySailing{}
-
-                    row.Find("td").Each(func(l int, td *goquery.Selection) {
-                        rowTextLower := strings.ToLower(row.Text())
-
-                        // Handle explicitly cancelled rows
-                        if strings.Contains(rowTextLower, "cancelled") {
-                            sailing.SailingStatus = "cancelled"
-
-                            if l == 0 {
-                                // Scheduled time and vessel
-                                timeString := strings.Join(strings.Fields(strings.TrimSpace(td.Text())), " ")
-                                re := regexp.MustCompile(`(?P<Time>\d{1,2}:\d{2} [ap]m)(?: \(Tomorrow\))? (?P<VesselName>.+)`)
-                                matches := re.FindStringSubmatch(strings.Join(strings.Fields(timeString), " "))
-                                if len(matches) >= 3 {
-                                    sailing.DepartureTime = matches[1]
-                                    sailing.VesselName = matches[2]
-                                }
-                            } else if l == 1 {
-                                // Capture reason if present under the red text block
-                                // Prefer the second <p> which often holds the reason
-                                reason := strings.TrimSpace(td.Find("div.text-red p").Eq(1).Text())
-                                if reason == "" {
-                                    // Fallback to the whole red block text
-                                    reason = strings.TrimSpace(td.Find("div.text-red").Text())
-                                }
-                                if reason != "" {
-                                    sailing.VesselStatus = reason
-                                }
-                            }
-                        } else if strings.Contains(row.Text(), "Arrived") {
-                            sailing.SailingStatus = "past"
-
-                            if l == 0 {
-                                timeString := strings.Join(strings.Fields(strings.TrimSpace(td.Find("p").Text())), " ")
-
-							re := regexp.MustCompile(`(?P<DepartureTime>\d{1,2}:\d{2} [ap]m) Departed (?P<ActualDepartureTime>\d{1,2}:\d{2} [ap]m) (?P<VesselName>.+)`)
-
-							// Find the matches
-							matches := re.FindStringSubmatch(strings.Join(strings.Fields(timeString), " "))
-
-							if len(matches) == 0 {
-								fmt.Println("No matches found, regex error")
-							} else {
-								// Extracting named groups
-								actualDepartureTime := matches[2]
-								vesselName := matches[3]
-
-								sailing.DepartureTime = actualDepartureTime
-								sailing.VesselName = vesselName
-							}
-						} else if l == 1 {
-							arrivalString := td.Find("div.cc-message-updates").Text()
-
-							re := regexp.MustCompile(`Arrived: (?P<ArrivalTime>\d{1,2}:\d{2} [ap]m)`)
-
-							// Find the matches
-							matches := re.FindStringSubmatch(strings.Join(strings.Fields(arrivalString), " "))
-
-							if len(matches) == 0 {
-								fmt.Println("No matches found, regex error")
-							} else {
-								// Extracting named group
-								arrivalTime := matches[1]
-
-								sailing.ArrivalTime = arrivalTime
-							}
-						}
-                        } else if strings.Contains(row.Text(), "ETA") || strings.Contains(row.Text(), "...") {
-                            sailing.SailingStatus = "current"
-
-						if l == 0 {
-							timeString := strings.Join(strings.Fields(strings.TrimSpace(td.Find("p").Text())), " ")
-
-							re := regexp.MustCompile(`(?P<DepartureTime>\d{1,2}:\d{2} [ap]m) Departed (?P<ActualDepartureTime>\d{1,2}:\d{2} [ap]m) (?P<VesselName>.+)`)
-
-							// Find the matches
-							matches := re.FindStringSubmatch(strings.Join(strings.Fields(timeString), " "))
-
-							if len(matches) == 0 {
-								fmt.Println("No matches found, regex error")
-							} else {
-								// Extracting named groups
-								actualDepartureTime := matches[2]
-								vesselName := matches[3]
-
-								sailing.DepartureTime = actualDepartureTime
-								sailing.VesselName = vesselName
-							}
-						} else if l == 1 {
-							etaString := td.Find("div.cc-message-updates").Text()
-
-							re := regexp.MustCompile(`ETA : (?P<ETA>\d{1,2}:\d{2} [ap]m|Variable)`)
-
-							// Find the matches
-							matches := re.FindStringSubmatch(strings.Join(strings.Fields(etaString), " "))
-
-							if len(matches) == 0 {
-								sailing.ArrivalTime = "..."
-							} else {
-								// Extracting named group
-								etaTime := matches[1]
-
-								sailing.ArrivalTime = etaTime
-							}
-						}
-                        } else if strings.Contains(row.Text(), "Details") || strings.Contains(row.Text(), "%") || strings.Contains(strings.ToLower(row.Text()), "full") {
-                            sailing.SailingStatus = "future"
-
-						if l == 0 {
-							// schedule time, vessel
-							timeString := strings.Join(strings.Fields(strings.TrimSpace(td.Text())), " ")
-
-							re := regexp.MustCompile(`(?P<Time>\d{1,2}:\d{2} [ap]m)(?: \(Tomorrow\))? (?P<VesselName>.+)`)
-
-							// Find the matches
-							matches := re.FindStringSubmatch(strings.Join(strings.Fields(timeString), " "))
-
-							if len(matches) == 0 {
-								fmt.Println("No matches found, regex error")
-							} else {
-								// Extracting named groups
-								time := matches[1]
-								vesselName := matches[2]
-
-								sailing.DepartureTime = time
-								sailing.VesselName = vesselName
-							}
-						} else if l == 1 {
-							// details link
-							// if word "Details" is in row, request from link, otherwise take percentage
-							fillDetailsString := td.Text()
-
-                                if strings.Contains(fillDetailsString, "Details") {
-                                    td.Find("a.vehicle-info-link").Each(func(m int, s *goquery.Selection) {
-									href, exists := s.Attr("href")
-									link := strings.ReplaceAll("https://www.bcferries.com"+href, " ", "%20")
-
-									if exists {
-										req, err := http.NewRequest("GET", link, nil)
-										if err != nil {
-											log.Printf("ScrapeCapacityRoute: failed to create details request for %s: %v", link, err)
-											return
-										}
-
-										req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-										response, err := httpClient.Do(req)
-										if err != nil {
-											log.Printf("ScrapeCapacityRoute: failed to fetch details from %s: %v", link, err)
-											return
-										}
-
-										defer response.Body.Close()
-
-										fillDocument, err := goquery.NewDocumentFromReader(response.Body)
-										if err != nil {
-											log.Printf("ScrapeCapacityRoute: failed to parse fill details from %s: %v", link, err)
-											return
-										}
-
-										// fmt.Println(fillDocument.Text())
-										fillDocument.Find("p.vehicle-icon-text").Each(func(o int, percentageText *goquery.Selection) {
-                                            if o == 0 {
-                                                fillPercentage := strings.TrimSpace(percentageText.Text())
-
-                                                if strings.Contains(strings.ToLower(fillPercentage), "full") {
-                                                    sailing.Fill = 100
-                                                    sailing.CarFill = 100
-                                                    sailing.OversizeFill = 100
-                                                } else {
-                                                    fillPercentageInt, err := strconv.Atoi(strings.ReplaceAll(fillPercentage, "%", ""))
-													if err != nil {
-														// ... handle error
-													}
-
-													sailing.Fill = 100 - fillPercentageInt
-												}
-                                            } else if o == 1 {
-                                                fillPercentage := strings.TrimSpace(percentageText.Text())
-
-                                                if strings.Contains(strings.ToLower(fillPercentage), "full") {
-                                                    sailing.CarFill = 100
-                                                } else {
-                                                    fillPercentageInt, err := strconv.Atoi(strings.ReplaceAll(fillPercentage, "%", ""))
-													if err != nil {
-														// ... handle error
-													}
-
-													sailing.CarFill = 100 - fillPercentageInt
-												}
-                                            } else if o == 2 {
-                                                fillPercentage := strings.TrimSpace(percentageText.Text())
-
-                                                if strings.Contains(strings.ToLower(fillPercentage), "full") {
-                                                    sailing.OversizeFill = 100
-                                                } else {
-                                                    fillPercentageInt, err := strconv.Atoi(strings.ReplaceAll(fillPercentage, "%", ""))
-													if err != nil {
-														// ... handle error
-													}
-
-													sailing.OversizeFill = 100 - fillPercentageInt
-												}
-											}
-										})
-
-									}
-								})
-							} else {
-                                if strings.Contains(strings.ToLower(fillDetailsString), "full") {
-                                    sailing.Fill = 100
-                                    sailing.CarFill = 100
-                                    sailing.OversizeFill = 100
-                                } else {
-                                    fillPercentage := strings.TrimSpace(td.Find("span.cc-vessel-percent-full").Text())
-
-									fillPercentageInt, err := strconv.Atoi(strings.ReplaceAll(fillPercentage, "%", ""))
-									if err != nil {
-										// ... handle error
-									}
-
-									sailing.Fill = 100 - fillPercentageInt
-								}
-							}
-						}
-					}
-				})
+	document.Find("table.detail-departure-table tbody tr.mobile-friendly-row").Each(func(_ int, row *goquery.Selection) {
+		sailing := parseCapacitySailing(row, fetchDetails)
 
-				// Generate unique sailing ID
-				if sailing.DepartureTime != "" {
-					sailing.ID = generateSailingID(route.RouteCode, currentDate, sailing.DepartureTime)
-				}
+		// Generate unique sailing ID from the scheduled time, so it stays the same once the sailing departs
+		if sailing.ScheduledTime != "" {
+			sailing.ID = generateSailingID(route.RouteCode, currentDate, sailing.ScheduledTime)
+		}
 
-				// Add sailing to route
-				route.Sailings = append(route.Sailings, sailing)
-			})
-		})
+		// Add sailing to route
+		route.Sailings = append(route.Sailings, sailing)
 	})
 
-    // Try to find sailing duration text in a case-insensitive way
-    sailingDuration := ""
-    document.Find("span").Each(func(_ int, s *goquery.Selection) {
-        if sailingDuration != "" {
-            return
-        }
-        txt := strings.ReplaceAll(s.Text(), "\u00A0", " ")
-        if strings.Contains(strings.ToLower(txt), "sailing duration:") {
-            sailingDuration = txt
-        }
-    })
-    sailingDuration = strings.ReplaceAll(sailingDuration, "Sailing duration:", "")
-    sailingDuration = strings.ReplaceAll(sailingDuration, "sailing duration:", "")
-    sailingDuration = strings.TrimSpace(sailingDuration)
-
-	sailingsJson, err := json.Marshal(route.Sailings)
-	if err != nil {
-		log.Printf("ScrapeCapacityRoute: failed to marshal sailings for route %s: %v", route.RouteCode, err)
+	// Try to find sailing duration text in a case-insensitive way
+	sailingDuration := ""
+	document.Find("span").Each(func(_ int, s *goquery.Selection) {
+		if sailingDuration != "" {
+			return
+		}
+		txt := strings.ReplaceAll(s.Text(), "\u00A0", " ")
+		if strings.Contains(strings.ToLower(txt), "sailing duration:") {
+			sailingDuration = txt
+		}
+	})
+	sailingDuration = strings.ReplaceAll(sailingDuration, "Sailing duration:", "")
+	sailingDuration = strings.ReplaceAll(sailingDuration, "sailing duration:", "")
+	route.SailingDuration = strings.TrimSpace(sailingDuration)
+
+	return models.WithCapacityTimes(route)
+}
+
+var (
+	// "7:00 am Queen of New Westminster" or "7:00 am (Tomorrow) Queen of New Westminster"
+	scheduledSailingRe = regexp.MustCompile(`(?P<Time>\d{1,2}:\d{2} [ap]m)(?: \(Tomorrow\))? (?P<VesselName>.+)`)
+	// "7:00 am Departed 7:04 am Queen of New Westminster"
+	departedSailingRe = regexp.MustCompile(`(?P<DepartureTime>\d{1,2}:\d{2} [ap]m) Departed (?P<ActualDepartureTime>\d{1,2}:\d{2} [ap]m) (?P<VesselName>.+)`)
+	arrivedRe         = regexp.MustCompile(`Arrived: (?P<ArrivalTime>\d{1,2}:\d{2} [ap]m)`)
+	etaRe             = regexp.MustCompile(`ETA : (?P<ETA>\d{1,2}:\d{2} [ap]m|Variable)`)
+)
+
+/*
+ * parseCapacitySailing
+ *
+ * Parses one row of the current conditions departure table. The row's status decides
+ * how its cells are read:
+ *   - "cancelled": scheduled time, vessel and cancellation reason
+ *   - "Arrived" (past): scheduled and actual departure time, vessel and arrival time
+ *   - "ETA" or "..." (current): scheduled and actual departure time, vessel and ETA
+ *   - "Details", "%" or "full" (future): scheduled time, vessel and fill
+ *
+ * @param *goquery.Selection row - a tr.mobile-friendly-row
+ * @param DocumentFetcher fetchDetails - fetches the vehicle info page behind "Details" links
+ *
+ * @return models.CapacitySailing - sailing without an ID
+ */
+func parseCapacitySailing(row *goquery.Selection, fetchDetails DocumentFetcher) models.CapacitySailing {
+	sailing := models.CapacitySailing{}
+	rowText := row.Text()
+	rowTextLower := strings.ToLower(rowText)
+
+	tds := row.Find("td")
+	timeCell := tds.Eq(0)
+	statusCell := tds.Eq(1)
+	hasStatusCell := tds.Length() > 1
+
+	switch {
+	// Handle explicitly cancelled rows
+	case strings.Contains(rowTextLower, "cancelled"):
+		sailing.SailingStatus = "cancelled"
+
+		// Scheduled time and vessel
+		if matches := scheduledSailingRe.FindStringSubmatch(collapseSpaces(timeCell.Text())); len(matches) >= 3 {
+			sailing.DepartureTime = matches[1]
+			sailing.ScheduledTime = matches[1]
+			sailing.VesselName = matches[2]
+		}
+
+		if hasStatusCell {
+			// Capture reason if present under the red text block
+			// Prefer the second <p> which often holds the reason
+			reason := strings.TrimSpace(statusCell.Find("div.text-red p").Eq(1).Text())
+			if reason == "" {
+				// Fallback to the whole red block text
+				reason = strings.TrimSpace(statusCell.Find("div.text-red").Text())
+			}
+			if reason != "" {
+				sailing.VesselStatus = reason
+			}
+		}
+
+	case strings.Contains(rowText, "Arrived"):
+		sailing.SailingStatus = "past"
+
+		if matches := departedSailingRe.FindStringSubmatch(collapseSpaces(timeCell.Find("p").Text())); len(matches) == 0 {
+			log.Printf("parseCapacitySailing: no departed time/vessel match in arrived row")
+		} else {
+			sailing.ScheduledTime = matches[1]
+			sailing.DepartureTime = matches[2]
+			sailing.ActualDepartureTime = matches[2]
+			sailing.VesselName = matches[3]
+		}
+
+		if hasStatusCell {
+			if matches := arrivedRe.FindStringSubmatch(collapseSpaces(statusCell.Find("div.cc-message-updates").Text())); len(matches) == 0 {
+				log.Printf("parseCapacitySailing: no arrival time match in arrived row")
+			} else {
+				sailing.ArrivalTime = matches[1]
+				sailing.ActualArrivalTime = matches[1]
+			}
+		}
+
+	case strings.Contains(rowText, "ETA") || strings.Contains(rowText, "..."):
+		sailing.SailingStatus = "current"
+
+		if matches := departedSailingRe.FindStringSubmatch(collapseSpaces(timeCell.Find("p").Text())); len(matches) == 0 {
+			log.Printf("parseCapacitySailing: no departed time/vessel match in current row")
+		} else {
+			sailing.ScheduledTime = matches[1]
+			sailing.DepartureTime = matches[2]
+			sailing.ActualDepartureTime = matches[2]
+			sailing.VesselName = matches[3]
+		}
+
+		if hasStatusCell {
+			if matches := etaRe.FindStringSubmatch(collapseSpaces(statusCell.Find("div.cc-message-updates").Text())); len(matches) == 0 {
+				sailing.ArrivalTime = "..."
+			} else {
+				sailing.ArrivalTime = matches[1]
+				sailing.ETA = matches[1]
+			}
+		}
+
+	case strings.Contains(rowText, "Details") || strings.Contains(rowText, "%") || strings.Contains(rowTextLower, "full"):
+		sailing.SailingStatus = "future"
+
+		// Scheduled time, vessel
+		if matches := scheduledSailingRe.FindStringSubmatch(collapseSpaces(timeCell.Text())); len(matches) == 0 {
+			log.Printf("parseCapacitySailing: no scheduled time/vessel match in future row")
+		} else {
+			sailing.DepartureTime = matches[1]
+			sailing.ScheduledTime = matches[1]
+			sailing.VesselName = matches[2]
+		}
+
+		if hasStatusCell {
+			parseCapacityFill(statusCell, &sailing, fetchDetails)
+		}
+	}
+
+	return sailing
+}
+
+/*
+ * parseCapacityFill
+ *
+ * Reads the fill of a future sailing. If the cell has a "Details" link, the vehicle info
+ * page is fetched for the total, car and oversize fill; otherwise the percentage shown
+ * in the cell is used. BC Ferries reports space available, so fill = 100 - percentage.
+ *
+ * @param *goquery.Selection statusCell - the second cell of the row
+ * @param *models.CapacitySailing sailing - sailing to update
+ * @param DocumentFetcher fetchDetails - fetches the vehicle info page behind "Details" links
+ *
+ * @return void
+ */
+func parseCapacityFill(statusCell *goquery.Selection, sailing *models.CapacitySailing, fetchDetails DocumentFetcher) {
+	// If word "Details" is in the cell, request from link, otherwise take percentage
+	fillDetailsString := statusCell.Text()
+
+	if !strings.Contains(fillDetailsString, "Details") {
+		if strings.Contains(strings.ToLower(fillDetailsString), "full") {
+			sailing.Fill = 100
+			sailing.CarFill = 100
+			sailing.OversizeFill = 100
+		} else if fill, ok := parseFillPercentage(statusCell.Find("span.cc-vessel-percent-full").Text()); ok {
+			sailing.Fill = fill
+		}
 		return
 	}
 
-	sqlStatement := `
-		INSERT INTO capacity_routes (
-			route_code,
-			from_terminal_code,
-			to_terminal_code,
-			date,
-			sailing_duration,
-			sailings
-		)
-		VALUES
-			($1, $2, $3, $4, $5, $6) ON CONFLICT (route_code) DO
-		UPDATE
-		SET
-			route_code = EXCLUDED.route_code,
-			from_terminal_code = EXCLUDED.from_terminal_code,
-			to_terminal_code = EXCLUDED.to_terminal_code,
-			date = EXCLUDED.date,
-			sailing_duration = EXCLUDED.sailing_duration,
-			sailings = EXCLUDED.sailings
-		WHERE
-			capacity_routes.route_code = EXCLUDED.route_code`
-	_, err = db.Conn.Exec(sqlStatement, route.RouteCode, route.FromTerminalCode, route.ToTerminalCode, currentDate, sailingDuration, sailingsJson)
+	statusCell.Find("a.vehicle-info-link").Each(func(_ int, s *goquery.Selection) {
+		href, exists := s.Attr("href")
+		if !exists || fetchDetails == nil {
+			return
+		}
+
+		link := strings.ReplaceAll(config.BCFBaseURL+href, " ", "%20")
+		fillDocument, err := fetchDetails(link)
+		if err != nil {
+			log.Printf("parseCapacityFill: failed to fetch details from %s: %v", link, err)
+			return
+		}
+
+		fillDocument.Find("p.vehicle-icon-text").Each(func(o int, percentageText *goquery.Selection) {
+			fillPercentage := strings.TrimSpace(percentageText.Text())
+			isFull := strings.Contains(strings.ToLower(fillPercentage), "full")
+
+			switch o {
+			case 0:
+				if isFull {
+					sailing.Fill = 100
+					sailing.CarFill = 100
+					sailing.OversizeFill = 100
+				} else if fill, ok := parseFillPercentage(fillPercentage); ok {
+					sailing.Fill = fill
+				}
+			case 1:
+				if isFull {
+					sailing.CarFill = 100
+				} else if fill, ok := parseFillPercentage(fillPercentage); ok {
+					sailing.CarFill = fill
+				}
+			case 2:
+				if isFull {
+					sailing.OversizeFill = 100
+				} else if fill, ok := parseFillPercentage(fillPercentage); ok {
+					sailing.OversizeFill = fill
+				}
+			}
+		})
+	})
+}
+
+/*
+ * parseFillPercentage
+ *
+ * Converts a "space available" percentage (e.g. "35%") into a fill percentage (65).
+ *
+ * @param string text
+ *
+ * @return int - fill percentage
+ * @return bool - false if the text is not a percentage
+ */
+func parseFillPercentage(text string) (int, bool) {
+	available, err := strconv.Atoi(strings.TrimSpace(strings.ReplaceAll(text, "%", "")))
 	if err != nil {
-		log.Printf("ScrapeCapacityRoute: failed to insert route %s: %v", route.RouteCode, err)
-		return
+		log.Printf("parseFillPercentage: failed to parse %q: %v", text, err)
+		return 0, false
+	}
+	return 100 - available, true
+}
+
+/*
+ * saveCapacityRoute
+ *
+ * Upserts a capacity route keyed by route code, records a history snapshot and
+ * publishes what changed since the previous scrape.
+ *
+ * @param models.CapacityRoute route
+ * @param time.Time scrapedAt - when the route was scraped
+ *
+ * @return error
+ */
+func (s *Scraper) saveCapacityRoute(route models.CapacityRoute, scrapedAt time.Time) error {
+	var previous *models.CapacityRoute
+	if s.events != nil {
+		previous = s.storedCapacityRoute(route.RouteCode, route.Date)
+	}
+
+	if err := s.store.SaveCapacityRoute(route); err != nil {
+		return err
+	}
+
+	if s.events != nil {
+		s.events.Publish(events.DiffCapacity(previous, route, scrapedAt))
+	}
+
+	if err := s.store.SaveCapacitySnapshot(route, scrapedAt); err != nil {
+		log.Printf("ScrapeCapacityRoute: failed to save snapshot for route %s: %v", route.RouteCode, err)
+	}
+
+	if err := s.store.SaveFillSamples(route, scrapedAt); err != nil {
+		log.Printf("ScrapeCapacityRoute: failed to save fill samples for route %s: %v", route.RouteCode, err)
+	}
+
+	if err := s.store.SaveSailingDepartures(route); err != nil {
+		log.Printf("ScrapeCapacityRoute: failed to save departures for route %s: %v", route.RouteCode, err)
 	}
+
+	return nil
 }
 
 /*
  * ScrapeNonCapacityRoutes
  *
- * Scrapes non-capacity routes
+ * Scrapes non-capacity routes on the worker pool (see scrapeRoutes)
  *
  * @return void
  */
-func ScrapeNonCapacityRoutes() {
+func (s *Scraper) ScrapeNonCapacityRoutes() {
 	log.Println("ScrapeNonCapacityRoutes: Starting scrape of Southern Gulf Islands routes...")
 
-	ctx, cancel := chromedp.NewContext(context.Background())
-	defer cancel()
+	defer releaseFetcher(s.fetchers.NonCapacity)
 
 	// Build vessel database from departures pages
 	log.Println("ScrapeNonCapacityRoutes: Building vessel database...")
-	vesselDatabase := BuildVesselDatabase(ctx)
+	vesselDatabase := BuildVesselDatabase(context.Background(), s.fetchers.NonCapacity)
 	log.Printf("ScrapeNonCapacityRoutes: Vessel database built with %d terminals", len(vesselDatabase))
 
-	departureTerminals := staticdata.GetNonCapacityDepartureTerminals()
-	destinationTerminals := staticdata.GetNonCapacityDestinationTerminals()
+	pairs := routePairs(staticdata.GetNonCapacityDepartureTerminals(), staticdata.GetNonCapacityDestinationTerminals())
 
-	successCount := 0
-	totalAttempts := 0
+	// The horizon starts today in Pacific Time (BC Ferries operates in PT)
+	now := time.Now().In(models.Pacific())
+	horizonEnd := now.AddDate(0, 0, config.ScheduleHorizonDays-1)
 
-	for i := 0; i < len(departureTerminals); i++ {
-		for j := 0; j < len(destinationTerminals[i]); j++ {
-			totalAttempts++
-			link := MakeScheduleLink(departureTerminals[i], destinationTerminals[i][j])
+	s.scrapeRoutes(models.ScrapeKindNonCapacity, "ScrapeNonCapacityRoutes", pairs, func(ctx context.Context, pair routePair) (int, error) {
+		pages, seasons, err := fetchSchedulePages(ctx, s.fetchers.NonCapacity, pair.From, pair.To, now, horizonEnd)
+		if err != nil {
+			log.Printf("ScrapeNonCapacityRoutes: %v", err)
+			return 0, err
+		}
 
-			html, err := fetchWithChromedp(ctx, link)
-			if err != nil {
-				fmt.Printf("ScrapeBCNonCapacityRoutes: chromedp fetch failed for %s: %v\n", link, err)
-				continue
+		if len(seasons) > 0 {
+			if err := s.store.SaveScheduleSeasons(pair.Code(), seasons); err != nil {
+				log.Printf("ScrapeNonCapacityRoutes: failed to save seasons for %s: %v", pair.Code(), err)
 			}
+		}
 
-			document, err := goquery.NewDocumentFromReader(strings.NewReader(html))
-			if err != nil {
-				fmt.Printf("ScrapeBCNonCapacityRoutes: failed to parse HTML for %s: %v\n", link, err)
-				continue
-			}
+		return s.ScrapeNonCapacityRoute(pages, pair.From, pair.To, vesselDatabase)
+	})
+}
 
-			if ScrapeNonCapacityRoute(document, departureTerminals[i], destinationTerminals[i][j], vesselDatabase) {
-				successCount++
-			}
+/*
+ * ScrapeNonCapacityRoute
+ *
+ * Scrapes schedule data for a given route over the configured schedule horizon and saves it.
+ *
+ * The seasonal table lists sailings for every weekday, so each page is expanded
+ * into one route record per date (today through config.ScheduleHorizonDays - 1).
+ * Dates no fetched season covers are skipped.
+ *
+ * @param []SchedulePage pages - seasonal schedule pages (default page first)
+ * @param string fromTerminalCode
+ * @param string toTerminalCode
+ * @param map[string]map[string]string vesselDatabase - Vessel database (terminal → time → vessel), only valid for today
+ *
+ * @return int - number of sailings saved for today
+ * @return error - unless every covered date was successfully scraped and saved
+ */
+func (s *Scraper) ScrapeNonCapacityRoute(pages []SchedulePage, fromTerminalCode, toTerminalCode string, vesselDatabase map[string]map[string]string) (int, error) {
+	loc := models.Pacific()
+
+	routeCode := fromTerminalCode + toTerminalCode
+
+	timetables := parseTimetables(pages, routeCode)
+	if len(timetables) == 0 {
+		return 0, ErrScheduleNotFound
+	}
+
+	today := time.Now().In(loc)
+	scrapedAt := time.Now()
+	coveredDays := 0
+	savedDays := 0
+	todaySailings := 0
+
+	for offset := 0; offset < config.ScheduleHorizonDays; offset++ {
+		date := today.AddDate(0, 0, offset)
+
+		// Vessel assignments come from today's departures page, so only use them for today
+		dayVessels := vesselDatabase
+		if offset > 0 {
+			dayVessels = nil
+		}
+
+		route, err := buildNonCapacityRoute(timetables, fromTerminalCode, toTerminalCode, date, dayVessels)
+		if err != nil {
+			log.Printf("ScrapeNonCapacityRoute: %s on %s: %v, skipping", routeCode, date.Format("2006-01-02"), err)
+			continue
+		}
+		coveredDays++
+
+		if err := s.saveNonCapacityRoute(route, scrapedAt); err != nil {
+			log.Printf("ScrapeNonCapacityRoute: DB insert/update failed for %s on %s: %v", route.RouteCode, route.Date, err)
+			continue
 		}
+
+		savedDays++
+		if offset == 0 {
+			todaySailings = len(route.Sailings)
+		}
+	}
+
+	if savedDays == 0 || savedDays < coveredDays {
+		log.Printf("ScrapeNonCapacityRoute: ✗ %s saved %d/%d day(s)", routeCode, savedDays, coveredDays)
+		return todaySailings, fmt.Errorf("saved %d/%d day(s)", savedDays, coveredDays)
 	}
 
-	log.Printf("ScrapeNonCapacityRoutes: Completed! Successfully scraped %d/%d routes", successCount, totalAttempts)
+	log.Printf("ScrapeNonCapacityRoute: ✓ %s scraped successfully with %d sailing(s) today across %d day(s)", routeCode, todaySailings, savedDays)
+	return todaySailings, nil
 }
 
 /*
- * ScrapeNonCapacityRoute
+ * ParseNonCapacityRoute
  *
- * Scrapes schedule data for a given route
+ * Parses seasonal schedule pages into a non-capacity route for one service date.
+ * Does not touch the database.
  *
- * @param *goquery.Document document
+ * The date uses the page whose season is in effect on that date, so dates after a
+ * schedule changeover use the new season's timetable. "Only on" / "Except on" notes
+ * are applied to the date, and dangerous-goods-only sailings are dropped.
+ *
+ * @param []SchedulePage pages - seasonal schedule pages (default page first)
  * @param string fromTerminalCode
  * @param string toTerminalCode
- * @param map[string]map[string]string vesselDatabase - Vessel database (terminal → time → vessel)
+ * @param time.Time date - service date, in Pacific Time
+ * @param map[string]map[string]string vesselDatabase - Vessel database (nil when unknown)
  *
- * @return bool - true if route was successfully scraped and saved, false otherwise
+ * @return models.NonCapacityRoute
+ * @return error - ErrScheduleNotFound or ErrDateNotCovered
  */
-func ScrapeNonCapacityRoute(document *goquery.Document, fromTerminalCode, toTerminalCode string, vesselDatabase map[string]map[string]string) bool {
-	loc, err := time.LoadLocation("America/Vancouver")
-	if err != nil {
-		log.Printf("ScrapeNonCapacityRoute: failed to load PT location: %v", err)
-		return false
+func ParseNonCapacityRoute(pages []SchedulePage, fromTerminalCode, toTerminalCode string, date time.Time, vesselDatabase map[string]map[string]string) (models.NonCapacityRoute, error) {
+	timetables := parseTimetables(pages, fromTerminalCode+toTerminalCode)
+	if len(timetables) == 0 {
+		return models.NonCapacityRoute{}, ErrScheduleNotFound
 	}
 
-	normalizeDay := func(s string) string {
-		s = strings.TrimSpace(strings.ToUpper(s))
-		// Treat trailing "S" as optional: MONDAY == MONDAYS
-		if strings.HasSuffix(s, "S") {
-			s = strings.TrimSuffix(s, "S")
+	return buildNonCapacityRoute(timetables, fromTerminalCode, toTerminalCode, date, vesselDatabase)
+}
+
+var (
+	ErrScheduleNotFound = errors.New("seasonal schedule table not found")
+	ErrDateNotCovered   = errors.New("no known schedule season covers date")
+)
+
+/*
+ * timetable
+ *
+ * A seasonal schedule page's weekday tables, keyed by normalized weekday (e.g. "MONDAY").
+ */
+type timetable struct {
+	season    models.ScheduleSeason
+	dayBodies map[string]*goquery.Selection
+}
+
+/*
+ * parseTimetables
+ *
+ * Finds each page's schedule table and maps its weekdays to <tbody>s.
+ * Pages without a usable schedule table are logged and skipped.
+ *
+ * @param []SchedulePage pages
+ * @param string routeCode - used in log messages
+ *
+ * @return []timetable
+ */
+func parseTimetables(pages []SchedulePage, routeCode string) []timetable {
+	var timetables []timetable
+
+	for _, page := range pages {
+		// ---- Step 1: find the seasonal schedule table that contains weekday theads
+		scheduleTable := findScheduleTable(page.Document)
+		if scheduleTable == nil {
+			log.Printf("parseTimetables: seasonal schedule table not found for %s (%s)", routeCode, page.Season.Label)
+			continue
+		}
+
+		// ---- Step 2: map each weekday to its <tbody>
+		dayBodies := findDayBodies(scheduleTable)
+		if len(dayBodies) == 0 {
+			log.Printf("parseTimetables: no weekday tbody found in schedule table for %s (%s)", routeCode, page.Season.Label)
+			continue
 		}
-		return s
+
+		timetables = append(timetables, timetable{season: page.Season, dayBodies: dayBodies})
+	}
+
+	return timetables
+}
+
+/*
+ * buildNonCapacityRoute
+ *
+ * Builds a non-capacity route for one service date from parsed timetables.
+ *
+ * @param []timetable timetables
+ * @param string fromTerminalCode
+ * @param string toTerminalCode
+ * @param time.Time date - service date, in Pacific Time
+ * @param map[string]map[string]string vesselDatabase - Vessel database (nil when unknown)
+ *
+ * @return models.NonCapacityRoute
+ * @return error - ErrDateNotCovered if no timetable's season covers the date
+ */
+func buildNonCapacityRoute(timetables []timetable, fromTerminalCode, toTerminalCode string, date time.Time, vesselDatabase map[string]map[string]string) (models.NonCapacityRoute, error) {
+	dateStr := date.Format("2006-01-02")
+
+	current := timetableForDate(timetables, dateStr)
+	if current == -1 {
+		return models.NonCapacityRoute{}, ErrDateNotCovered
 	}
-	today := time.Now().In(loc)
-	todayNorm := normalizeDay(today.Weekday().String()) // e.g. "MONDAY"
-	currentDate := today.Format("2006-01-02")
 
 	route := models.NonCapacityRoute{
-		Date:             currentDate,
+		Date:             dateStr,
 		RouteCode:        fromTerminalCode + toTerminalCode,
 		FromTerminalCode: fromTerminalCode,
 		ToTerminalCode:   toTerminalCode,
+		EffectiveFrom:    timetables[current].season.EffectiveFrom,
+		EffectiveTo:      timetables[current].season.EffectiveTo,
 		Sailings:         []models.NonCapacitySailing{},
 	}
 
-    // ---- Step 1: find the seasonal schedule table that contains weekday theads
-    var scheduleTable *goquery.Selection
-    document.Find("table.table-seasonal-schedule").Each(func(_ int, t *goquery.Selection) {
-        if scheduleTable != nil {
-            return
-        }
-        // Heuristic: a real schedule table has thead rows with day labels
-        if t.Find("thead tr[data-schedule-day], thead [data-schedule-day], thead h4, thead b").Length() > 0 {
-            scheduleTable = t
-        }
-    })
-    // Fallback to the historical assumption (2nd table) if heuristic fails
-    if scheduleTable == nil {
-        scheduleTable = document.Find("table.table-seasonal-schedule").Eq(1)
-    }
-    if scheduleTable == nil || scheduleTable.Length() == 0 {
-        log.Printf("ScrapeNonCapacityRoute: seasonal schedule table not found")
-        return false
-    }
-
-	// ---- Step 2: find the <thead> whose day matches today (MONDAY vs MONDAYS, any case)
-    var dayBody *goquery.Selection
-    scheduleTable.Find("thead").Each(func(_ int, thead *goquery.Selection) {
-		if dayBody != nil {
+	// ---- Step 3: parse rows for this date's weekday (no tbody = no sailings that day)
+	if dayBody, ok := timetables[current].dayBodies[normalizeDay(date.Weekday().String())]; ok {
+		route.Sailings = parseNonCapacitySailings(dayBody, route.RouteCode, date, vesselDatabase)
+		route.SailingDuration = parseRouteSailingDuration(dayBody)
+	}
+
+	return models.WithNonCapacityTimes(route), nil
+}
+
+/*
+ * findScheduleTable
+ *
+ * Finds the seasonal schedule table that contains weekday theads.
+ *
+ * @param *goquery.Document document
+ *
+ * @return *goquery.Selection - nil if no table was found
+ */
+func findScheduleTable(document *goquery.Document) *goquery.Selection {
+	var scheduleTable *goquery.Selection
+	document.Find("table.table-seasonal-schedule").Each(func(_ int, t *goquery.Selection) {
+		if scheduleTable != nil {
 			return
 		}
+		// Heuristic: a real schedule table has thead rows with day labels
+		if t.Find("thead tr[data-schedule-day], thead [data-schedule-day], thead h4, thead b").Length() > 0 {
+			scheduleTable = t
+		}
+	})
+	// Fallback to the historical assumption (2nd table) if heuristic fails
+	if scheduleTable == nil {
+		scheduleTable = document.Find("table.table-seasonal-schedule").Eq(1)
+	}
+	if scheduleTable.Length() == 0 {
+		return nil
+	}
+
+	return scheduleTable
+}
 
+/*
+ * findDayBodies
+ *
+ * Maps each weekday (normalized, e.g. "MONDAY") to the <tbody> that follows its <thead>.
+ * Matching follows the day label rules: MONDAY vs MONDAYS, any case, attribute first
+ * and visible thead text as a fallback. The first matching thead wins.
+ *
+ * @param *goquery.Selection scheduleTable
+ *
+ * @return map[string]*goquery.Selection - weekday → tbody
+ */
+func findDayBodies(scheduleTable *goquery.Selection) map[string]*goquery.Selection {
+	dayBodies := make(map[string]*goquery.Selection)
+
+	scheduleTable.Find("thead").Each(func(_ int, thead *goquery.Selection) {
 		// Prefer the attribute if present.
-		dayAttr := thead.Find("tr").First().AttrOr("data-schedule-day", "")
-		dayAttrNorm := normalizeDay(dayAttr)
-
-		match := (dayAttrNorm != "" && dayAttrNorm == todayNorm)
-		if !match {
-			// Fallback: try visible text inside thead (e.g., MONDAY Depart)
-			txt := thead.Find("h4, b, th").First().Text()
-			txtNorm := normalizeDay(txt)
-			// If the text contains the weekday token (e.g., "MONDAY DEPART"), accept it.
-			match = (txtNorm == todayNorm) || strings.Contains(txtNorm, todayNorm)
+		dayAttrNorm := normalizeDay(thead.Find("tr").First().AttrOr("data-schedule-day", ""))
+		// Fallback: visible text inside thead (e.g., MONDAY Depart)
+		txtNorm := normalizeDay(thead.Find("h4, b, th").First().Text())
+
+		// Go to the NEXT sibling under the table; skip to the first <tbody>
+		tb := thead.Next()
+		for tb.Length() > 0 && goquery.NodeName(tb) != "tbody" {
+			tb = tb.Next()
+		}
+		if tb.Length() == 0 {
+			return
 		}
 
-		if match {
-			// ---- Step 3: go to the NEXT sibling under the table; skip to the first <tbody>
-			tb := thead.Next()
-			for tb.Length() > 0 && goquery.NodeName(tb) != "tbody" {
-				tb = tb.Next()
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			dayNorm := normalizeDay(weekday.String())
+			if _, exists := dayBodies[dayNorm]; exists {
+				continue
 			}
-			if tb.Length() > 0 && goquery.NodeName(tb) == "tbody" {
-				dayBody = tb
+
+			match := dayAttrNorm != "" && dayAttrNorm == dayNorm
+			if !match {
+				// If the text contains the weekday token (e.g., "MONDAY DEPART"), accept it.
+				match = txtNorm == dayNorm || strings.Contains(txtNorm, dayNorm)
+			}
+
+			if match {
+				dayBodies[dayNorm] = tb
 			}
 		}
 	})
 
-	if dayBody == nil {
-		log.Printf("ScrapeNonCapacityRoute: no tbody found for today (%s) in second table", todayNorm)
-		return false
+	return dayBodies
+}
+
+// Departure or arrival time in a schedule cell, e.g. "7:10 am"
+var scheduleTimeRe = regexp.MustCompile(`(?i)\b\d{1,2}:\d{2}\s*[ap]m\b`)
+
+/*
+ * parseNonCapacitySailings
+ *
+ * Parses the schedule rows of a weekday <tbody> into sailings for a specific date.
+ * Dangerous-goods-only sailings are dropped, and "Only on" / "Except on" notes are
+ * evaluated against the given date.
+ *
+ * @param *goquery.Selection dayBody - the weekday's <tbody>
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param time.Time date - the service date being built
+ * @param map[string]map[string]string vesselDatabase - Vessel database (nil when unknown)
+ *
+ * @return []models.NonCapacitySailing
+ */
+func parseNonCapacitySailings(dayBody *goquery.Selection, routeCode string, date time.Time, vesselDatabase map[string]map[string]string) []models.NonCapacitySailing {
+	sailings := []models.NonCapacitySailing{}
+	currentDate := date.Format("2006-01-02")
+	dateKey := fmt.Sprintf("%02d-%02d", int(date.Month()), date.Day())
+
+	dayBody.Find("tr.schedule-table-row").Each(func(_ int, row *goquery.Selection) {
+		tds := row.Find("td")
+		if tds.Length() < 3 {
+			return
+		}
+
+		// Extract clean departure time (first time token) and any status notes
+		depCell := tds.Eq(1)
+		depRaw := cleanText(depCell.Text())
+
+		// Capture red status notes if present (e.g., Only on..., Except on...)
+		var redNotes []string
+		depCell.Find("p.red-text").Each(func(_ int, p *goquery.Selection) {
+			if txt := cleanText(p.Text()); txt != "" {
+				redNotes = append(redNotes, txt)
+			}
+		})
+
+		// Extract the first time-like token from the departure cell
+		depTime := depRaw
+		if m := scheduleTimeRe.FindString(depRaw); m != "" {
+			depTime = m
+		}
+
+		// Extract clean arrival time (first time token)
+		arrRaw := cleanText(tds.Eq(2).Text())
+		arrTime := arrRaw
+		if m := scheduleTimeRe.FindString(arrRaw); m != "" {
+			arrTime = m
+		}
+
+		// Extract sailing duration from the 4th column
+		var sailingDuration string
+		if tds.Length() > 3 {
+			sailingDuration = cleanText(tds.Eq(3).Text())
+		}
+
+		// Extract events from the 5th column (stops, thru fares, transfers)
+		var events []models.SailingEvent
+		if tds.Length() > 4 {
+			events = parseSailingEvents(tds.Eq(4))
+		}
+
+		// Filter: drop dangerous goods only sailings outright
+		depLower := strings.ToLower(depCell.Text())
+		if strings.Contains(depLower, "dangerous goods only") || strings.Contains(depLower, "no passengers permitted") {
+			return
+		}
+
+		// Apply exception rules: "Only on <dates>" and "Except on <dates>"
+		combinedRed := strings.ToLower(strings.Join(redNotes, "; "))
+
+		// If there is an "only on" note, include only if the date is listed
+		if strings.Contains(combinedRed, "only on") {
+			if _, ok := parseMentionedDates(combinedRed)[dateKey]; !ok {
+				return
+			}
+		}
+		// If there is an "except on" note, exclude if the date is listed
+		if strings.Contains(combinedRed, "except on") {
+			if _, ok := parseMentionedDates(combinedRed)[dateKey]; ok {
+				return
+			}
+		}
+
+		s := buildNonCapacitySailing(routeCode, depTime, arrTime, sailingDuration, events, vesselDatabase)
+
+		// Generate unique sailing ID
+		if s.DepartureTime != "" {
+			s.ID = generateSailingID(routeCode, currentDate, s.DepartureTime)
+		}
+
+		if s.DepartureTime != "" || s.ArrivalTime != "" {
+			sailings = append(sailings, s)
+		}
+	})
+
+	return sailings
+}
+
+/*
+ * parseSailingEvents
+ *
+ * Extracts stop, thru-fare and transfer events from the events cell of a schedule row.
+ *
+ * @param *goquery.Selection eventsCell - the 5th column of a schedule row
+ *
+ * @return []models.SailingEvent
+ */
+func parseSailingEvents(eventsCell *goquery.Selection) []models.SailingEvent {
+	var events []models.SailingEvent
+
+	eventsCell.Find("p.mb-1").Each(func(_ int, p *goquery.Selection) {
+		// Look for the event type span
+		var eventType string
+		if p.Find(".schedule-leg-type-thru-fare").Length() > 0 {
+			eventType = "thruFare"
+		} else if p.Find(".schedule-leg-type-stop").Length() > 0 {
+			eventType = "stop"
+		} else if p.Find(".schedule-leg-type-transfer").Length() > 0 {
+			eventType = "transfer"
+		}
+
+		// Extract the terminal name (the text after the link/icon)
+		terminalName := ""
+		p.Contents().Each(func(_ int, node *goquery.Selection) {
+			// Look for <span> elements that are NOT part of the link/icon
+			if goquery.NodeName(node) == "span" {
+				// Skip if it's one of the icon/type spans
+				if node.HasClass("bcf") || node.HasClass("schedule-leg-type-thru-fare") ||
+					node.HasClass("schedule-leg-type-stop") || node.HasClass("schedule-leg-type-transfer") {
+					return
+				}
+				if text := cleanText(node.Text()); text != "" {
+					terminalName = text
+				}
+			}
+		})
+
+		// Add the event if we found both type and terminal name
+		if eventType != "" && terminalName != "" {
+			events = append(events, models.SailingEvent{
+				Type:         eventType,
+				TerminalName: terminalName,
+			})
+		}
+	})
+
+	return events
+}
+
+/*
+ * buildNonCapacitySailing
+ *
+ * Builds a sailing from its parsed row values: estimates dwell time, builds legs
+ * (with vessel lookups) and derives the stop/thru-fare flags and travel totals.
+ *
+ * @param string routeCode - e.g. "TSAPOB"
+ * @param string depTime - departure time (e.g. "7:10 am")
+ * @param string arrTime - arrival time (e.g. "9:05 am")
+ * @param string sailingDuration - duration text from the schedule (e.g. "1h 55m")
+ * @param []models.SailingEvent events - stops, transfers, thru fares
+ * @param map[string]map[string]string vesselDatabase - Vessel database (nil when unknown)
+ *
+ * @return models.NonCapacitySailing - sailing without an ID
+ */
+func buildNonCapacitySailing(routeCode, depTime, arrTime, sailingDuration string, events []models.SailingEvent, vesselDatabase map[string]map[string]string) models.NonCapacitySailing {
+	// Pre-calculate dwell time for vessel lookup
+	// We need to estimate this before building legs since BuildLegs needs it for time calculations
+	sailingDurationMin := models.ParseDurationMin(sailingDuration)
+
+	// Count stops/transfers (exclude thruFares)
+	stopCount := 0
+	for _, event := range events {
+		if event.Type == "stop" || event.Type == "transfer" {
+			stopCount++
+		}
 	}
 
-    clean := func(s string) string {
-        s = strings.ReplaceAll(s, "\u00a0", " ") // NBSP -> space
-        return strings.TrimSpace(s)
-    }
-
-    // Parse a list of month/day mentions from a status string like
-    // "Only on Sep 14, 28 & Oct 12" or "Except on Oct 13".
-    // Returns a set keyed by "MM-DD" for quick lookup.
-    parseMentionedDates := func(note string, year int) map[string]struct{} {
-        res := make(map[string]struct{})
-        if note == "" {
-            return res
-        }
-        lower := strings.ToLower(note)
-
-        monthMap := map[string]time.Month{
-            "jan": time.January, "january": time.January,
-            "feb": time.February, "february": time.February,
-            "mar": time.March, "march": time.March,
-            "apr": time.April, "april": time.April,
-            "may": time.May,
-            "jun": time.June, "june": time.June,
-            "jul": time.July, "july": time.July,
-            "aug": time.August, "august": time.August,
-            "sep": time.September, "sept": time.September, "september": time.September,
-            "oct": time.October, "october": time.October,
-            "nov": time.November, "november": time.November,
-            "dec": time.December, "december": time.December,
-        }
-
-        // 1) Find explicit Month Day pairs
-        mdRe := regexp.MustCompile(`(?i)(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|jun(?:e)?|jul(?:y)?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\s+(\d{1,2})`)
-        matches := mdRe.FindAllStringSubmatch(lower, -1)
-
-        for _, m := range matches {
-            monKey := m[1]
-            dayStr := m[2]
-            if mon, ok := monthMap[monKey]; ok {
-                if d, err := strconv.Atoi(dayStr); err == nil {
-                    key := fmt.Sprintf("%02d-%02d", int(mon), d)
-                    res[key] = struct{}{}
-                }
-            }
-        }
-
-        // 2) Handle shorthand days following a month (e.g., "Sep 14, 28 & Oct 12")
-        //    For each segment that starts with a month, capture trailing , <day> pieces until next month appears
-        segRe := regexp.MustCompile(`(?i)(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|jun(?:e)?|jul(?:y)?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\s+\d{1,2}([^a-z]*)`)
-        pos := 0
-        for {
-            loc := segRe.FindStringSubmatchIndex(lower[pos:])
-            if loc == nil {
-                break
-            }
-            // Extract month for this segment
-            seg := lower[pos+loc[0] : pos+loc[1]]
-            mon := mdRe.FindStringSubmatch(seg)
-            if len(mon) >= 3 {
-                monKey := mon[1]
-                if monVal, ok := monthMap[monKey]; ok {
-                    // After the first "Month DD", scan the tail for , DD patterns
-                    tail := seg[len(mon[0]):]
-                    // Match bare days like ", 28" without unsupported lookaheads
-                    ddRe := regexp.MustCompile(`(?i)[,&\s]+(\d{1,2})\b`)
-                    ddMatches := ddRe.FindAllStringSubmatch(tail, -1)
-                    for _, dm := range ddMatches {
-                        if d, err := strconv.Atoi(dm[1]); err == nil {
-                            key := fmt.Sprintf("%02d-%02d", int(monVal), d)
-                            res[key] = struct{}{}
-                        }
-                    }
-                }
-            }
-            pos += loc[1]
-        }
-
-        return res
-    }
-
-    // ---- Step 4: parse rows in the found <tbody>
-    dayBody.Find("tr.schedule-table-row").Each(func(_ int, row *goquery.Selection) {
-        tds := row.Find("td")
-        if tds.Length() < 3 {
-            return
-        }
-
-        // Extract clean departure time (first time token) and any status notes
-        depCell := tds.Eq(1)
-        depRaw := clean(depCell.Text())
-
-        // Capture red/black status notes if present (e.g., Only on..., Except on..., Foot passengers only, Dangerous goods only)
-        var statuses []string
-        var redNotes []string
-        depCell.Find("p").Each(func(_ int, p *goquery.Selection) {
-            txt := clean(p.Text())
-            if txt == "" {
-                return
-            }
-            // Only keep informative notes, skip if it's just whitespace
-            // Common classes include red-text italic-style or text-black
-            if p.HasClass("red-text") || p.HasClass("text-black") {
-                statuses = append(statuses, txt)
-                if p.HasClass("red-text") {
-                    redNotes = append(redNotes, txt)
-                }
-            }
-        })
-
-        // Extract the first time-like token from the departure cell
-        depTime := depRaw
-        if re := regexp.MustCompile(`(?i)\b\d{1,2}:\d{2}\s*[ap]m\b`); re != nil {
-            if m := re.FindString(depRaw); m != "" {
-                depTime = m
-            }
-        }
-
-        // Extract clean arrival time (first time token)
-        arrCell := tds.Eq(2)
-        arrRaw := clean(arrCell.Text())
-        arrTime := arrRaw
-        if re := regexp.MustCompile(`(?i)\b\d{1,2}:\d{2}\s*[ap]m\b`); re != nil {
-            if m := re.FindString(arrRaw); m != "" {
-                arrTime = m
-            }
-        }
-
-        // Extract sailing duration from the 4th column
-        var sailingDuration string
-        if tds.Length() > 3 {
-            durationCell := tds.Eq(3)
-            sailingDuration = clean(durationCell.Text())
-        }
-
-        // Extract events from the 5th column (stops, thru fares, transfers)
-        var events []models.SailingEvent
-        if tds.Length() > 4 {
-            eventsCell := tds.Eq(4)
-            eventsCell.Find("p.mb-1").Each(func(_ int, p *goquery.Selection) {
-                // Look for the event type span
-                var eventType string
-                if p.Find(".schedule-leg-type-thru-fare").Length() > 0 {
-                    eventType = "thruFare"
-                } else if p.Find(".schedule-leg-type-stop").Length() > 0 {
-                    eventType = "stop"
-                } else if p.Find(".schedule-leg-type-transfer").Length() > 0 {
-                    eventType = "transfer"
-                }
-
-                // Extract the terminal name (the text after the link/icon)
-                terminalName := ""
-                p.Contents().Each(func(_ int, node *goquery.Selection) {
-                    // Look for <span> elements that are NOT part of the link/icon
-                    if goquery.NodeName(node) == "span" {
-                        // Skip if it's one of the icon/type spans
-                        if node.HasClass("bcf") || node.HasClass("schedule-leg-type-thru-fare") ||
-                           node.HasClass("schedule-leg-type-stop") || node.HasClass("schedule-leg-type-transfer") {
-                            return
-                        }
-                        text := clean(node.Text())
-                        if text != "" {
-                            terminalName = text
-                        }
-                    }
-                })
-
-                // Add the event if we found both type and terminal name
-                if eventType != "" && terminalName != "" {
-                    events = append(events, models.SailingEvent{
-                        Type:         eventType,
-                        TerminalName: terminalName,
-                    })
-                }
-            })
-        }
-
-        // Filter: drop dangerous goods only sailings outright
-        depLower := strings.ToLower(depCell.Text())
-        if strings.Contains(depLower, "dangerous goods only") || strings.Contains(depLower, "no passengers permitted") {
-            return
-        }
-
-        // Apply exception rules: "Only on <dates>" and "Except on <dates>"
-        // Build a combined note string from red notes
-        combinedRed := strings.ToLower(strings.Join(redNotes, "; "))
-        todayKey := fmt.Sprintf("%02d-%02d", int(today.Month()), today.Day())
-
-        // If there is an "only on" note, include only if today is listed
-        if strings.Contains(combinedRed, "only on") {
-            dates := parseMentionedDates(combinedRed, today.Year())
-            if _, ok := dates[todayKey]; !ok {
-                return
-            }
-        }
-        // If there is an "except on" note, exclude if today is listed
-        if strings.Contains(combinedRed, "except on") {
-            dates := parseMentionedDates(combinedRed, today.Year())
-            if _, ok := dates[todayKey]; ok {
-                return
-            }
-        }
-
-        // Pre-calculate dwell time for vessel lookup
-        // We need to estimate this before building legs since BuildLegs needs it for time calculations
-        sailingDurationMin := parseDurationToMinutes(sailingDuration)
-
-        // Count stops/transfers (exclude thruFares)
-        stopCount := 0
-        for _, event := range events {
-            if event.Type == "stop" || event.Type == "transfer" {
-                stopCount++
-            }
-        }
-
-        // Estimate total travel time from leg info
-        // Build a temporary route to get leg durations
-        originCode := route.RouteCode[:3]
-        destinationCode := route.RouteCode[3:]
-        estimatedTravelMin := 0
-
-        if len(events) == 0 {
-            // Direct sailing - just one leg
-            if legInfo := staticdata.GetLegInfo(originCode, destinationCode); legInfo != nil {
-                estimatedTravelMin = legInfo.AvgDurationMin
-            }
-        } else {
-            // Multi-leg sailing - estimate each leg
-            // First leg: origin to first event terminal
-            firstEventCode := staticdata.GetTerminalCodeByName(events[0].TerminalName)
-            if legInfo := staticdata.GetLegInfo(originCode, firstEventCode); legInfo != nil {
-                estimatedTravelMin += legInfo.AvgDurationMin
-            }
-
-            // Intermediate legs: between event terminals
-            for i := 0; i < len(events)-1; i++ {
-                fromCode := staticdata.GetTerminalCodeByName(events[i].TerminalName)
-                toCode := staticdata.GetTerminalCodeByName(events[i+1].TerminalName)
-                if legInfo := staticdata.GetLegInfo(fromCode, toCode); legInfo != nil {
-                    estimatedTravelMin += legInfo.AvgDurationMin
-                }
-            }
-
-            // Final leg: last event terminal to destination
-            lastEventCode := staticdata.GetTerminalCodeByName(events[len(events)-1].TerminalName)
-            if legInfo := staticdata.GetLegInfo(lastEventCode, destinationCode); legInfo != nil {
-                estimatedTravelMin += legInfo.AvgDurationMin
-            }
-        }
-
-        // Calculate estimated average dwell time
-        avgDwellMin := 0
-        estimatedDwellMin := sailingDurationMin - estimatedTravelMin
-        if stopCount > 0 && estimatedDwellMin > 0 {
-            avgDwellMin = estimatedDwellMin / stopCount
-        }
-
-        // Now build legs with vessel lookup using calculated avg dwell time
-        legs := models.BuildLegs(route.RouteCode, events, depTime, vesselDatabase, avgDwellMin)
-
-        // Check event types
-        hasStops := false
-        isThruFare := false
-        for _, event := range events {
-            if event.Type == "stop" {
-                hasStops = true
-            } else if event.Type == "thruFare" {
-                isThruFare = true
-            }
-        }
-
-        s := models.NonCapacitySailing{
-            DepartureTime:   depTime,
-            ArrivalTime:     arrTime,
-            SailingDuration: sailingDuration,
-            IsNonStop:       len(legs) == 1,
-            HasStops:        hasStops,
-            IsThruFare:      isThruFare,
-            Events:          events,
-            Legs:            legs,
-        }
-
-        // Generate unique sailing ID
-        if s.DepartureTime != "" {
-            s.ID = generateSailingID(route.RouteCode, currentDate, s.DepartureTime)
-        }
-
-        // Calculate actual dwell time (time spent at stops) from built legs
-        totalTravelMin := 0
-        for _, leg := range legs {
-            if leg.AvgDurationMin != nil {
-                totalTravelMin += *leg.AvgDurationMin
-            }
-        }
-
-        s.TotalTravelMin = totalTravelMin
-        s.TotalDwellMin = sailingDurationMin - totalTravelMin
-
-        // Calculate actual average dwell time per stop
-        if stopCount > 0 && s.TotalDwellMin > 0 {
-            avgDwell := s.TotalDwellMin / stopCount
-            s.AvgDwellPerStopMin = &avgDwell
-        }
-
-        if s.DepartureTime != "" || s.ArrivalTime != "" {
-            route.Sailings = append(route.Sailings, s)
-        }
-    })
-
-	// Optional: route-level duration (from the first row's 4th cell, if present)
-	sailingDuration := ""
+	// Estimate total travel time from leg info
+	originCode := routeCode[:3]
+	destinationCode := routeCode[3:]
+	estimatedTravelMin := 0
+
+	if len(events) == 0 {
+		// Direct sailing - just one leg
+		if legInfo := staticdata.GetLegInfo(originCode, destinationCode); legInfo != nil {
+			estimatedTravelMin = legInfo.AvgDurationMin
+		}
+	} else {
+		// Multi-leg sailing - estimate each leg
+		// First leg: origin to first event terminal
+		firstEventCode := staticdata.GetTerminalCodeByName(events[0].TerminalName)
+		if legInfo := staticdata.GetLegInfo(originCode, firstEventCode); legInfo != nil {
+			estimatedTravelMin += legInfo.AvgDurationMin
+		}
+
+		// Intermediate legs: between event terminals
+		for i := 0; i < len(events)-1; i++ {
+			fromCode := staticdata.GetTerminalCodeByName(events[i].TerminalName)
+			toCode := staticdata.GetTerminalCodeByName(events[i+1].TerminalName)
+			if legInfo := staticdata.GetLegInfo(fromCode, toCode); legInfo != nil {
+				estimatedTravelMin += legInfo.AvgDurationMin
+			}
+		}
+
+		// Final leg: last event terminal to destination
+		lastEventCode := staticdata.GetTerminalCodeByName(events[len(events)-1].TerminalName)
+		if legInfo := staticdata.GetLegInfo(lastEventCode, destinationCode); legInfo != nil {
+			estimatedTravelMin += legInfo.AvgDurationMin
+		}
+	}
+
+	// Calculate estimated average dwell time
+	avgDwellMin := 0
+	estimatedDwellMin := sailingDurationMin - estimatedTravelMin
+	if stopCount > 0 && estimatedDwellMin > 0 {
+		avgDwellMin = estimatedDwellMin / stopCount
+	}
+
+	// Now build legs with vessel lookup using calculated avg dwell time
+	legs := models.BuildLegs(routeCode, events, depTime, vesselDatabase, avgDwellMin)
+
+	// Check event types
+	hasStops := false
+	isThruFare := false
+	for _, event := range events {
+		if event.Type == "stop" {
+			hasStops = true
+		} else if event.Type == "thruFare" {
+			isThruFare = true
+		}
+	}
+
+	s := models.NonCapacitySailing{
+		DepartureTime:   depTime,
+		ArrivalTime:     arrTime,
+		SailingDuration: sailingDuration,
+		IsNonStop:       len(legs) == 1,
+		HasStops:        hasStops,
+		IsThruFare:      isThruFare,
+		Events:          events,
+		Legs:            legs,
+	}
+
+	// Calculate actual dwell time (time spent at stops) from built legs
+	totalTravelMin := 0
+	for _, leg := range legs {
+		if leg.AvgDurationMin != nil {
+			totalTravelMin += *leg.AvgDurationMin
+		}
+	}
+
+	s.TotalTravelMin = totalTravelMin
+	s.TotalDwellMin = sailingDurationMin - totalTravelMin
+
+	// Calculate actual average dwell time per stop
+	if stopCount > 0 && s.TotalDwellMin > 0 {
+		avgDwell := s.TotalDwellMin / stopCount
+		s.AvgDwellPerStopMin = &avgDwell
+	}
+
+	return s
+}
+
+/*
+ * parseRouteSailingDuration
+ *
+ * Returns the route-level sailing duration from the first row's 4th cell, if present.
+ *
+ * @param *goquery.Selection dayBody - a weekday's <tbody>
+ *
+ * @return string
+ */
+func parseRouteSailingDuration(dayBody *goquery.Selection) string {
 	if firstRow := dayBody.Find("tr.schedule-table-row").First(); firstRow.Length() > 0 {
 		if cell := firstRow.Find("td").Eq(3); cell.Length() > 0 {
-			sailingDuration = clean(cell.Text())
+			return cleanText(cell.Text())
 		}
 	}
+	return ""
+}
 
-	// ---- Step 5: save
-	sailingsJSON, err := json.Marshal(route.Sailings)
-	if err != nil {
-		log.Printf("ScrapeNonCapacityRoute: marshal error for %s: %v", route.RouteCode, err)
-		return false
+/*
+ * saveNonCapacityRoute
+ *
+ * Upserts a non-capacity route keyed by (route_code, date), records a history snapshot
+ * and publishes what changed since the previous scrape.
+ *
+ * @param models.NonCapacityRoute route
+ * @param time.Time scrapedAt - when the route was scraped
+ *
+ * @return error
+ */
+func (s *Scraper) saveNonCapacityRoute(route models.NonCapacityRoute, scrapedAt time.Time) error {
+	var previous *models.NonCapacityRoute
+	if s.events != nil {
+		previous = s.store.GetNonCapacityRoute(route.RouteCode, route.Date)
 	}
 
-	sqlStatement := `
-		INSERT INTO non_capacity_routes (
-			route_code,
-			from_terminal_code,
-			to_terminal_code,
-			date,
-			sailing_duration,
-			sailings
-		)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (route_code) DO UPDATE SET
-			from_terminal_code = EXCLUDED.from_terminal_code,
-			to_terminal_code = EXCLUDED.to_terminal_code,
-			date = EXCLUDED.date,
-			sailing_duration = EXCLUDED.sailing_duration,
-			sailings = EXCLUDED.sailings
-	`
-	_, err = db.Conn.Exec(sqlStatement,
-		route.RouteCode, route.FromTerminalCode, route.ToTerminalCode, currentDate, sailingDuration, sailingsJSON,
-	)
-	if err != nil {
-		log.Printf("ScrapeNonCapacityRoute: DB insert/update failed for %s: %v", route.RouteCode, err)
-		return false
+	if err := s.store.SaveNonCapacityRoute(route); err != nil {
+		return err
+	}
+
+	if s.events != nil {
+		s.events.Publish(events.DiffNonCapacity(previous, route, scrapedAt))
+	}
+
+	if err := s.store.SaveNonCapacitySnapshot(route, scrapedAt); err != nil {
+		log.Printf("ScrapeNonCapacityRoute: failed to save snapshot for %s on %s: %v", route.RouteCode, route.Date, err)
 	}
 
-	log.Printf("ScrapeNonCapacityRoute: ✓ %s scraped successfully with %d sailing(s)", route.RouteCode, len(route.Sailings))
-	return true
+	return nil
 }
 
 /********************/
@@ -959,73 +1209,93 @@ func ScrapeNonCapacityRoute(document *goquery.Document, fromTerminalCode, toTerm
  * BuildVesselDatabase
  *
  * Scrapes BC Ferries departures pages for all non-capacity terminals to build a database
- * of vessel names indexed by terminal code and departure time.
+ * of vessel names indexed by terminal code and departure time. Pages are fetched
+ * config.Scrape.Concurrency at a time, each within the config.Scrape.RouteTimeout
+ * deadline; a terminal whose page cannot be fetched gets no vessels.
  *
- * @param ctx context.Context - Chromedp context for rendering pages
+ * @param ctx context.Context
+ * @param Fetcher fetcher - fetcher for the departures pages
  *
  * @return map[string]map[string]string - Map of terminal code → (departure time → vessel name)
  */
-func BuildVesselDatabase(ctx context.Context) map[string]map[string]string {
+func BuildVesselDatabase(ctx context.Context, fetcher Fetcher) map[string]map[string]string {
 	log.Println("BuildVesselDatabase: Starting to build vessel database...")
 
-	vesselDB := make(map[string]map[string]string)
 	terminals := staticdata.GetNonCapacityDepartureTerminals()
+	departures := make([]map[string]string, len(terminals))
 
-	for _, terminalCode := range terminals {
-		url := fmt.Sprintf("https://www.bcferries.com/current-conditions/departures?terminalCode=%s", terminalCode)
+	// Terminals are fetched on the worker pool; each writes only its own slot
+	runParallel(len(terminals), config.Scrape.Concurrency, func(i int) {
+		terminalCode := terminals[i]
+		url := fmt.Sprintf("%s/current-conditions/departures?terminalCode=%s", config.BCFBaseURL, terminalCode)
 		log.Printf("BuildVesselDatabase: Fetching departures for terminal %s", terminalCode)
 
-		html, err := fetchWithChromedp(ctx, url)
-		if err != nil {
-			log.Printf("BuildVesselDatabase: Failed to fetch %s: %v", url, err)
-			vesselDB[terminalCode] = make(map[string]string)
-			continue
+		fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+		if config.Scrape.RouteTimeout > 0 {
+			fetchCtx, cancel = context.WithTimeout(ctx, config.Scrape.RouteTimeout)
 		}
+		defer cancel()
 
-		document, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		document, err := fetcher.Fetch(fetchCtx, url, nil)
 		if err != nil {
-			log.Printf("BuildVesselDatabase: Failed to parse HTML for %s: %v", terminalCode, err)
-			vesselDB[terminalCode] = make(map[string]string)
-			continue
+			log.Printf("BuildVesselDatabase: Failed to fetch %s: %v", url, err)
+			departures[i] = make(map[string]string)
+			return
 		}
 
-		// Initialize map for this terminal
-		vesselDB[terminalCode] = make(map[string]string)
-		sailingCount := 0
-
-		// Find all sailing rows across all tables on the page
-		document.Find("tr.padding-departures-td").Each(func(i int, row *goquery.Selection) {
-			// Extract vessel name from first column
-			vesselName := strings.TrimSpace(row.Find("td").Eq(0).Find("a[href*='/on-the-ferry/our-fleet/']").Text())
-
-			// Extract SCHEDULED time from second column
-			scheduledTime := ""
-			row.Find("td").Eq(1).Find("ul.departures-time-ul").Each(func(j int, ul *goquery.Selection) {
-				// Look for the UL that contains "SCHEDULED:"
-				if strings.Contains(ul.Text(), "SCHEDULED:") {
-					// Extract the time from the span
-					timeText := strings.TrimSpace(ul.Find("span.text-lowercase").Text())
-					if timeText != "" {
-						// Convert to lowercase (e.g., "7:10 AM" → "7:10 am")
-						scheduledTime = strings.ToLower(timeText)
-					}
-				}
-			})
-
-			// Only store if we found both vessel name and scheduled time
-			if vesselName != "" && scheduledTime != "" {
-				vesselDB[terminalCode][scheduledTime] = vesselName
-				sailingCount++
-			}
-		})
+		departures[i] = ParseDepartures(document)
+		log.Printf("BuildVesselDatabase: Terminal %s - extracted %d sailings", terminalCode, len(departures[i]))
+	})
 
-		log.Printf("BuildVesselDatabase: Terminal %s - extracted %d sailings", terminalCode, sailingCount)
+	vesselDB := make(map[string]map[string]string)
+	for i, terminalCode := range terminals {
+		vesselDB[terminalCode] = departures[i]
 	}
 
 	log.Printf("BuildVesselDatabase: Completed! Built database for %d terminals", len(vesselDB))
 	return vesselDB
 }
 
+/*
+ * ParseDepartures
+ *
+ * Parses a terminal's departures page into a map of scheduled departure time → vessel name.
+ *
+ * @param *goquery.Document document - departures page for one terminal
+ *
+ * @return map[string]string - e.g. "7:10 am" → "Queen of Cumberland"
+ */
+func ParseDepartures(document *goquery.Document) map[string]string {
+	departures := make(map[string]string)
+
+	// Find all sailing rows across all tables on the page
+	document.Find("tr.padding-departures-td").Each(func(i int, row *goquery.Selection) {
+		// Extract vessel name from first column
+		vesselName := strings.TrimSpace(row.Find("td").Eq(0).Find("a[href*='/on-the-ferry/our-fleet/']").Text())
+
+		// Extract SCHEDULED time from second column
+		scheduledTime := ""
+		row.Find("td").Eq(1).Find("ul.departures-time-ul").Each(func(j int, ul *goquery.Selection) {
+			// Look for the UL that contains "SCHEDULED:"
+			if strings.Contains(ul.Text(), "SCHEDULED:") {
+				// Extract the time from the span
+				timeText := strings.TrimSpace(ul.Find("span.text-lowercase").Text())
+				if timeText != "" {
+					// Convert to lowercase (e.g., "7:10 AM" → "7:10 am")
+					scheduledTime = strings.ToLower(timeText)
+				}
+			}
+		})
+
+		// Only store if we found both vessel name and scheduled time
+		if vesselName != "" && scheduledTime != "" {
+			departures[scheduledTime] = vesselName
+		}
+	})
+
+	return departures
+}
+
 /*
  * FindVesselByTimeWindow
  *
@@ -1112,26 +1382,121 @@ func FindVesselByTimeWindow(vesselDB map[string]string, targetTime string, windo
 }
 
 /*
- * fetchWithChromedp
+ * normalizeDay
+ *
+ * Normalizes a weekday label for comparison: upper case, trimmed, with a trailing
+ * "S" treated as optional (MONDAY == MONDAYS).
+ *
+ * @param string s - e.g. "Mondays"
+ *
+ * @return string - e.g. "MONDAY"
+ */
+func normalizeDay(s string) string {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	return strings.TrimSuffix(s, "S")
+}
+
+/*
+ * collapseSpaces
+ *
+ * Trims a string and collapses runs of whitespace into single spaces.
+ *
+ * @param string s
+ *
+ * @return string
+ */
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+/*
+ * cleanText
+ *
+ * Replaces non-breaking spaces and trims surrounding whitespace.
+ *
+ * @param string s
+ *
+ * @return string
+ */
+func cleanText(s string) string {
+	s = strings.ReplaceAll(s, "\u00a0", " ") // NBSP -> space
+	return strings.TrimSpace(s)
+}
+
+// Month name (or abbreviation) → month, used when parsing schedule notes
+var noteMonths = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+var (
+	noteMonthDayRe = regexp.MustCompile(`(?i)(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|jun(?:e)?|jul(?:y)?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\s+(\d{1,2})`)
+	noteSegmentRe  = regexp.MustCompile(`(?i)(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|jun(?:e)?|jul(?:y)?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\s+\d{1,2}([^a-z]*)`)
+	noteBareDayRe  = regexp.MustCompile(`(?i)[,&\s]+(\d{1,2})\b`)
+)
+
+/*
+ * parseMentionedDates
  *
- * Uses a headless Chrome browser to fetch and render the full HTML content of a given URL.
- * This is used to bypass JavaScript-based protections like Queue-it by executing the page
- * in a real browser environment.
+ * Parses a list of month/day mentions from a status string like
+ * "Only on Sep 14, 28 & Oct 12" or "Except on Oct 13".
  *
- * @param string url - The URL to navigate to
+ * @param string note - the schedule note
  *
- * @return string - The full outer HTML of the rendered page
- * @return error - Any error encountered during navigation or retrieval
+ * @return map[string]struct{} - set keyed by "MM-DD" for quick lookup
  */
-func fetchWithChromedp(ctx context.Context, url string) (string, error) {
-	var html string
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(url),
-		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.OuterHTML("html", &html),
-	)
-
-	return html, err
+func parseMentionedDates(note string) map[string]struct{} {
+	res := make(map[string]struct{})
+	if note == "" {
+		return res
+	}
+	lower := strings.ToLower(note)
+
+	// 1) Find explicit Month Day pairs
+	for _, m := range noteMonthDayRe.FindAllStringSubmatch(lower, -1) {
+		if mon, ok := noteMonths[m[1]]; ok {
+			if d, err := strconv.Atoi(m[2]); err == nil {
+				res[fmt.Sprintf("%02d-%02d", int(mon), d)] = struct{}{}
+			}
+		}
+	}
+
+	// 2) Handle shorthand days following a month (e.g., "Sep 14, 28 & Oct 12")
+	//    For each segment that starts with a month, capture trailing , <day> pieces until next month appears
+	pos := 0
+	for {
+		loc := noteSegmentRe.FindStringSubmatchIndex(lower[pos:])
+		if loc == nil {
+			break
+		}
+		// Extract month for this segment
+		seg := lower[pos+loc[0] : pos+loc[1]]
+		mon := noteMonthDayRe.FindStringSubmatch(seg)
+		if len(mon) >= 3 {
+			if monVal, ok := noteMonths[mon[1]]; ok {
+				// After the first "Month DD", scan the tail for , DD patterns
+				tail := seg[len(mon[0]):]
+				for _, dm := range noteBareDayRe.FindAllStringSubmatch(tail, -1) {
+					if d, err := strconv.Atoi(dm[1]); err == nil {
+						res[fmt.Sprintf("%02d-%02d", int(monVal), d)] = struct{}{}
+					}
+				}
+			}
+		}
+		pos += loc[1]
+	}
+
+	return res
 }
 
 /*
@@ -1151,37 +1516,15 @@ func fetchWithChromedp(ctx context.Context, url string) (string, error) {
  * @return string - The 24-hour format time without colons (e.g., "0700", "1515")
  */
 func convertTo24HourFormat(time12h string) string {
-	// Remove "(Tomorrow)" suffix if present
-	time12h = strings.ReplaceAll(time12h, "(Tomorrow)", "")
-	time12h = strings.TrimSpace(time12h)
-
-	// Parse the time using Go's time package
-	// Expected formats: "3:04 pm", "3:04 PM", "03:04 pm", etc.
-	layouts := []string{
-		"3:04 pm",
-		"3:04 PM",
-		"03:04 pm",
-		"03:04 PM",
-		"3:04pm",
-		"3:04PM",
-	}
-
-	var parsedTime time.Time
-	var err error
-	for _, layout := range layouts {
-		parsedTime, err = time.Parse(layout, time12h)
-		if err == nil {
-			break
-		}
-	}
-
-	if err != nil {
-		log.Printf("convertTo24HourFormat: failed to parse time '%s': %v", time12h, err)
+	minutes, ok := models.ParseClock(time12h)
+	if !ok {
+		log.Printf("convertTo24HourFormat: failed to parse time '%s'", time12h)
 		return "0000"
 	}
 
 	// Format as 24-hour time without colons
-	return parsedTime.Format("1504")
+	minutes %= 1440
+	return fmt.Sprintf("%02d%02d", minutes/60, minutes%60)
 }
 
 /*
@@ -1203,55 +1546,3 @@ func generateSailingID(routeCode, date, departureTime string) string {
 	time24h := convertTo24HourFormat(departureTime)
 	return fmt.Sprintf("%s-%s-%s", routeCode, date, time24h)
 }
-
-/*
- * parseDurationToMinutes
- *
- * Converts a duration string to total minutes.
- * Handles multiple formats from BC Ferries schedules.
- *
- * Examples:
- *   "2h 15m" → 135
- *   "1h 30m" → 90
- *   "55m" → 55
- *   "01:40" → 100
- *   "00:50" → 50
- *
- * @param string duration - Duration string in various formats
- *
- * @return int - Total duration in minutes (0 if parsing fails)
- */
-func parseDurationToMinutes(duration string) int {
-	if duration == "" {
-		return 0
-	}
-
-	totalMinutes := 0
-
-	// Format 1: "2h 15m" or "1h" or "45m"
-	hoursMatch := regexp.MustCompile(`(\d+)h`).FindStringSubmatch(duration)
-	if len(hoursMatch) > 1 {
-		hours, _ := strconv.Atoi(hoursMatch[1])
-		totalMinutes += hours * 60
-	}
-
-	minutesMatch := regexp.MustCompile(`(\d+)m`).FindStringSubmatch(duration)
-	if len(minutesMatch) > 1 {
-		minutes, _ := strconv.Atoi(minutesMatch[1])
-		totalMinutes += minutes
-	}
-
-	// Format 2: "01:40" (HH:MM)
-	if totalMinutes == 0 && strings.Contains(duration, ":") {
-		parts := strings.Split(duration, ":")
-		if len(parts) == 2 {
-			hours, err1 := strconv.Atoi(parts[0])
-			minutes, err2 := strconv.Atoi(parts[1])
-			if err1 == nil && err2 == nil {
-				totalMinutes = hours*60 + minutes
-			}
-		}
-	}
-
-	return totalMinutes
-}
@@ -0,0 +1,248 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Run `go test ./cmd/scraper -update` to regenerate the golden files after an intentional parser change
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func loadFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open fixture %s: %v", name, err)
+	}
+	defer f.Close()
+
+	document, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatalf("parse fixture %s: %v", name, err)
+	}
+	return document
+}
+
+func assertGolden(t *testing.T, name string, got interface{}) {
+	t.Helper()
+
+	actual, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal %s: %v", name, err)
+	}
+	actual = append(actual, '\n')
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatalf("write golden %s: %v", name, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden %s (run with -update to create it): %v", name, err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("%s mismatch (run with -update to accept)\n--- got ---\n%s\n--- want ---\n%s", name, actual, expected)
+	}
+}
+
+func pacificDate(t *testing.T, year int, month time.Month, day, hour int) time.Time {
+	t.Helper()
+	return time.Date(year, month, day, hour, 0, 0, 0, models.Pacific())
+}
+
+func TestParseCapacityRoute(t *testing.T) {
+	var fetched []string
+	fetchDetails := func(link string) (*goquery.Document, error) {
+		fetched = append(fetched, link)
+		return loadFixture(t, "capacity/vehicle-info.html"), nil
+	}
+
+	document := loadFixture(t, "capacity/TSA-SWB.html")
+	route := ParseCapacityRoute(document, "TSA", "SWB", pacificDate(t, 2025, time.October, 20, 12), fetchDetails)
+
+	assertGolden(t, "capacity/TSA-SWB.golden.json", route)
+
+	wantFetched := []string{"https://www.bcferries.com/current-conditions/vehicle-info?route=TSA-SWB&sailing=5:00%20pm"}
+	if !reflect.DeepEqual(fetched, wantFetched) {
+		t.Errorf("fetched details %v, want %v", fetched, wantFetched)
+	}
+}
+
+func TestParseCapacityRouteDetailsUnavailable(t *testing.T) {
+	fetchDetails := func(link string) (*goquery.Document, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+
+	document := loadFixture(t, "capacity/TSA-SWB.html")
+	route := ParseCapacityRoute(document, "TSA", "SWB", pacificDate(t, 2025, time.October, 20, 12), fetchDetails)
+
+	if len(route.Sailings) != 8 {
+		t.Fatalf("got %d sailings, want 8", len(route.Sailings))
+	}
+	if s := route.Sailings[6]; s.DepartureTime != "5:00 pm" || s.Fill != 0 || s.CarFill != 0 || s.OversizeFill != 0 {
+		t.Errorf("sailing with unavailable details = %+v, want 5:00 pm with no fill", s)
+	}
+}
+
+func TestParseNonCapacityRoute(t *testing.T) {
+	fall := loadFixture(t, "noncapacity/TSA-POB.html")
+	spring := loadFixture(t, "noncapacity/TSA-POB-2026-04-01.html")
+
+	options := parseScheduleSeasons(fall, "TSAPOB")
+	if len(options) != 2 {
+		t.Fatalf("got %d seasons, want 2: %+v", len(options), options)
+	}
+
+	pages := []SchedulePage{
+		{Season: currentSeason(options, pacificDate(t, 2025, time.October, 20, 12)).Season, Document: fall},
+		{Season: options[1].Season, Document: spring},
+	}
+
+	vesselDB := map[string]map[string]string{
+		"TSA": ParseDepartures(loadFixture(t, "departures/TSA.html")),
+	}
+
+	tests := []struct {
+		name     string
+		date     time.Time
+		vesselDB map[string]map[string]string
+		golden   string
+	}{
+		// Monday listed in the "Only on" note, with today's vessel assignments
+		{"only-on date", pacificDate(t, 2025, time.October, 20, 0), vesselDB, "noncapacity/TSA-POB-2025-10-20.golden.json"},
+		// Monday listed in both the "Only on" and "Except on" notes
+		{"except-on date", pacificDate(t, 2025, time.October, 27, 0), nil, "noncapacity/TSA-POB-2025-10-27.golden.json"},
+		// Day heading without a data-schedule-day attribute
+		{"text day heading", pacificDate(t, 2025, time.October, 21, 0), nil, "noncapacity/TSA-POB-2025-10-21.golden.json"},
+		// No sailings on Saturdays
+		{"no sailings", pacificDate(t, 2025, time.October, 18, 0), nil, "noncapacity/TSA-POB-2025-10-18.golden.json"},
+		// Last day of the fall season, then the first Monday of the spring season
+		{"before changeover", pacificDate(t, 2026, time.March, 30, 0), nil, "noncapacity/TSA-POB-2026-03-30.golden.json"},
+		{"after changeover", pacificDate(t, 2026, time.April, 6, 0), nil, "noncapacity/TSA-POB-2026-04-06.golden.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := ParseNonCapacityRoute(pages, "TSA", "POB", tt.date, tt.vesselDB)
+			if err != nil {
+				t.Fatalf("ParseNonCapacityRoute: %v", err)
+			}
+			assertGolden(t, tt.golden, route)
+		})
+	}
+
+	if _, err := ParseNonCapacityRoute(pages, "TSA", "POB", pacificDate(t, 2026, time.July, 6, 0), nil); err != ErrDateNotCovered {
+		t.Errorf("date past the last season: got error %v, want %v", err, ErrDateNotCovered)
+	}
+
+	empty := []SchedulePage{{Document: loadFixture(t, "capacity/vehicle-info.html")}}
+	if _, err := ParseNonCapacityRoute(empty, "TSA", "POB", pacificDate(t, 2025, time.October, 20, 0), nil); err != ErrScheduleNotFound {
+		t.Errorf("page without schedule: got error %v, want %v", err, ErrScheduleNotFound)
+	}
+}
+
+func TestParseDepartures(t *testing.T) {
+	got := ParseDepartures(loadFixture(t, "departures/TSA.html"))
+	want := map[string]string{
+		"7:10 am": "Queen of Cumberland",
+		"2:15 pm": "Queen of Cumberland",
+		"8:00 pm": "Salish Eagle",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDepartures = %v, want %v", got, want)
+	}
+}
+
+func TestParseScheduleSeasons(t *testing.T) {
+	options := parseScheduleSeasons(loadFixture(t, "noncapacity/TSA-POB.html"), "TSAPOB")
+	if len(options) != 2 {
+		t.Fatalf("got %d seasons, want 2: %+v", len(options), options)
+	}
+
+	first, second := options[0], options[1]
+	if first.Season.EffectiveFrom != "2025-10-14" || first.Season.EffectiveTo != "2026-03-31" || !first.Selected {
+		t.Errorf("first season = %+v", first)
+	}
+	if second.Season.EffectiveFrom != "2026-04-01" || second.Season.EffectiveTo != "2026-06-23" || second.Selected {
+		t.Errorf("second season = %+v", second)
+	}
+	if want := "https://www.bcferries.com/routes-fares/schedules/seasonal/TSA-POB?departureDate=2026-04-01"; second.Link != want {
+		t.Errorf("second season link = %q, want %q", second.Link, want)
+	}
+}
+
+func TestParseSeasonRange(t *testing.T) {
+	tests := []struct {
+		text     string
+		from, to string
+		ok       bool
+	}{
+		{"October 14, 2025 - March 31, 2026", "2025-10-14", "2026-03-31", true},
+		{"Oct 14 – Mar 31, 2026", "2025-10-14", "2026-03-31", true},
+		{"Sep 2, 2025 to Oct 13, 2025", "2025-09-02", "2025-10-13", true},
+		{"Schedules valid Jun 24 - Sep 1, 2026", "2026-06-24", "2026-09-01", true},
+		{"Feb 30 - Mar 31, 2026", "", "", false},
+		{"Only on Oct 20, 27 & Nov 3", "", "", false},
+	}
+
+	for _, tt := range tests {
+		from, to, ok := parseSeasonRange(tt.text)
+		if from != tt.from || to != tt.to || ok != tt.ok {
+			t.Errorf("parseSeasonRange(%q) = (%q, %q, %v), want (%q, %q, %v)", tt.text, from, to, ok, tt.from, tt.to, tt.ok)
+		}
+	}
+}
+
+func TestParseMentionedDates(t *testing.T) {
+	tests := []struct {
+		note string
+		want []string
+	}{
+		{"Only on Sep 14, 28 & Oct 12", []string{"09-14", "09-28", "10-12"}},
+		{"Except on Oct 13", []string{"10-13"}},
+		{"only on december 24 & 31", []string{"12-24", "12-31"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		want := make(map[string]struct{})
+		for _, d := range tt.want {
+			want[d] = struct{}{}
+		}
+		if got := parseMentionedDates(tt.note); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseMentionedDates(%q) = %v, want %v", tt.note, got, want)
+		}
+	}
+}
+
+func TestConvertTo24HourFormat(t *testing.T) {
+	tests := map[string]string{
+		"7:00 am":            "0700",
+		"3:15 pm":            "1515",
+		"12:05 am":           "0005",
+		"12:30 pm":           "1230",
+		"7:00 am (Tomorrow)": "0700",
+	}
+
+	for in, want := range tests {
+		if got := convertTo24HourFormat(in); got != want {
+			t.Errorf("convertTo24HourFormat(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package scraper
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+/*
+ * SchedulePage
+ *
+ * A fetched seasonal schedule page together with the season its timetable covers.
+ * An empty EffectiveFrom/EffectiveTo means the bound is unknown (open-ended).
+ */
+type SchedulePage struct {
+	Season   models.ScheduleSeason
+	Document *goquery.Document
+}
+
+/*
+ * seasonOption
+ *
+ * A season advertised on a seasonal schedule page (e.g. in the date range selector),
+ * with the link to its timetable if the page provides one.
+ */
+type seasonOption struct {
+	Season   models.ScheduleSeason
+	Link     string
+	Selected bool
+}
+
+// Matches ranges like "October 14, 2025 - March 31, 2026", "Oct 14 – Mar 31, 2026" or "Sep 2, 2025 to Oct 13, 2025"
+var seasonRangeRe = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+(\d{1,2})(?:,?\s+(\d{4}))?\s*(?:-|–|—|to)\s*(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+(\d{1,2}),?\s+(\d{4})\b`)
+
+/*
+ * MakeSeasonalScheduleLink
+ *
+ * Builds a link to the seasonal schedule page for the season starting on a given date.
+ *
+ * @param string departure
+ * @param string destination
+ * @param string effectiveFrom - ISO date the season starts (e.g. "2025-10-14")
+ *
+ * @return string
+ */
+func MakeSeasonalScheduleLink(departure, destination, effectiveFrom string) string {
+	return MakeScheduleLink(departure, destination) + "?departureDate=" + effectiveFrom
+}
+
+/*
+ * fetchSchedulePages
+ *
+ * Fetches the default seasonal schedule page for a route and, when the page advertises
+ * an upcoming season that starts within the schedule horizon, that season's page too.
+ * Also returns every season advertised on the default page.
+ *
+ * @param context.Context ctx
+ * @param Fetcher fetcher - fetcher for the schedule pages
+ * @param string departure
+ * @param string destination
+ * @param time.Time now - scrape time in Pacific Time, used to pick the current season
+ * @param time.Time horizonEnd - last service date that will be scraped
+ *
+ * @return []SchedulePage - default page first, then upcoming seasons in start order
+ * @return []models.ScheduleSeason - all advertised seasons
+ * @return error - if the default page could not be fetched or parsed
+ */
+func fetchSchedulePages(ctx context.Context, fetcher Fetcher, departure, destination string, now, horizonEnd time.Time) ([]SchedulePage, []models.ScheduleSeason, error) {
+	routeCode := departure + destination
+	link := MakeScheduleLink(departure, destination)
+
+	document, err := fetcher.Fetch(ctx, link, validateSchedulePage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := parseScheduleSeasons(document, routeCode)
+	current := currentSeason(options, now)
+
+	pages := []SchedulePage{{Document: document}}
+	if current != nil {
+		pages[0].Season = current.Season
+	} else {
+		pages[0].Season = models.ScheduleSeason{RouteCode: routeCode}
+	}
+
+	var seasons []models.ScheduleSeason
+	for _, option := range options {
+		seasons = append(seasons, option.Season)
+	}
+
+	// Fetch upcoming seasons that take over before the end of the horizon
+	if current != nil {
+		horizonEndDate := horizonEnd.Format("2006-01-02")
+		for _, option := range options {
+			if option.Season.EffectiveFrom <= current.Season.EffectiveTo || option.Season.EffectiveFrom > horizonEndDate {
+				continue
+			}
+
+			seasonLink := option.Link
+			if seasonLink == "" {
+				seasonLink = MakeSeasonalScheduleLink(departure, destination, option.Season.EffectiveFrom)
+			}
+
+			seasonDocument, err := fetcher.Fetch(ctx, seasonLink, validateSchedulePage)
+			if err != nil {
+				log.Printf("fetchSchedulePages: failed to fetch upcoming season %s for %s: %v", option.Season.Label, routeCode, err)
+				continue
+			}
+
+			pages = append(pages, SchedulePage{Season: option.Season, Document: seasonDocument})
+		}
+	}
+
+	return pages, seasons, nil
+}
+
+/*
+ * parseScheduleSeasons
+ *
+ * Finds the season date ranges advertised on a seasonal schedule page, such as the
+ * options of the schedule date selector or a "valid from ... to ..." heading.
+ *
+ * @param *goquery.Document document
+ * @param string routeCode - e.g. "TSAPOB"
+ *
+ * @return []seasonOption - unique seasons sorted by start date
+ */
+func parseScheduleSeasons(document *goquery.Document, routeCode string) []seasonOption {
+	byStart := make(map[string]*seasonOption)
+
+	document.Find("option, a, li, button, h1, h2, h3, h4, h5, p, span").Each(func(_ int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(cleanText(s.Text())), " ")
+		if text == "" || len(text) > 200 {
+			return
+		}
+
+		from, to, ok := parseSeasonRange(text)
+		if !ok {
+			return
+		}
+
+		option, exists := byStart[from]
+		if !exists {
+			option = &seasonOption{
+				Season: models.ScheduleSeason{
+					RouteCode:     routeCode,
+					Label:         text,
+					EffectiveFrom: from,
+					EffectiveTo:   to,
+				},
+			}
+			byStart[from] = option
+		}
+
+		// Keep the most specific label (the shortest element text containing the range)
+		if len(text) < len(option.Season.Label) {
+			option.Season.Label = text
+		}
+
+		if link := seasonLink(s); link != "" && option.Link == "" {
+			option.Link = link
+		}
+
+		if s.Is("option[selected], .active, .selected, [aria-selected='true'], [aria-current]") {
+			option.Selected = true
+		}
+	})
+
+	var options []seasonOption
+	for _, option := range byStart {
+		options = append(options, *option)
+	}
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].Season.EffectiveFrom < options[j].Season.EffectiveFrom
+	})
+
+	return options
+}
+
+/*
+ * parseSeasonRange
+ *
+ * Extracts an effective date range from text such as "October 14, 2025 - March 31, 2026".
+ * When the start year is omitted it is inferred from the end date.
+ *
+ * @param string text
+ *
+ * @return string - effective-from ISO date
+ * @return string - effective-to ISO date
+ * @return bool - false if the text contains no date range
+ */
+func parseSeasonRange(text string) (string, string, bool) {
+	m := seasonRangeRe.FindStringSubmatch(text)
+	if m == nil {
+		return "", "", false
+	}
+
+	fromMonth, fromOk := noteMonths[strings.ToLower(m[1])]
+	toMonth, toOk := noteMonths[strings.ToLower(m[4])]
+	fromDay, _ := strconv.Atoi(m[2])
+	toDay, _ := strconv.Atoi(m[5])
+	toYear, _ := strconv.Atoi(m[6])
+	if !fromOk || !toOk {
+		return "", "", false
+	}
+
+	fromYear := toYear
+	if m[3] != "" {
+		fromYear, _ = strconv.Atoi(m[3])
+	} else if fromMonth > toMonth {
+		// e.g. "Oct 14 - Mar 31, 2026" starts in 2025
+		fromYear = toYear - 1
+	}
+
+	from := time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, time.UTC)
+	to := time.Date(toYear, toMonth, toDay, 0, 0, 0, 0, time.UTC)
+	if to.Before(from) || from.Day() != fromDay || to.Day() != toDay {
+		return "", "", false
+	}
+
+	return from.Format("2006-01-02"), to.Format("2006-01-02"), true
+}
+
+/*
+ * seasonLink
+ *
+ * Returns the absolute URL of a season's timetable from a selector element,
+ * using the href of links or a URL-like value of options.
+ *
+ * @param *goquery.Selection s
+ *
+ * @return string - empty if the element does not carry a link
+ */
+func seasonLink(s *goquery.Selection) string {
+	link := s.AttrOr("href", "")
+	if link == "" {
+		link = s.AttrOr("value", "")
+	}
+	link = strings.TrimSpace(link)
+
+	switch {
+	case strings.HasPrefix(link, "http://"), strings.HasPrefix(link, "https://"):
+		return link
+	case strings.HasPrefix(link, "/"):
+		return config.BCFBaseURL + link
+	case strings.HasPrefix(link, "?"):
+		return config.BCFBaseURL + "/routes-fares/schedules/seasonal/" + link
+	}
+
+	return ""
+}
+
+/*
+ * currentSeason
+ *
+ * Picks the season the default page's timetable covers: the selected option if the
+ * page marks one, otherwise the season containing today, otherwise the earliest season.
+ *
+ * @param []seasonOption options - sorted by start date
+ * @param time.Time now - in Pacific Time, since season dates are Pacific service dates
+ *
+ * @return *seasonOption - nil if the page advertises no seasons
+ */
+func currentSeason(options []seasonOption, now time.Time) *seasonOption {
+	if len(options) == 0 {
+		return nil
+	}
+
+	for i := range options {
+		if options[i].Selected {
+			return &options[i]
+		}
+	}
+
+	today := now.Format("2006-01-02")
+	for i := range options {
+		if seasonCovers(options[i].Season, today) {
+			return &options[i]
+		}
+	}
+
+	return &options[0]
+}
+
+/*
+ * seasonCovers
+ *
+ * Reports whether a season's effective range includes a date. Empty bounds are open.
+ *
+ * @param models.ScheduleSeason season
+ * @param string date - ISO date
+ *
+ * @return bool
+ */
+func seasonCovers(season models.ScheduleSeason, date string) bool {
+	if season.EffectiveFrom != "" && date < season.EffectiveFrom {
+		return false
+	}
+	if season.EffectiveTo != "" && date > season.EffectiveTo {
+		return false
+	}
+	return true
+}
+
+/*
+ * timetableForDate
+ *
+ * Returns the timetable in effect on a date: of the timetables whose season covers the
+ * date, the one that started most recently, so dates after a changeover use the new timetable.
+ *
+ * @param []timetable timetables
+ * @param string date - ISO date
+ *
+ * @return int - index into timetables, or -1 if no timetable covers the date
+ */
+func timetableForDate(timetables []timetable, date string) int {
+	best := -1
+	for i, t := range timetables {
+		if !seasonCovers(t.season, date) {
+			continue
+		}
+		if best == -1 || t.season.EffectiveFrom > timetables[best].season.EffectiveFrom {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,164 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+/*
+ * PageValidator
+ *
+ * Checks that a fetched page has the content the caller is about to parse. A failed
+ * check is retried like a failed request, so a waiting-room page or a partial render is
+ * never parsed (and saved) as a route without sailings.
+ */
+type PageValidator func(document *goquery.Document) error
+
+/*
+ * InvalidPageError
+ *
+ * Returned by fetchers for pages that failed validation, e.g. a Queue-it waiting room.
+ */
+type InvalidPageError struct {
+	URL    string
+	Reason string
+}
+
+func (e *InvalidPageError) Error() string {
+	return fmt.Sprintf("invalid page %s: %s", e.URL, e.Reason)
+}
+
+// Pages served instead of the requested one, matched against the lower-cased <title> and body text
+var (
+	// Queue-it waiting rooms and bot challenges, which clear up after a while
+	interstitialTitleMarkers = []string{
+		"queue-it",
+		"waiting room",
+		"just a moment...",
+		"attention required",
+		"access denied",
+	}
+	interstitialTextMarkers = []string{
+		"queue-it",
+		"you are now in line",
+		"checking your browser",
+	}
+	// Server error pages
+	errorPageMarkers = []string{
+		"service unavailable",
+		"bad gateway",
+		"gateway timeout",
+		"internal server error",
+	}
+	// Not found pages; retrying won't help
+	notFoundMarkers = []string{
+		"page not found",
+		"404 not found",
+	}
+)
+
+/*
+ * validatePage
+ *
+ * Rejects empty pages, interstitials (Queue-it, bot challenges) and error pages, then
+ * runs the caller's validator, if any.
+ *
+ * @param *goquery.Document document
+ * @param string link - used in errors
+ * @param PageValidator validate - page-specific check, may be nil
+ *
+ * @return error - *InvalidPageError, wrapped with permanent() for not found pages
+ */
+func validatePage(document *goquery.Document, link string, validate PageValidator) error {
+	invalid := func(reason string) error {
+		return &InvalidPageError{URL: link, Reason: reason}
+	}
+
+	body := document.Find("body")
+	bodyText := strings.ToLower(collapseSpaces(body.Text()))
+	if body.Children().Length() == 0 && bodyText == "" {
+		return invalid("empty page")
+	}
+
+	title := strings.ToLower(collapseSpaces(document.Find("title").Text()))
+	// Waiting rooms and error pages are short; only look at the start of real pages' text
+	if len(bodyText) > 2000 {
+		bodyText = bodyText[:2000]
+	}
+
+	for _, marker := range interstitialTitleMarkers {
+		if strings.Contains(title, marker) {
+			return invalid(fmt.Sprintf("interstitial page (%q)", marker))
+		}
+	}
+	for _, marker := range interstitialTextMarkers {
+		if strings.Contains(bodyText, marker) {
+			return invalid(fmt.Sprintf("interstitial page (%q)", marker))
+		}
+	}
+	for _, marker := range errorPageMarkers {
+		if strings.Contains(title, marker) {
+			return invalid(fmt.Sprintf("error page (%q)", marker))
+		}
+	}
+	for _, marker := range notFoundMarkers {
+		if strings.Contains(title, marker) {
+			return permanent(invalid(fmt.Sprintf("not found page (%q)", marker)))
+		}
+	}
+
+	if validate != nil {
+		if err := validate(document); err != nil {
+			return invalid(err.Error())
+		}
+	}
+
+	return nil
+}
+
+/*
+ * requireElement
+ *
+ * Builds a validator that requires at least one element matching selector.
+ *
+ * @param string selector - goquery selector
+ * @param string description - what the element is, for errors
+ *
+ * @return PageValidator
+ */
+func requireElement(selector, description string) PageValidator {
+	return func(document *goquery.Document) error {
+		if document.Find(selector).Length() == 0 {
+			return fmt.Errorf("no %s", description)
+		}
+		return nil
+	}
+}
+
+// Validators for the pages the scraper parses
+var (
+	validateCurrentConditions = requireElement("table.detail-departure-table", "departure table")
+	validateVehicleInfo       = requireElement("p.vehicle-icon-text", "vehicle space figures")
+)
+
+/*
+ * validateSchedulePage
+ *
+ * Requires a seasonal schedule table with at least one weekday.
+ *
+ * @param *goquery.Document document
+ *
+ * @return error
+ */
+func validateSchedulePage(document *goquery.Document) error {
+	scheduleTable := findScheduleTable(document)
+	if scheduleTable == nil {
+		return ErrScheduleNotFound
+	}
+	if len(findDayBodies(scheduleTable)) == 0 {
+		return fmt.Errorf("schedule table has no weekdays")
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/events"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func htmlDocument(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func TestValidatePage(t *testing.T) {
+	tests := []struct {
+		name      string
+		document  *goquery.Document
+		validate  PageValidator
+		wantValid bool
+		permanent bool
+	}{
+		{"current conditions", loadFixture(t, "capacity/TSA-SWB.html"), validateCurrentConditions, true, false},
+		{"vehicle info", loadFixture(t, "capacity/vehicle-info.html"), validateVehicleInfo, true, false},
+		{"departures", loadFixture(t, "departures/TSA.html"), nil, true, false},
+		{"seasonal schedule", loadFixture(t, "noncapacity/TSA-POB.html"), validateSchedulePage, true, false},
+		{"queue-it", loadFixture(t, "interstitial/queue-it.html"), nil, false, false},
+		{"queue-it as schedule", loadFixture(t, "interstitial/queue-it.html"), validateSchedulePage, false, false},
+		{"empty", htmlDocument(t, "<html><head></head><body>  </body></html>"), nil, false, false},
+		{"bot challenge", htmlDocument(t, "<html><head><title>Just a moment...</title></head><body><p>Checking your browser before accessing</p></body></html>"), nil, false, false},
+		{"server error", htmlDocument(t, "<html><head><title>503 Service Unavailable</title></head><body><h1>Service Unavailable</h1></body></html>"), nil, false, false},
+		{"not found", htmlDocument(t, "<html><head><title>Page Not Found | BC Ferries</title></head><body><h1>Sorry</h1></body></html>"), nil, false, true},
+		{"partial schedule render", htmlDocument(t, "<html><head><title>Seasonal Schedule | BC Ferries</title></head><body><div class=\"loading\"></div></body></html>"), validateSchedulePage, false, false},
+		{"capacity page as schedule", loadFixture(t, "capacity/TSA-SWB.html"), validateSchedulePage, false, false},
+		{"schedule page as capacity", loadFixture(t, "noncapacity/TSA-POB.html"), validateCurrentConditions, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePage(tt.document, "https://www.bcferries.com/test", tt.validate)
+			if tt.wantValid {
+				if err != nil {
+					t.Errorf("validatePage = %v, want valid", err)
+				}
+				return
+			}
+
+			var invalid *InvalidPageError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("validatePage = %v, want an *InvalidPageError", err)
+			}
+			var permanentErr *permanentError
+			if errors.As(err, &permanentErr) != tt.permanent {
+				t.Errorf("validatePage = %v, permanent = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherRetriesInterstitial(t *testing.T) {
+	queueIt, err := os.ReadFile(filepath.Join("testdata", "interstitial", "queue-it.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join("testdata", "capacity", "TSA-SWB.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The waiting room is served (with 200 OK) for the first request only
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Write(queueIt)
+			return
+		}
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(config.FetcherConfig{Timeout: time.Second, Retries: 2, RetryBackoff: time.Millisecond})
+
+	document, err := fetcher.Fetch(context.Background(), server.URL, validateCurrentConditions)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if document.Find("table.detail-departure-table").Length() == 0 || requests.Load() != 2 {
+		t.Errorf("got %d requests, want the real page on the second", requests.Load())
+	}
+
+	// Without retries the waiting room is reported, not returned
+	fetcher = NewHTTPFetcher(config.FetcherConfig{Timeout: time.Second})
+	requests.Store(0)
+	var invalid *InvalidPageError
+	if _, err := fetcher.Fetch(context.Background(), server.URL, validateCurrentConditions); !errors.As(err, &invalid) {
+		t.Errorf("Fetch = %v, want an *InvalidPageError", err)
+	}
+}
+
+// A route's saved data survives a later scrape that only gets a waiting-room page
+func TestScrapeKeepsLastKnownGoodData(t *testing.T) {
+	previous := config.Scrape
+	config.Scrape.RateLimit = 0
+	defer func() { config.Scrape = previous }()
+
+	site := t.TempDir()
+	copyFixture(t, "capacity/TSA-SWB.html", filepath.Join(site, "current-conditions", "TSA-SWB.html"))
+	copyFixture(t, "capacity/vehicle-info.html", filepath.Join(site, "current-conditions", "vehicle-info.html"))
+
+	store := db.NewMemoryStore()
+	fetcher := NewFileFetcher(site)
+	scraper := New(store, Fetchers{Capacity: fetcher, NonCapacity: fetcher}, nil)
+
+	scraper.ScrapeCapacityRoutes()
+	before := store.GetCapacitySailings()
+	if len(before) != 1 || len(before[0].Sailings) != 8 {
+		t.Fatalf("first scrape saved %+v, want TSASWB with 8 sailings", before)
+	}
+
+	copyFixture(t, "interstitial/queue-it.html", filepath.Join(site, "current-conditions", "TSA-SWB.html"))
+	scraper.ScrapeCapacityRoutes()
+
+	after := store.GetCapacitySailings()
+	if len(after) != 1 || len(after[0].Sailings) != 8 {
+		t.Errorf("after a waiting-room scrape the store has %+v, want the 8 saved sailings", after)
+	}
+	if snapshots := store.GetCapacitySnapshots("TSASWB", before[0].Date); len(snapshots) != 1 {
+		t.Errorf("got %d snapshots, want 1", len(snapshots))
+	}
+}
+
+// Scraping a route again publishes what changed on the page
+func TestScrapePublishesChanges(t *testing.T) {
+	previous := config.Scrape
+	config.Scrape.RateLimit = 0
+	defer func() { config.Scrape = previous }()
+
+	site := t.TempDir()
+	page := filepath.Join(site, "current-conditions", "TSA-SWB.html")
+	copyFixture(t, "capacity/TSA-SWB.html", page)
+	copyFixture(t, "capacity/vehicle-info.html", filepath.Join(site, "current-conditions", "vehicle-info.html"))
+
+	bus := events.NewBus(10)
+	_, stream, unsubscribe := bus.Subscribe(0, false)
+	defer unsubscribe()
+
+	fetcher := NewFileFetcher(site)
+	store := db.NewMemoryStore()
+	scraper := New(store, Fetchers{Capacity: fetcher, NonCapacity: fetcher}, bus)
+
+	// Nothing to compare the first scrape with, and nothing changes on the second
+	scraper.ScrapeCapacityRoutes()
+	scraper.ScrapeCapacityRoutes()
+	if len(stream) != 0 {
+		t.Fatalf("got %d events without changes", len(stream))
+	}
+
+	html, err := os.ReadFile(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(page, bytes.Replace(html, []byte("64%"), []byte("20%"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scraper.ScrapeCapacityRoutes()
+
+	if len(stream) != 1 {
+		t.Fatalf("got %d events, want 1", len(stream))
+	}
+	event := <-stream
+	if event.Type != models.ChangeFillChanged || len(event.SailingIDs) != 1 || event.Changes[0].From != 36 || event.Changes[0].To != 80 {
+		t.Errorf("event = %+v, want the 1:00 pm sailing's fill going from 36 to 80", event)
+	}
+
+	// Sampled on the first scrape and when the fill changed
+	history := store.GetFillHistory(event.SailingIDs[0])
+	if history == nil || len(history.Samples) != 2 || history.Samples[0].Fill != 36 || history.Samples[1].Fill != 80 {
+		t.Errorf("fill history = %+v, want samples at 36 and 80", history)
+	}
+}
+
+func copyFixture(t *testing.T, name, dest string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// hangingFetcher never answers; it returns only once its context is done
+type hangingFetcher struct{}
+
+func (hangingFetcher) Fetch(ctx context.Context, link string, validate PageValidator) (*goquery.Document, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// A departures page that never loads holds up the vessel database only until the route deadline
+func TestBuildVesselDatabaseDeadline(t *testing.T) {
+	previous := config.Scrape
+	config.Scrape.RouteTimeout = 50 * time.Millisecond
+	defer func() { config.Scrape = previous }()
+
+	done := make(chan map[string]map[string]string)
+	go func() { done <- BuildVesselDatabase(context.Background(), hangingFetcher{}) }()
+
+	select {
+	case vesselDB := <-done:
+		for terminalCode, departures := range vesselDB {
+			if len(departures) != 0 {
+				t.Errorf("terminal %s has vessels %v, want none", terminalCode, departures)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("BuildVesselDatabase did not return after the route deadline")
+	}
+}
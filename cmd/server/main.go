@@ -1,29 +1,253 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
 	"github.com/jeffcstock/bc-ferries-api/cmd/config"
 	"github.com/jeffcstock/bc-ferries-api/cmd/cron"
 	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/events"
+	"github.com/jeffcstock/bc-ferries-api/cmd/gtfs"
 	"github.com/jeffcstock/bc-ferries-api/cmd/router"
+	"github.com/jeffcstock/bc-ferries-api/cmd/scraper"
+	"github.com/jeffcstock/bc-ferries-api/cmd/staticdata"
+	"github.com/jeffcstock/bc-ferries-api/cmd/webhooks"
 )
 
 func main() {
-	// Set up environment variables, database connection
+	// Set up environment variables, storage
 	config.LoadEnv()
-	db.Init()
-	defer db.Conn.Close()
 
-	cron.SetupCron()
+	// Terminals, legs and routes, validated before anything uses them
+	if config.CatalogueFile != "" {
+		if err := staticdata.LoadCatalogueFile(config.CatalogueFile); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("INFO: Using catalogue %s", config.CatalogueFile)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "export-gtfs" {
+		exportGTFS(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+
+	store := newStore()
+	defer store.Close()
+
+	fetchers, err := scraper.NewFetchersFromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Changes the scraper finds, streamed by /v2/stream
+	bus := events.NewBus(config.StreamReplayEvents)
+
+	// POSTs the changes to matching webhook subscriptions
+	dispatcher := webhooks.NewDispatcher(store)
+	bus.AddListener(dispatcher.Notify)
+	go dispatcher.Run()
+
+	cron.SetupCron(scraper.New(store, fetchers, bus))
 
 	if config.ServerPort == "" {
 		config.ServerPort = "8080"
 		fmt.Println("INFO: No PORT environment variable detected, defaulting to " + config.ServerPort)
 	}
 
-	router := router.SetupRouter()
+	router := router.SetupRouter(store, bus)
 	http.ListenAndServe(":"+config.ServerPort, router)
 }
+
+/*
+ * newStore
+ *
+ * Creates the storage backend selected by config.Store.
+ * Exits if the PostgreSQL connection cannot be set up. With PostgreSQL, applies
+ * pending migrations if config.MigrateOnStart is set, then exits unless the schema
+ * is the version this build needs.
+ *
+ * @return db.Store
+ */
+func newStore() db.Store {
+	if config.Store == config.StoreMemory {
+		log.Println("INFO: Using in-memory store, data will not survive a restart")
+		return db.NewMemoryStore()
+	}
+
+	store, err := db.NewPostgresStore(config.DB.URL)
+	if err != nil {
+		log.Fatalf("Connecting to PostgreSQL failed: %v", err)
+	}
+
+	if config.MigrateOnStart {
+		version, err := store.Migrate(db.LatestSchemaVersion(), logMigration)
+		if err != nil {
+			log.Fatalf("Schema migration failed: %v", err)
+		}
+		log.Printf("INFO: Database schema is at version %d", version)
+	}
+	if err := store.CheckSchemaVersion(); err != nil {
+		log.Fatal(err)
+	}
+	return store
+}
+
+/*
+ * migrate
+ *
+ * The migrate command: applies or reverts the schema migrations embedded in this
+ * build, without starting the server or the scrapers.
+ *
+ *   go run ./cmd/server migrate [up] [-to VERSION]  # default: the latest version
+ *   go run ./cmd/server migrate down [-to VERSION]  # default: revert the last migration
+ *   go run ./cmd/server migrate down -to 0 -drop-all
+ *   go run ./cmd/server migrate status
+ *
+ * Reverting migration 1 drops every table, so it needs both `-to 0` and `-drop-all`.
+ *
+ * @param []string args - arguments after the command name
+ *
+ * @return void
+ */
+func migrate(args []string) {
+	action := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	to := flags.Int("to", -1, "schema version to migrate to")
+	dropAll := flags.Bool("drop-all", false, "confirm reverting migration 1, which drops every table and its data")
+	flags.Parse(args)
+
+	if config.Store != config.StorePostgres {
+		log.Fatalf("migrate: STORE=%s has no schema to migrate", config.Store)
+	}
+	store, err := db.NewPostgresStore(config.DB.URL)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	defer store.Close()
+
+	version, err := store.SchemaVersion()
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+
+	target := *to
+	switch action {
+	case "up":
+		if target < 0 {
+			target = db.LatestSchemaVersion()
+		}
+		if target < version {
+			log.Fatalf("migrate: schema is at version %d, use migrate down to go back to %d", version, target)
+		}
+	case "down":
+		if target < 0 {
+			if version == 1 {
+				log.Fatal("migrate: reverting migration 1 drops every table; run migrate down -to 0 -drop-all to do so")
+			}
+			target = max(version-1, 0)
+		}
+		if target > version {
+			log.Fatalf("migrate: schema is at version %d, use migrate up to go forward to %d", version, target)
+		}
+		if target == 0 && version > 0 && !*dropAll {
+			log.Fatal("migrate: reverting migration 1 drops every table and its data; add -drop-all to confirm")
+		}
+	case "status":
+		printMigrationStatus(store)
+		return
+	default:
+		log.Fatalf("migrate: unknown action %q, expected up, down or status", action)
+	}
+
+	version, err = store.Migrate(target, logMigration)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	log.Printf("migrate: schema is at version %d", version)
+}
+
+// logMigration logs each migration as it is applied or reverted
+func logMigration(migration db.Migration, down bool) {
+	direction := "Applying"
+	if down {
+		direction = "Reverting"
+	}
+	log.Printf("INFO: %s migration %d_%s", direction, migration.Version, migration.Name)
+}
+
+// printMigrationStatus lists this build's migrations and which the database has
+func printMigrationStatus(store *db.PostgresStore) {
+	statuses, version, err := store.MigrationStatuses()
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+
+	fmt.Printf("Schema version %d, this build's latest is %d\n", version, db.LatestSchemaVersion())
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("  %04d_%s  %s\n", status.Version, status.Name, applied)
+	}
+	if version > db.LatestSchemaVersion() {
+		fmt.Println("The schema is ahead of this build: a newer build has migrated it")
+	}
+}
+
+/*
+ * exportGTFS
+ *
+ * The export-gtfs command: writes the stored non-capacity schedules as a GTFS zip
+ * without starting the server or the scrapers.
+ *
+ *   go run ./cmd/server export-gtfs -o gtfs.zip [-start YYYY-MM-DD] [-days N]
+ *
+ * @param []string args - arguments after the command name
+ *
+ * @return void
+ */
+func exportGTFS(args []string) {
+	flags := flag.NewFlagSet("export-gtfs", flag.ExitOnError)
+	output := flags.String("o", "gtfs.zip", "file to write the feed to")
+	startDate := flags.String("start", db.CurrentServiceDate(), "first service date, YYYY-MM-DD")
+	days := flags.Int("days", config.ScheduleHorizonDays, "number of service dates to export")
+	flags.Parse(args)
+
+	start, err := time.Parse("2006-01-02", *startDate)
+	if err != nil {
+		log.Fatalf("export-gtfs: invalid -start %q, expected YYYY-MM-DD", *startDate)
+	}
+
+	store := newStore()
+	defer store.Close()
+
+	file, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("export-gtfs: %v", err)
+	}
+
+	if err := gtfs.Export(store, start, *days, file); err != nil {
+		file.Close()
+		os.Remove(*output)
+		log.Fatalf("export-gtfs: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatalf("export-gtfs: %v", err)
+	}
+
+	log.Printf("export-gtfs: wrote %s (%d day(s) from %s)", *output, *days, *startDate)
+}
@@ -0,0 +1,214 @@
+package staticdata
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// Version of the catalogue file format this build reads
+const CatalogueVersion = 1
+
+// Built-in catalogue, used unless CATALOGUE_FILE names another one
+//
+//go:embed catalogue.json
+var defaultCatalogue []byte
+
+/*
+ * Catalogue
+ *
+ * The terminals, leg distances and durations, and capacity and non-capacity routes,
+ * as read from a catalogue file. Routes are listed per departure terminal, in the
+ * order they are scraped.
+ */
+type Catalogue struct {
+	Version           int            `json:"version"`
+	Terminals         []Terminal     `json:"terminals"`
+	Legs              []CatalogueLeg `json:"legs"`
+	CapacityRoutes    []RouteGroup   `json:"capacityRoutes"`
+	NonCapacityRoutes []RouteGroup   `json:"nonCapacityRoutes"`
+
+	terminals       map[string]Terminal
+	terminalsByName map[string]string // "ServiceArea (Name)" -> code
+	legs            map[string]LegInfo
+}
+
+type CatalogueLeg struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	DistanceKm     float64 `json:"distanceKm"`
+	AvgDurationMin int     `json:"avgDurationMin"`
+}
+
+type RouteGroup struct {
+	From string   `json:"from"`
+	To   []string `json:"to"`
+}
+
+var current atomic.Pointer[Catalogue]
+
+func init() {
+	catalogue, err := ParseCatalogue(defaultCatalogue)
+	if err != nil {
+		panic("staticdata: built-in catalogue: " + err.Error())
+	}
+	current.Store(catalogue)
+}
+
+/*
+ * ParseCatalogue
+ *
+ * Decodes and validates a catalogue file:
+ *   - the version is CatalogueVersion
+ *   - terminal codes are unique and coordinates are in range
+ *   - every leg endpoint is a terminal, and no leg or route pair is listed twice
+ *
+ * @param []byte data - JSON catalogue
+ *
+ * @return *Catalogue
+ * @return error - every problem found, joined
+ */
+func ParseCatalogue(data []byte) (*Catalogue, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var catalogue Catalogue
+	if err := decoder.Decode(&catalogue); err != nil {
+		return nil, fmt.Errorf("invalid catalogue: %w", err)
+	}
+	if err := catalogue.validate(); err != nil {
+		return nil, err
+	}
+	return &catalogue, nil
+}
+
+/*
+ * LoadCatalogueFile
+ *
+ * Reads a catalogue file and uses it in place of the current catalogue. Meant to be
+ * called at startup; the current catalogue is kept if the file is invalid.
+ *
+ * @param string path
+ *
+ * @return error
+ */
+func LoadCatalogueFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("catalogue %s: %w", path, err)
+	}
+	catalogue, err := ParseCatalogue(data)
+	if err != nil {
+		return fmt.Errorf("catalogue %s: %w", path, err)
+	}
+	UseCatalogue(catalogue)
+	return nil
+}
+
+/*
+ * UseCatalogue
+ *
+ * Replaces the current catalogue, e.g. with one made by ParseCatalogue
+ *
+ * @param *Catalogue catalogue
+ *
+ * @return void
+ */
+func UseCatalogue(catalogue *Catalogue) {
+	current.Store(catalogue)
+}
+
+// validate checks the catalogue and builds its lookup maps
+func (c *Catalogue) validate() error {
+	var errs []error
+
+	if c.Version != CatalogueVersion {
+		errs = append(errs, fmt.Errorf("unsupported version %d, expected %d", c.Version, CatalogueVersion))
+	}
+
+	c.terminals = make(map[string]Terminal, len(c.Terminals))
+	c.terminalsByName = make(map[string]string, len(c.Terminals))
+	for _, terminal := range c.Terminals {
+		switch {
+		case terminal.Code == "" || terminal.Name == "":
+			errs = append(errs, fmt.Errorf("terminal %q: code and name are required", terminal.Code))
+			continue
+		case c.terminals[terminal.Code].Code != "":
+			errs = append(errs, fmt.Errorf("terminal %s: listed twice", terminal.Code))
+			continue
+		}
+		if terminal.Lat < -90 || terminal.Lat > 90 || terminal.Lon < -180 || terminal.Lon > 180 {
+			errs = append(errs, fmt.Errorf("terminal %s: coordinates %v, %v out of range", terminal.Code, terminal.Lat, terminal.Lon))
+		}
+		c.terminals[terminal.Code] = terminal
+		c.terminalsByName[terminal.ServiceArea+" ("+terminal.Name+")"] = terminal.Code
+	}
+
+	c.legs = make(map[string]LegInfo, len(c.Legs))
+	for _, leg := range c.Legs {
+		key := leg.From + "-" + leg.To
+		if _, exists := c.legs[key]; exists {
+			errs = append(errs, fmt.Errorf("leg %s: listed twice", key))
+			continue
+		}
+		for _, code := range []string{leg.From, leg.To} {
+			if _, exists := c.terminals[code]; !exists {
+				errs = append(errs, fmt.Errorf("leg %s: unknown terminal %q", key, code))
+			}
+		}
+		if leg.From == leg.To {
+			errs = append(errs, fmt.Errorf("leg %s: starts and ends at the same terminal", key))
+		}
+		if leg.DistanceKm <= 0 || leg.AvgDurationMin <= 0 {
+			errs = append(errs, fmt.Errorf("leg %s: distance and duration must be positive", key))
+		}
+		c.legs[key] = LegInfo{DistanceKm: leg.DistanceKm, AvgDurationMin: leg.AvgDurationMin}
+	}
+
+	errs = append(errs, validateRoutes("capacity", c.CapacityRoutes)...)
+	errs = append(errs, validateRoutes("non-capacity", c.NonCapacityRoutes)...)
+
+	return errors.Join(errs...)
+}
+
+func validateRoutes(kind string, groups []RouteGroup) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		for _, to := range group.To {
+			pair := group.From + "-" + to
+			switch {
+			case group.From == "" || to == "":
+				errs = append(errs, fmt.Errorf("%s route %q: terminal codes are required", kind, pair))
+			case group.From == to:
+				errs = append(errs, fmt.Errorf("%s route %s: starts and ends at the same terminal", kind, pair))
+			case seen[pair]:
+				errs = append(errs, fmt.Errorf("%s route %s: listed twice", kind, pair))
+			}
+			seen[pair] = true
+		}
+	}
+	return errs
+}
+
+// routeLists flattens route groups into the departure and destination lists the scrapers use
+func routeLists(groups []RouteGroup) ([]string, [][]string) {
+	var departures []string
+	var destinations [][]string
+	index := make(map[string]int)
+	for _, group := range groups {
+		i, exists := index[group.From]
+		if !exists {
+			i = len(departures)
+			index[group.From] = i
+			departures = append(departures, group.From)
+			destinations = append(destinations, nil)
+		}
+		destinations[i] = append(destinations[i], group.To...)
+	}
+	return departures, destinations
+}
@@ -0,0 +1,95 @@
+package staticdata
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuiltInCatalogue(t *testing.T) {
+	if got := GetTerminals()["SWB"]; got.Name != "Swartz Bay" || got.ServiceArea != "Victoria" {
+		t.Errorf("SWB = %+v", got)
+	}
+	if code := GetTerminalCodeByName("Galiano Island (Sturdies Bay)"); code != "PSB" {
+		t.Errorf("GetTerminalCodeByName = %q, want PSB", code)
+	}
+	if leg := GetLegInfo("POB", "SWB"); leg == nil || leg.DistanceKm != 14 || leg.AvgDurationMin != 42 {
+		t.Errorf("GetLegInfo(POB, SWB) = %+v", leg)
+	}
+
+	departures := GetNonCapacityDepartureTerminals()
+	destinations := GetNonCapacityDestinationTerminals()
+	if len(departures) != 8 || len(destinations) != 8 || departures[1] != "SWB" {
+		t.Fatalf("non-capacity routes = %v, %v", departures, destinations)
+	}
+	if want := []string{"PSB", "PVB", "POB", "FUL", "PST", "TSA"}; !reflect.DeepEqual(destinations[1], want) {
+		t.Errorf("SWB destinations = %v, want %v", destinations[1], want)
+	}
+
+	if departures := GetCapacityDepartureTerminals(); !reflect.DeepEqual(departures, []string{"TSA", "SWB", "HSB", "DUK", "LNG", "NAN"}) {
+		t.Errorf("capacity departures = %v", departures)
+	}
+}
+
+func TestParseCatalogue(t *testing.T) {
+	valid := `{
+		"version": 1,
+		"terminals": [
+			{"code": "AAA", "name": "Alpha", "serviceArea": "Somewhere", "lat": 49, "lon": -123},
+			{"code": "BBB", "name": "Bravo", "serviceArea": "Elsewhere", "lat": 48.5, "lon": -123.5}
+		],
+		"legs": [{"from": "AAA", "to": "BBB", "distanceKm": 10, "avgDurationMin": 30}],
+		"capacityRoutes": [],
+		"nonCapacityRoutes": [{"from": "AAA", "to": ["BBB"]}, {"from": "BBB", "to": ["AAA"]}, {"from": "AAA", "to": ["CCC"]}]
+	}`
+	catalogue, err := ParseCatalogue([]byte(valid))
+	if err != nil {
+		t.Fatalf("ParseCatalogue: %v", err)
+	}
+	departures, destinations := routeLists(catalogue.NonCapacityRoutes)
+	if !reflect.DeepEqual(departures, []string{"AAA", "BBB"}) || !reflect.DeepEqual(destinations, [][]string{{"BBB", "CCC"}, {"AAA"}}) {
+		t.Errorf("routeLists = %v, %v", departures, destinations)
+	}
+
+	tests := map[string]struct {
+		old, new string
+		want     string
+	}{
+		"version":            {`"version": 1`, `"version": 2`, "unsupported version 2"},
+		"duplicate route":    {`{"from": "AAA", "to": ["CCC"]}`, `{"from": "AAA", "to": ["BBB"]}`, "non-capacity route AAA-BBB: listed twice"},
+		"unknown endpoint":   {`"to": "BBB", "distanceKm"`, `"to": "ZZZ", "distanceKm"`, `leg AAA-ZZZ: unknown terminal "ZZZ"`},
+		"latitude":           {`"lat": 49,`, `"lat": 91,`, "terminal AAA: coordinates 91, -123 out of range"},
+		"duplicate terminal": {`"code": "BBB"`, `"code": "AAA"`, "terminal AAA: listed twice"},
+		"unknown field":      {`"legs"`, `"leg"`, `unknown field "leg"`},
+	}
+	for name, tt := range tests {
+		_, err := ParseCatalogue([]byte(strings.Replace(valid, tt.old, tt.new, 1)))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadCatalogueFile(t *testing.T) {
+	builtIn := current.Load()
+	defer UseCatalogue(builtIn)
+
+	path := filepath.Join(t.TempDir(), "catalogue.json")
+	os.WriteFile(path, []byte(`{"version": 1, "terminals": [{"code": "AAA", "name": "Alpha", "lat": 49, "lon": -123}], "legs": [{"from": "AAA", "to": "BBB", "distanceKm": 1, "avgDurationMin": 1}]}`), 0o644)
+	if err := LoadCatalogueFile(path); err == nil {
+		t.Fatal("LoadCatalogueFile accepted a leg to an unknown terminal")
+	}
+	if current.Load() != builtIn {
+		t.Error("an invalid file replaced the catalogue")
+	}
+
+	os.WriteFile(path, []byte(`{"version": 1, "terminals": [{"code": "AAA", "name": "Alpha", "lat": 49, "lon": -123}], "capacityRoutes": [{"from": "AAA", "to": ["BBB"]}]}`), 0o644)
+	if err := LoadCatalogueFile(path); err != nil {
+		t.Fatalf("LoadCatalogueFile: %v", err)
+	}
+	if departures := GetCapacityDepartureTerminals(); !reflect.DeepEqual(departures, []string{"AAA"}) || len(GetTerminals()) != 1 {
+		t.Errorf("after loading: departures %v, terminals %v", departures, GetTerminals())
+	}
+}
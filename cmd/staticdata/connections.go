@@ -0,0 +1,69 @@
+package staticdata
+
+/*
+ * ConnectionTime
+ *
+ * Minimum time needed at a terminal to get off one sailing and onto another
+ */
+type ConnectionTime struct {
+	WalkOnMin  int // Foot passengers: disembark, walk back to the waiting area
+	VehicleMin int // Vehicles: offload, re-enter the lanes before the check-in cutoff
+}
+
+/*
+ * GetConnectionTime
+ *
+ * Returns the minimum connection time at a terminal. Terminals without an entry
+ * get defaultConnectionTime.
+ *
+ * @param terminalCode string - e.g. "SWB"
+ * @return ConnectionTime
+ */
+func GetConnectionTime(terminalCode string) ConnectionTime {
+	if connection, exists := connectionData[terminalCode]; exists {
+		return connection
+	}
+	return defaultConnectionTime
+}
+
+/*
+ * GetMinConnectionMin
+ *
+ * Returns the minimum connection time at a terminal in minutes for walk-on
+ * passengers or vehicles.
+ *
+ * @param terminalCode string - e.g. "SWB"
+ * @param vehicle bool - true for vehicle traffic, false for foot passengers
+ * @return int - minutes
+ */
+func GetMinConnectionMin(terminalCode string, vehicle bool) int {
+	connection := GetConnectionTime(terminalCode)
+	if vehicle {
+		return connection.VehicleMin
+	}
+	return connection.WalkOnMin
+}
+
+var defaultConnectionTime = ConnectionTime{WalkOnMin: 15, VehicleMin: 30}
+
+/*
+ * connectionData
+ *
+ * Hardcoded minimum connection times per terminal. The major terminals need more
+ * time (long walkways, check-in cutoffs); the Gulf Islands docks are small.
+ */
+var connectionData = map[string]ConnectionTime{
+	// Major terminals
+	"TSA": {WalkOnMin: 20, VehicleMin: 45},
+	"SWB": {WalkOnMin: 15, VehicleMin: 30},
+	"HSB": {WalkOnMin: 20, VehicleMin: 45},
+	"DUK": {WalkOnMin: 15, VehicleMin: 30},
+
+	// Southern Gulf Islands
+	"POB": {WalkOnMin: 10, VehicleMin: 20},
+	"PLH": {WalkOnMin: 10, VehicleMin: 20},
+	"PVB": {WalkOnMin: 10, VehicleMin: 20},
+	"PST": {WalkOnMin: 10, VehicleMin: 20},
+	"PSB": {WalkOnMin: 10, VehicleMin: 20},
+	"FUL": {WalkOnMin: 10, VehicleMin: 20},
+}
@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Sailings that leave less than this many minutes late are on time
+const OnTimeMinutes = 5
+
+// Delay histogram buckets: delays below `below` minutes that are not in an earlier bucket
+var delayBuckets = []struct {
+	label string
+	below int
+}{
+	{"early", 0},
+	{"0-4", 5},
+	{"5-9", 10},
+	{"10-14", 15},
+	{"15-29", 30},
+	{"30-59", 60},
+	{"60+", math.MaxInt},
+}
+
+/*
+ * OnTime
+ *
+ * Summarizes departure delays overall and per route, vessel and hour of the timetabled
+ * departure (Pacific Time)
+ *
+ * @param []models.SailingDeparture departures
+ * @param string from - first service date covered, for the response
+ * @param string to - last service date covered, for the response
+ *
+ * @return models.OnTimeStats - groups ordered by key
+ */
+func OnTime(departures []models.SailingDeparture, from, to string) models.OnTimeStats {
+	location := models.Pacific()
+
+	var all []int
+	byRoute := make(map[string][]int)
+	byVessel := make(map[string][]int)
+	byHour := make(map[string][]int)
+	for _, d := range departures {
+		all = append(all, d.DelayMinutes)
+		byRoute[d.RouteCode] = append(byRoute[d.RouteCode], d.DelayMinutes)
+		if d.VesselName != "" {
+			byVessel[d.VesselName] = append(byVessel[d.VesselName], d.DelayMinutes)
+		}
+		hour := fmt.Sprintf("%02d", d.ScheduledDeparture.In(location).Hour())
+		byHour[hour] = append(byHour[hour], d.DelayMinutes)
+	}
+
+	return models.OnTimeStats{
+		From:          from,
+		To:            to,
+		OnTimeMinutes: OnTimeMinutes,
+		Overall:       summarize("", all),
+		ByRoute:       summarizeGroups(byRoute),
+		ByVessel:      summarizeGroups(byVessel),
+		ByHour:        summarizeGroups(byHour),
+	}
+}
+
+/********************/
+/* Helper Functions */
+/********************/
+
+func summarizeGroups(groups map[string][]int) []models.DelayStats {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	summaries := []models.DelayStats{}
+	for _, key := range keys {
+		summaries = append(summaries, summarize(key, groups[key]))
+	}
+	return summaries
+}
+
+/*
+ * summarize
+ *
+ * Computes the on-time rate, mean, median, 90th percentile, maximum and histogram of
+ * a set of delays
+ *
+ * @param string key
+ * @param []int delays - in minutes
+ *
+ * @return models.DelayStats
+ */
+func summarize(key string, delays []int) models.DelayStats {
+	summary := models.DelayStats{Key: key, Count: len(delays), Distribution: make([]models.DelayBucket, len(delayBuckets))}
+	for i, bucket := range delayBuckets {
+		summary.Distribution[i].Label = bucket.label
+	}
+	if len(delays) == 0 {
+		return summary
+	}
+
+	sorted := append([]int(nil), delays...)
+	sort.Ints(sorted)
+
+	total, onTime := 0, 0
+	for _, delay := range sorted {
+		total += delay
+		if delay < OnTimeMinutes {
+			onTime++
+		}
+		for i, bucket := range delayBuckets {
+			if delay < bucket.below {
+				summary.Distribution[i].Count++
+				break
+			}
+		}
+	}
+
+	n := float64(len(sorted))
+	summary.OnTimeRate = math.Round(float64(onTime)/n*100) / 100
+	summary.MeanDelayMinutes = math.Round(float64(total)/n*10) / 10
+	summary.MedianDelayMinutes = percentile(sorted, 0.5)
+	summary.P90DelayMinutes = percentile(sorted, 0.9)
+	summary.MaxDelayMinutes = sorted[len(sorted)-1]
+	return summary
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int, p float64) int {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
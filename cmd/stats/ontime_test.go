@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func TestOnTime(t *testing.T) {
+	location := models.Pacific()
+	departure := func(routeCode, vessel string, hour, delay int) models.SailingDeparture {
+		return models.SailingDeparture{
+			RouteCode:          routeCode,
+			VesselName:         vessel,
+			ScheduledDeparture: time.Date(2025, 10, 20, hour, 0, 0, 0, location),
+			DelayMinutes:       delay,
+		}
+	}
+	departures := []models.SailingDeparture{
+		departure("TSASWB", "Spirit of British Columbia", 7, 4),
+		departure("TSASWB", "Spirit of British Columbia", 9, 12),
+		departure("TSASWB", "Coastal Celebration", 17, 45),
+		departure("SWBTSA", "Coastal Celebration", 7, -2),
+		departure("SWBTSA", "", 9, 0),
+	}
+
+	stats := OnTime(departures, "2025-10-20", "2025-10-20")
+
+	overall := stats.Overall
+	if overall.Count != 5 || overall.OnTimeRate != 0.6 || overall.MeanDelayMinutes != 11.8 || overall.MedianDelayMinutes != 4 || overall.P90DelayMinutes != 45 || overall.MaxDelayMinutes != 45 {
+		t.Errorf("overall = %+v", overall)
+	}
+	wantBuckets := map[string]int{"early": 1, "0-4": 2, "5-9": 0, "10-14": 1, "15-29": 0, "30-59": 1, "60+": 0}
+	for _, bucket := range overall.Distribution {
+		if bucket.Count != wantBuckets[bucket.Label] {
+			t.Errorf("bucket %s = %d, want %d", bucket.Label, bucket.Count, wantBuckets[bucket.Label])
+		}
+	}
+
+	if len(stats.ByRoute) != 2 || stats.ByRoute[0].Key != "SWBTSA" || stats.ByRoute[1].Count != 3 || stats.ByRoute[1].MedianDelayMinutes != 12 {
+		t.Errorf("by route = %+v", stats.ByRoute)
+	}
+	// The sailing without a vessel is only left out of this breakdown
+	if len(stats.ByVessel) != 2 || stats.ByVessel[0].Key != "Coastal Celebration" || stats.ByVessel[0].Count != 2 {
+		t.Errorf("by vessel = %+v", stats.ByVessel)
+	}
+	if len(stats.ByHour) != 3 || stats.ByHour[0].Key != "07" || stats.ByHour[0].Count != 2 || stats.ByHour[2].Key != "17" {
+		t.Errorf("by hour = %+v", stats.ByHour)
+	}
+
+	if empty := OnTime(nil, "2025-10-20", "2025-10-20"); empty.Overall.Count != 0 || len(empty.Overall.Distribution) != 7 || empty.ByRoute == nil {
+		t.Errorf("stats without departures = %+v", empty)
+	}
+}
@@ -0,0 +1,305 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+// Events waiting to be dispatched before new ones are dropped
+const queueSize = 256
+
+/*
+ * Dispatcher
+ *
+ * POSTs change events to the webhook subscriptions they match. Every attempt is recorded
+ * in the delivery log; failed deliveries are retried with exponential backoff.
+ */
+type Dispatcher struct {
+	store        db.Store
+	client       *http.Client
+	queue        chan models.ChangeEvent
+	maxAttempts  int
+	retryBackoff time.Duration
+}
+
+/*
+ * NewDispatcher
+ *
+ * Creates a dispatcher using config.Webhooks. Call Run to start dispatching events
+ * passed to Notify.
+ *
+ * @param db.Store store - where subscriptions are read and deliveries recorded
+ *
+ * @return *Dispatcher
+ */
+func NewDispatcher(store db.Store) *Dispatcher {
+	return &Dispatcher{
+		store:        store,
+		client:       &http.Client{Timeout: config.Webhooks.Timeout},
+		queue:        make(chan models.ChangeEvent, queueSize),
+		maxAttempts:  max(config.Webhooks.MaxAttempts, 1),
+		retryBackoff: config.Webhooks.RetryBackoff,
+	}
+}
+
+/*
+ * Notify
+ *
+ * Queues an event for dispatch without blocking, so it can be registered as an
+ * events.Bus listener. The event is dropped if the queue is full.
+ *
+ * @param models.ChangeEvent event
+ *
+ * @return void
+ */
+func (d *Dispatcher) Notify(event models.ChangeEvent) {
+	select {
+	case d.queue <- event:
+	default:
+		log.Printf("webhooks: queue full, dropping %s event %d for %s", event.Type, event.ID, event.RouteCode)
+	}
+}
+
+/*
+ * Run
+ *
+ * Dispatches queued events until the process exits. Each event is dispatched in its own
+ * goroutine so retries don't hold up the events behind it.
+ *
+ * @return void
+ */
+func (d *Dispatcher) Run() {
+	for event := range d.queue {
+		go d.Dispatch(event)
+	}
+}
+
+/*
+ * Dispatch
+ *
+ * Delivers an event to every subscription it matches, concurrently, and waits until
+ * each delivery has succeeded or run out of attempts.
+ *
+ * @param models.ChangeEvent event
+ *
+ * @return void
+ */
+func (d *Dispatcher) Dispatch(event models.ChangeEvent) {
+	var wg sync.WaitGroup
+	for _, subscription := range d.store.GetWebhookSubscriptions() {
+		matched, ok := Match(subscription, event)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(subscription models.WebhookSubscription) {
+			defer wg.Done()
+			d.deliver(subscription, matched)
+		}(subscription)
+	}
+	wg.Wait()
+}
+
+/*
+ * Match
+ *
+ * Checks an event against a subscription's filters. Fill thresholds only apply to
+ * fill-changed events, which match when a sailing's fill or car fill rises past them;
+ * the returned event then only holds those changes.
+ *
+ * @param models.WebhookSubscription subscription
+ * @param models.ChangeEvent event
+ *
+ * @return models.ChangeEvent - the event to deliver
+ * @return bool - false if the subscription does not want the event
+ */
+func Match(subscription models.WebhookSubscription, event models.ChangeEvent) (models.ChangeEvent, bool) {
+	if event.Type == models.ChangeResync {
+		return event, false
+	}
+	if len(subscription.EventTypes) > 0 && !contains(subscription.EventTypes, event.Type) {
+		return event, false
+	}
+	if len(subscription.RouteCodes) > 0 && !contains(subscription.RouteCodes, event.RouteCode) {
+		return event, false
+	}
+	if len(subscription.TerminalCodes) > 0 {
+		if len(event.RouteCode) != 6 || !(contains(subscription.TerminalCodes, event.RouteCode[:3]) || contains(subscription.TerminalCodes, event.RouteCode[3:])) {
+			return event, false
+		}
+	}
+
+	if event.Type != models.ChangeFillChanged || (subscription.FillAbove == nil && subscription.CarFillAbove == nil) {
+		return event, true
+	}
+
+	var changes []models.SailingChange
+	for _, change := range event.Changes {
+		if (change.Field == "fill" && crosses(change, subscription.FillAbove)) || (change.Field == "carFill" && crosses(change, subscription.CarFillAbove)) {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return event, false
+	}
+
+	event.Changes = changes
+	event.SailingIDs = nil
+	for _, change := range changes {
+		if !contains(event.SailingIDs, change.SailingID) {
+			event.SailingIDs = append(event.SailingIDs, change.SailingID)
+		}
+	}
+	return event, true
+}
+
+/*
+ * Sign
+ *
+ * Computes the X-Webhook-Signature header: an HMAC-SHA256 of the timestamp, a dot and
+ * the body, keyed with the subscription's secret. Receivers recompute it to check the
+ * payload came from this API and reject old timestamps to prevent replays.
+ *
+ * @param string secret
+ * @param int64 timestamp - X-Webhook-Timestamp, Unix seconds
+ * @param []byte body
+ *
+ * @return string - "sha256=" followed by the hex digest
+ */
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/********************/
+/* Helper Functions */
+/********************/
+
+/*
+ * deliver
+ *
+ * POSTs an event to a subscription, retrying network errors, 5xx and 429 responses
+ * after retryBackoff, doubled each time. Every attempt is saved to the delivery log.
+ *
+ * @param models.WebhookSubscription subscription
+ * @param models.ChangeEvent event
+ *
+ * @return void
+ */
+func (d *Dispatcher) deliver(subscription models.WebhookSubscription, event models.ChangeEvent) {
+	body, err := json.Marshal(models.WebhookPayload{SubscriptionID: subscription.ID, Event: event})
+	if err != nil {
+		log.Printf("webhooks: marshal event %d failed: %v", event.ID, err)
+		return
+	}
+
+	backoff := d.retryBackoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		delivery, retry := d.post(subscription, event, body, attempt)
+		if err := d.store.SaveWebhookDelivery(delivery); err != nil {
+			log.Printf("webhooks: save delivery of event %d to subscription %d failed: %v", event.ID, subscription.ID, err)
+		}
+		if delivery.Success || !retry || attempt == d.maxAttempts {
+			if !delivery.Success {
+				log.Printf("webhooks: giving up on event %d for subscription %d after %d attempt(s): %s", event.ID, subscription.ID, attempt, delivery.Error)
+			}
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+/*
+ * post
+ *
+ * Makes one delivery attempt
+ *
+ * @return models.WebhookDelivery - the attempt, for the delivery log
+ * @return bool - whether a failed attempt should be retried
+ */
+func (d *Dispatcher) post(subscription models.WebhookSubscription, event models.ChangeEvent, body []byte, attempt int) (models.WebhookDelivery, bool) {
+	delivery := models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Attempt:        attempt,
+		DeliveredAt:    time.Now(),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, false
+	}
+	timestamp := delivery.DeliveredAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bc-ferries-api-webhooks")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	// The same for every attempt, so receivers can ignore duplicates
+	req.Header.Set("X-Webhook-Delivery", fmt.Sprintf("%d-%d", event.ID, subscription.ID))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	delivery.DurationMs = time.Since(delivery.DeliveredAt).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, true
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Success = true
+		return delivery, false
+	}
+	delivery.Error = resp.Status
+	return delivery, resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// crosses reports whether a change went from at or below the threshold to above it
+func crosses(change models.SailingChange, threshold *int) bool {
+	if threshold == nil {
+		return false
+	}
+	from, fromOK := percent(change.From)
+	to, toOK := percent(change.To)
+	return fromOK && toOK && from <= *threshold && to > *threshold
+}
+
+func percent(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func contains(s []string, v string) bool {
+	for _, existing := range s {
+		if existing == v {
+			return true
+		}
+	}
+	return false
+}
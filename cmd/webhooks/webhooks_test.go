@@ -0,0 +1,145 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeffcstock/bc-ferries-api/cmd/config"
+	"github.com/jeffcstock/bc-ferries-api/cmd/db"
+	"github.com/jeffcstock/bc-ferries-api/cmd/models"
+)
+
+func fillEvent(routeCode string, changes ...models.SailingChange) models.ChangeEvent {
+	event := models.ChangeEvent{ID: 42, Type: models.ChangeFillChanged, RouteCode: routeCode, Date: "2025-10-20", Changes: changes}
+	for _, change := range changes {
+		event.SailingIDs = append(event.SailingIDs, change.SailingID)
+	}
+	return event
+}
+
+func TestMatch(t *testing.T) {
+	ninety := 90
+	carFill := models.WebhookSubscription{EventTypes: []string{models.ChangeFillChanged}, RouteCodes: []string{"TSASWB"}, CarFillAbove: &ninety}
+
+	event := fillEvent("TSASWB",
+		models.SailingChange{SailingID: "TSASWB-2025-10-20-0900", Field: "carFill", From: 88, To: 92},
+		models.SailingChange{SailingID: "TSASWB-2025-10-20-0900", Field: "fill", From: 70, To: 74},
+		models.SailingChange{SailingID: "TSASWB-2025-10-20-1100", Field: "carFill", From: 92, To: 95},
+	)
+	matched, ok := Match(carFill, event)
+	if !ok || len(matched.Changes) != 1 || matched.Changes[0].To != 92 || len(matched.SailingIDs) != 1 || matched.SailingIDs[0] != "TSASWB-2025-10-20-0900" {
+		t.Errorf("Match = %+v, %v, want only the 88 -> 92 car fill change", matched, ok)
+	}
+
+	// Already above the threshold
+	if _, ok := Match(carFill, fillEvent("TSASWB", event.Changes[2])); ok {
+		t.Errorf("92 -> 95 should not cross 90")
+	}
+	// Another route
+	if _, ok := Match(carFill, fillEvent("SWBTSA", event.Changes[0])); ok {
+		t.Errorf("SWBTSA should not match a TSASWB subscription")
+	}
+
+	// Any cancellation from or to Salt Spring Island (Long Harbour)
+	cancelled := models.WebhookSubscription{EventTypes: []string{models.ChangeCancelled}, TerminalCodes: []string{"SGI"}}
+	for routeCode, want := range map[string]bool{"SGITSA": true, "TSASGI": true, "TSASWB": false} {
+		event := models.ChangeEvent{Type: models.ChangeCancelled, RouteCode: routeCode}
+		if _, ok := Match(cancelled, event); ok != want {
+			t.Errorf("cancellation on %s matched = %v, want %v", routeCode, ok, want)
+		}
+	}
+	if _, ok := Match(cancelled, fillEvent("SGITSA", event.Changes[0])); ok {
+		t.Errorf("fill change should not match a cancellation subscription")
+	}
+
+	// No filters: everything but resyncs
+	if _, ok := Match(models.WebhookSubscription{}, event); !ok {
+		t.Errorf("subscription without filters should match")
+	}
+	if _, ok := Match(models.WebhookSubscription{}, models.ChangeEvent{Type: models.ChangeResync}); ok {
+		t.Errorf("resync events should not be delivered")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	config.Webhooks.Timeout = 5 * time.Second
+	config.Webhooks.MaxAttempts = 3
+	config.Webhooks.RetryBackoff = time.Millisecond
+
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	hookAttempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		hookAttempts++
+		if hookAttempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := db.NewMemoryStore()
+	retried, _ := store.CreateWebhookSubscription(models.WebhookSubscription{URL: server.URL + "/hook", Secret: "s3cret", EventTypes: []string{models.ChangeCancelled}})
+	gone, _ := store.CreateWebhookSubscription(models.WebhookSubscription{URL: server.URL + "/gone", Secret: "other", RouteCodes: []string{"TSASWB"}})
+	other, _ := store.CreateWebhookSubscription(models.WebhookSubscription{URL: server.URL + "/other", RouteCodes: []string{"HSBNAN"}})
+
+	event := models.ChangeEvent{ID: 7, Type: models.ChangeCancelled, RouteCode: "TSASWB", Date: "2025-10-20", SailingIDs: []string{"TSASWB-2025-10-20-1100"}}
+	NewDispatcher(store).Dispatch(event)
+
+	// 503 then 204 for /hook, a single 410 for /gone
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	for i, r := range requests {
+		if r.URL.Path != "/hook" {
+			continue
+		}
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		if r.Header.Get("X-Webhook-Signature") != Sign("s3cret", timestamp, bodies[i]) {
+			t.Errorf("signature %q does not match the body", r.Header.Get("X-Webhook-Signature"))
+		}
+		if r.Header.Get("X-Webhook-Event") != models.ChangeCancelled || r.Header.Get("X-Webhook-Delivery") != "7-1" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		var payload models.WebhookPayload
+		if err := json.Unmarshal(bodies[i], &payload); err != nil || payload.SubscriptionID != retried.ID || payload.Event.ID != 7 {
+			t.Errorf("payload = %s (%v)", bodies[i], err)
+		}
+	}
+
+	deliveries := store.GetWebhookDeliveries(retried.ID, 10)
+	if len(deliveries) != 2 || !deliveries[0].Success || deliveries[0].Attempt != 2 || deliveries[1].Success || deliveries[1].StatusCode != 503 {
+		t.Errorf("deliveries = %+v, want a failed attempt then a successful one", deliveries)
+	}
+	// Client errors other than 429 are not retried
+	if deliveries := store.GetWebhookDeliveries(gone.ID, 10); len(deliveries) != 1 || deliveries[0].StatusCode != 410 || deliveries[0].Error == "" {
+		t.Errorf("deliveries = %+v, want one 410", deliveries)
+	}
+	if deliveries := store.GetWebhookDeliveries(other.ID, 10); len(deliveries) != 0 {
+		t.Errorf("deliveries = %+v, want none", deliveries)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1760976000.{}' | openssl dgst -sha256 -hmac secret
+	if got, want := Sign("secret", 1760976000, []byte("{}")), "sha256=cdfa675f30ee294af56afa2e7dd719f7827f989f31de406a6d2ef8c877343e64"; got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}